@@ -19,6 +19,52 @@ SPDX-License-Identifier: Apache-2.0
 // Fabric programming model.
 // Reference: https://godoc.org/github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/gateway
 //
+// pkg/fabricgateway: Enables interaction with a FPC chaincode through the Fabric Gateway service of a peer instead of
+// the Fabric SDK Go. It is a separate Go module.
+// Reference: https://godoc.org/github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/fabricgateway
+//
+// # Interacting with a FPC chaincode
+//
+// The Contract returned by gateway.GetContract encrypts the arguments of a transaction with the chaincode encryption
+// key and decrypts the response of the enclave. Before decrypting, it checks that the response answers the request it
+// sent and is signed by an enclave registered at the FPC Enclave Registry (ERCC). To additionally re-verify the
+// attestation evidence of the enclaves locally, pass a credential verifier with contract.WithCredentialVerifier; see
+// also contract.WithExpectedMrenclave and contract.WithVerificationPolicy.
+//
+// With the additional contract.WithAttestedEncryptionKey option, the chaincode encryption key is not taken from ERCC
+// as is, but from the attested data of the enclaves whose evidence passes the local verification; the accepted key
+// is pinned per chaincode sequence.
+//
+// The chaincode encryption key, the peer endpoints, and the enclave credentials queried from ERCC are cached for
+// contract.DefaultCacheTTL (see contract.WithCacheTTL). The cache is invalidated when an enclave fails to process a
+// request or its response cannot be revealed, and when an enclave of a newer chaincode sequence responds.
+//
+// Besides EvaluateTransaction and SubmitTransaction, the contract offers Evaluate, Submit, and SubmitAsync, which take
+// a context and transaction options. The options set the arguments and optional settings of a transaction and can be
+// combined with each of these calls:
+//
+//	result, err := fpcContract.Submit(ctx, "someFunction",
+//		contract.WithArgs("someArg"),
+//		contract.WithBytesArgs(serializedMessage),
+//		contract.WithTransient(map[string][]byte{"secret": secret}),
+//		contract.WithEvent(func(event *contract.Event) { ... }))
+//
+// contract.WithBytesArgs passes binary arguments, such as serialized protobuf messages, to the chaincode as they are;
+// contract.WithTransient passes transient data as part of the encrypted request; and contract.WithEvent provides the
+// chaincode event set by the transaction. To decode a JSON or protobuf result into a typed value, use
+// contract.EvaluateJSON, contract.SubmitJSON, contract.EvaluateProto, and contract.SubmitProto.
+//
+// The deadline and cancellation of the context apply to the ERCC lookup, the __invoke, and the __endorse phase. If one
+// of these phases fails or times out, a contract.PhaseError naming the phase is returned.
+//
+// To submit a transaction without waiting for its commit, use SubmitAsync; the returned handle provides the decrypted
+// result via Result, and the commit status of the __endorse transaction via Status and Wait. A transaction committed
+// as invalid, e.g., due to a MVCC read conflict, is reported as contract.CommitError.
+//
+// As the state read by a transaction may change between __invoke and __endorse, contended chaincodes may fail with
+// read conflicts (see contract.IsReadConflict). With the contract.WithRetryPolicy option, SubmitTransaction and Submit
+// rerun such transactions with a fresh encryption context.
+//
 // # Usage samples
 //
 // $FPC_PATH/samples/application: Illustrates the use of the FPC Client SDK.
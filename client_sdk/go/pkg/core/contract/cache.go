@@ -0,0 +1,169 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+)
+
+// DefaultCacheTTL is the default time the metadata queried from ERCC is cached
+const DefaultCacheTTL = 5 * time.Minute
+
+type cacheEntry struct {
+	value  interface{}
+	expiry time.Time
+}
+
+// erccCache caches the metadata of a chaincode queried from ERCC, i.e., the chaincode encryption key, the peer
+// endpoints, and the enclave credentials. Entries expire after the TTL, and all entries are invalidated when an
+// error suggests stale metadata or when an enclave of a newer chaincode sequence is observed.
+// The mutex is not held while querying ERCC; a queried value is only cached if no invalidation happened meanwhile.
+type erccCache struct {
+	ercc        Contract
+	chaincodeID string
+	verifier    CredentialVerifier
+	ttl         time.Duration
+	now         func() time.Time
+
+	// lifecycle is used to query the chaincode definition if no expectedMrenclave is set;
+	// expectedMrenclave and policy are used for the verification of the enclave credentials with the verifier
+	lifecycle         Contract
+	expectedMrenclave string
+	policy            *protos.AttestationVerificationPolicy
+
+	mutex           sync.Mutex
+	encryptionKey   *cacheEntry
+	endpoints       *cacheEntry
+	credentialsList *cacheEntry
+	credentials     map[string]*cacheEntry
+	registrations   map[string]*cacheEntry
+	sequence        int64
+	// generation is incremented whenever the cache is invalidated
+	generation uint64
+}
+
+func newErccCache(ercc Contract, chaincodeID string, verifier CredentialVerifier, ttl time.Duration) *erccCache {
+	return &erccCache{
+		ercc:          ercc,
+		chaincodeID:   chaincodeID,
+		verifier:      verifier,
+		ttl:           ttl,
+		now:           time.Now,
+		credentials:   make(map[string]*cacheEntry),
+		registrations: make(map[string]*cacheEntry),
+	}
+}
+
+func (c *erccCache) lookup(entry *cacheEntry) (interface{}, bool) {
+	if entry == nil || !c.now().Before(entry.expiry) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// get returns the value of the entry selected by entry, if cached, and the current generation of the cache
+func (c *erccCache) get(entry func() *cacheEntry) (interface{}, uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	value, ok := c.lookup(entry())
+	return value, c.generation, ok
+}
+
+// put runs store with the mutex held unless the cache was invalidated since generation
+func (c *erccCache) put(generation uint64, store func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.generation == generation {
+		store()
+	}
+}
+
+func (c *erccCache) newEntry(value interface{}) *cacheEntry {
+	return &cacheEntry{value: value, expiry: c.now().Add(c.ttl)}
+}
+
+// getEncryptionKey returns the (base64 encoded) chaincode encryption key as returned by queryChaincodeEncryptionKey
+func (c *erccCache) getEncryptionKey() ([]byte, error) {
+	key, generation, ok := c.get(func() *cacheEntry { return c.encryptionKey })
+	if ok {
+		return key.([]byte), nil
+	}
+
+	resp, err := c.ercc.EvaluateTransaction("queryChaincodeEncryptionKey", c.chaincodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the key is not registered yet
+	if len(resp) == 0 {
+		return resp, nil
+	}
+
+	c.put(generation, func() { c.encryptionKey = c.newEntry(resp) })
+	return resp, nil
+}
+
+// getPeerEndpoints returns the endpoints of the peers hosting an enclave of the chaincode
+func (c *erccCache) getPeerEndpoints() ([]string, error) {
+	endpoints, generation, ok := c.get(func() *cacheEntry { return c.endpoints })
+	if ok {
+		return endpoints.([]string), nil
+	}
+
+	resp, err := c.ercc.EvaluateTransaction("queryChaincodeEndPoints", c.chaincodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// no enclave is registered yet
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("no enclave registered for chaincode %s", c.chaincodeID)
+	}
+
+	peerEndpoints := strings.Split(string(resp), ",")
+	c.put(generation, func() { c.endpoints = c.newEntry(peerEndpoints) })
+	return peerEndpoints, nil
+}
+
+// invalidate drops all cached entries
+func (c *erccCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.invalidateLocked()
+}
+
+func (c *erccCache) invalidateLocked() {
+	logger.Debugf("Invalidate cached ercc metadata of chaincode %s", c.chaincodeID)
+	c.encryptionKey = nil
+	c.endpoints = nil
+	c.credentialsList = nil
+	c.credentials = make(map[string]*cacheEntry)
+	c.registrations = make(map[string]*cacheEntry)
+	c.generation++
+}
+
+// observeSequence invalidates all cached entries when a newer chaincode sequence is observed.
+// It must be called with the mutex held.
+func (c *erccCache) observeSequence(sequence int64) {
+	if sequence <= c.sequence {
+		return
+	}
+
+	if c.sequence != 0 {
+		logger.Infof("Observed chaincode %s sequence %d (was %d)", c.chaincodeID, sequence, c.sequence)
+		c.invalidateLocked()
+	}
+	c.sequence = sequence
+}
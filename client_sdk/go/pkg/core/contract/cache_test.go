@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractCachesErccMetadata(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	contract, ercc := setupContract(t, enclave)
+
+	for i := 0; i < 3; i++ {
+		_, err := contract.EvaluateTransaction("someFunction", "someArg")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, countCalls(ercc, "queryChaincodeEncryptionKey"))
+	assert.Equal(t, 1, countCalls(ercc, "queryChaincodeEndPoints"))
+	assert.Equal(t, 1, countCalls(ercc, "queryEnclaveCredentials"))
+
+	// caching disabled
+	contract, ercc = setupContract(t, enclave, fpccontract.WithCacheTTL(0))
+	for i := 0; i < 3; i++ {
+		_, err := contract.EvaluateTransaction("someFunction", "someArg")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, countCalls(ercc, "queryChaincodeEncryptionKey"))
+	assert.Equal(t, 3, countCalls(ercc, "queryChaincodeEndPoints"))
+	assert.Equal(t, 3, countCalls(ercc, "queryEnclaveCredentials"))
+}
+
+func TestContractInvalidatesStaleEncryptionKey(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	contract, ercc := setupContract(t, enclave)
+
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+
+	// the enclave is replaced and registers a new chaincode encryption key
+	enclave.chaincodeEk, enclave.chaincodeDk, err = crypto.GetDefaultCSP().NewRSAKeys()
+	require.NoError(t, err)
+	enclave.erccEk = enclave.chaincodeEk
+	enclave.register(t, "myChaincode", 1)
+
+	// the request is encrypted with the cached key
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot decrypt request")
+
+	// the cache was invalidated and the new key is used
+	result, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+	assert.Equal(t, 2, countCalls(ercc, "queryChaincodeEncryptionKey"))
+	assert.Equal(t, 2, countCalls(ercc, "queryChaincodeEndPoints"))
+	assert.Equal(t, 2, countCalls(ercc, "queryEnclaveCredentials"))
+}
+
+func TestContractRefreshesOnSequenceChange(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	contract, ercc := setupContract(t, enclave)
+
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+
+	// an enclave registered for the next chaincode sequence responds
+	enclave.enclaveId = "otherEnclaveId"
+	enclave.register(t, "myChaincode", 2)
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countCalls(ercc, "queryChaincodeEncryptionKey"))
+
+	// the metadata of the previous sequence is refreshed
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, countCalls(ercc, "queryChaincodeEncryptionKey"))
+	assert.Equal(t, 2, countCalls(ercc, "queryChaincodeEndPoints"))
+	assert.Equal(t, 2, countCalls(ercc, "queryEnclaveCredentials"))
+
+	// responses of the new sequence do not invalidate the cache again
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, countCalls(ercc, "queryChaincodeEncryptionKey"))
+}
+
+func TestContractDoesNotCacheMissingEndpoints(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	enclave.peerEndpoints = ""
+	contract, ercc := setupContract(t, enclave)
+
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no enclave registered for chaincode myChaincode")
+
+	// the endpoints are queried again once an enclave is registered
+	enclave.peerEndpoints = "peer0:7051"
+	result, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+	assert.Equal(t, 2, countCalls(ercc, "queryChaincodeEndPoints"))
+}
+
+func TestContractQueriesErccConcurrently(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+
+	// the first ERCC query only returns once the concurrent invocation queries ERCC as well,
+	// which requires that the cache is not locked during queries
+	var once sync.Once
+	concurrent := make(chan struct{})
+	first := true
+	var mutex sync.Mutex
+	enclave.onErccQuery = func(name string) {
+		mutex.Lock()
+		isFirst := first
+		first = false
+		mutex.Unlock()
+		if isFirst {
+			select {
+			case <-concurrent:
+			case <-time.After(5 * time.Second):
+				t.Error("concurrent ERCC query blocked")
+			}
+			return
+		}
+		once.Do(func() { close(concurrent) })
+	}
+	contract, _ := setupContract(t, enclave)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := contract.EvaluateTransaction("someFunction", "someArg")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// CommitStatus is the final status of a transaction committed to the ledger
+type CommitStatus struct {
+	// TransactionID is the ID of the transaction; it is empty if not provided by the underlying SDK
+	TransactionID string
+	// Code is the validation code of the transaction, e.g., MVCC_READ_CONFLICT
+	Code peer.TxValidationCode
+	// BlockNumber is the number of the block containing the transaction; it is zero if not provided by the
+	// underlying SDK
+	BlockNumber uint64
+}
+
+// Successful returns true if the transaction was committed as valid
+func (s *CommitStatus) Successful() bool {
+	return s.Code == peer.TxValidationCode_VALID
+}
+
+// CommitError is returned when a transaction is committed with a validation code other than VALID
+type CommitError struct {
+	TransactionID string
+	Code          peer.TxValidationCode
+}
+
+func (e *CommitError) Error() string {
+	if e.TransactionID == "" {
+		return fmt.Sprintf("transaction committed with status %s", e.Code)
+	}
+	return fmt.Sprintf("transaction %s committed with status %s", e.TransactionID, e.Code)
+}
+
+// Commit provides the commit status of a submitted transaction
+type Commit interface {
+	// TransactionID returns the ID of the transaction, or an empty string if the ID is not known (yet)
+	TransactionID() string
+	// Status waits until the transaction is committed and returns its commit status. An error is returned if the
+	// transaction could not be submitted or the context is done before the status is known.
+	Status(ctx context.Context) (*CommitStatus, error)
+}
+
+// AsyncSubmitter is implemented by contracts that can submit a transaction without waiting for its commit; the
+// context bounds the submission. If the target contract passed to New does not implement AsyncSubmitter, SubmitAsync
+// submits __endorse in the background using SubmitTransaction.
+type AsyncSubmitter interface {
+	SubmitTransactionAsync(ctx context.Context, name string, args ...string) (Commit, error)
+}
+
+// NewCommit runs submit in the background and returns a Commit that provides the status returned by submit.
+// If submit returns a CommitError, the error is turned into the commit status.
+func NewCommit(submit func() (*CommitStatus, error)) Commit {
+	return NewCommitWithTransactionID("", submit)
+}
+
+// NewCommitWithTransactionID behaves like NewCommit but for a transaction whose ID is known before it is committed
+func NewCommitWithTransactionID(txID string, submit func() (*CommitStatus, error)) Commit {
+	c := &asyncCommit{txID: txID, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		status, err := submit()
+
+		var commitErr *CommitError
+		if errors.As(err, &commitErr) {
+			status, err = &CommitStatus{TransactionID: commitErr.TransactionID, Code: commitErr.Code}, nil
+		}
+
+		c.mutex.Lock()
+		c.status, c.err = status, err
+		c.mutex.Unlock()
+	}()
+	return c
+}
+
+type asyncCommit struct {
+	txID   string
+	done   chan struct{}
+	mutex  sync.Mutex
+	status *CommitStatus
+	err    error
+}
+
+func (c *asyncCommit) TransactionID() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.status == nil {
+		return c.txID
+	}
+	return c.status.TransactionID
+}
+
+func (c *asyncCommit) Status(ctx context.Context) (*CommitStatus, error) {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.status, c.err
+}
+
+// SubmitHandle is returned by SubmitAsync and provides the result of the transaction function and the
+// commit status of the __endorse transaction
+type SubmitHandle struct {
+	result []byte
+	commit Commit
+}
+
+// TransactionID returns the ID of the __endorse transaction, or an empty string if the ID is not known (yet).
+// Note that with SDKs that do not expose the ID on submission, the ID is only known once the transaction is
+// committed as valid.
+func (h *SubmitHandle) TransactionID() string {
+	return h.commit.TransactionID()
+}
+
+// Result returns the decrypted result of the transaction function. Note that the result is only final once the
+// transaction is committed as valid, see Wait.
+func (h *SubmitHandle) Result() []byte {
+	return h.result
+}
+
+// Status waits until the __endorse transaction is committed and returns its commit status
+func (h *SubmitHandle) Status(ctx context.Context) (*CommitStatus, error) {
+	status, err := h.commit.Status(ctx)
+	if err != nil {
+		return nil, &PhaseError{Phase: PhaseEndorse, Err: err}
+	}
+	return status, nil
+}
+
+// Wait waits until the __endorse transaction is committed and returns the decrypted result of the transaction
+// function if the transaction is valid; otherwise, a CommitError with the validation code is returned.
+func (h *SubmitHandle) Wait(ctx context.Context) ([]byte, error) {
+	status, err := h.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !status.Successful() {
+		return nil, &CommitError{TransactionID: status.TransactionID, Code: status.Code}
+	}
+
+	return h.result, nil
+}
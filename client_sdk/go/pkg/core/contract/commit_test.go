@@ -0,0 +1,179 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract/fakes"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asyncContract is a target contract that submits transactions asynchronously
+type asyncContract struct {
+	*fakes.Contract
+	commit fpccontract.Commit
+	err    error
+	args   []string
+	ctx    context.Context
+}
+
+func (c *asyncContract) SubmitTransactionAsync(ctx context.Context, name string, args ...string) (fpccontract.Commit, error) {
+	c.ctx = ctx
+	c.args = append([]string{name}, args...)
+	return c.commit, c.err
+}
+
+type asyncSubmitter interface {
+	SubmitAsync(ctx context.Context, name string, opts ...fpccontract.TransactionOption) (*fpccontract.SubmitHandle, error)
+}
+
+func newAsyncTestContract(target fpccontract.Contract, mockContract *fakes.Contract, result []byte) (asyncSubmitter, *fakes.EncryptionContext) {
+	invokeTx := &fakes.Transaction{}
+	invokeTx.EvaluateReturns([]byte("someEncryptedResponse"), nil)
+	mockContract.CreateTransactionReturns(invokeTx, nil)
+
+	mockERCC := &fakes.Contract{}
+	mockERCC.EvaluateTransactionReturns([]byte("peer1"), nil)
+
+	mockEncryptionContext := &fakes.EncryptionContext{}
+	mockEncryptionContext.ConcealBytesReturns("someEncryptedArgs", nil)
+	mockEncryptionContext.RevealReturns(asResponseBytes(result), nil)
+	mockEncryptionProvider := &fakes.EncryptionProvider{}
+	mockEncryptionProvider.NewEncryptionContextReturns(mockEncryptionContext, nil)
+
+	return fpccontract.New(target, mockERCC, nil, mockEncryptionProvider), mockEncryptionContext
+}
+
+func TestSubmitAsync(t *testing.T) {
+	expectedResult := []byte("result")
+	ctx := context.Background()
+
+	// the target submits __endorse synchronously
+	mockContract := &fakes.Contract{}
+	release := make(chan struct{})
+	mockContract.SubmitTransactionCalls(func(name string, args ...string) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+	contract, _ := newAsyncTestContract(mockContract, mockContract, expectedResult)
+
+	handle, err := contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	require.NoError(t, err)
+	assert.Equal(t, expectedResult, handle.Result())
+	assert.Empty(t, handle.TransactionID())
+
+	// not committed yet
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = handle.Status(timeoutCtx)
+	assert.EqualError(t, err, "__endorse failed: context deadline exceeded")
+
+	close(release)
+	status, err := handle.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Successful())
+	result, err := handle.Wait(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	name, args := mockContract.SubmitTransactionArgsForCall(0)
+	assert.Equal(t, "__endorse", name)
+	assert.Equal(t, []string{"someEncryptedResponse"}, args)
+
+	// the validation code is reported
+	mockContract.SubmitTransactionCalls(nil)
+	mockContract.SubmitTransactionReturns(nil, &fpccontract.CommitError{TransactionID: "someTxID", Code: peer.TxValidationCode_MVCC_READ_CONFLICT})
+	handle, err = contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	require.NoError(t, err)
+	status, err = handle.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Successful())
+	assert.Equal(t, peer.TxValidationCode_MVCC_READ_CONFLICT, status.Code)
+	assert.Equal(t, "someTxID", handle.TransactionID())
+	result, err = handle.Wait(ctx)
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "transaction someTxID committed with status MVCC_READ_CONFLICT")
+	var commitErr *fpccontract.CommitError
+	assert.True(t, errors.As(err, &commitErr))
+
+	// the submission fails
+	mockContract.SubmitTransactionReturns(nil, fmt.Errorf("some error"))
+	handle, err = contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	require.NoError(t, err)
+	_, err = handle.Wait(ctx)
+	assert.EqualError(t, err, "__endorse failed: some error")
+}
+
+func TestSubmitAsyncWithAsyncSubmitter(t *testing.T) {
+	expectedResult := []byte("result")
+	ctx := context.Background()
+
+	mockContract := &fakes.Contract{}
+	target := &asyncContract{
+		Contract: mockContract,
+		commit: fpccontract.NewCommit(func() (*fpccontract.CommitStatus, error) {
+			return &fpccontract.CommitStatus{TransactionID: "someTxID", Code: peer.TxValidationCode_VALID, BlockNumber: 42}, nil
+		}),
+	}
+	contract, mockEncryptionContext := newAsyncTestContract(target, mockContract, expectedResult)
+
+	handle, err := contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"__endorse", "someEncryptedResponse"}, target.args)
+	assert.Equal(t, 0, mockContract.SubmitTransactionCallCount())
+
+	status, err := handle.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &fpccontract.CommitStatus{TransactionID: "someTxID", Code: peer.TxValidationCode_VALID, BlockNumber: 42}, status)
+	assert.Equal(t, "someTxID", handle.TransactionID())
+	result, err := handle.Wait(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+
+	// the context is passed on to the submission, and the options apply as for Submit
+	type key struct{}
+	transient := map[string][]byte{"secret": []byte("some secret")}
+	var event *fpccontract.Event
+	mockEncryptionContext.RevealEventReturns(&protos.FPCEvent{EventName: "someEvent"}, []byte("payload"), nil)
+	_, err = contract.SubmitAsync(context.WithValue(ctx, key{}, "someValue"), "someFunction",
+		fpccontract.WithBytesArgs([]byte{0x00}), fpccontract.WithTransient(transient),
+		fpccontract.WithEvent(func(e *fpccontract.Event) { event = e }))
+	require.NoError(t, err)
+	assert.Equal(t, "someValue", target.ctx.Value(key{}))
+	_, args, m := mockEncryptionContext.ConcealBytesArgsForCall(1)
+	assert.Equal(t, [][]byte{{0x00}}, args)
+	assert.Equal(t, transient, m)
+	assert.Equal(t, &fpccontract.Event{Name: "someEvent", Payload: []byte("payload")}, event)
+
+	// the context is done
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	target.args = nil
+	_, err = contract.SubmitAsync(cancelledCtx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.EqualError(t, err, "ercc lookup failed: context canceled")
+	assert.Nil(t, target.args)
+
+	// the submission fails
+	target.err = fmt.Errorf("some error")
+	_, err = contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.EqualError(t, err, "__endorse failed: some error")
+
+	// the response cannot be revealed; __endorse is not submitted
+	target.args = nil
+	mockEncryptionContext.RevealReturns(nil, fmt.Errorf("reveal error"))
+	_, err = contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.EqualError(t, err, "reveal error")
+	assert.Nil(t, target.args)
+}
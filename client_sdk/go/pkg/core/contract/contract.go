@@ -9,10 +9,12 @@ SPDX-License-Identifier: Apache-2.0
 package contract
 
 import (
-	"strings"
+	"context"
+	"sync"
 
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/common/flogging"
 )
 
@@ -31,26 +33,145 @@ type Contract interface {
 	CreateTransaction(name string, peerEndpoints ...string) (Transaction, error)
 }
 
+// FpcContract provides the FPC operations of the contract returned by GetContract, i.e., it encrypts the chaincode
+// requests, invokes the chaincode enclave, and decrypts and verifies the responses.
+// The transaction functions are invoked with the arguments and settings given by transaction options, e.g.,
+// WithArgs, WithBytesArgs, WithTransient, and WithEvent, which can be combined with each of Evaluate, Submit, and
+// SubmitAsync.
+type FpcContract interface {
+	// Name returns the name of the smart contract
+	Name() string
+
+	// EvaluateTransaction will evaluate a transaction function and return its results.
+	// The transaction function 'name'
+	// will be evaluated on the endorsing peers but the responses will not be sent to
+	// the ordering service and hence will not be committed to the ledger.
+	// This can be used for querying the world state.
+	//  Parameters:
+	//  name is the name of the transaction function to be invoked in the smart contract.
+	//  args are the arguments to be sent to the transaction function.
+	//
+	//  Returns:
+	//  The return value of the transaction function in the smart contract.
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+
+	// SubmitTransaction will submit a transaction to the ledger. The transaction function 'name'
+	// will be evaluated on the endorsing peers and then submitted to the ordering service
+	// for committing to the ledger.
+	//  Parameters:
+	//  name is the name of the transaction function to be invoked in the smart contract.
+	//  args are the arguments to be sent to the transaction function.
+	//
+	//  Returns:
+	//  The return value of the transaction function in the smart contract.
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+
+	// Evaluate will evaluate a transaction function as EvaluateTransaction does and applies the deadline and
+	// cancellation of the context to the ERCC lookup and the __invoke evaluation.
+	// If one of these phases fails, is cancelled or times out, a PhaseError identifying the phase is returned.
+	//  Parameters:
+	//  ctx is the context that bounds the evaluation.
+	//  name is the name of the transaction function to be invoked in the smart contract.
+	//  opts are the arguments and settings of the transaction, e.g., WithArgs and WithTransient.
+	//
+	//  Returns:
+	//  The return value of the transaction function in the smart contract.
+	Evaluate(ctx context.Context, name string, opts ...TransactionOption) ([]byte, error)
+
+	// Submit will submit a transaction to the ledger as SubmitTransaction does and applies the deadline and
+	// cancellation of the context to the ERCC lookup, the __invoke evaluation, and the __endorse submission.
+	// If one of these phases fails, is cancelled or times out, a PhaseError identifying the phase is returned.
+	// Note that a transaction whose __endorse submission is cancelled may still be committed.
+	//  Parameters:
+	//  ctx is the context that bounds the submission.
+	//  name is the name of the transaction function to be invoked in the smart contract.
+	//  opts are the arguments and settings of the transaction, e.g., WithArgs and WithTransient.
+	//
+	//  Returns:
+	//  The return value of the transaction function in the smart contract.
+	Submit(ctx context.Context, name string, opts ...TransactionOption) ([]byte, error)
+
+	// SubmitAsync will submit a transaction to the ledger as Submit does but returns once the transaction is
+	// submitted, without waiting for its commit.
+	// If a transaction is committed as invalid, e.g., due to a MVCC read conflict, the validation code is reported
+	// by the commit status of the handle, and Wait returns a CommitError.
+	//  Parameters:
+	//  ctx is the context that bounds the submission until SubmitAsync returns.
+	//  name is the name of the transaction function to be invoked in the smart contract.
+	//  opts are the arguments and settings of the transaction, e.g., WithArgs and WithTransient.
+	//
+	//  Returns:
+	//  The handle that provides the return value of the transaction function, and the transaction ID and
+	//  commit status of the submitted transaction.
+	SubmitAsync(ctx context.Context, name string, opts ...TransactionOption) (*SubmitHandle, error)
+}
+
+// ContextTransaction is implemented by transactions whose evaluation can be cancelled through a context, e.g., the
+// transactions of the fabricgateway package. If the transaction created for __invoke implements ContextTransaction,
+// the context of an invocation is passed on; otherwise, a cancelled evaluation is abandoned (see PhaseError).
+type ContextTransaction interface {
+	EvaluateWithContext(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// ContextSubmitter is implemented by contracts whose submissions can be cancelled through a context. If the target
+// contract implements ContextSubmitter, the context of an invocation is passed on to the __endorse submission;
+// otherwise, a cancelled submission is abandoned (see PhaseError).
+type ContextSubmitter interface {
+	SubmitTransactionWithContext(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
 type Provider interface {
 	GetContract(id string) Contract
 }
 
 // GetContract is the factory method for creating FPC Contract objects.
+// Responses are only accepted if they are signed by an enclave registered at ERCC for the chaincode.
+// The metadata queried from ERCC (i.e., the chaincode encryption key, the peer endpoints, and the enclave credentials)
+// is cached for DefaultCacheTTL (see WithCacheTTL). The cache is invalidated when an invocation fails in a way that
+// suggests stale metadata, e.g., the enclave cannot decrypt the request, and when an enclave of a newer chaincode
+// sequence is observed.
 //
 //	Parameters:
 //	network is an initialized Fabric network object
 //	chaincodeID is the ID of the target chaincode
+//	opts are optional settings, e.g., WithCredentialVerifier or WithCacheTTL
 //
 //	Returns:
 //	The contractImpl object
-func GetContract(p Provider, chaincodeID string) *contractImpl {
+func GetContract(p Provider, chaincodeID string, opts ...Option) *contractImpl {
+	o := &options{cacheTTL: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	ercc := p.GetContract("ercc")
-	return New(p.GetContract(chaincodeID), ercc, nil, &crypto.EncryptionProviderImpl{
+	cache := newErccCache(ercc, chaincodeID, o.verifier, o.cacheTTL)
+	cache.expectedMrenclave = o.expectedMrenclave
+	cache.policy = o.policy
+	if o.verifier != nil && o.expectedMrenclave == "" {
+		// the credentials are verified against the mrenclave of the chaincode definition
+		cache.lifecycle = p.GetContract("_lifecycle")
+	}
+	ep := &crypto.EncryptionProviderImpl{
 		CSP: crypto.GetDefaultCSP(),
-		GetCcEncryptionKey: func() ([]byte, error) {
-			// Note that this function is called during EncryptionProvider.NewEncryptionContext()
-			return ercc.EvaluateTransaction("queryChaincodeEncryptionKey", chaincodeID)
-		}})
+		// Note that this function is called during EncryptionProvider.NewEncryptionContext()
+		GetCcEncryptionKey:    cache.getEncryptionKey,
+		GetEnclaveCredentials: cache.getCredentials,
+	}
+
+	if o.attestedKey {
+		ep.GetCcCredentials = cache.listCredentials
+		ep.GetCcKeyRegistration = cache.getKeyRegistration
+		ep.KeyPins = crypto.NewKeyPins()
+		if o.verifier != nil {
+			ep.VerifyCredentials = cache.verify
+		}
+	}
+
+	c := New(p.GetContract(chaincodeID), ercc, nil, ep)
+	c.cache = cache
+	c.retry = o.retry
+	return c
 }
 
 // contractImpl implements the client-side FPC protocol
@@ -59,6 +180,10 @@ type contractImpl struct {
 	ercc          Contract
 	peerEndpoints []string
 	ep            crypto.EncryptionProvider
+
+	cacheOnce sync.Once
+	cache     *erccCache
+	retry     RetryPolicy
 }
 
 func New(fpc Contract, ercc Contract, peerEndpoints []string, ep crypto.EncryptionProvider) *contractImpl {
@@ -75,56 +200,231 @@ func (c *contractImpl) Name() string {
 }
 
 func (c *contractImpl) EvaluateTransaction(name string, args ...string) ([]byte, error) {
-	ctx, err := c.ep.NewEncryptionContext()
+	return c.Evaluate(context.Background(), name, WithArgs(args...))
+}
+
+// Evaluate evaluates the transaction function with the arguments and settings given by the options, e.g., WithArgs
+// and WithTransient, and returns its result.
+// The deadline and cancellation of the context apply to the ERCC lookup and the __invoke evaluation. Failures of these
+// phases are returned as PhaseError. The context is passed on to the __invoke evaluation if the transaction
+// implements ContextTransaction; otherwise, as for the ERCC lookup, cancellation only abandons the wait for the call,
+// which keeps running in the background.
+func (c *contractImpl) Evaluate(ctx context.Context, name string, opts ...TransactionOption) ([]byte, error) {
+	o := newTransactionOptions(opts)
+	encCtx, encryptedResponse, err := c.invoke(ctx, name, o)
 	if err != nil {
 		return nil, err
 	}
 
-	encryptedRequest, err := ctx.Conceal(name, args)
+	result, event, err := c.revealResult(encCtx, encryptedResponse, o)
 	if err != nil {
 		return nil, err
 	}
 
-	// call __invoke
-	encryptedResponse, err := c.evaluateTransaction(encryptedRequest)
+	o.handleEvent(event)
+	return result, nil
+}
+
+func (c *contractImpl) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	return c.Submit(context.Background(), name, WithArgs(args...))
+}
+
+// Submit submits the transaction function with the arguments and settings given by the options, e.g., WithArgs and
+// WithTransient, waits for the commit of the __endorse transaction, and returns the result of the transaction
+// function.
+// The deadline and cancellation of the context apply to the ERCC lookup, the __invoke evaluation, and the __endorse
+// submission. Failures of these phases are returned as PhaseError. The context is passed on to the __endorse
+// submission if the target contract implements ContextSubmitter; otherwise, cancellation only abandons the wait for
+// the submission, which keeps running in the background.
+// Note that a transaction whose __endorse submission is cancelled may still be committed.
+func (c *contractImpl) Submit(ctx context.Context, name string, opts ...TransactionOption) ([]byte, error) {
+	o := newTransactionOptions(opts)
+	result, event, _, err := c.submitTransaction(ctx, name, o, false)
 	if err != nil {
 		return nil, err
 	}
 
-	clearResponseBytes, err := ctx.Reveal(encryptedResponse)
+	o.handleEvent(event)
+	return result, nil
+}
+
+// SubmitAsync behaves like Submit but returns once the __endorse transaction is submitted, without waiting for its
+// commit. The returned handle provides the decrypted result of the transaction function and the commit status of the
+// __endorse transaction, including its validation code.
+// The context applies until SubmitAsync returns and is passed on to the __endorse submission if the target contract
+// implements AsyncSubmitter. Read conflicts detected while endorsing are retried according to the retry policy
+// (see WithRetryPolicy), whereas read conflicts detected at commit are only reported by the handle.
+func (c *contractImpl) SubmitAsync(ctx context.Context, name string, opts ...TransactionOption) (*SubmitHandle, error) {
+	o := newTransactionOptions(opts)
+	result, event, commit, err := c.submitTransaction(ctx, name, o, true)
 	if err != nil {
 		return nil, err
 	}
 
-	// unwrap Response.Payload
-	return utils.UnwrapResponse(clearResponseBytes)
+	o.handleEvent(event)
+	return &SubmitHandle{result: result, commit: commit}, nil
 }
 
-func (c *contractImpl) SubmitTransaction(name string, args ...string) ([]byte, error) {
-	ctx, err := c.ep.NewEncryptionContext()
+// invoke encrypts the request and evaluates it using __invoke at the peers hosting the chaincode enclave
+func (c *contractImpl) invoke(ctx context.Context, name string, o *transactionOptions) (crypto.EncryptionContext, []byte, error) {
+	var encCtx crypto.EncryptionContext
+	err := runPhase(ctx, PhaseErccLookup, func() error {
+		var err error
+		encCtx, err = c.ep.NewEncryptionContext()
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	encryptedRequest, err := ctx.Conceal(name, args)
+	encryptedRequest, err := o.conceal(encCtx, name)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var peers []string
+	err = runPhase(ctx, PhaseErccLookup, func() error {
+		var err error
+		peers, err = c.getPeerEndpoints()
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// call __invoke
-	encryptedResponse, err := c.evaluateTransaction(encryptedRequest)
+	encryptedResponse, err := c.evaluateTransaction(ctx, peers, encryptedRequest)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return encCtx, encryptedResponse, nil
+}
+
+// submitTransaction runs the encrypt/invoke/endorse cycle and reruns it after read conflicts according to the retry
+// policy. If async is set, the returned commit provides the commit status of the __endorse transaction.
+func (c *contractImpl) submitTransaction(ctx context.Context, name string, o *transactionOptions, async bool) ([]byte, *Event, Commit, error) {
+	for retry := 1; ; retry++ {
+		result, event, commit, err := c.submitTransactionOnce(ctx, name, o, async)
+		if err == nil || retry > c.retry.MaxRetries || !IsReadConflict(err) {
+			return result, event, commit, err
+		}
+
+		logger.Warnf("Retrying transaction after read conflict (retry %d of %d): %v", retry, c.retry.MaxRetries, err)
+		if err := c.retry.wait(ctx, retry); err != nil {
+			return nil, nil, nil, &PhaseError{Phase: PhaseEndorse, Err: err}
+		}
+	}
+}
+
+func (c *contractImpl) submitTransactionOnce(ctx context.Context, name string, o *transactionOptions, async bool) ([]byte, *Event, Commit, error) {
+	encCtx, encryptedResponse, err := c.invoke(ctx, name, o)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
+	if async {
+		// as the commit is not awaited, the response is revealed before __endorse is submitted
+		result, event, err := c.revealResult(encCtx, encryptedResponse, o)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		commit, err := c.endorseAsync(ctx, string(encryptedResponse))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return result, event, commit, nil
+	}
+
+	if err := c.endorse(ctx, string(encryptedResponse)); err != nil {
+		return nil, nil, nil, err
+	}
+	result, event, err := c.revealResult(encCtx, encryptedResponse, o)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return result, event, nil, nil
+}
+
+// endorse submits __endorse and waits for its commit
+func (c *contractImpl) endorse(ctx context.Context, encryptedResponse string) error {
 	logger.Debugf("calling __endorse!")
-	_, err = c.target.SubmitTransaction("__endorse", string(encryptedResponse))
+	var err error
+	if submitter, ok := c.target.(ContextSubmitter); ok {
+		err = runPhaseWithContext(ctx, PhaseEndorse, func(ctx context.Context) error {
+			_, err := submitter.SubmitTransactionWithContext(ctx, "__endorse", encryptedResponse)
+			return err
+		})
+	} else {
+		err = runPhase(ctx, PhaseEndorse, func() error {
+			_, err := c.target.SubmitTransaction("__endorse", encryptedResponse)
+			return err
+		})
+	}
 	if err != nil {
-		return nil, err
+		c.invalidateCacheAfterEndorse(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// endorseAsync submits __endorse using the target contract if it implements AsyncSubmitter; otherwise, __endorse is
+// submitted in the background and the transaction is considered valid if the submission succeeds.
+func (c *contractImpl) endorseAsync(ctx context.Context, encryptedResponse string) (Commit, error) {
+	logger.Debugf("calling __endorse asynchronously!")
+	if submitter, ok := c.target.(AsyncSubmitter); ok {
+		var commit Commit
+		err := runPhaseWithContext(ctx, PhaseEndorse, func(ctx context.Context) error {
+			var err error
+			commit, err = submitter.SubmitTransactionAsync(ctx, "__endorse", encryptedResponse)
+			return err
+		})
+		if err != nil {
+			c.invalidateCacheAfterEndorse(ctx, err)
+			return nil, err
+		}
+		return commit, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, &PhaseError{Phase: PhaseEndorse, Err: err}
+	}
+	return NewCommit(func() (*CommitStatus, error) {
+		if _, err := c.target.SubmitTransaction("__endorse", encryptedResponse); err != nil {
+			c.invalidateCacheAfterEndorse(context.Background(), err)
+			return nil, err
+		}
+		return &CommitStatus{Code: peer.TxValidationCode_VALID}, nil
+	}), nil
+}
+
+// invalidateCacheAfterEndorse invalidates the cache if __endorse fails for reasons other than a read conflict or the
+// context, e.g., if the enclave is not registered for the current chaincode sequence
+func (c *contractImpl) invalidateCacheAfterEndorse(ctx context.Context, err error) {
+	if !IsReadConflict(err) && ctx.Err() == nil {
+		c.invalidateCache()
 	}
+}
 
+// revealResult decrypts the response of __invoke and the chaincode event, if requested by the options
+func (c *contractImpl) revealResult(encCtx crypto.EncryptionContext, encryptedResponse []byte, o *transactionOptions) ([]byte, *Event, error) {
+	result, err := c.reveal(encCtx, encryptedResponse)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event, err := o.revealEvent(encCtx, encryptedResponse)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, event, nil
+}
+
+func (c *contractImpl) reveal(ctx crypto.EncryptionContext, encryptedResponse []byte) ([]byte, error) {
 	clearResponseBytes, err := ctx.Reveal(encryptedResponse)
 	if err != nil {
+		// the response cannot be decrypted or verified, e.g., if it was produced by an enclave unknown to the cache
+		c.invalidateCache()
 		return nil, err
 	}
 
@@ -134,31 +434,59 @@ func (c *contractImpl) SubmitTransaction(name string, args ...string) ([]byte, e
 
 // getPeerEndpoints returns an array of peer endpoints that host the FPC chaincode enclave
 // An endpoint is a simple string with the format `host:port`
+// Unless the endpoints are set statically, they are queried from ERCC and cached.
 func (c *contractImpl) getPeerEndpoints() ([]string, error) {
-	if len(c.peerEndpoints) == 0 {
-		resp, err := c.ercc.EvaluateTransaction("queryChaincodeEndPoints", c.Name())
-		if err != nil {
-			return nil, err
-		}
-		c.peerEndpoints = strings.Split(string(resp), ",")
+	if len(c.peerEndpoints) > 0 {
+		return c.peerEndpoints, nil
 	}
-	return c.peerEndpoints, nil
+	return c.getCache().getPeerEndpoints()
 }
 
-func (c *contractImpl) evaluateTransaction(args ...string) ([]byte, error) {
-	peers, err := c.getPeerEndpoints()
-	if err != nil {
-		return nil, err
-	}
+// getCache returns the ercc metadata cache; the cache is created on first use if not set by GetContract
+func (c *contractImpl) getCache() *erccCache {
+	c.cacheOnce.Do(func() {
+		if c.cache == nil {
+			c.cache = newErccCache(c.ercc, c.Name(), nil, DefaultCacheTTL)
+		}
+	})
+	return c.cache
+}
+
+// invalidateCache drops the cached ercc metadata, so that it is queried again with the next invocation
+func (c *contractImpl) invalidateCache() {
+	c.getCache().invalidate()
+}
 
+func (c *contractImpl) evaluateTransaction(ctx context.Context, peers []string, args ...string) ([]byte, error) {
 	txn, err := c.target.CreateTransaction(
 		"__invoke",
 		peers...,
 	)
 	if err != nil {
-		return nil, err
+		return nil, &PhaseError{Phase: PhaseInvoke, Err: err}
 	}
 
 	logger.Debugf("calling __invoke!")
-	return txn.Evaluate(args...)
+	var resp []byte
+	if contextTxn, ok := txn.(ContextTransaction); ok {
+		err = runPhaseWithContext(ctx, PhaseInvoke, func(ctx context.Context) error {
+			var err error
+			resp, err = contextTxn.EvaluateWithContext(ctx, args...)
+			return err
+		})
+	} else {
+		err = runPhase(ctx, PhaseInvoke, func() error {
+			var err error
+			resp, err = txn.Evaluate(args...)
+			return err
+		})
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			// the enclave fails to process the request, e.g., if it was encrypted with a stale chaincode encryption key
+			c.invalidateCache()
+		}
+		return nil, err
+	}
+	return resp, nil
 }
@@ -7,12 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package contract_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
 	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract/fakes"
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
@@ -93,7 +97,7 @@ func TestContractEvaluateTransactionSuccess(t *testing.T) {
 	// mock encryption
 	mockEncryptionContext := &fakes.EncryptionContext{}
 	expectedEvalArgs := "someEncryptedArgs"
-	mockEncryptionContext.ConcealCalls(func(f string, args []string) (string, error) {
+	mockEncryptionContext.ConcealBytesCalls(func(f string, args [][]byte, transient map[string][]byte) (string, error) {
 		return expectedEvalArgs, nil
 	})
 	mockEncryptionContext.RevealCalls(func(input []byte) ([]byte, error) {
@@ -147,7 +151,7 @@ func TestContractEvaluateAndSubmitTransactionFail(t *testing.T) {
 
 	// see what happens if conceal returns an error
 	mockEncryptionContext := &fakes.EncryptionContext{}
-	mockEncryptionContext.ConcealCalls(func(f string, args []string) (string, error) {
+	mockEncryptionContext.ConcealBytesCalls(func(f string, args [][]byte, transient map[string][]byte) (string, error) {
 		return "", fmt.Errorf("conceal failed")
 	})
 
@@ -166,7 +170,7 @@ func TestContractEvaluateAndSubmitTransactionFail(t *testing.T) {
 	mockERCC.EvaluateTransactionReturns(nil, fmt.Errorf("ercc error"))
 	mockContract := &fakes.Contract{}
 
-	mockEncryptionContext.ConcealCalls(func(f string, args []string) (string, error) {
+	mockEncryptionContext.ConcealBytesCalls(func(f string, args [][]byte, transient map[string][]byte) (string, error) {
 		return "", nil
 	})
 
@@ -219,7 +223,7 @@ func TestContractSubmitTransaction(t *testing.T) {
 	// mock encryption
 	mockEncryptionContext := &fakes.EncryptionContext{}
 	expectedEvalArgs := "someEncryptedArgs"
-	mockEncryptionContext.ConcealCalls(func(f string, args []string) (string, error) {
+	mockEncryptionContext.ConcealBytesCalls(func(f string, args [][]byte, transient map[string][]byte) (string, error) {
 		return expectedEvalArgs, nil
 	})
 	mockEncryptionContext.RevealCalls(func(input []byte) ([]byte, error) {
@@ -248,6 +252,316 @@ func TestContractSubmitTransaction(t *testing.T) {
 	assert.Equal(t, 1, mockContract.SubmitTransactionCallCount())
 }
 
+func TestContractTransactionWithTransient(t *testing.T) {
+	expectedResult := []byte("result")
+	transient := map[string][]byte{"secret": []byte("some secret")}
+	ctx := context.Background()
+
+	invokeTx := &fakes.Transaction{}
+	invokeTx.EvaluateReturns(expectedResult, nil)
+
+	mockContract := &fakes.Contract{}
+	mockContract.CreateTransactionReturns(invokeTx, nil)
+
+	mockERCC := &fakes.Contract{}
+	mockERCC.EvaluateTransactionReturns([]byte("peer1,peer2,peer3"), nil)
+
+	mockEncryptionContext := &fakes.EncryptionContext{}
+	mockEncryptionContext.ConcealBytesReturns("someEncryptedArgs", nil)
+	mockEncryptionContext.RevealCalls(func(input []byte) ([]byte, error) {
+		return asResponseBytes(input), nil
+	})
+
+	mockEncryptionProvider := &fakes.EncryptionProvider{}
+	mockEncryptionProvider.NewEncryptionContextReturns(mockEncryptionContext, nil)
+
+	contract := fpccontract.New(mockContract, mockERCC, nil, mockEncryptionProvider)
+
+	// evaluate passes the transient data to the encryption context
+	resp, err := contract.Evaluate(ctx, "someFunction", fpccontract.WithArgs("arg1"), fpccontract.WithTransient(transient))
+	assert.Equal(t, expectedResult, resp)
+	assert.NoError(t, err)
+	f, args, m := mockEncryptionContext.ConcealBytesArgsForCall(0)
+	assert.Equal(t, "someFunction", f)
+	assert.Equal(t, [][]byte{[]byte("arg1")}, args)
+	assert.Equal(t, transient, m)
+
+	// submit passes the transient data to the encryption context
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), fpccontract.WithTransient(transient))
+	assert.Equal(t, expectedResult, resp)
+	assert.NoError(t, err)
+	_, _, m = mockEncryptionContext.ConcealBytesArgsForCall(1)
+	assert.Equal(t, transient, m)
+
+	// the transient data is only passed to the target contract as part of the encrypted request
+	assert.Equal(t, 1, mockContract.SubmitTransactionCallCount())
+
+	// conceal fails
+	mockEncryptionContext.ConcealBytesReturns("", fmt.Errorf("conceal error"))
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), fpccontract.WithTransient(transient))
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+}
+
+func TestContractTransactionBytes(t *testing.T) {
+	expectedResult := []byte("result")
+	binaryArg := []byte{0x00, 0xff, 0xfe}
+	ctx := context.Background()
+
+	invokeTx := &fakes.Transaction{}
+	invokeTx.EvaluateReturns(expectedResult, nil)
+
+	mockContract := &fakes.Contract{}
+	mockContract.CreateTransactionReturns(invokeTx, nil)
+
+	mockERCC := &fakes.Contract{}
+	mockERCC.EvaluateTransactionReturns([]byte("peer1,peer2,peer3"), nil)
+
+	mockEncryptionContext := &fakes.EncryptionContext{}
+	mockEncryptionContext.ConcealBytesReturns("someEncryptedArgs", nil)
+	mockEncryptionContext.RevealCalls(func(input []byte) ([]byte, error) {
+		return asResponseBytes(input), nil
+	})
+
+	mockEncryptionProvider := &fakes.EncryptionProvider{}
+	mockEncryptionProvider.NewEncryptionContextReturns(mockEncryptionContext, nil)
+
+	contract := fpccontract.New(mockContract, mockERCC, nil, mockEncryptionProvider)
+
+	// evaluate passes the args as they are to the encryption context
+	resp, err := contract.Evaluate(ctx, "someFunction", fpccontract.WithBytesArgs(binaryArg))
+	assert.Equal(t, expectedResult, resp)
+	assert.NoError(t, err)
+	f, args, m := mockEncryptionContext.ConcealBytesArgsForCall(0)
+	assert.Equal(t, "someFunction", f)
+	assert.Equal(t, [][]byte{binaryArg}, args)
+	assert.Nil(t, m)
+	assert.Equal(t, "someEncryptedArgs", invokeTx.EvaluateArgsForCall(0)[0])
+
+	// submit passes the args as they are to the encryption context, also along with string args and transient data
+	transient := map[string][]byte{"secret": []byte("some secret")}
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), fpccontract.WithBytesArgs(binaryArg), fpccontract.WithTransient(transient))
+	assert.Equal(t, expectedResult, resp)
+	assert.NoError(t, err)
+	_, args, m = mockEncryptionContext.ConcealBytesArgsForCall(1)
+	assert.Equal(t, [][]byte{[]byte("arg1"), binaryArg}, args)
+	assert.Equal(t, transient, m)
+	assert.Equal(t, 1, mockContract.SubmitTransactionCallCount())
+
+	// conceal fails
+	mockEncryptionContext.ConcealBytesReturns("", fmt.Errorf("conceal error"))
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithBytesArgs(binaryArg))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "conceal error")
+}
+
+func TestContractTransactionWithEvent(t *testing.T) {
+	expectedResult := []byte("result")
+	ctx := context.Background()
+
+	invokeTx := &fakes.Transaction{}
+	invokeTx.EvaluateReturns(expectedResult, nil)
+
+	mockContract := &fakes.Contract{}
+	mockContract.CreateTransactionReturns(invokeTx, nil)
+
+	mockERCC := &fakes.Contract{}
+	mockERCC.EvaluateTransactionReturns([]byte("peer1,peer2,peer3"), nil)
+
+	mockEncryptionContext := &fakes.EncryptionContext{}
+	mockEncryptionContext.ConcealBytesReturns("someEncryptedArgs", nil)
+	mockEncryptionContext.RevealCalls(func(input []byte) ([]byte, error) {
+		return asResponseBytes(input), nil
+	})
+
+	mockEncryptionProvider := &fakes.EncryptionProvider{}
+	mockEncryptionProvider.NewEncryptionContextReturns(mockEncryptionContext, nil)
+
+	contract := fpccontract.New(mockContract, mockERCC, nil, mockEncryptionProvider)
+
+	var event *fpccontract.Event
+	var eventCalls int
+	withEvent := fpccontract.WithEvent(func(e *fpccontract.Event) {
+		event = e
+		eventCalls++
+	})
+
+	// no event
+	resp, err := contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), withEvent)
+	assert.Equal(t, expectedResult, resp)
+	assert.Nil(t, event)
+	assert.Equal(t, 1, eventCalls)
+	assert.NoError(t, err)
+
+	// with event
+	mockEncryptionContext.RevealEventReturns(&protos.FPCEvent{EventName: "someEvent"}, []byte("payload"), nil)
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), withEvent)
+	assert.Equal(t, expectedResult, resp)
+	assert.Equal(t, &fpccontract.Event{Name: "someEvent", Payload: []byte("payload")}, event)
+	assert.NoError(t, err)
+
+	// the event is also revealed on evaluation
+	event = nil
+	resp, err = contract.Evaluate(ctx, "someFunction", fpccontract.WithArgs("arg1"), withEvent)
+	assert.Equal(t, expectedResult, resp)
+	assert.Equal(t, &fpccontract.Event{Name: "someEvent", Payload: []byte("payload")}, event)
+	assert.NoError(t, err)
+
+	// the event is not revealed without the option
+	_, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, mockEncryptionContext.RevealEventCallCount())
+
+	// reveal event fails
+	eventCalls = 0
+	mockEncryptionContext.RevealEventReturns(nil, nil, fmt.Errorf("reveal event error"))
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), withEvent)
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+
+	// __endorse fails
+	mockContract.SubmitTransactionReturns(nil, fmt.Errorf("endorse error"))
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"), withEvent)
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+	assert.Equal(t, 0, eventCalls)
+	assert.Equal(t, 5, mockContract.SubmitTransactionCallCount())
+}
+
+func TestContractWithContext(t *testing.T) {
+	expectedResult := []byte("result")
+
+	// __invoke blocks until released
+	release := make(chan struct{})
+	defer close(release)
+	invokeTx := &fakes.Transaction{}
+	invokeTx.EvaluateCalls(func(args ...string) ([]byte, error) {
+		<-release
+		return expectedResult, nil
+	})
+
+	mockContract := &fakes.Contract{}
+	mockContract.CreateTransactionReturns(invokeTx, nil)
+
+	mockERCC := &fakes.Contract{}
+	mockERCC.EvaluateTransactionReturns([]byte("peer1"), nil)
+
+	mockEncryptionContext := &fakes.EncryptionContext{}
+	mockEncryptionContext.RevealCalls(func(input []byte) ([]byte, error) {
+		return asResponseBytes(input), nil
+	})
+	mockEncryptionProvider := &fakes.EncryptionProvider{}
+	mockEncryptionProvider.NewEncryptionContextReturns(mockEncryptionContext, nil)
+
+	contract := fpccontract.New(mockContract, mockERCC, nil, mockEncryptionProvider)
+
+	// __invoke times out
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp, err := contract.Evaluate(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "__invoke failed: context deadline exceeded")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	var phaseErr *fpccontract.PhaseError
+	assert.True(t, errors.As(err, &phaseErr))
+	assert.Equal(t, fpccontract.PhaseInvoke, phaseErr.Phase)
+
+	// nothing is started with a cancelled context
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "ercc lookup failed: context canceled")
+	assert.Equal(t, 1, mockEncryptionProvider.NewEncryptionContextCallCount())
+
+	// __endorse times out
+	invokeTx.EvaluateReturns(expectedResult, nil)
+	mockContract.SubmitTransactionCalls(func(name string, args ...string) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "__endorse failed: context deadline exceeded")
+
+	// errors of a phase are reported with the phase
+	mockContract.SubmitTransactionCalls(nil)
+	mockContract.SubmitTransactionReturns(nil, fmt.Errorf("endorse failed"))
+	resp, err = contract.Submit(context.Background(), "someFunction", fpccontract.WithArgs("arg1"))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "__endorse failed: endorse failed")
+
+	// success
+	mockContract.SubmitTransactionReturns(nil, nil)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, resp)
+}
+
+// contextTransaction is a transaction that supports cancellation through a context
+type contextTransaction struct {
+	*fakes.Transaction
+	returned chan struct{}
+}
+
+func (t *contextTransaction) EvaluateWithContext(ctx context.Context, args ...string) ([]byte, error) {
+	defer close(t.returned)
+	<-ctx.Done()
+	return nil, fmt.Errorf("evaluate aborted")
+}
+
+// contextContract is a contract that supports cancellation of submissions through a context
+type contextContract struct {
+	*fakes.Contract
+	returned chan struct{}
+}
+
+func (c *contextContract) SubmitTransactionWithContext(ctx context.Context, name string, args ...string) ([]byte, error) {
+	defer close(c.returned)
+	<-ctx.Done()
+	return nil, fmt.Errorf("submit aborted")
+}
+
+func TestContractWithContextAwareTarget(t *testing.T) {
+	invokeTx := &contextTransaction{Transaction: &fakes.Transaction{}, returned: make(chan struct{})}
+	mockContract := &contextContract{Contract: &fakes.Contract{}, returned: make(chan struct{})}
+	mockContract.CreateTransactionReturns(invokeTx, nil)
+
+	mockERCC := &fakes.Contract{}
+	mockERCC.EvaluateTransactionReturns([]byte("peer1"), nil)
+
+	mockEncryptionProvider := &fakes.EncryptionProvider{}
+	mockEncryptionProvider.NewEncryptionContextReturns(&fakes.EncryptionContext{}, nil)
+
+	contract := fpccontract.New(mockContract, mockERCC, nil, mockEncryptionProvider)
+
+	// the context is passed on to __invoke, which returns when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp, err := contract.Evaluate(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "__invoke failed: context deadline exceeded")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	<-invokeTx.returned
+	assert.Equal(t, 0, invokeTx.EvaluateCallCount())
+
+	// the context is passed on to __endorse
+	txn := &fakes.Transaction{}
+	txn.EvaluateReturns([]byte("result"), nil)
+	mockContract.CreateTransactionReturns(txn, nil)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("arg1"))
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "__endorse failed: context deadline exceeded")
+	<-mockContract.returned
+	assert.Equal(t, 0, mockContract.SubmitTransactionCallCount())
+}
+
 func asResponseBytes(input []byte) []byte {
 	return protoutil.MarshalOrPanic(&peer.Response{Payload: input, Status: 200})
 }
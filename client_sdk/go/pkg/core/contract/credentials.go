@@ -0,0 +1,202 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/peer/lifecycle"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// CredentialVerifier verifies the attestation evidence of enclave credentials,
+// e.g., as implemented by attestation.CredentialVerifier
+type CredentialVerifier interface {
+	VerifyCredentials(credentials *protos.Credentials, expectedMrenclave string, policy *protos.AttestationVerificationPolicy) error
+}
+
+// getCredentials returns the credentials of a registered enclave of the chaincode
+func (c *erccCache) getCredentials(enclaveId string) (*protos.Credentials, error) {
+	cached, generation, ok := c.get(func() *cacheEntry { return c.credentials[enclaveId] })
+	if ok {
+		return cached.(*protos.Credentials), nil
+	}
+
+	resp, err := c.ercc.EvaluateTransaction("queryEnclaveCredentials", c.chaincodeID, enclaveId)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("enclave %s is not registered", enclaveId)
+	}
+
+	credentials, err := utils.UnmarshalCredentials(string(resp))
+	if err != nil {
+		return nil, err
+	}
+
+	sequence, err := c.verifyCredentials(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(generation, func() {
+		c.observeSequence(sequence)
+		c.credentials[enclaveId] = c.newEntry(credentials)
+	})
+	return credentials, nil
+}
+
+// getKeyRegistration returns the signed cc key registration message of an enclave of the chaincode provisioned
+// via the key distribution protocol, or nil if there is none
+func (c *erccCache) getKeyRegistration(enclaveId string) (*protos.SignedCCKeyRegistrationMessage, error) {
+	cached, generation, ok := c.get(func() *cacheEntry { return c.registrations[enclaveId] })
+	if ok {
+		return cached.(*protos.SignedCCKeyRegistrationMessage), nil
+	}
+
+	resp, err := c.ercc.EvaluateTransaction("queryCCKeyRegistration", c.chaincodeID, enclaveId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the enclave is not provisioned yet
+	if len(resp) == 0 {
+		return nil, nil
+	}
+
+	signedMsgBytes, err := base64.StdEncoding.DecodeString(string(resp))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cc key registration message")
+	}
+
+	signedMsg, err := utils.UnmarshalSignedCCKeyRegistrationMessage(signedMsgBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(generation, func() { c.registrations[enclaveId] = c.newEntry(signedMsg) })
+	return signedMsg, nil
+}
+
+// listCredentials returns the credentials of all registered enclaves of the chaincode
+func (c *erccCache) listCredentials() ([]*protos.Credentials, error) {
+	cached, generation, ok := c.get(func() *cacheEntry { return c.credentialsList })
+	if ok {
+		return cached.([]*protos.Credentials), nil
+	}
+
+	resp, err := c.ercc.EvaluateTransaction("queryListEnclaveCredentials", c.chaincodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentialsBase64 []string
+	if len(resp) > 0 {
+		if err := json.Unmarshal(resp, &credentialsBase64); err != nil {
+			return nil, errors.Wrap(err, "invalid enclave credentials list")
+		}
+	}
+
+	credentialsList := make([]*protos.Credentials, 0, len(credentialsBase64))
+	for _, cred := range credentialsBase64 {
+		credentials, err := utils.UnmarshalCredentials(cred)
+		if err != nil {
+			return nil, err
+		}
+		credentialsList = append(credentialsList, credentials)
+	}
+
+	sequences := make([]int64, 0, len(credentialsList))
+	for _, credentials := range credentialsList {
+		attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+		if err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, attestedData.GetCcParams().GetSequence())
+	}
+
+	c.put(generation, func() {
+		for _, sequence := range sequences {
+			c.observeSequence(sequence)
+		}
+		c.credentialsList = c.newEntry(credentialsList)
+	})
+	return credentialsList, nil
+}
+
+// verify checks that the credentials belong to the chaincode and, if a verifier is set, verifies the evidence
+func (c *erccCache) verify(credentials *protos.Credentials) error {
+	_, err := c.verifyCredentials(credentials)
+	return err
+}
+
+// verifyCredentials verifies the credentials as verify and returns the chaincode sequence of the enclave
+func (c *erccCache) verifyCredentials(credentials *protos.Credentials) (int64, error) {
+	attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+	if err != nil {
+		return 0, err
+	}
+	sequence := attestedData.GetCcParams().GetSequence()
+
+	if attestedData.GetCcParams().GetChaincodeId() != c.chaincodeID {
+		return 0, fmt.Errorf("credentials are registered for chaincode %s", attestedData.GetCcParams().GetChaincodeId())
+	}
+
+	if c.verifier == nil {
+		return sequence, nil
+	}
+
+	expectedMrenclave := c.expectedMrenclave
+	if len(expectedMrenclave) == 0 {
+		ccDef, err := c.queryChaincodeDefinition()
+		if err != nil {
+			return 0, err
+		}
+		if sequence != ccDef.GetSequence() {
+			return 0, fmt.Errorf("sequence of credentials does not match chaincode definition")
+		}
+		expectedMrenclave = ccDef.GetVersion()
+	}
+
+	// the mrenclave of the chaincode is the version of the cc params, which must match the chaincode definition;
+	// the mrenclave of the evidence may only differ if allowed by the policy of the client
+	if attestedData.GetCcParams().GetVersion() != expectedMrenclave {
+		return 0, fmt.Errorf("mrenclave of credentials does not match chaincode definition")
+	}
+
+	if err := c.verifier.VerifyCredentials(credentials, expectedMrenclave, c.policy); err != nil {
+		return 0, errors.Wrap(err, "evidence verification failed")
+	}
+
+	return sequence, nil
+}
+
+// queryChaincodeDefinition returns the definition of the chaincode as committed on the channel
+func (c *erccCache) queryChaincodeDefinition() (*lifecycle.QueryChaincodeDefinitionResult, error) {
+	if c.lifecycle == nil {
+		return nil, fmt.Errorf("no expected mrenclave configured")
+	}
+
+	// note that we use Fabric's Marshal as the lifecycle protos still use protobuf V1
+	argsBytes, err := protoutil.Marshal(&lifecycle.QueryChaincodeDefinitionArgs{Name: c.chaincodeID})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.lifecycle.EvaluateTransaction("QueryChaincodeDefinition", string(argsBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot query chaincode definition")
+	}
+
+	return utils.UnmarshalQueryChaincodeDefinitionResult(resp)
+}
@@ -0,0 +1,387 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	protov1 "github.com/golang/protobuf/proto"
+	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract/fakes"
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-protos-go/peer/lifecycle"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type credentialVerifier struct {
+	err       error
+	mrenclave string
+	policy    *protos.AttestationVerificationPolicy
+}
+
+func (v *credentialVerifier) VerifyCredentials(credentials *protos.Credentials, expectedMrenclave string, policy *protos.AttestationVerificationPolicy) error {
+	v.mrenclave = expectedMrenclave
+	v.policy = policy
+	return v.err
+}
+
+// testEnclave is a stand-in for a FPC chaincode enclave registered at ERCC
+type testEnclave struct {
+	csp                      crypto.CSP
+	chaincodeEk, chaincodeDk []byte
+	enclaveId                string
+	enclaveVk                []byte
+	credentials              string
+	signingKey               []byte
+	// the chaincode encryption key returned by queryChaincodeEncryptionKey
+	erccEk []byte
+	// the chaincode definition returned by _lifecycle
+	definition *lifecycle.QueryChaincodeDefinitionResult
+	// the cc key registration message returned by queryCCKeyRegistration, if the chaincode encryption key is not attested
+	keyRegistration string
+	// the peer endpoints returned by queryChaincodeEndPoints
+	peerEndpoints string
+	// onErccQuery, if set, is called on every ERCC query before it is answered
+	onErccQuery func(name string)
+}
+
+func newTestEnclave(t *testing.T, chaincodeID string) *testEnclave {
+	csp := crypto.GetDefaultCSP()
+	chaincodeEk, chaincodeDk, err := csp.NewRSAKeys()
+	require.NoError(t, err)
+	enclaveVk, enclaveSk, err := csp.NewECDSAKeys()
+	require.NoError(t, err)
+
+	e := &testEnclave{
+		csp:           csp,
+		chaincodeEk:   chaincodeEk,
+		chaincodeDk:   chaincodeDk,
+		enclaveId:     "someEnclaveId",
+		enclaveVk:     enclaveVk,
+		signingKey:    enclaveSk,
+		erccEk:        chaincodeEk,
+		peerEndpoints: "peer0:7051",
+	}
+	e.register(t, chaincodeID, 1)
+	return e
+}
+
+// register sets the credentials of the enclave for the given chaincode sequence
+func (e *testEnclave) register(t *testing.T, chaincodeID string, sequence int64) {
+	attestedData, err := anypb.New(&protos.AttestedData{
+		EnclaveVk:   e.enclaveVk,
+		CcParams:    &protos.CCParameters{ChaincodeId: chaincodeID, Version: "someMrenclave", Sequence: sequence},
+		ChaincodeEk: e.chaincodeEk,
+	})
+	require.NoError(t, err)
+	e.credentials = utils.MarshallProtoBase64(&protos.Credentials{SerializedAttestedData: attestedData})
+	e.definition = &lifecycle.QueryChaincodeDefinitionResult{Version: "someMrenclave", Sequence: sequence}
+}
+
+// registerCCKeys sets the credentials of the enclave without chaincode encryption key and provisions the key with a
+// cc key registration message signed with the given key, as done by enclaves using the key distribution protocol
+func (e *testEnclave) registerCCKeys(t *testing.T, chaincodeID string, signingKey []byte) {
+	ccParams := &protos.CCParameters{ChaincodeId: chaincodeID, Version: "someMrenclave", Sequence: 1}
+	attestedData, err := anypb.New(&protos.AttestedData{EnclaveVk: e.enclaveVk, CcParams: ccParams})
+	require.NoError(t, err)
+	e.credentials = utils.MarshallProtoBase64(&protos.Credentials{SerializedAttestedData: attestedData})
+
+	ccParamsHash, err := utils.GetCCParamsHash(ccParams)
+	require.NoError(t, err)
+	enclaveId := sha256.Sum256(e.enclaveVk)
+	msg, err := anypb.New(&protos.CCKeyRegistrationMessage{CcParamsHash: ccParamsHash, ChaincodeEk: e.chaincodeEk, EnclaveId: enclaveId[:]})
+	require.NoError(t, err)
+	signature, err := e.csp.SignMessage(signingKey, msg.GetValue())
+	require.NoError(t, err)
+	e.keyRegistration = utils.MarshallProtoBase64(&protos.SignedCCKeyRegistrationMessage{SerializedCckeyRegMsg: msg, Signature: signature})
+}
+
+// invoke decrypts the request and returns a signed response with the given payload
+func (e *testEnclave) invoke(t *testing.T, request string, payload []byte) (string, error) {
+	requestBytes, err := base64.StdEncoding.DecodeString(request)
+	require.NoError(t, err)
+	requestMsg := &protos.ChaincodeRequestMessage{}
+	require.NoError(t, proto.Unmarshal(requestBytes, requestMsg))
+
+	keyTransportBytes, err := e.csp.PkDecryptMessage(e.chaincodeDk, requestMsg.GetEncryptedKeyTransportMessage())
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt request: %v", err)
+	}
+	keyTransport := &protos.KeyTransportMessage{}
+	require.NoError(t, proto.Unmarshal(keyTransportBytes, keyTransport))
+
+	responseBytes := protoutil.MarshalOrPanic(&peer.Response{Status: 200, Payload: payload})
+	encryptedResponse, err := e.csp.EncryptMessage(keyTransport.GetResponseEncryptionKey(), responseBytes)
+	require.NoError(t, err)
+
+	requestHash := sha256.Sum256(requestBytes)
+	responseMsgBytes := protoutil.MarshalOrPanic(&protos.ChaincodeResponseMessage{
+		EncryptedResponse:           encryptedResponse,
+		EnclaveId:                   e.enclaveId,
+		ChaincodeRequestMessageHash: requestHash[:],
+	})
+	signature, err := e.csp.SignMessage(e.signingKey, responseMsgBytes)
+	require.NoError(t, err)
+
+	return utils.MarshallProtoBase64(&protos.SignedChaincodeResponseMessage{
+		ChaincodeResponseMessage: responseMsgBytes,
+		Signature:                signature,
+	}), nil
+}
+
+type evaluator interface {
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+}
+
+type submitter interface {
+	evaluator
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+	Submit(ctx context.Context, name string, opts ...fpccontract.TransactionOption) ([]byte, error)
+	SubmitAsync(ctx context.Context, name string, opts ...fpccontract.TransactionOption) (*fpccontract.SubmitHandle, error)
+}
+
+func setupContract(t *testing.T, enclave *testEnclave, opts ...fpccontract.Option) (evaluator, *fakes.Contract) {
+	contract, ercc, _ := setupContractWithTarget(t, enclave, opts...)
+	return contract, ercc
+}
+
+// setupContractWithTarget behaves like setupContract but additionally returns the target contract,
+// which receives the __endorse transactions
+func setupContractWithTarget(t *testing.T, enclave *testEnclave, opts ...fpccontract.Option) (submitter, *fakes.Contract, *fakes.Contract) {
+	return setupContractWithWrappedTarget(t, enclave, nil, opts...)
+}
+
+// setupContractWithWrappedTarget behaves like setupContractWithTarget but passes the target contract wrapped by wrap,
+// if set, to the FPC contract, e.g., to implement optional interfaces such as AsyncSubmitter
+func setupContractWithWrappedTarget(t *testing.T, enclave *testEnclave, wrap func(target *fakes.Contract) fpccontract.Contract, opts ...fpccontract.Option) (submitter, *fakes.Contract, *fakes.Contract) {
+	chaincodeID := "myChaincode"
+
+	ercc := &fakes.Contract{}
+	ercc.EvaluateTransactionCalls(func(name string, args ...string) ([]byte, error) {
+		if enclave.onErccQuery != nil {
+			enclave.onErccQuery(name)
+		}
+		switch name {
+		case "queryChaincodeEncryptionKey":
+			return []byte(base64.StdEncoding.EncodeToString(enclave.erccEk)), nil
+		case "queryChaincodeEndPoints":
+			return []byte(enclave.peerEndpoints), nil
+		case "queryEnclaveCredentials":
+			if args[1] != enclave.enclaveId {
+				return nil, nil
+			}
+			return []byte(enclave.credentials), nil
+		case "queryListEnclaveCredentials":
+			return json.Marshal([]string{enclave.credentials})
+		case "queryCCKeyRegistration":
+			return []byte(enclave.keyRegistration), nil
+		}
+		return nil, fmt.Errorf("unexpected ercc function %s", name)
+	})
+
+	txn := &fakes.Transaction{}
+	txn.EvaluateCalls(func(args ...string) ([]byte, error) {
+		response, err := enclave.invoke(t, args[0], []byte("some result"))
+		return []byte(response), err
+	})
+	target := &fakes.Contract{}
+	target.CreateTransactionReturns(txn, nil)
+
+	lifecycleContract := &fakes.Contract{}
+	lifecycleContract.EvaluateTransactionCalls(func(name string, args ...string) ([]byte, error) {
+		queryArgs := &lifecycle.QueryChaincodeDefinitionArgs{}
+		require.NoError(t, protov1.Unmarshal([]byte(args[0]), queryArgs))
+		require.Equal(t, "QueryChaincodeDefinition", name)
+		require.Equal(t, chaincodeID, queryArgs.Name)
+		return protoutil.Marshal(enclave.definition)
+	})
+
+	provider := &fakes.ContractProvider{}
+	provider.GetContractCalls(func(id string) fpccontract.Contract {
+		switch id {
+		case "ercc":
+			return ercc
+		case "_lifecycle":
+			return lifecycleContract
+		}
+		if wrap != nil {
+			return wrap(target)
+		}
+		return target
+	})
+
+	return fpccontract.GetContract(provider, chaincodeID, opts...), ercc, target
+}
+
+func countCalls(ercc *fakes.Contract, function string) int {
+	count := 0
+	for i := 0; i < ercc.EvaluateTransactionCallCount(); i++ {
+		if name, _ := ercc.EvaluateTransactionArgsForCall(i); name == function {
+			count++
+		}
+	}
+	return count
+}
+
+func TestContractVerifiesEnclaveResponse(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	contract, ercc := setupContract(t, enclave)
+
+	result, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+
+	// credentials are cached
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countCalls(ercc, "queryEnclaveCredentials"))
+
+	// response signed by another key
+	_, enclave.signingKey, err = crypto.GetDefaultCSP().NewECDSAKeys()
+	require.NoError(t, err)
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: enclave signature verification failed")
+}
+
+func TestContractRejectsUnregisteredEnclave(t *testing.T) {
+	enclave := newTestEnclave(t, "otherChaincode")
+	contract, _ := setupContract(t, enclave)
+
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someEnclaveId: credentials are registered for chaincode otherChaincode")
+
+	enclave.credentials = ""
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someEnclaveId: enclave someEnclaveId is not registered")
+}
+
+func TestContractWithCredentialVerifier(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+
+	verifier := &credentialVerifier{err: fmt.Errorf("MRENCLAVE_MISMATCH: some mismatch")}
+	contract, _ := setupContract(t, enclave, fpccontract.WithCredentialVerifier(verifier))
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someEnclaveId: evidence verification failed: MRENCLAVE_MISMATCH: some mismatch")
+	assert.Equal(t, "someMrenclave", verifier.mrenclave)
+	assert.Nil(t, verifier.policy)
+
+	// rejected credentials are not cached
+	verifier.err = nil
+	result, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+}
+
+func TestContractVerifiesCredentialsAgainstChaincodeDefinition(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	verifier := &credentialVerifier{}
+
+	// the enclave claims another mrenclave than the chaincode definition
+	enclave.definition.Version = "otherMrenclave"
+	contract, _ := setupContract(t, enclave, fpccontract.WithCredentialVerifier(verifier))
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someEnclaveId: mrenclave of credentials does not match chaincode definition")
+	assert.Empty(t, verifier.mrenclave)
+
+	// the enclave runs an outdated sequence of the chaincode
+	enclave.definition = &lifecycle.QueryChaincodeDefinitionResult{Version: "someMrenclave", Sequence: 2}
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someEnclaveId: sequence of credentials does not match chaincode definition")
+
+	// the mrenclave expected by the client
+	contract, _ = setupContract(t, enclave, fpccontract.WithCredentialVerifier(verifier), fpccontract.WithExpectedMrenclave("otherMrenclave"))
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someEnclaveId: mrenclave of credentials does not match chaincode definition")
+
+	contract, _ = setupContract(t, enclave, fpccontract.WithCredentialVerifier(verifier), fpccontract.WithExpectedMrenclave("someMrenclave"))
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, "someMrenclave", verifier.mrenclave)
+}
+
+func TestContractWithVerificationPolicy(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	verifier := &credentialVerifier{}
+	policy := &protos.AttestationVerificationPolicy{AcceptedTcbStatus: []string{"UpToDate", "SWHardeningNeeded"}}
+
+	contract, ercc := setupContract(t, enclave, fpccontract.WithCredentialVerifier(verifier), fpccontract.WithVerificationPolicy(policy))
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, "someMrenclave", verifier.mrenclave)
+	assert.True(t, proto.Equal(policy, verifier.policy))
+
+	// the verification policy of ERCC is not used
+	assert.Equal(t, 0, countCalls(ercc, "queryVerificationPolicy"))
+}
+
+func TestContractWithAttestedEncryptionKey(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+
+	// a malicious peer answers queryChaincodeEncryptionKey with its own key
+	enclave.erccEk, _, _ = crypto.GetDefaultCSP().NewRSAKeys()
+
+	// without a verifier, no key is accepted
+	contract, _ := setupContract(t, enclave, fpccontract.WithAttestedEncryptionKey())
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "ercc lookup failed: no credential verifier configured")
+
+	// the attested key is used
+	verifier := &credentialVerifier{}
+	contract, ercc := setupContract(t, enclave, fpccontract.WithAttestedEncryptionKey(), fpccontract.WithCredentialVerifier(verifier))
+	result, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+	assert.Equal(t, 0, countCalls(ercc, "queryChaincodeEncryptionKey"))
+
+	// the evidence of the enclave is rejected
+	verifier.err = fmt.Errorf("some error")
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "ercc lookup failed: no verified enclave credentials with chaincode encryption key found")
+}
+
+func TestContractWithAttestedEncryptionKeyFromKeyRegistration(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	enclave.erccEk, _, _ = crypto.GetDefaultCSP().NewRSAKeys()
+	verifier := &credentialVerifier{}
+
+	// the enclave is not provisioned yet
+	enclave.registerCCKeys(t, "myChaincode", enclave.signingKey)
+	registration := enclave.keyRegistration
+	enclave.keyRegistration = ""
+	contract, _ := setupContract(t, enclave, fpccontract.WithAttestedEncryptionKey(), fpccontract.WithCredentialVerifier(verifier))
+	_, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "ercc lookup failed: no verified enclave credentials with chaincode encryption key found")
+
+	// the key of the registration message signed by the enclave is used
+	enclave.keyRegistration = registration
+	contract, ercc := setupContract(t, enclave, fpccontract.WithAttestedEncryptionKey(), fpccontract.WithCredentialVerifier(verifier))
+	result, err := contract.EvaluateTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+	assert.Equal(t, 1, countCalls(ercc, "queryCCKeyRegistration"))
+	assert.Equal(t, 0, countCalls(ercc, "queryChaincodeEncryptionKey"))
+
+	// a registration message not signed by the enclave is rejected
+	_, otherKey, err := crypto.GetDefaultCSP().NewECDSAKeys()
+	require.NoError(t, err)
+	enclave.registerCCKeys(t, "myChaincode", otherKey)
+	contract, _ = setupContract(t, enclave, fpccontract.WithAttestedEncryptionKey(), fpccontract.WithCredentialVerifier(verifier))
+	_, err = contract.EvaluateTransaction("someFunction", "someArg")
+	assert.ErrorContains(t, err, "cc key registration message signature verification failed")
+}
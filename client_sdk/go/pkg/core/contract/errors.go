@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"context"
+	"fmt"
+)
+
+// Phase identifies a step of the FPC transaction flow that involves the network
+type Phase string
+
+const (
+	// PhaseErccLookup is the lookup of the chaincode encryption key and the peer endpoints at ERCC
+	PhaseErccLookup Phase = "ercc lookup"
+	// PhaseInvoke is the evaluation of the encrypted request by the enclave via __invoke
+	PhaseInvoke Phase = "__invoke"
+	// PhaseEndorse is the submission of the enclave response via __endorse
+	PhaseEndorse Phase = "__endorse"
+)
+
+// PhaseError is returned when a phase of the FPC transaction flow fails, or is cancelled or times out through its
+// context. The underlying error, e.g., context.DeadlineExceeded, is available through errors.Is and errors.As.
+type PhaseError struct {
+	Phase Phase
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.Phase, e.Err)
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// ChaincodeError is returned by target contracts when a chaincode function fails with a response status,
+// e.g., when __endorse detects a read conflict (see IsReadConflict)
+type ChaincodeError struct {
+	Status int32
+	Err    error
+}
+
+func (e *ChaincodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ChaincodeError) Unwrap() error {
+	return e.Err
+}
+
+// runPhase runs f and returns once f completes or the context is done, whichever comes first.
+// It is used for calls that do not take a context, e.g., of the Fabric SDK Go. Such calls cannot be aborted, hence,
+// cancellation only abandons the wait: f keeps running in the background until the call returns, and its result is
+// discarded. Calls that take a context are run with runPhaseWithContext instead.
+func runPhase(ctx context.Context, phase Phase, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return &PhaseError{Phase: phase, Err: err}
+	}
+
+	// no deadline nor cancellation
+	if ctx.Done() == nil {
+		if err := f(); err != nil {
+			return &PhaseError{Phase: phase, Err: err}
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return &PhaseError{Phase: phase, Err: err}
+		}
+		return nil
+	case <-ctx.Done():
+		return &PhaseError{Phase: phase, Err: ctx.Err()}
+	}
+}
+
+// runPhaseWithContext runs f with the context, which is expected to abort f when the context is done
+func runPhaseWithContext(ctx context.Context, phase Phase, f func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return &PhaseError{Phase: phase, Err: err}
+	}
+
+	if err := f(ctx); err != nil {
+		// report the cancellation rather than the error it caused
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return &PhaseError{Phase: phase, Err: ctxErr}
+		}
+		return &PhaseError{Phase: phase, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// Event is a chaincode event emitted by a FPC chaincode
+type Event struct {
+	// Name is the (cleartext) name of the event
+	Name string
+	// EventKeyId identifies the key used by the chaincode to encrypt the payload.
+	// It is empty if the payload was encrypted for the invoking client.
+	EventKeyId string
+	// Payload is the decrypted event payload
+	Payload []byte
+}
+
+// DecryptEvent decrypts a chaincode event received through a Fabric event subscription, i.e., the payload of the
+// Fabric chaincode event is a serialized FPCEvent as set by __endorse.
+// getEventKey is called with the event key id set by the chaincode and must return the corresponding event key.
+func DecryptEvent(eventPayload []byte, getEventKey func(eventKeyId string) ([]byte, error)) (*Event, error) {
+	fpcEvent := &protos.FPCEvent{}
+	if err := proto.Unmarshal(eventPayload, fpcEvent); err != nil {
+		return nil, errors.Wrap(err, "invalid fpc event")
+	}
+
+	if fpcEvent.GetEventKeyId() == "" {
+		return nil, fmt.Errorf("event %s is encrypted for the invoking client only", fpcEvent.GetEventName())
+	}
+
+	eventKey, err := getEventKey(fpcEvent.GetEventKeyId())
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get event key %s", fpcEvent.GetEventKeyId())
+	}
+
+	payload, err := crypto.GetDefaultCSP().DecryptMessage(eventKey, fpcEvent.GetEncryptedPayload())
+	if err != nil {
+		return nil, errors.Wrap(err, "decryption of event payload failed")
+	}
+
+	return &Event{
+		Name:       fpcEvent.GetEventName(),
+		EventKeyId: fpcEvent.GetEventKeyId(),
+		Payload:    payload,
+	}, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract_test
+
+import (
+	"fmt"
+	"testing"
+
+	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptEvent(t *testing.T) {
+	payload := []byte("some event payload")
+
+	eventKey, err := crypto.GetDefaultCSP().NewSymmetricKey()
+	assert.NoError(t, err)
+	encryptedPayload, err := crypto.GetDefaultCSP().EncryptMessage(eventKey, payload)
+	assert.NoError(t, err)
+
+	getEventKey := func(eventKeyId string) ([]byte, error) {
+		if eventKeyId != "someKey" {
+			return nil, fmt.Errorf("unknown key")
+		}
+		return eventKey, nil
+	}
+
+	// invalid event
+	event, err := fpccontract.DecryptEvent([]byte("not an fpc event"), getEventKey)
+	assert.Nil(t, event)
+	assert.Error(t, err)
+
+	// event for the invoking client only
+	eventBytes := protoutil.MarshalOrPanic(&protos.FPCEvent{EventName: "someEvent", EncryptedPayload: encryptedPayload})
+	event, err = fpccontract.DecryptEvent(eventBytes, getEventKey)
+	assert.Nil(t, event)
+	assert.Error(t, err)
+
+	// unknown key
+	eventBytes = protoutil.MarshalOrPanic(&protos.FPCEvent{EventName: "someEvent", EncryptedPayload: encryptedPayload, EventKeyId: "otherKey"})
+	event, err = fpccontract.DecryptEvent(eventBytes, getEventKey)
+	assert.Nil(t, event)
+	assert.Error(t, err)
+
+	// wrong key
+	otherKey, err := crypto.GetDefaultCSP().NewSymmetricKey()
+	assert.NoError(t, err)
+	event, err = fpccontract.DecryptEvent(eventBytes, func(string) ([]byte, error) { return otherKey, nil })
+	assert.Nil(t, event)
+	assert.Error(t, err)
+
+	// should succeed
+	eventBytes = protoutil.MarshalOrPanic(&protos.FPCEvent{EventName: "someEvent", EncryptedPayload: encryptedPayload, EventKeyId: "someKey"})
+	event, err = fpccontract.DecryptEvent(eventBytes, getEventKey)
+	assert.NoError(t, err)
+	assert.Equal(t, &fpccontract.Event{Name: "someEvent", EventKeyId: "someKey", Payload: payload}, event)
+}
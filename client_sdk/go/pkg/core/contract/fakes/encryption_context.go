@@ -3,6 +3,8 @@ package fakes
 
 import (
 	"sync"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 )
 
 type EncryptionContext struct {
@@ -20,6 +22,36 @@ type EncryptionContext struct {
 		result1 string
 		result2 error
 	}
+	ConcealBytesStub        func(string, [][]byte, map[string][]byte) (string, error)
+	concealBytesMutex       sync.RWMutex
+	concealBytesArgsForCall []struct {
+		arg1 string
+		arg2 [][]byte
+		arg3 map[string][]byte
+	}
+	concealBytesReturns struct {
+		result1 string
+		result2 error
+	}
+	concealBytesReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	ConcealWithTransientStub        func(string, []string, map[string][]byte) (string, error)
+	concealWithTransientMutex       sync.RWMutex
+	concealWithTransientArgsForCall []struct {
+		arg1 string
+		arg2 []string
+		arg3 map[string][]byte
+	}
+	concealWithTransientReturns struct {
+		result1 string
+		result2 error
+	}
+	concealWithTransientReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	RevealStub        func([]byte) ([]byte, error)
 	revealMutex       sync.RWMutex
 	revealArgsForCall []struct {
@@ -33,6 +65,21 @@ type EncryptionContext struct {
 		result1 []byte
 		result2 error
 	}
+	RevealEventStub        func([]byte) (*protos.FPCEvent, []byte, error)
+	revealEventMutex       sync.RWMutex
+	revealEventArgsForCall []struct {
+		arg1 []byte
+	}
+	revealEventReturns struct {
+		result1 *protos.FPCEvent
+		result2 []byte
+		result3 error
+	}
+	revealEventReturnsOnCall map[int]struct {
+		result1 *protos.FPCEvent
+		result2 []byte
+		result3 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -107,6 +154,148 @@ func (fake *EncryptionContext) ConcealReturnsOnCall(i int, result1 string, resul
 	}{result1, result2}
 }
 
+func (fake *EncryptionContext) ConcealBytes(arg1 string, arg2 [][]byte, arg3 map[string][]byte) (string, error) {
+	var arg2Copy [][]byte
+	if arg2 != nil {
+		arg2Copy = make([][]byte, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.concealBytesMutex.Lock()
+	ret, specificReturn := fake.concealBytesReturnsOnCall[len(fake.concealBytesArgsForCall)]
+	fake.concealBytesArgsForCall = append(fake.concealBytesArgsForCall, struct {
+		arg1 string
+		arg2 [][]byte
+		arg3 map[string][]byte
+	}{arg1, arg2Copy, arg3})
+	stub := fake.ConcealBytesStub
+	fakeReturns := fake.concealBytesReturns
+	fake.recordInvocation("ConcealBytes", []interface{}{arg1, arg2Copy, arg3})
+	fake.concealBytesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *EncryptionContext) ConcealBytesCallCount() int {
+	fake.concealBytesMutex.RLock()
+	defer fake.concealBytesMutex.RUnlock()
+	return len(fake.concealBytesArgsForCall)
+}
+
+func (fake *EncryptionContext) ConcealBytesCalls(stub func(string, [][]byte, map[string][]byte) (string, error)) {
+	fake.concealBytesMutex.Lock()
+	defer fake.concealBytesMutex.Unlock()
+	fake.ConcealBytesStub = stub
+}
+
+func (fake *EncryptionContext) ConcealBytesArgsForCall(i int) (string, [][]byte, map[string][]byte) {
+	fake.concealBytesMutex.RLock()
+	defer fake.concealBytesMutex.RUnlock()
+	argsForCall := fake.concealBytesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *EncryptionContext) ConcealBytesReturns(result1 string, result2 error) {
+	fake.concealBytesMutex.Lock()
+	defer fake.concealBytesMutex.Unlock()
+	fake.ConcealBytesStub = nil
+	fake.concealBytesReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *EncryptionContext) ConcealBytesReturnsOnCall(i int, result1 string, result2 error) {
+	fake.concealBytesMutex.Lock()
+	defer fake.concealBytesMutex.Unlock()
+	fake.ConcealBytesStub = nil
+	if fake.concealBytesReturnsOnCall == nil {
+		fake.concealBytesReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.concealBytesReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *EncryptionContext) ConcealWithTransient(arg1 string, arg2 []string, arg3 map[string][]byte) (string, error) {
+	var arg2Copy []string
+	if arg2 != nil {
+		arg2Copy = make([]string, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.concealWithTransientMutex.Lock()
+	ret, specificReturn := fake.concealWithTransientReturnsOnCall[len(fake.concealWithTransientArgsForCall)]
+	fake.concealWithTransientArgsForCall = append(fake.concealWithTransientArgsForCall, struct {
+		arg1 string
+		arg2 []string
+		arg3 map[string][]byte
+	}{arg1, arg2Copy, arg3})
+	stub := fake.ConcealWithTransientStub
+	fakeReturns := fake.concealWithTransientReturns
+	fake.recordInvocation("ConcealWithTransient", []interface{}{arg1, arg2Copy, arg3})
+	fake.concealWithTransientMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *EncryptionContext) ConcealWithTransientCallCount() int {
+	fake.concealWithTransientMutex.RLock()
+	defer fake.concealWithTransientMutex.RUnlock()
+	return len(fake.concealWithTransientArgsForCall)
+}
+
+func (fake *EncryptionContext) ConcealWithTransientCalls(stub func(string, []string, map[string][]byte) (string, error)) {
+	fake.concealWithTransientMutex.Lock()
+	defer fake.concealWithTransientMutex.Unlock()
+	fake.ConcealWithTransientStub = stub
+}
+
+func (fake *EncryptionContext) ConcealWithTransientArgsForCall(i int) (string, []string, map[string][]byte) {
+	fake.concealWithTransientMutex.RLock()
+	defer fake.concealWithTransientMutex.RUnlock()
+	argsForCall := fake.concealWithTransientArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *EncryptionContext) ConcealWithTransientReturns(result1 string, result2 error) {
+	fake.concealWithTransientMutex.Lock()
+	defer fake.concealWithTransientMutex.Unlock()
+	fake.ConcealWithTransientStub = nil
+	fake.concealWithTransientReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *EncryptionContext) ConcealWithTransientReturnsOnCall(i int, result1 string, result2 error) {
+	fake.concealWithTransientMutex.Lock()
+	defer fake.concealWithTransientMutex.Unlock()
+	fake.ConcealWithTransientStub = nil
+	if fake.concealWithTransientReturnsOnCall == nil {
+		fake.concealWithTransientReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.concealWithTransientReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *EncryptionContext) Reveal(arg1 []byte) ([]byte, error) {
 	var arg1Copy []byte
 	if arg1 != nil {
@@ -176,13 +365,91 @@ func (fake *EncryptionContext) RevealReturnsOnCall(i int, result1 []byte, result
 	}{result1, result2}
 }
 
+func (fake *EncryptionContext) RevealEvent(arg1 []byte) (*protos.FPCEvent, []byte, error) {
+	var arg1Copy []byte
+	if arg1 != nil {
+		arg1Copy = make([]byte, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.revealEventMutex.Lock()
+	ret, specificReturn := fake.revealEventReturnsOnCall[len(fake.revealEventArgsForCall)]
+	fake.revealEventArgsForCall = append(fake.revealEventArgsForCall, struct {
+		arg1 []byte
+	}{arg1Copy})
+	stub := fake.RevealEventStub
+	fakeReturns := fake.revealEventReturns
+	fake.recordInvocation("RevealEvent", []interface{}{arg1Copy})
+	fake.revealEventMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *EncryptionContext) RevealEventCallCount() int {
+	fake.revealEventMutex.RLock()
+	defer fake.revealEventMutex.RUnlock()
+	return len(fake.revealEventArgsForCall)
+}
+
+func (fake *EncryptionContext) RevealEventCalls(stub func([]byte) (*protos.FPCEvent, []byte, error)) {
+	fake.revealEventMutex.Lock()
+	defer fake.revealEventMutex.Unlock()
+	fake.RevealEventStub = stub
+}
+
+func (fake *EncryptionContext) RevealEventArgsForCall(i int) []byte {
+	fake.revealEventMutex.RLock()
+	defer fake.revealEventMutex.RUnlock()
+	argsForCall := fake.revealEventArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *EncryptionContext) RevealEventReturns(result1 *protos.FPCEvent, result2 []byte, result3 error) {
+	fake.revealEventMutex.Lock()
+	defer fake.revealEventMutex.Unlock()
+	fake.RevealEventStub = nil
+	fake.revealEventReturns = struct {
+		result1 *protos.FPCEvent
+		result2 []byte
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *EncryptionContext) RevealEventReturnsOnCall(i int, result1 *protos.FPCEvent, result2 []byte, result3 error) {
+	fake.revealEventMutex.Lock()
+	defer fake.revealEventMutex.Unlock()
+	fake.RevealEventStub = nil
+	if fake.revealEventReturnsOnCall == nil {
+		fake.revealEventReturnsOnCall = make(map[int]struct {
+			result1 *protos.FPCEvent
+			result2 []byte
+			result3 error
+		})
+	}
+	fake.revealEventReturnsOnCall[i] = struct {
+		result1 *protos.FPCEvent
+		result2 []byte
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *EncryptionContext) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.concealMutex.RLock()
 	defer fake.concealMutex.RUnlock()
+	fake.concealBytesMutex.RLock()
+	defer fake.concealBytesMutex.RUnlock()
+	fake.concealWithTransientMutex.RLock()
+	defer fake.concealWithTransientMutex.RUnlock()
 	fake.revealMutex.RLock()
 	defer fake.revealMutex.RUnlock()
+	fake.revealEventMutex.RLock()
+	defer fake.revealEventMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
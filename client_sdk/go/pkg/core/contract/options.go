@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+)
+
+// Option configures the FPC contract
+type Option func(*options)
+
+type options struct {
+	verifier          CredentialVerifier
+	expectedMrenclave string
+	policy            *protos.AttestationVerificationPolicy
+	attestedKey       bool
+	cacheTTL          time.Duration
+	retry             RetryPolicy
+}
+
+// WithCredentialVerifier option enables the local verification of the attestation evidence of each enclave before
+// its responses are accepted, instead of relying on the verification by ERCC.
+// The evidence is verified against the mrenclave of the chaincode definition (see WithExpectedMrenclave) and the
+// verification policy of the client (see WithVerificationPolicy); the claims of the enclave and the verification
+// policy set at ERCC are not trusted.
+func WithCredentialVerifier(verifier CredentialVerifier) Option {
+	return func(o *options) {
+		o.verifier = verifier
+	}
+}
+
+// WithExpectedMrenclave option sets the mrenclave that the enclaves of the chaincode must run, i.e., the version of
+// the chaincode definition, as expected by the client. If not set, the chaincode definition is queried from the
+// _lifecycle chaincode, and the version and sequence of the enclaves must match it.
+func WithExpectedMrenclave(mrenclave string) Option {
+	return func(o *options) {
+		o.expectedMrenclave = mrenclave
+	}
+}
+
+// WithVerificationPolicy option sets the policy used by the verifier set by WithCredentialVerifier, e.g., to accept
+// platforms that are not UpToDate or enclaves other than the expected mrenclave. If not set, only UpToDate platforms
+// running the expected mrenclave are accepted.
+func WithVerificationPolicy(policy *protos.AttestationVerificationPolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithAttestedEncryptionKey option takes the chaincode encryption key bound to the enclave credentials registered at
+// ERCC, instead of trusting the key returned by queryChaincodeEncryptionKey. The key is either part of the attested
+// data of an enclave or, for enclaves provisioned via the key distribution protocol, part of the cc key registration
+// message signed by the enclave (see queryCCKeyRegistration).
+// The credentials are verified using the verifier set by WithCredentialVerifier, which is required with this option,
+// and the accepted key is pinned per chaincode sequence.
+func WithAttestedEncryptionKey() Option {
+	return func(o *options) {
+		o.attestedKey = true
+	}
+}
+
+// WithCacheTTL option overrides how long the metadata queried from ERCC (i.e., the chaincode encryption key,
+// the peer endpoints, and the enclave credentials) is cached (DefaultCacheTTL). A TTL of zero disables caching.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.cacheTTL = ttl
+	}
+}
+
+// WithRetryPolicy option reruns submitted transactions that fail due to a read conflict (see IsReadConflict),
+// i.e., the transaction is encrypted with a new encryption context, invoked, and endorsed again.
+// By default, transactions are not retried. Note that SubmitAsync only retries transactions whose read conflict is
+// detected while endorsing, as it does not wait for the commit.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// RetryPolicy configures how often and when a transaction is rerun after a read conflict
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a transaction is rerun; no retries are made if zero
+	MaxRetries int
+	// Backoff is the delay before the first retry; the delay is doubled with every retry
+	Backoff time.Duration
+	// MaxBackoff bounds the delay between retries; the delay is not bound if zero
+	MaxBackoff time.Duration
+}
+
+// IsReadConflict returns true if a transaction failed because the state read by the transaction was changed by
+// another transaction, i.e., if __endorse failed with a ChaincodeError with status utils.ReadConflictStatus as the
+// state changed after __invoke, or the transaction was committed with a MVCC or phantom read conflict.
+// Such transactions may succeed when rerun.
+func IsReadConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var commitErr *CommitError
+	if errors.As(err, &commitErr) {
+		return commitErr.Code == peer.TxValidationCode_MVCC_READ_CONFLICT ||
+			commitErr.Code == peer.TxValidationCode_PHANTOM_READ_CONFLICT
+	}
+
+	var chaincodeErr *ChaincodeError
+	return errors.As(err, &chaincodeErr) && chaincodeErr.Status == utils.ReadConflictStatus
+}
+
+// backoff returns the delay before the given retry, starting with 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry && (p.MaxBackoff == 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// wait blocks for the delay before the given retry or until the context is done
+func (p RetryPolicy) wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.backoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract/fakes"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReadConflict(t *testing.T) {
+	assert.False(t, fpccontract.IsReadConflict(nil))
+	assert.False(t, fpccontract.IsReadConflict(fmt.Errorf("some error")))
+	assert.False(t, fpccontract.IsReadConflict(fmt.Errorf("value hash mismatch for key k1")))
+	assert.True(t, fpccontract.IsReadConflict(fmt.Errorf("wrapped: %w", &fpccontract.ChaincodeError{
+		Status: utils.ReadConflictStatus,
+		Err:    fmt.Errorf("value hash mismatch for key k1"),
+	})))
+	assert.False(t, fpccontract.IsReadConflict(&fpccontract.ChaincodeError{Status: 500, Err: fmt.Errorf("some error")}))
+	assert.True(t, fpccontract.IsReadConflict(&fpccontract.PhaseError{
+		Phase: fpccontract.PhaseEndorse,
+		Err:   &fpccontract.CommitError{Code: peer.TxValidationCode_MVCC_READ_CONFLICT},
+	}))
+	assert.True(t, fpccontract.IsReadConflict(&fpccontract.CommitError{Code: peer.TxValidationCode_PHANTOM_READ_CONFLICT}))
+	assert.False(t, fpccontract.IsReadConflict(&fpccontract.CommitError{Code: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}))
+}
+
+func TestContractRetriesReadConflicts(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	conflict := &fpccontract.ChaincodeError{Status: utils.ReadConflictStatus, Err: fmt.Errorf("value hash mismatch for key k1")}
+
+	// without retry policy
+	contract, _, target := setupContractWithTarget(t, enclave)
+	target.SubmitTransactionReturnsOnCall(0, nil, conflict)
+	_, err := contract.SubmitTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "__endorse failed: value hash mismatch for key k1")
+	assert.Equal(t, 1, target.SubmitTransactionCallCount())
+
+	// the cycle is rerun until it succeeds
+	policy := fpccontract.RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond}
+	contract, ercc, target := setupContractWithTarget(t, enclave, fpccontract.WithRetryPolicy(policy))
+	target.SubmitTransactionReturnsOnCall(0, nil, conflict)
+	target.SubmitTransactionReturnsOnCall(1, nil, &fpccontract.CommitError{Code: peer.TxValidationCode_MVCC_READ_CONFLICT})
+	result, err := contract.SubmitTransaction("someFunction", "someArg")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+	assert.Equal(t, 3, target.SubmitTransactionCallCount())
+	assert.Equal(t, 3, target.CreateTransactionCallCount())
+
+	// each run uses a new request
+	_, args1 := target.SubmitTransactionArgsForCall(0)
+	_, args2 := target.SubmitTransactionArgsForCall(1)
+	assert.NotEqual(t, args1, args2)
+
+	// read conflicts do not invalidate the ercc cache
+	assert.Equal(t, 1, countCalls(ercc, "queryChaincodeEncryptionKey"))
+
+	// give up after max retries
+	target.SubmitTransactionReturns(nil, conflict)
+	_, err = contract.SubmitTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "__endorse failed: value hash mismatch for key k1")
+	assert.Equal(t, 3+4, target.SubmitTransactionCallCount())
+
+	// other errors are not retried
+	target.SubmitTransactionReturns(nil, fmt.Errorf("some error"))
+	_, err = contract.SubmitTransaction("someFunction", "someArg")
+	assert.EqualError(t, err, "__endorse failed: some error")
+	assert.Equal(t, 3+4+1, target.SubmitTransactionCallCount())
+
+	// the backoff is aborted when the context is done
+	policy = fpccontract.RetryPolicy{MaxRetries: 3, Backoff: time.Minute}
+	contract, _, target = setupContractWithTarget(t, enclave, fpccontract.WithRetryPolicy(policy))
+	target.SubmitTransactionReturns(nil, conflict)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = contract.Submit(ctx, "someFunction", fpccontract.WithArgs("someArg"))
+	assert.EqualError(t, err, "__endorse failed: context deadline exceeded")
+	assert.Equal(t, 1, target.SubmitTransactionCallCount())
+}
+
+// asyncTarget submits __endorse asynchronously; the submission fails with the errors of the SubmitTransaction stub
+type asyncTarget struct {
+	*fakes.Contract
+}
+
+func (c *asyncTarget) SubmitTransactionAsync(ctx context.Context, name string, args ...string) (fpccontract.Commit, error) {
+	if _, err := c.SubmitTransaction(name, args...); err != nil {
+		return nil, err
+	}
+	return fpccontract.NewCommit(func() (*fpccontract.CommitStatus, error) {
+		return &fpccontract.CommitStatus{Code: peer.TxValidationCode_VALID}, nil
+	}), nil
+}
+
+func TestContractRetriesReadConflictsAsync(t *testing.T) {
+	enclave := newTestEnclave(t, "myChaincode")
+	conflict := &fpccontract.ChaincodeError{Status: utils.ReadConflictStatus, Err: fmt.Errorf("value hash mismatch for key k1")}
+	policy := fpccontract.RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond}
+	wrap := func(target *fakes.Contract) fpccontract.Contract { return &asyncTarget{Contract: target} }
+	ctx := context.Background()
+
+	// read conflicts detected while endorsing are retried
+	contract, _, target := setupContractWithWrappedTarget(t, enclave, wrap, fpccontract.WithRetryPolicy(policy))
+	target.SubmitTransactionReturnsOnCall(0, nil, conflict)
+	handle, err := contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("someArg"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, target.SubmitTransactionCallCount())
+	assert.Equal(t, 2, target.CreateTransactionCallCount())
+	result, err := handle.Wait(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+
+	// give up after max retries
+	target.SubmitTransactionReturns(nil, conflict)
+	_, err = contract.SubmitAsync(ctx, "someFunction", fpccontract.WithArgs("someArg"))
+	assert.EqualError(t, err, "__endorse failed: value hash mismatch for key k1")
+	assert.Equal(t, 2+4, target.SubmitTransactionCallCount())
+}
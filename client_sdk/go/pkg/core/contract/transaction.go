@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+)
+
+// TransactionOption sets the arguments and optional settings of a transaction invoked using Evaluate, Submit, or
+// SubmitAsync. The options can be combined, e.g., WithBytesArgs, WithTransient and WithEvent.
+type TransactionOption func(*transactionOptions)
+
+type transactionOptions struct {
+	args      [][]byte
+	transient map[string][]byte
+	event     func(event *Event)
+}
+
+// WithArgs option adds the given arguments to the arguments passed to the transaction function
+func WithArgs(args ...string) TransactionOption {
+	return func(o *transactionOptions) {
+		for _, arg := range args {
+			o.args = append(o.args, []byte(arg))
+		}
+	}
+}
+
+// WithBytesArgs option adds the given arguments to the arguments passed to the transaction function. The arguments
+// are passed as they are; thus, binary data such as serialized protobuf messages does not need to be encoded as
+// strings.
+func WithBytesArgs(args ...[]byte) TransactionOption {
+	return func(o *transactionOptions) {
+		o.args = append(o.args, args...)
+	}
+}
+
+// WithTransient option passes transient data to the transaction function. The transient data is encrypted as part
+// of the chaincode request, can be accessed by the chaincode via GetTransient, and is not committed to the ledger.
+func WithTransient(transient map[string][]byte) TransactionOption {
+	return func(o *transactionOptions) {
+		o.transient = transient
+	}
+}
+
+// WithEvent option calls handler with the chaincode event set by the transaction function, or with nil if no event
+// was set, once the transaction succeeds.
+// If the event was encrypted by the chaincode using an event key (see FpcStubInterface.SetEventWithKey), the event
+// payload is not revealed to the invoking client and must be decrypted by subscribers using DecryptEvent.
+func WithEvent(handler func(event *Event)) TransactionOption {
+	return func(o *transactionOptions) {
+		o.event = handler
+	}
+}
+
+func newTransactionOptions(opts []TransactionOption) *transactionOptions {
+	o := &transactionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// conceal encrypts the request of the transaction function using the given encryption context
+func (o *transactionOptions) conceal(encCtx crypto.EncryptionContext, name string) (string, error) {
+	return encCtx.ConcealBytes(name, o.args, o.transient)
+}
+
+// revealEvent decrypts the chaincode event contained in the response if an event handler is set
+func (o *transactionOptions) revealEvent(encCtx crypto.EncryptionContext, encryptedResponse []byte) (*Event, error) {
+	if o.event == nil {
+		return nil, nil
+	}
+
+	fpcEvent, payload, err := encCtx.RevealEvent(encryptedResponse)
+	if err != nil {
+		return nil, err
+	}
+	if fpcEvent == nil {
+		return nil, nil
+	}
+
+	return &Event{
+		Name:       fpcEvent.GetEventName(),
+		EventKeyId: fpcEvent.GetEventKeyId(),
+		Payload:    payload,
+	}, nil
+}
+
+// handleEvent passes the event to the event handler, if set
+func (o *transactionOptions) handleEvent(event *Event) {
+	if o.event != nil {
+		o.event(event)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// Evaluator evaluates a transaction function, e.g., the FPC contract returned by GetContract
+type Evaluator interface {
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+}
+
+// Submitter submits a transaction, e.g., the FPC contract returned by GetContract
+type Submitter interface {
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+}
+
+// EvaluateJSON evaluates the transaction function and decodes its JSON result into the value pointed to by result
+func EvaluateJSON(c Evaluator, result interface{}, name string, args ...string) error {
+	resp, err := c.EvaluateTransaction(name, args...)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(resp, result)
+}
+
+// SubmitJSON submits the transaction and decodes the JSON result of the transaction function into the value
+// pointed to by result
+func SubmitJSON(c Submitter, result interface{}, name string, args ...string) error {
+	resp, err := c.SubmitTransaction(name, args...)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(resp, result)
+}
+
+// EvaluateProto evaluates the transaction function and decodes its result, a serialized protobuf message, into result
+func EvaluateProto(c Evaluator, result proto.Message, name string, args ...string) error {
+	resp, err := c.EvaluateTransaction(name, args...)
+	if err != nil {
+		return err
+	}
+	return decodeProto(resp, result)
+}
+
+// SubmitProto submits the transaction and decodes the result of the transaction function, a serialized protobuf
+// message, into result
+func SubmitProto(c Submitter, result proto.Message, name string, args ...string) error {
+	resp, err := c.SubmitTransaction(name, args...)
+	if err != nil {
+		return err
+	}
+	return decodeProto(resp, result)
+}
+
+func decodeJSON(resp []byte, result interface{}) error {
+	if err := json.Unmarshal(resp, result); err != nil {
+		return errors.Wrap(err, "cannot decode JSON result")
+	}
+	return nil
+}
+
+func decodeProto(resp []byte, result proto.Message) error {
+	if err := proto.Unmarshal(resp, result); err != nil {
+		return errors.Wrap(err, "cannot decode protobuf result")
+	}
+	return nil
+}
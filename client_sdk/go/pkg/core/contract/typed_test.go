@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contract_test
+
+import (
+	"fmt"
+	"testing"
+
+	fpccontract "github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract/fakes"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+type asset struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+func TestJSONResult(t *testing.T) {
+	c := &fakes.Contract{}
+	c.EvaluateTransactionReturns([]byte(`{"name":"asset1","value":100}`), nil)
+	c.SubmitTransactionReturns([]byte(`{"name":"asset2","value":200}`), nil)
+
+	var a asset
+	assert.NoError(t, fpccontract.EvaluateJSON(c, &a, "getAsset", "asset1"))
+	assert.Equal(t, asset{Name: "asset1", Value: 100}, a)
+	name, args := c.EvaluateTransactionArgsForCall(0)
+	assert.Equal(t, "getAsset", name)
+	assert.Equal(t, []string{"asset1"}, args)
+
+	assert.NoError(t, fpccontract.SubmitJSON(c, &a, "createAsset", "asset2", "200"))
+	assert.Equal(t, asset{Name: "asset2", Value: 200}, a)
+
+	// invalid result
+	c.EvaluateTransactionReturns([]byte("not json"), nil)
+	err := fpccontract.EvaluateJSON(c, &a, "getAsset", "asset1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot decode JSON result")
+
+	// errors of the transaction are returned as they are
+	c.SubmitTransactionReturns(nil, fmt.Errorf("some error"))
+	assert.EqualError(t, fpccontract.SubmitJSON(c, &a, "createAsset", "asset2", "200"), "some error")
+}
+
+func TestProtoResult(t *testing.T) {
+	expected := &protos.CCParameters{ChaincodeId: "myChaincode", Sequence: 3}
+
+	c := &fakes.Contract{}
+	c.EvaluateTransactionReturns(protoutil.MarshalOrPanic(expected), nil)
+	c.SubmitTransactionReturns(protoutil.MarshalOrPanic(expected), nil)
+
+	result := &protos.CCParameters{}
+	assert.NoError(t, fpccontract.EvaluateProto(c, result, "getParams"))
+	assert.True(t, proto.Equal(expected, result))
+
+	result = &protos.CCParameters{}
+	assert.NoError(t, fpccontract.SubmitProto(c, result, "getParams"))
+	assert.True(t, proto.Equal(expected, result))
+
+	// invalid result
+	c.EvaluateTransactionReturns([]byte{0xff, 0xff}, nil)
+	err := fpccontract.EvaluateProto(c, result, "getParams")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot decode protobuf result")
+
+	// errors of the transaction are returned as they are
+	c.SubmitTransactionReturns(nil, fmt.Errorf("some error"))
+	assert.EqualError(t, fpccontract.SubmitProto(c, result, "getParams"), "some error")
+}
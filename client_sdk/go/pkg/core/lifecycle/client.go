@@ -39,6 +39,9 @@ SPDX-License-Identifier: Apache-2.0
 package lifecycle
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/pkg/errors"
 
@@ -49,9 +52,18 @@ import (
 )
 
 const (
-	ERCC               = "ercc"
-	InitEnclaveCMD     = "__initEnclave"
-	RegisterEnclaveCMD = "registerEnclave"
+	ERCC                           = "ercc"
+	InitEnclaveCMD                 = "__initEnclave"
+	GenerateCCKeysCMD              = "__generateCCKeys"
+	ExportCCKeysCMD                = "__exportCCKeys"
+	ImportCCKeysCMD                = "__importCCKeys"
+	RegisterEnclaveCMD             = "registerEnclave"
+	RegisterCCKeysCMD              = "registerCCKeys"
+	PutKeyExportCMD                = "putKeyExport"
+	QueryChaincodeEndPointsCMD     = "queryChaincodeEndPoints"
+	QueryChaincodeEncryptionKeyCMD = "queryChaincodeEncryptionKey"
+	QueryEnclaveCredentialsCMD     = "queryEnclaveCredentials"
+	QueryProvisionedEnclavesCMD    = "queryListProvisionedEnclaves"
 )
 
 var logger = flogging.MustGetLogger("fpc-client-lifecycle")
@@ -98,6 +110,9 @@ func New(getChannelClient GetChannelClientFunction) (*Client, error) {
 }
 
 // LifecycleInitEnclave initializes and registers an enclave for a particular FPC chaincode.
+// If no chaincode keys are registered for the chaincode yet, the new enclave generates them and registers them at ERCC.
+// If the enclave is already registered and provisioned, for instance, because it restored its sealed state after a
+// restart, the existing registration is reused and the returned txID is empty.
 func (rc *Client) LifecycleInitEnclave(channelID string, req LifecycleInitEnclaveRequest) (string, error) {
 	err := rc.verifyInitEnclaveRequest(req)
 	if err != nil {
@@ -141,16 +156,146 @@ func (rc *Client) LifecycleInitEnclave(channelID string, req LifecycleInitEnclav
 		return "", errors.Wrap(err, "credentials conversion error")
 	}
 
-	logger.Debugf("calling registerEnclave")
-	// invoke registerEnclave at enclave registry
-	txID, err := channelClient.Execute(ERCC, RegisterEnclaveCMD, [][]byte{[]byte(convertedCredentials)})
+	attestedData, err := getAttestedData(convertedCredentials)
+	if err != nil {
+		return "", err
+	}
+	enclaveId := utils.GetEnclaveId(attestedData)
+
+	// an enclave that restored its sealed state returns the credentials it was registered with before
+	registeredCredentials, err := channelClient.Query(ERCC, QueryEnclaveCredentialsCMD, [][]byte{[]byte(req.ChaincodeID), []byte(enclaveId)})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to query enclave credentials")
+	}
+
+	var txID string
+	if len(registeredCredentials) > 0 {
+		logger.Debugf("enclave %s already registered", enclaveId)
+		provisioned, err := rc.isProvisioned(channelClient, req.ChaincodeID, enclaveId)
+		if err != nil {
+			return "", err
+		}
+		if provisioned {
+			logger.Debugf("enclave %s already provisioned", enclaveId)
+			return "", nil
+		}
+	} else {
+		logger.Debugf("calling registerEnclave")
+		// invoke registerEnclave at enclave registry
+		txID, err = channelClient.Execute(ERCC, RegisterEnclaveCMD, [][]byte{[]byte(convertedCredentials)})
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to execute register enclave")
+		}
+	}
+
+	// enclaves that include the chaincode encryption key in their attested data (i.e., the C++ enclaves) create their
+	// chaincode keys at initialization and are provisioned with their registration; they do not support key generation
+	// and distribution
+	if len(attestedData.GetChaincodeEk()) > 0 {
+		logger.Debugf("enclave %s attests its chaincode keys", enclaveId)
+		return txID, nil
+	}
+
+	// check if chaincode keys are already registered for this chaincode
+	chaincodeEk, err := channelClient.Query(ERCC, QueryChaincodeEncryptionKeyCMD, [][]byte{[]byte(req.ChaincodeID)})
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to execute register enclave")
+		return "", errors.Wrap(err, "Failed to query chaincode encryption key")
+	}
+
+	var signedCCKeyRegistrationMessage []byte
+	if len(chaincodeEk) > 0 {
+		// this enclave receives the chaincode keys from an already provisioned enclave
+		logger.Debugf("chaincode keys already registered for %s", req.ChaincodeID)
+		signedCCKeyRegistrationMessage, err = rc.provisionEnclave(channelClient, req, convertedCredentials)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		logger.Debugf("calling __generateCCKeys")
+		// no chaincode keys yet, so let the new enclave generate them
+		signedCCKeyRegistrationMessage, err = channelClient.Query(req.ChaincodeID, GenerateCCKeysCMD, [][]byte{}, req.EnclavePeerEndpoint)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to query generate chaincode keys")
+		}
+	}
+
+	logger.Debugf("calling registerCCKeys")
+	txID, err = channelClient.Execute(ERCC, RegisterCCKeysCMD, [][]byte{[]byte(req.ChaincodeID), signedCCKeyRegistrationMessage})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to execute register chaincode keys")
 	}
 
 	return txID, nil
 }
 
+// provisionEnclave exports the chaincode keys from an already provisioned enclave to the new enclave and
+// returns the cc key registration message of the new enclave after importing the keys.
+func (rc *Client) provisionEnclave(channelClient ChannelClient, req LifecycleInitEnclaveRequest, credentials string) ([]byte, error) {
+	endpoints, err := channelClient.Query(ERCC, QueryChaincodeEndPointsCMD, [][]byte{[]byte(req.ChaincodeID)})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to query chaincode endpoints")
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("no provisioned enclave available to export chaincode keys")
+	}
+	senderEndpoint := strings.Split(string(endpoints), ",")[0]
+
+	logger.Debugf("calling __exportCCKeys at %s", senderEndpoint)
+	signedExportMessage, err := channelClient.Query(req.ChaincodeID, ExportCCKeysCMD, [][]byte{[]byte(credentials)}, senderEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to query export chaincode keys")
+	}
+
+	logger.Debugf("calling putKeyExport")
+	if _, err := channelClient.Execute(ERCC, PutKeyExportCMD, [][]byte{[]byte(req.ChaincodeID), signedExportMessage}); err != nil {
+		return nil, errors.Wrap(err, "Failed to execute put key export")
+	}
+
+	logger.Debugf("calling __importCCKeys")
+	signedCCKeyRegistrationMessage, err := channelClient.Query(req.ChaincodeID, ImportCCKeysCMD, [][]byte{}, req.EnclavePeerEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to query import chaincode keys")
+	}
+
+	return signedCCKeyRegistrationMessage, nil
+}
+
+// isProvisioned returns true if the chaincode keys are registered for the given enclave
+func (rc *Client) isProvisioned(channelClient ChannelClient, chaincodeID, enclaveId string) (bool, error) {
+	resp, err := channelClient.Query(ERCC, QueryProvisionedEnclavesCMD, [][]byte{[]byte(chaincodeID)})
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to query provisioned enclaves")
+	}
+
+	var provisionedEnclaves []string
+	if len(resp) > 0 {
+		if err := json.Unmarshal(resp, &provisionedEnclaves); err != nil {
+			return false, errors.Wrap(err, "invalid list of provisioned enclaves")
+		}
+	}
+
+	for _, id := range provisionedEnclaves {
+		if id == enclaveId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func getAttestedData(credentialsBase64 string) (*protos.AttestedData, error) {
+	credentials, err := utils.UnmarshalCredentials(credentialsBase64)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid credentials")
+	}
+
+	attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid attested data")
+	}
+
+	return attestedData, nil
+}
+
 func (rc *Client) verifyInitEnclaveRequest(req LifecycleInitEnclaveRequest) error {
 	if req.ChaincodeID == "" {
 		return errors.New("chaincodeId is required")
@@ -11,10 +11,13 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/anypb"
 
 	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/lifecycle"
 	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/lifecycle/fakes"
 	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/sgx"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 )
 
 //go:generate counterfeiter -o fakes/channelclient.go -fake-name ChannelClient . chClient
@@ -37,6 +40,12 @@ const (
 	expectedTxID        = "someTxID"
 )
 
+var (
+	attestedData, _ = anypb.New(&protos.AttestedData{EnclaveVk: []byte("someEnclaveVk")})
+	someCredentials = utils.MarshallProtoBase64(&protos.Credentials{SerializedAttestedData: attestedData, Evidence: []byte("someEvidence")})
+	someEnclaveId   = utils.GetEnclaveId(&protos.AttestedData{EnclaveVk: []byte("someEnclaveVk")})
+)
+
 func setupClient(client lifecycle.ChannelClient, converter lifecycle.CredentialConverter) *lifecycle.Client {
 	getChannelClient := func(channelId string) (lifecycle.ChannelClient, error) {
 		return client, nil
@@ -148,6 +157,7 @@ func TestLifecycleInitEnclaveFailedToRegisterEnclave(t *testing.T) {
 	fakeChannelClient := &fakes.ChannelClient{}
 	fakeChannelClient.ExecuteReturns("", expectedError)
 	fakeConverter := &fakes.CredentialConverter{}
+	fakeConverter.ConvertCredentialsReturns(someCredentials, nil)
 	client := setupClient(fakeChannelClient, fakeConverter)
 
 	initReq := lifecycle.LifecycleInitEnclaveRequest{
@@ -167,6 +177,7 @@ func TestLifecycleInitEnclaveSuccess(t *testing.T) {
 	fakeChannelClient.QueryReturns(nil, nil)
 	fakeChannelClient.ExecuteReturns(expectedTxID, nil)
 	fakeConverter := &fakes.CredentialConverter{}
+	fakeConverter.ConvertCredentialsReturns(someCredentials, nil)
 
 	client := setupClient(fakeChannelClient, fakeConverter)
 
@@ -182,16 +193,199 @@ func TestLifecycleInitEnclaveSuccess(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTxID, txId)
 
-	assert.Equal(t, 1, fakeChannelClient.QueryCallCount())
-	assert.Equal(t, 1, fakeChannelClient.ExecuteCallCount())
+	assert.Equal(t, 4, fakeChannelClient.QueryCallCount())
+	assert.Equal(t, 2, fakeChannelClient.ExecuteCallCount())
 
 	chaincodeID, Fcn, Args, _ := fakeChannelClient.QueryArgsForCall(0)
 	assert.Equal(t, chaincodeId, chaincodeID)
 	assert.Equal(t, lifecycle.InitEnclaveCMD, Fcn)
 	assert.Len(t, Args, 1)
 
+	// enclave is not registered yet
+	chaincodeID, Fcn, Args, _ = fakeChannelClient.QueryArgsForCall(1)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.QueryEnclaveCredentialsCMD, Fcn)
+	assert.Equal(t, [][]byte{[]byte(chaincodeId), []byte(someEnclaveId)}, Args)
+
 	chaincodeID, Fcn, Args = fakeChannelClient.ExecuteArgsForCall(0)
 	assert.Equal(t, lifecycle.ERCC, chaincodeID)
 	assert.Equal(t, lifecycle.RegisterEnclaveCMD, Fcn)
 	assert.Len(t, Args, 1)
+
+	// no chaincode keys registered yet, so they are generated
+	chaincodeID, Fcn, Args, _ = fakeChannelClient.QueryArgsForCall(2)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.QueryChaincodeEncryptionKeyCMD, Fcn)
+	assert.Equal(t, [][]byte{[]byte(chaincodeId)}, Args)
+
+	chaincodeID, Fcn, _, targets := fakeChannelClient.QueryArgsForCall(3)
+	assert.Equal(t, chaincodeId, chaincodeID)
+	assert.Equal(t, lifecycle.GenerateCCKeysCMD, Fcn)
+	assert.Equal(t, []string{enclavePeerEndpoint}, targets)
+
+	chaincodeID, Fcn, Args = fakeChannelClient.ExecuteArgsForCall(1)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.RegisterCCKeysCMD, Fcn)
+	assert.Len(t, Args, 2)
+	assert.Equal(t, []byte(chaincodeId), Args[0])
+}
+
+func TestLifecycleInitEnclaveWithAttestedChaincodeKeys(t *testing.T) {
+	fakeChannelClient := &fakes.ChannelClient{}
+	fakeChannelClient.QueryReturns(nil, nil)
+	fakeChannelClient.ExecuteReturns(expectedTxID, nil)
+	fakeConverter := &fakes.CredentialConverter{}
+	attestedDataWithEk, _ := anypb.New(&protos.AttestedData{EnclaveVk: []byte("someEnclaveVk"), ChaincodeEk: []byte("someChaincodeEk")})
+	fakeConverter.ConvertCredentialsReturns(utils.MarshallProtoBase64(&protos.Credentials{SerializedAttestedData: attestedDataWithEk, Evidence: []byte("someEvidence")}), nil)
+
+	client := setupClient(fakeChannelClient, fakeConverter)
+
+	initReq := lifecycle.LifecycleInitEnclaveRequest{
+		ChaincodeID:         chaincodeId,
+		EnclavePeerEndpoint: enclavePeerEndpoint,
+		AttestationParams: &sgx.AttestationParams{
+			AttestationType: attestationType,
+		},
+	}
+
+	txId, err := client.LifecycleInitEnclave(channelID, initReq)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTxID, txId)
+
+	// the enclave is provisioned with its registration, i.e., no keys are generated or distributed
+	assert.Equal(t, 2, fakeChannelClient.QueryCallCount())
+	assert.Equal(t, 1, fakeChannelClient.ExecuteCallCount())
+
+	chaincodeID, Fcn, _ := fakeChannelClient.ExecuteArgsForCall(0)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.RegisterEnclaveCMD, Fcn)
+}
+
+func TestLifecycleInitEnclaveWithExistingChaincodeKeys(t *testing.T) {
+	fakeChannelClient := &fakes.ChannelClient{}
+	fakeChannelClient.QueryReturns([]byte("someResponse"), nil)
+	fakeChannelClient.QueryReturnsOnCall(1, nil, nil)
+	fakeChannelClient.QueryReturnsOnCall(2, []byte("someChaincodeEk"), nil)
+	fakeChannelClient.QueryReturnsOnCall(3, []byte("otherPeer:7051,anotherPeer:7051"), nil)
+	fakeChannelClient.ExecuteReturns(expectedTxID, nil)
+	fakeConverter := &fakes.CredentialConverter{}
+	fakeConverter.ConvertCredentialsReturns(someCredentials, nil)
+
+	client := setupClient(fakeChannelClient, fakeConverter)
+
+	initReq := lifecycle.LifecycleInitEnclaveRequest{
+		ChaincodeID:         chaincodeId,
+		EnclavePeerEndpoint: enclavePeerEndpoint,
+		AttestationParams: &sgx.AttestationParams{
+			AttestationType: attestationType,
+		},
+	}
+
+	txId, err := client.LifecycleInitEnclave(channelID, initReq)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTxID, txId)
+
+	// keys are exported from a provisioned enclave and imported by the new enclave
+	assert.Equal(t, 6, fakeChannelClient.QueryCallCount())
+	assert.Equal(t, 3, fakeChannelClient.ExecuteCallCount())
+
+	chaincodeID, Fcn, Args, _ := fakeChannelClient.QueryArgsForCall(3)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.QueryChaincodeEndPointsCMD, Fcn)
+	assert.Equal(t, [][]byte{[]byte(chaincodeId)}, Args)
+
+	chaincodeID, Fcn, Args, targets := fakeChannelClient.QueryArgsForCall(4)
+	assert.Equal(t, chaincodeId, chaincodeID)
+	assert.Equal(t, lifecycle.ExportCCKeysCMD, Fcn)
+	assert.Equal(t, [][]byte{[]byte(someCredentials)}, Args)
+	assert.Equal(t, []string{"otherPeer:7051"}, targets)
+
+	chaincodeID, Fcn, Args = fakeChannelClient.ExecuteArgsForCall(1)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.PutKeyExportCMD, Fcn)
+	assert.Equal(t, [][]byte{[]byte(chaincodeId), []byte("someResponse")}, Args)
+
+	chaincodeID, Fcn, _, targets = fakeChannelClient.QueryArgsForCall(5)
+	assert.Equal(t, chaincodeId, chaincodeID)
+	assert.Equal(t, lifecycle.ImportCCKeysCMD, Fcn)
+	assert.Equal(t, []string{enclavePeerEndpoint}, targets)
+
+	chaincodeID, Fcn, _ = fakeChannelClient.ExecuteArgsForCall(2)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.RegisterCCKeysCMD, Fcn)
+
+	// no provisioned enclave available
+	fakeChannelClient.QueryReturnsOnCall(7, nil, nil)
+	fakeChannelClient.QueryReturnsOnCall(9, nil, nil)
+	_, err = client.LifecycleInitEnclave(channelID, initReq)
+	assert.EqualError(t, err, "no provisioned enclave available to export chaincode keys")
+}
+
+func TestLifecycleInitEnclaveFailedToRegisterCCKeys(t *testing.T) {
+	expectedError := fmt.Errorf("someRegisterError")
+	fakeChannelClient := &fakes.ChannelClient{}
+	fakeChannelClient.ExecuteReturnsOnCall(0, expectedTxID, nil)
+	fakeChannelClient.ExecuteReturnsOnCall(1, "", expectedError)
+	fakeConverter := &fakes.CredentialConverter{}
+	fakeConverter.ConvertCredentialsReturns(someCredentials, nil)
+	client := setupClient(fakeChannelClient, fakeConverter)
+
+	initReq := lifecycle.LifecycleInitEnclaveRequest{
+		ChaincodeID:         chaincodeId,
+		EnclavePeerEndpoint: enclavePeerEndpoint,
+		AttestationParams: &sgx.AttestationParams{
+			AttestationType: attestationType,
+		},
+	}
+
+	_, err := client.LifecycleInitEnclave(channelID, initReq)
+	assert.ErrorIs(t, err, expectedError)
+}
+
+func TestLifecycleInitEnclaveAlreadyRegistered(t *testing.T) {
+	fakeChannelClient := &fakes.ChannelClient{}
+	fakeChannelClient.QueryReturnsOnCall(1, []byte(someCredentials), nil)
+	fakeChannelClient.QueryReturnsOnCall(2, []byte(fmt.Sprintf("[\"otherEnclaveId\",\"%s\"]", someEnclaveId)), nil)
+	fakeChannelClient.ExecuteReturns(expectedTxID, nil)
+	fakeConverter := &fakes.CredentialConverter{}
+	fakeConverter.ConvertCredentialsReturns(someCredentials, nil)
+
+	client := setupClient(fakeChannelClient, fakeConverter)
+
+	initReq := lifecycle.LifecycleInitEnclaveRequest{
+		ChaincodeID:         chaincodeId,
+		EnclavePeerEndpoint: enclavePeerEndpoint,
+		AttestationParams: &sgx.AttestationParams{
+			AttestationType: attestationType,
+		},
+	}
+
+	// registered and provisioned enclave is reused
+	txId, err := client.LifecycleInitEnclave(channelID, initReq)
+	assert.NoError(t, err)
+	assert.Empty(t, txId)
+	assert.Equal(t, 3, fakeChannelClient.QueryCallCount())
+	assert.Equal(t, 0, fakeChannelClient.ExecuteCallCount())
+
+	chaincodeID, Fcn, Args, _ := fakeChannelClient.QueryArgsForCall(2)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.QueryProvisionedEnclavesCMD, Fcn)
+	assert.Equal(t, [][]byte{[]byte(chaincodeId)}, Args)
+
+	// registered but not yet provisioned enclave receives the chaincode keys without registering again
+	fakeChannelClient.QueryReturnsOnCall(4, []byte(someCredentials), nil)
+	fakeChannelClient.QueryReturnsOnCall(5, []byte("[]"), nil)
+	txId, err = client.LifecycleInitEnclave(channelID, initReq)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTxID, txId)
+	assert.Equal(t, 1, fakeChannelClient.ExecuteCallCount())
+
+	chaincodeID, Fcn, _ = fakeChannelClient.ExecuteArgsForCall(0)
+	assert.Equal(t, lifecycle.ERCC, chaincodeID)
+	assert.Equal(t, lifecycle.RegisterCCKeysCMD, Fcn)
+
+	// invalid credentials
+	fakeConverter.ConvertCredentialsReturns("invalid credentials", nil)
+	_, err = client.LifecycleInitEnclave(channelID, initReq)
+	assert.ErrorContains(t, err, "invalid credentials")
 }
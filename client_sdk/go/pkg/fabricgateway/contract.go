@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fabricgateway enables interaction with a FPC chaincode through the Fabric Gateway service of a peer
+// (https://hyperledger-fabric.readthedocs.io/en/latest/gateway.html).
+//
+// Unlike the gateway package, which builds on the deprecated gateway of the Fabric SDK Go, this package is a separate
+// Go module, as it requires newer gRPC versions than the Fabric SDK Go.
+//
+// The package talks to the Gateway service directly rather than through the Fabric Gateway client
+// (github.com/hyperledger/fabric-gateway/pkg/client), as that client uses the fabric-protos-go-apiv2 protobuf bindings,
+// which register the same protobuf messages as the fabric-protos-go bindings used by FPC. Identities and signing
+// functions created with the identity package of the Fabric Gateway client can be used with NewNetwork.
+package fabricgateway
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Contract provides functions to query/invoke FPC chaincodes based on the Fabric Gateway service.
+// It offers the same FPC operations as the Contract of the gateway package; see there for their documentation.
+//
+// A Contract object is created using the GetContract() factory method.
+type Contract interface {
+	contract.FpcContract
+}
+
+// GetContract is the factory method for creating FPC Contract objects.
+//
+//	Parameters:
+//	network is the network of the channel, as returned by NewNetwork
+//	chaincodeID is the ID of the target chaincode
+//	opts are optional settings, e.g., contract.WithCredentialVerifier
+//
+//	Returns:
+//	The contract object
+func GetContract(network *Network, chaincodeID string, opts ...contract.Option) Contract {
+	return contract.GetContract(NewProvider(network), chaincodeID, opts...)
+}
+
+// NewProvider returns a contract.Provider based on the Fabric Gateway service.
+// The FPC chaincode enclaves are targeted by restricting the __invoke evaluation to the organizations of the peers
+// hosting an enclave, as the Fabric Gateway does not support the selection of individual peers. The organization of
+// a peer is taken from the enclave registration at ERCC.
+// The context of an invocation is passed on to the Fabric Gateway, i.e., cancelling it aborts the pending request.
+func NewProvider(network *Network) contract.Provider {
+	return &contractProvider{network: network}
+}
+
+type contractProvider struct {
+	network *Network
+}
+
+func (p *contractProvider) GetContract(id string) contract.Contract {
+	return &gatewayContract{
+		network:       p.network,
+		name:          id,
+		organizations: newOrganizationResolver(&gatewayContract{network: p.network, name: "ercc"}, id),
+	}
+}
+
+// gatewayContract implements contract.Contract, contract.ContextSubmitter, and contract.AsyncSubmitter
+type gatewayContract struct {
+	network       *Network
+	name          string
+	organizations *organizationResolver
+}
+
+func (c *gatewayContract) Name() string {
+	return c.name
+}
+
+func (c *gatewayContract) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	return c.evaluate(context.Background(), name, toBytes(args), nil)
+}
+
+func (c *gatewayContract) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	return c.SubmitTransactionWithContext(context.Background(), name, args...)
+}
+
+// SubmitTransactionWithContext submits the transaction and waits for its commit; the context is passed on to the
+// requests to the Fabric Gateway
+func (c *gatewayContract) SubmitTransactionWithContext(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return c.submit(ctx, name, args...)
+}
+
+// CreateTransaction returns a transaction that is evaluated at the organizations of the given peers
+func (c *gatewayContract) CreateTransaction(name string, peerEndpoints ...string) (contract.Transaction, error) {
+	organizations, err := c.organizations.resolve(peerEndpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{
+		c:             c,
+		name:          name,
+		organizations: organizations,
+	}, nil
+}
+
+// SubmitTransactionAsync submits the transaction and returns its commit, which provides the transaction ID; the
+// context is passed on to the endorsement and submission requests to the Fabric Gateway
+func (c *gatewayContract) SubmitTransactionAsync(ctx context.Context, name string, args ...string) (contract.Commit, error) {
+	prop, err := c.network.newProposal(c.name, name, toBytes(args))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.network.submit(ctx, prop); err != nil {
+		return nil, err
+	}
+	return &gatewayCommit{network: c.network, txID: prop.txID}, nil
+}
+
+func (c *gatewayContract) evaluate(ctx context.Context, name string, args [][]byte, organizations []string) ([]byte, error) {
+	prop, err := c.network.newProposal(c.name, name, args)
+	if err != nil {
+		return nil, err
+	}
+	return c.network.evaluate(ctx, prop, organizations)
+}
+
+// submit submits the transaction and waits for its commit; transactions committed as invalid are reported as
+// contract.CommitError
+func (c *gatewayContract) submit(ctx context.Context, name string, args ...string) ([]byte, error) {
+	prop, err := c.network.newProposal(c.name, name, toBytes(args))
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.network.submit(ctx, prop)
+	if err != nil {
+		return nil, err
+	}
+
+	commit := &gatewayCommit{network: c.network, txID: prop.txID}
+	status, err := commit.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Code != peer.TxValidationCode_VALID {
+		return nil, &contract.CommitError{TransactionID: status.TransactionID, Code: status.Code}
+	}
+	return result, nil
+}
+
+// transaction implements contract.Transaction and contract.ContextTransaction
+type transaction struct {
+	c             *gatewayContract
+	name          string
+	organizations []string
+}
+
+func (t *transaction) Evaluate(args ...string) ([]byte, error) {
+	return t.EvaluateWithContext(context.Background(), args...)
+}
+
+func (t *transaction) EvaluateWithContext(ctx context.Context, args ...string) ([]byte, error) {
+	return t.c.evaluate(ctx, t.name, toBytes(args), t.organizations)
+}
+
+type gatewayCommit struct {
+	network *Network
+	txID    string
+}
+
+func (c *gatewayCommit) TransactionID() string {
+	return c.txID
+}
+
+func (c *gatewayCommit) Status(ctx context.Context) (*contract.CommitStatus, error) {
+	status, err := c.network.commitStatus(ctx, c.txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contract.CommitStatus{
+		TransactionID: c.txID,
+		Code:          status.GetResult(),
+		BlockNumber:   status.GetBlockNumber(),
+	}, nil
+}
+
+func toBytes(args []string) [][]byte {
+	argsBytes := make([][]byte, len(args))
+	for i, arg := range args {
+		argsBytes[i] = []byte(arg)
+	}
+	return argsBytes
+}
@@ -0,0 +1,317 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabricgateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type evaluation struct {
+	chaincode     string
+	args          []string
+	organizations []string
+}
+
+// gatewayServer is a stand-in for the Fabric Gateway that answers evaluate requests and commits submitted
+// transactions with commitCode
+type gatewayServer struct {
+	gateway.UnimplementedGatewayServer
+
+	mutex       sync.Mutex
+	evaluations []evaluation
+	submissions []evaluation
+	submitted   []*common.Envelope
+	credentials []string
+	commitCode  peer.TxValidationCode
+	// endorseErr, if set, is returned by Endorse
+	endorseErr error
+}
+
+func parseProposal(signed *peer.SignedProposal) (evaluation, error) {
+	proposal, err := protoutil.UnmarshalProposal(signed.GetProposalBytes())
+	if err != nil {
+		return evaluation{}, err
+	}
+	payload, err := protoutil.UnmarshalChaincodeProposalPayload(proposal.GetPayload())
+	if err != nil {
+		return evaluation{}, err
+	}
+	spec, err := protoutil.UnmarshalChaincodeInvocationSpec(payload.GetInput())
+	if err != nil {
+		return evaluation{}, err
+	}
+
+	e := evaluation{chaincode: spec.GetChaincodeSpec().GetChaincodeId().GetName()}
+	for _, arg := range spec.GetChaincodeSpec().GetInput().GetArgs() {
+		e.args = append(e.args, string(arg))
+	}
+	return e, nil
+}
+
+func (s *gatewayServer) Evaluate(_ context.Context, req *gateway.EvaluateRequest) (*gateway.EvaluateResponse, error) {
+	e, err := parseProposal(req.GetProposedTransaction())
+	if err != nil {
+		return nil, err
+	}
+	e.organizations = req.GetTargetOrganizations()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.evaluations = append(s.evaluations, e)
+
+	result := []byte("some result")
+	if e.chaincode == "ercc" && e.args[0] == "queryListEnclaveCredentials" {
+		var err error
+		if result, err = json.Marshal(s.credentials); err != nil {
+			return nil, err
+		}
+	}
+
+	return &gateway.EvaluateResponse{Result: &peer.Response{Status: 200, Payload: result}}, nil
+}
+
+// Endorse returns a prepared transaction whose chaincode response is "some result"
+func (s *gatewayServer) Endorse(_ context.Context, req *gateway.EndorseRequest) (*gateway.EndorseResponse, error) {
+	e, err := parseProposal(req.GetProposedTransaction())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.submissions = append(s.submissions, e)
+	if s.endorseErr != nil {
+		return nil, s.endorseErr
+	}
+
+	action := protoutil.MarshalOrPanic(&peer.ChaincodeAction{Response: &peer.Response{Status: 200, Payload: []byte("some result")}})
+	actionPayload := protoutil.MarshalOrPanic(&peer.ChaincodeActionPayload{Action: &peer.ChaincodeEndorsedAction{
+		ProposalResponsePayload: protoutil.MarshalOrPanic(&peer.ProposalResponsePayload{Extension: action}),
+	}})
+	tx := protoutil.MarshalOrPanic(&peer.Transaction{Actions: []*peer.TransactionAction{{Payload: actionPayload}}})
+	payload := protoutil.MarshalOrPanic(&common.Payload{Data: tx})
+
+	return &gateway.EndorseResponse{PreparedTransaction: &common.Envelope{Payload: payload}}, nil
+}
+
+func (s *gatewayServer) Submit(_ context.Context, req *gateway.SubmitRequest) (*gateway.SubmitResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.submitted = append(s.submitted, req.GetPreparedTransaction())
+	return &gateway.SubmitResponse{}, nil
+}
+
+func (s *gatewayServer) CommitStatus(_ context.Context, req *gateway.SignedCommitStatusRequest) (*gateway.CommitStatusResponse, error) {
+	if len(req.GetSignature()) == 0 {
+		return nil, fmt.Errorf("commit status request not signed")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return &gateway.CommitStatusResponse{Result: s.commitCode, BlockNumber: 42}, nil
+}
+
+func (s *gatewayServer) register(t *testing.T, chaincodeID, peerEndpoint, mspId string) {
+	attestedData, err := anypb.New(&protos.AttestedData{
+		CcParams:   &protos.CCParameters{ChaincodeId: chaincodeID},
+		HostParams: &protos.HostParameters{PeerEndpoint: peerEndpoint, PeerMspId: mspId},
+	})
+	require.NoError(t, err)
+	s.credentials = append(s.credentials, utils.MarshallProtoBase64(&protos.Credentials{SerializedAttestedData: attestedData}))
+}
+
+func connect(t *testing.T, server *gatewayServer) *Network {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	gateway.RegisterGatewayServer(grpcServer, server)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "someUser"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certBytes)
+	require.NoError(t, err)
+
+	id, err := identity.NewX509Identity("Org1MSP", cert)
+	require.NoError(t, err)
+	sign, err := identity.NewPrivateKeySign(key)
+	require.NoError(t, err)
+
+	return NewNetwork(conn, "mychannel", id, sign)
+}
+
+func TestProviderTargetsEnclaveOrganizations(t *testing.T) {
+	server := &gatewayServer{}
+	server.register(t, "myChaincode", "peer0.org1:7051", "Org1MSP")
+	server.register(t, "myChaincode", "peer0.org2:7051", "Org2MSP")
+	server.register(t, "myChaincode", "peer1.org2:7051", "Org2MSP")
+	provider := NewProvider(connect(t, server))
+
+	target := provider.GetContract("myChaincode")
+	assert.Equal(t, "myChaincode", target.Name())
+
+	txn, err := target.CreateTransaction("__invoke", "peer0.org1:7051", "peer0.org2:7051", "peer1.org2:7051")
+	require.NoError(t, err)
+	result, err := txn.Evaluate("someRequest")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+
+	// the organizations are resolved using the enclave registrations at ERCC
+	require.Len(t, server.evaluations, 2)
+	assert.Equal(t, evaluation{chaincode: "ercc", args: []string{"queryListEnclaveCredentials", "myChaincode"}}, server.evaluations[0])
+	assert.Equal(t, evaluation{
+		chaincode:     "myChaincode",
+		args:          []string{"__invoke", "someRequest"},
+		organizations: []string{"Org1MSP", "Org2MSP"},
+	}, server.evaluations[1])
+
+	// known peers are not resolved again
+	txn, err = target.CreateTransaction("__invoke", "peer0.org2:7051")
+	require.NoError(t, err)
+	_, err = txn.Evaluate("someRequest")
+	require.NoError(t, err)
+	require.Len(t, server.evaluations, 3)
+	assert.Equal(t, []string{"Org2MSP"}, server.evaluations[2].organizations)
+
+	// unknown peer
+	_, err = target.CreateTransaction("__invoke", "peer0.org3:7051")
+	assert.EqualError(t, err, "no enclave registered for peer peer0.org3:7051")
+
+	// without peers, the gateway selects the peers
+	txn, err = target.CreateTransaction("__invoke")
+	require.NoError(t, err)
+	_, err = txn.Evaluate("someRequest")
+	require.NoError(t, err)
+	assert.Empty(t, server.evaluations[len(server.evaluations)-1].organizations)
+
+	// other transactions are passed through
+	ercc := provider.GetContract("ercc")
+	_, err = ercc.EvaluateTransaction("queryChaincodeEncryptionKey", "myChaincode")
+	require.NoError(t, err)
+	assert.Equal(t, evaluation{chaincode: "ercc", args: []string{"queryChaincodeEncryptionKey", "myChaincode"}}, server.evaluations[len(server.evaluations)-1])
+}
+
+func TestProviderSubmitsTransactions(t *testing.T) {
+	ctx := context.Background()
+	server := &gatewayServer{}
+	target := NewProvider(connect(t, server)).GetContract("myChaincode")
+
+	result, err := target.SubmitTransaction("__endorse", "someResponse")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("some result"), result)
+	assert.Equal(t, []evaluation{{chaincode: "myChaincode", args: []string{"__endorse", "someResponse"}}}, server.submissions)
+	// the endorsed transaction is signed before it is submitted
+	require.Len(t, server.submitted, 1)
+	assert.NotEmpty(t, server.submitted[0].GetSignature())
+
+	// the transaction ID is known before the transaction is committed
+	commit, err := target.(contract.AsyncSubmitter).SubmitTransactionAsync(ctx, "__endorse", "someResponse")
+	require.NoError(t, err)
+	assert.NotEmpty(t, commit.TransactionID())
+	commitStatus, err := commit.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &contract.CommitStatus{TransactionID: commit.TransactionID(), Code: peer.TxValidationCode_VALID, BlockNumber: 42}, commitStatus)
+
+	// transactions committed as invalid are reported as contract.CommitError
+	server.commitCode = peer.TxValidationCode_MVCC_READ_CONFLICT
+	_, err = target.SubmitTransaction("__endorse", "someResponse")
+	var commitErr *contract.CommitError
+	require.True(t, errors.As(err, &commitErr))
+	assert.Equal(t, peer.TxValidationCode_MVCC_READ_CONFLICT, commitErr.Code)
+	assert.NotEmpty(t, commitErr.TransactionID)
+	assert.True(t, contract.IsReadConflict(err))
+
+	// read conflicts detected by __endorse are reported with the chaincode response status
+	st, err := status.New(codes.Aborted, "failed to endorse transaction").WithDetails(&gateway.ErrorDetail{
+		Address: "peer0.org1:7051",
+		MspId:   "Org1MSP",
+		Message: fmt.Sprintf("chaincode response %d, value hash mismatch for key k1", utils.ReadConflictStatus),
+	})
+	require.NoError(t, err)
+	server.endorseErr = st.Err()
+	_, err = target.SubmitTransaction("__endorse", "someResponse")
+	var chaincodeErr *contract.ChaincodeError
+	require.True(t, errors.As(err, &chaincodeErr))
+	assert.EqualValues(t, utils.ReadConflictStatus, chaincodeErr.Status)
+	assert.True(t, contract.IsReadConflict(err))
+
+	// other endorsement errors
+	server.endorseErr = status.Error(codes.Aborted, "failed to endorse transaction")
+	_, err = target.SubmitTransaction("__endorse", "someResponse")
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.False(t, contract.IsReadConflict(err))
+}
+
+func TestProviderPassesContext(t *testing.T) {
+	server := &gatewayServer{}
+	target := NewProvider(connect(t, server)).GetContract("myChaincode")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	txn, err := target.CreateTransaction("__invoke")
+	require.NoError(t, err)
+	_, err = txn.(contract.ContextTransaction).EvaluateWithContext(ctx, "someRequest")
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	_, err = target.(contract.ContextSubmitter).SubmitTransactionWithContext(ctx, "__endorse", "someResponse")
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	_, err = target.(contract.AsyncSubmitter).SubmitTransactionAsync(ctx, "__endorse", "someResponse")
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	assert.Empty(t, server.evaluations)
+	assert.Empty(t, server.submissions)
+}
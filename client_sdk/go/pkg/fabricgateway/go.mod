@@ -0,0 +1,34 @@
+module github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/fabricgateway
+
+go 1.23.5
+
+require (
+	github.com/golang/protobuf v1.5.4
+	github.com/hyperledger/fabric v1.4.0-rc1.0.20230405174026-695dd57e01c2
+	github.com/hyperledger/fabric-gateway v1.7.1
+	github.com/hyperledger/fabric-private-chaincode v1.0.0-rc3.0.20241027225741-54c22bdd71b6
+	github.com/hyperledger/fabric-protos-go v0.3.0
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.10.0
+	google.golang.org/grpc v1.69.2
+	google.golang.org/protobuf v1.36.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20230228194215-b84622ba6a7a // indirect
+	github.com/miekg/pkcs11 v1.1.1 // indirect
+	github.com/mitchellh/mapstructure v1.4.3 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sykesm/zap-logfmt v0.0.4 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.25.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/net v0.33.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241015192408-796eee8c2d53 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/hyperledger/fabric-private-chaincode => ../../../../
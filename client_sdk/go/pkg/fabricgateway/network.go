@@ -0,0 +1,233 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabricgateway
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const nonceLength = 24
+
+// Network is a channel accessed through the Fabric Gateway service of a peer
+type Network struct {
+	client    gateway.GatewayClient
+	channelID string
+	id        identity.Identity
+	sign      identity.Sign
+	hash      hash.Hash
+}
+
+// NetworkOption is an optional setting of a Network
+type NetworkOption func(n *Network)
+
+// WithHash sets the hash function applied to messages before they are signed; the default is hash.SHA256
+func WithHash(h hash.Hash) NetworkOption {
+	return func(n *Network) {
+		n.hash = h
+	}
+}
+
+// NewNetwork returns the network of the given channel, accessed through the Fabric Gateway service at the client
+// connection. Proposals and transactions are signed using sign on behalf of id, e.g., as created with the identity
+// package of the Fabric Gateway client.
+func NewNetwork(conn *grpc.ClientConn, channelID string, id identity.Identity, sign identity.Sign, opts ...NetworkOption) *Network {
+	n := &Network{
+		client:    gateway.NewGatewayClient(conn),
+		channelID: channelID,
+		id:        id,
+		sign:      sign,
+		hash:      hash.SHA256,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// proposal is a signed proposal of a transaction
+type proposal struct {
+	txID   string
+	signed *peer.SignedProposal
+}
+
+func (n *Network) creator() ([]byte, error) {
+	return protoutil.Marshal(&msp.SerializedIdentity{Mspid: n.id.MspID(), IdBytes: n.id.Credentials()})
+}
+
+func (n *Network) signMessage(message []byte) ([]byte, error) {
+	signature, err := n.sign(n.hash(message))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot sign message")
+	}
+	return signature, nil
+}
+
+func (n *Network) newProposal(chaincodeID, name string, args [][]byte) (*proposal, error) {
+	creator, err := n.creator()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "cannot create nonce")
+	}
+	txID := protoutil.ComputeTxID(nonce, creator)
+
+	spec := &peer.ChaincodeInvocationSpec{
+		ChaincodeSpec: &peer.ChaincodeSpec{
+			Type:        peer.ChaincodeSpec_GOLANG,
+			ChaincodeId: &peer.ChaincodeID{Name: chaincodeID},
+			Input:       &peer.ChaincodeInput{Args: append([][]byte{[]byte(name)}, args...)},
+		},
+	}
+	prop, _, err := protoutil.CreateChaincodeProposalWithTxIDNonceAndTransient(txID, common.HeaderType_ENDORSER_TRANSACTION, n.channelID, spec, nonce, creator, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create proposal")
+	}
+	propBytes, err := protoutil.Marshal(prop)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := n.signMessage(propBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proposal{txID: txID, signed: &peer.SignedProposal{ProposalBytes: propBytes, Signature: signature}}, nil
+}
+
+// evaluate evaluates the proposal at peers of the given organizations, or at peers selected by the gateway if no
+// organizations are given
+func (n *Network) evaluate(ctx context.Context, prop *proposal, organizations []string) ([]byte, error) {
+	resp, err := n.client.Evaluate(ctx, &gateway.EvaluateRequest{
+		TransactionId:       prop.txID,
+		ChannelId:           n.channelID,
+		ProposedTransaction: prop.signed,
+		TargetOrganizations: organizations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetResult().GetPayload(), nil
+}
+
+// submit endorses the proposal and submits the endorsed transaction to the orderer; it returns the result of the
+// transaction without waiting for its commit
+func (n *Network) submit(ctx context.Context, prop *proposal) ([]byte, error) {
+	endorsement, err := n.client.Endorse(ctx, &gateway.EndorseRequest{
+		TransactionId:       prop.txID,
+		ChannelId:           n.channelID,
+		ProposedTransaction: prop.signed,
+	})
+	if err != nil {
+		return nil, asChaincodeError(err)
+	}
+
+	envelope := endorsement.GetPreparedTransaction()
+	result, err := transactionResult(envelope)
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Signature, err = n.signMessage(envelope.GetPayload()); err != nil {
+		return nil, err
+	}
+
+	_, err = n.client.Submit(ctx, &gateway.SubmitRequest{
+		TransactionId:       prop.txID,
+		ChannelId:           n.channelID,
+		PreparedTransaction: envelope,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// commitStatus waits until the transaction is committed and returns its validation code and block number
+func (n *Network) commitStatus(ctx context.Context, txID string) (*gateway.CommitStatusResponse, error) {
+	creator, err := n.creator()
+	if err != nil {
+		return nil, err
+	}
+	request, err := protoutil.Marshal(&gateway.CommitStatusRequest{
+		TransactionId: txID,
+		ChannelId:     n.channelID,
+		Identity:      creator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := n.signMessage(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.client.CommitStatus(ctx, &gateway.SignedCommitStatusRequest{Request: request, Signature: signature})
+}
+
+// transactionResult returns the chaincode response payload contained in an endorsed transaction
+func transactionResult(envelope *common.Envelope) ([]byte, error) {
+	payload, err := protoutil.UnmarshalPayload(envelope.GetPayload())
+	if err != nil {
+		return nil, err
+	}
+	tx, err := protoutil.UnmarshalTransaction(payload.GetData())
+	if err != nil {
+		return nil, err
+	}
+	if len(tx.GetActions()) == 0 {
+		return nil, errors.New("endorsed transaction contains no actions")
+	}
+	actionPayload, err := protoutil.UnmarshalChaincodeActionPayload(tx.GetActions()[0].GetPayload())
+	if err != nil {
+		return nil, err
+	}
+	responsePayload, err := protoutil.UnmarshalProposalResponsePayload(actionPayload.GetAction().GetProposalResponsePayload())
+	if err != nil {
+		return nil, err
+	}
+	action, err := protoutil.UnmarshalChaincodeAction(responsePayload.GetExtension())
+	if err != nil {
+		return nil, err
+	}
+	return action.GetResponse().GetPayload(), nil
+}
+
+// asChaincodeError turns an endorsement error of the Fabric Gateway whose details report the response status of the
+// chaincode into a contract.ChaincodeError, which carries this status
+func asChaincodeError(err error) error {
+	for _, detail := range status.Convert(err).Proto().GetDetails() {
+		errorDetail := &gateway.ErrorDetail{}
+		if !strings.HasSuffix(detail.GetTypeUrl(), "/gateway.ErrorDetail") || protov1.Unmarshal(detail.GetValue(), errorDetail) != nil {
+			continue
+		}
+
+		// the Fabric Gateway reports chaincode errors as "chaincode response <status>, <message>"
+		var code int32
+		if _, scanErr := fmt.Sscanf(errorDetail.GetMessage(), "chaincode response %d,", &code); scanErr == nil {
+			return &contract.ChaincodeError{Status: code, Err: err}
+		}
+	}
+	return err
+}
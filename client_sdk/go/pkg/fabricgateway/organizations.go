@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabricgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// organizationResolver maps the endpoints of the peers hosting an enclave of the chaincode to the MSP IDs of their
+// organizations, as registered at ERCC
+type organizationResolver struct {
+	ercc        contract.Evaluator
+	chaincodeID string
+
+	mutex         sync.Mutex
+	organizations map[string]string
+}
+
+func newOrganizationResolver(ercc contract.Evaluator, chaincodeID string) *organizationResolver {
+	return &organizationResolver{
+		ercc:          ercc,
+		chaincodeID:   chaincodeID,
+		organizations: make(map[string]string),
+	}
+}
+
+// resolve returns the MSP IDs of the organizations of the given peers; the enclave registrations are queried from
+// ERCC if a peer is not known yet
+func (r *organizationResolver) resolve(peerEndpoints []string) ([]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var organizations []string
+	seen := make(map[string]bool)
+	for _, endpoint := range peerEndpoints {
+		if endpoint == "" {
+			continue
+		}
+
+		mspId, ok := r.organizations[endpoint]
+		if !ok {
+			if err := r.refresh(); err != nil {
+				return nil, err
+			}
+			if mspId, ok = r.organizations[endpoint]; !ok {
+				return nil, fmt.Errorf("no enclave registered for peer %s", endpoint)
+			}
+		}
+
+		if !seen[mspId] {
+			seen[mspId] = true
+			organizations = append(organizations, mspId)
+		}
+	}
+
+	return organizations, nil
+}
+
+// refresh queries the enclave registrations of the chaincode from ERCC
+func (r *organizationResolver) refresh() error {
+	resp, err := r.ercc.EvaluateTransaction("queryListEnclaveCredentials", r.chaincodeID)
+	if err != nil {
+		return errors.Wrap(err, "cannot query enclave credentials")
+	}
+
+	var credentialsBase64 []string
+	if len(resp) > 0 {
+		if err := json.Unmarshal(resp, &credentialsBase64); err != nil {
+			return errors.Wrap(err, "invalid enclave credentials list")
+		}
+	}
+
+	for _, cred := range credentialsBase64 {
+		credentials, err := utils.UnmarshalCredentials(cred)
+		if err != nil {
+			return err
+		}
+		attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+		if err != nil {
+			return err
+		}
+
+		hostParams := attestedData.GetHostParams()
+		r.organizations[hostParams.GetPeerEndpoint()] = hostParams.GetPeerMspId()
+	}
+
+	return nil
+}
@@ -9,8 +9,15 @@ SPDX-License-Identifier: Apache-2.0
 package gateway
 
 import (
+	"context"
+
 	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
 	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"github.com/pkg/errors"
 )
 
 // Contract provides functions to query/invoke FPC chaincodes based on the Gateway API.
@@ -19,33 +26,28 @@ import (
 // but in addition to the normal FPC operations, it performs FPC specific steps such as encryption/decryption of chaincode requests/responses.
 //
 // A Contract object is created using the GetContract() factory method.
+// To decode JSON or protobuf results into typed values, see contract.EvaluateJSON, contract.SubmitJSON,
+// contract.EvaluateProto and contract.SubmitProto.
 // For an example of its use, see `contract_test.go`
 type Contract interface {
-	// Name returns the name of the smart contract
-	Name() string
-
-	// EvaluateTransaction will evaluate a transaction function and return its results.
-	// The transaction function 'name'
-	// will be evaluated on the endorsing peers but the responses will not be sent to
-	// the ordering service and hence will not be committed to the ledger.
-	// This can be used for querying the world state.
+	// FpcContract provides the FPC operations, i.e., EvaluateTransaction, SubmitTransaction, and Evaluate, Submit,
+	// and SubmitAsync, which take transaction options such as contract.WithArgs, contract.WithBytesArgs,
+	// contract.WithTransient, and contract.WithEvent.
+	contract.FpcContract
+
+	// RegisterEvent registers for chaincode events. Unregister must be called when the registration is no longer needed.
+	// The payload of the received events is a serialized FPC event which can be decrypted using contract.DecryptEvent.
 	//  Parameters:
-	//  name is the name of the transaction function to be invoked in the smart contract.
-	//  args are the arguments to be sent to the transaction function.
+	//  eventFilter is the chaincode event filter (regular expression) for which events are to be received
 	//
 	//  Returns:
-	EvaluateTransaction(name string, args ...string) ([]byte, error)
+	//  the registration and a channel that is used to receive events. The channel is closed when Unregister is called.
+	RegisterEvent(eventFilter string) (fab.Registration, <-chan *fab.CCEvent, error)
 
-	// SubmitTransaction will submit a transaction to the ledger. The transaction function 'name'
-	// will be evaluated on the endorsing peers and then submitted to the ordering service
-	// for committing to the ledger.
+	// Unregister removes the given registration and closes the event channel.
 	//  Parameters:
-	//  name is the name of the transaction function to be invoked in the smart contract.
-	//  args are the arguments to be sent to the transaction function.
-	//
-	//  Returns:
-	//  The return value of the transaction function in the smart contract.
-	SubmitTransaction(name string, args ...string) ([]byte, error)
+	//  registration is the registration handle that was returned from RegisterEvent method
+	Unregister(registration fab.Registration)
 }
 
 // Network interface that is needed by the FPC contract implementation
@@ -55,6 +57,8 @@ type Network interface {
 
 type gatewayContract struct {
 	c *gateway.Contract
+	// client is used for the asynchronous submission of transactions, if set
+	client ChannelClient
 }
 
 func (c *gatewayContract) Name() string {
@@ -66,7 +70,70 @@ func (c *gatewayContract) EvaluateTransaction(name string, args ...string) ([]by
 }
 
 func (c *gatewayContract) SubmitTransaction(name string, args ...string) ([]byte, error) {
-	return c.c.SubmitTransaction(name, args...)
+	resp, err := c.c.SubmitTransaction(name, args...)
+	if err != nil {
+		return nil, asContractError(err)
+	}
+	return resp, nil
+}
+
+// SubmitTransactionAsync submits the transaction in the background. If a channel client is set, the transaction ID
+// is created up front; otherwise, as the gateway of the Fabric SDK does not expose the transaction ID on submission,
+// the ID is only known once the transaction is committed as valid. The context bounds the wait for the transaction
+// ID; the submission itself is not bound by the context, as the Fabric SDK does not support cancelling it.
+func (c *gatewayContract) SubmitTransactionAsync(ctx context.Context, name string, args ...string) (contract.Commit, error) {
+	if c.client != nil {
+		return c.submitAsync(ctx, name, args...)
+	}
+
+	txn, err := c.c.CreateTransaction(name)
+	if err != nil {
+		return nil, err
+	}
+	commitEvents := txn.RegisterCommitEvent()
+
+	return contract.NewCommit(func() (*contract.CommitStatus, error) {
+		if _, err := txn.Submit(args...); err != nil {
+			return nil, asContractError(err)
+		}
+
+		event, ok := <-commitEvents
+		if !ok || event == nil {
+			return nil, errors.New("no commit event received")
+		}
+
+		return &contract.CommitStatus{
+			TransactionID: event.TxID,
+			Code:          event.TxValidationCode,
+			BlockNumber:   event.BlockNumber,
+		}, nil
+	}), nil
+}
+
+// asContractError turns the errors returned by the Fabric SDK for transactions committed as invalid into a
+// contract.CommitError, which carries the validation code, and chaincode errors into a contract.ChaincodeError,
+// which carries the response status
+func asContractError(err error) error {
+	if m, ok := errors.Cause(err).(multi.Errors); ok {
+		for _, e := range m {
+			if s, ok := status.FromError(e); ok && s.Group == status.ChaincodeStatus {
+				return &contract.ChaincodeError{Status: s.Code, Err: err}
+			}
+		}
+		return err
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Group {
+	case status.EventServerStatus:
+		return &contract.CommitError{Code: peer.TxValidationCode(s.Code)}
+	case status.ChaincodeStatus:
+		return &contract.ChaincodeError{Status: s.Code, Err: err}
+	}
+	return err
 }
 
 func (c *gatewayContract) CreateTransaction(name string, peerEndpoints ...string) (contract.Transaction, error) {
@@ -75,10 +142,11 @@ func (c *gatewayContract) CreateTransaction(name string, peerEndpoints ...string
 
 type contractProvider struct {
 	network Network
+	client  ChannelClient
 }
 
 func (cp *contractProvider) GetContract(id string) contract.Contract {
-	return &gatewayContract{cp.network.GetContract(id)}
+	return &gatewayContract{c: cp.network.GetContract(id), client: cp.client}
 }
 
 // GetContract is the factory method for creating FPC Contract objects.
@@ -86,9 +154,45 @@ func (cp *contractProvider) GetContract(id string) contract.Contract {
 //	Parameters:
 //	network is an initialized Fabric network object
 //	chaincodeID is the ID of the target chaincode
+//	opts are optional settings, e.g., contract.WithCredentialVerifier
+//
+//	Returns:
+//	The contract object
+func GetContract(network Network, chaincodeID string, opts ...contract.Option) Contract {
+	return GetContractWithChannelClient(network, nil, chaincodeID, opts...)
+}
+
+// GetContractWithChannelClient behaves like GetContract but submits the __endorse transactions of
+// SubmitAsync using the given channel client of the same channel and identity, e.g., as created by
+// channel.New(sdk.ChannelContext(channelID, fabsdk.WithUser(user))). Unlike the gateway of the Fabric SDK, the
+// channel client allows creating the transaction ID up front; hence, the ID is known when SubmitAsync returns rather than only once the transaction is committed as valid.
+//
+//	Parameters:
+//	network is an initialized Fabric network object
+//	client is the channel client, e.g., a channel.Client
+//	chaincodeID is the ID of the target chaincode
+//	opts are optional settings, e.g., contract.WithCredentialVerifier
 //
 //	Returns:
 //	The contract object
-func GetContract(network Network, chaincodeID string) Contract {
-	return contract.GetContract(&contractProvider{network: network}, chaincodeID)
+func GetContractWithChannelClient(network Network, client ChannelClient, chaincodeID string, opts ...contract.Option) Contract {
+	return &fpcContract{
+		FpcContract: contract.GetContract(&contractProvider{network: network, client: client}, chaincodeID, opts...),
+		target:      network.GetContract(chaincodeID),
+	}
+}
+
+// fpcContract extends the FPC contract with the event registration of the underlying gateway contract,
+// as chaincode events are committed by __endorse under the chaincode's name.
+type fpcContract struct {
+	contract.FpcContract
+	target *gateway.Contract
+}
+
+func (c *fpcContract) RegisterEvent(eventFilter string) (fab.Registration, <-chan *fab.CCEvent, error) {
+	return c.target.RegisterEvent(eventFilter)
+}
+
+func (c *fpcContract) Unregister(registration fab.Registration) {
+	c.target.Unregister(registration)
 }
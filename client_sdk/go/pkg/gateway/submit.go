@@ -0,0 +1,169 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gateway
+
+import (
+	"context"
+
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel/invoke"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/retry"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/pkg/errors"
+)
+
+// ChannelClient is the part of the channel client of the Fabric SDK Go (channel.Client) used to submit transactions
+// whose ID is known before they are committed
+type ChannelClient interface {
+	InvokeHandler(handler invoke.Handler, request channel.Request, options ...channel.RequestOption) (channel.Response, error)
+}
+
+type submitResult struct {
+	status *contract.CommitStatus
+	err    error
+}
+
+// submitAsync submits the transaction using the channel client and returns once the ID of the transaction is known,
+// i.e., before the transaction is endorsed, or once the context is done
+func (c *gatewayContract) submitAsync(ctx context.Context, name string, args ...string) (contract.Commit, error) {
+	argsBytes := make([][]byte, len(args))
+	for i, arg := range args {
+		argsBytes[i] = []byte(arg)
+	}
+	request := channel.Request{ChaincodeID: c.c.Name(), Fcn: name, Args: argsBytes}
+
+	txIDs := make(chan string, 1)
+	handler := &submitHandler{txIDs: txIDs}
+	results := make(chan submitResult, 1)
+	go func() {
+		resp, err := c.client.InvokeHandler(handler, request, channel.WithRetry(retry.DefaultChannelOpts))
+		if err != nil {
+			results <- submitResult{err: err}
+			return
+		}
+		results <- submitResult{status: &contract.CommitStatus{
+			TransactionID: string(resp.TransactionID),
+			Code:          resp.TxValidationCode,
+			BlockNumber:   handler.blockNumber,
+		}}
+	}()
+
+	var txID string
+	var result *submitResult
+	select {
+	case txID = <-txIDs:
+	case r := <-results:
+		result = &r
+		select {
+		case txID = <-txIDs:
+		default:
+			// the submission failed before the transaction ID was created
+			return nil, asContractError(r.err)
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return contract.NewCommitWithTransactionID(txID, func() (*contract.CommitStatus, error) {
+		if result == nil {
+			r := <-results
+			result = &r
+		}
+		if result.err != nil {
+			return nil, withTransactionID(asContractError(result.err), txID)
+		}
+		return result.status, nil
+	}), nil
+}
+
+// withTransactionID sets the transaction ID of a contract.CommitError
+func withTransactionID(err error, txID string) error {
+	var commitErr *contract.CommitError
+	if errors.As(err, &commitErr) {
+		commitErr.TransactionID = txID
+	}
+	return err
+}
+
+// submitHandler creates the header of the transaction first and reports its ID. The transaction is then endorsed
+// using the nonce and creator of this header, which results in the same transaction ID, and committed.
+// Retries of the channel client reuse the header, i.e., the transaction ID.
+type submitHandler struct {
+	txIDs chan<- string
+	txh   fab.TransactionHeader
+	// blockNumber is the number of the block containing the transaction, once it is committed
+	blockNumber uint64
+}
+
+func (h *submitHandler) Handle(requestContext *invoke.RequestContext, clientContext *invoke.ClientContext) {
+	if h.txh == nil {
+		txh, err := clientContext.Transactor.CreateTransactionHeader()
+		if err != nil {
+			requestContext.Error = errors.WithMessage(err, "cannot create transaction header")
+			return
+		}
+		h.txh = txh
+		h.txIDs <- string(txh.TransactionID())
+	}
+
+	headerOpts := func() []fab.TxnHeaderOpt {
+		return []fab.TxnHeaderOpt{fab.WithNonce(h.txh.Nonce()), fab.WithCreator(h.txh.Creator())}
+	}
+	next := invoke.NewSelectAndEndorseHandler(
+		invoke.NewEndorsementValidationHandler(
+			invoke.NewSignatureValidationHandler(&commitHandler{blockNumber: &h.blockNumber}),
+		),
+	)
+	next.(*invoke.SelectAndEndorseHandler).EndorsementHandler = invoke.NewEndorsementHandlerWithOpts(nil, headerOpts)
+	next.Handle(requestContext, clientContext)
+}
+
+// commitHandler sends the endorsed transaction to the orderer and waits for its commit like the commit handler of
+// the Fabric SDK, and additionally records the block number of the commit
+type commitHandler struct {
+	blockNumber *uint64
+}
+
+func (c *commitHandler) Handle(requestContext *invoke.RequestContext, clientContext *invoke.ClientContext) {
+	txID := requestContext.Response.TransactionID
+
+	reg, statusNotifier, err := clientContext.EventService.RegisterTxStatusEvent(string(txID))
+	if err != nil {
+		requestContext.Error = errors.Wrap(err, "error registering for TxStatus event")
+		return
+	}
+	defer clientContext.EventService.Unregister(reg)
+
+	tx, err := clientContext.Transactor.CreateTransaction(fab.TransactionRequest{
+		Proposal:          requestContext.Response.Proposal,
+		ProposalResponses: requestContext.Response.Responses,
+	})
+	if err != nil {
+		requestContext.Error = errors.WithMessage(err, "CreateTransaction failed")
+		return
+	}
+	if _, err := clientContext.Transactor.SendTransaction(tx); err != nil {
+		requestContext.Error = errors.WithMessage(err, "SendTransaction failed")
+		return
+	}
+
+	select {
+	case txStatus := <-statusNotifier:
+		requestContext.Response.TxValidationCode = txStatus.TxValidationCode
+		*c.blockNumber = txStatus.BlockNumber
+		if txStatus.TxValidationCode != peer.TxValidationCode_VALID {
+			requestContext.Error = status.New(status.EventServerStatus, int32(txStatus.TxValidationCode),
+				"received invalid transaction", nil)
+		}
+	case <-requestContext.Ctx.Done():
+		requestContext.Error = status.New(status.ClientStatus, status.Timeout.ToInt32(),
+			"Execute didn't receive block event", nil)
+	}
+}
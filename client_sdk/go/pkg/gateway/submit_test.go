@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/client_sdk/go/pkg/core/contract"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/channel/invoke"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/multi"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/errors/status"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// channelClient stands in for the channel client; the transaction ID is reported by the handler before the
+// transaction is committed once the commit is released
+type channelClient struct {
+	txID    string
+	err     error
+	release chan struct{}
+	request channel.Request
+}
+
+func (c *channelClient) InvokeHandler(handler invoke.Handler, request channel.Request, options ...channel.RequestOption) (channel.Response, error) {
+	c.request = request
+	if c.txID != "" {
+		handler.(*submitHandler).txIDs <- c.txID
+	}
+	<-c.release
+	if c.err != nil {
+		return channel.Response{}, c.err
+	}
+	return channel.Response{TransactionID: fab.TransactionID(c.txID), TxValidationCode: peer.TxValidationCode_VALID}, nil
+}
+
+func TestSubmitTransactionAsyncWithChannelClient(t *testing.T) {
+	ctx := context.Background()
+	client := &channelClient{txID: "someTxID", release: make(chan struct{})}
+	c := &gatewayContract{c: &gateway.Contract{}, client: client}
+
+	// the transaction ID is known before the transaction is committed
+	commit, err := c.SubmitTransactionAsync(ctx, "__endorse", "someResponse")
+	require.NoError(t, err)
+	assert.Equal(t, "someTxID", commit.TransactionID())
+
+	close(client.release)
+	commitStatus, err := commit.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &contract.CommitStatus{TransactionID: "someTxID", Code: peer.TxValidationCode_VALID}, commitStatus)
+	assert.Equal(t, "__endorse", client.request.Fcn)
+	assert.Equal(t, [][]byte{[]byte("someResponse")}, client.request.Args)
+
+	// the transaction is committed as invalid
+	client.err = status.New(status.EventServerStatus, int32(peer.TxValidationCode_MVCC_READ_CONFLICT), "received invalid transaction", nil)
+	commit, err = c.SubmitTransactionAsync(ctx, "__endorse", "someResponse")
+	require.NoError(t, err)
+	commitStatus, err = commit.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &contract.CommitStatus{TransactionID: "someTxID", Code: peer.TxValidationCode_MVCC_READ_CONFLICT}, commitStatus)
+
+	// the submission fails before the transaction ID is created
+	client.txID = ""
+	client.err = fmt.Errorf("some error")
+	_, err = c.SubmitTransactionAsync(ctx, "__endorse", "someResponse")
+	assert.EqualError(t, err, "some error")
+	var commitErr *contract.CommitError
+	assert.False(t, errors.As(err, &commitErr))
+
+	// the context is done before the transaction ID is known
+	client.release = make(chan struct{})
+	defer close(client.release)
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.SubmitTransactionAsync(cancelledCtx, "__endorse", "someResponse")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestAsContractError(t *testing.T) {
+	// chaincode errors carry the response status
+	var err error = status.New(status.ChaincodeStatus, utils.ReadConflictStatus, "value hash mismatch for key k1", nil)
+	assert.Equal(t, &contract.ChaincodeError{Status: utils.ReadConflictStatus, Err: err}, asContractError(err))
+	assert.True(t, contract.IsReadConflict(asContractError(err)))
+
+	// also if reported by several endorsers
+	err = multi.New(fmt.Errorf("some error"), status.New(status.ChaincodeStatus, utils.ReadConflictStatus, "value hash mismatch for key k1", nil))
+	assert.True(t, contract.IsReadConflict(asContractError(err)))
+
+	// transactions committed as invalid
+	err = status.New(status.EventServerStatus, int32(peer.TxValidationCode_MVCC_READ_CONFLICT), "received invalid transaction", nil)
+	assert.Equal(t, &contract.CommitError{Code: peer.TxValidationCode_MVCC_READ_CONFLICT}, asContractError(err))
+
+	// other errors
+	err = fmt.Errorf("some error")
+	assert.Equal(t, err, asContractError(err))
+	assert.False(t, contract.IsReadConflict(asContractError(status.New(status.ChaincodeStatus, 500, "some error", nil))))
+}
@@ -33,7 +33,8 @@ func main() {
 	exitIfError(err)
 
 	verifier := attestation.NewCredentialVerifier(
-		simulation.NewSimulationVerifier(),
+		// the C++ attestation api creates legacy simulated attestations
+		simulation.NewLegacySimulationVerifier(),
 		pdo.NewEpidLinkableVerifier(),
 		pdo.NewEpidUnlinkableVerifier(),
 	)
@@ -45,7 +46,7 @@ func main() {
 		Evidence: []byte(evidenceJson),
 	}
 
-	err = verifier.VerifyCredentials(cred, expectedMrenclave)
+	err = verifier.VerifyCredentials(cred, expectedMrenclave, nil)
 	exitIfError(err)
 }
 
@@ -8,6 +8,7 @@ package chaincode
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
@@ -18,6 +19,7 @@ import (
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/protobuf/proto"
 )
 
 var logger = flogging.MustGetLogger("ecc")
@@ -43,6 +45,12 @@ func (t *EnclaveChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response
 	switch function {
 	case "__initEnclave":
 		return t.initEnclave(stub)
+	case "__generateCCKeys":
+		return t.generateCCKeys(stub)
+	case "__exportCCKeys":
+		return t.exportCCKeys(stub)
+	case "__importCCKeys":
+		return t.importCCKeys(stub)
 	case "__invoke":
 		return t.invoke(stub)
 	case "__endorse":
@@ -96,6 +104,104 @@ func (t *EnclaveChaincode) initEnclave(stub shim.ChaincodeStubInterface) pb.Resp
 	return shim.Success([]byte(base64.StdEncoding.EncodeToString(credentialsBytes)))
 }
 
+func (t *EnclaveChaincode) generateCCKeys(stub shim.ChaincodeStubInterface) pb.Response {
+	signedCCKeyRegistrationMessage, err := t.Enclave.GenerateCCKeys()
+	if err != nil {
+		errMsg := fmt.Sprintf("Enclave GenerateCCKeys function failed: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	return shim.Success([]byte(base64.StdEncoding.EncodeToString(signedCCKeyRegistrationMessage)))
+}
+
+func (t *EnclaveChaincode) exportCCKeys(stub shim.ChaincodeStubInterface) pb.Response {
+	// credentials of the target enclave
+	serializedCredentials, err := t.Extractor.GetSerializedCredentials(stub)
+	if err != nil {
+		errMsg := fmt.Sprintf("getting credentials failed: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	// only export keys to enclaves registered (and thus attested) at ercc
+	credentials := &protos.Credentials{}
+	if err := proto.Unmarshal(serializedCredentials, credentials); err != nil {
+		errMsg := fmt.Sprintf("invalid credentials: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	attestedData, err := utils.UnmarshalAttestedData(credentials.SerializedAttestedData)
+	if err != nil {
+		errMsg := fmt.Sprintf("invalid attested data: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	chaincodeParams, err := t.Extractor.GetChaincodeParams(stub)
+	if err != nil {
+		errMsg := fmt.Sprintf("cannot extract chaincode params: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	enclaveId := utils.GetEnclaveId(attestedData)
+	registeredCredentials, err := t.Ercc.QueryEnclaveCredentials(stub, chaincodeParams.ChannelId, chaincodeParams.ChaincodeId, enclaveId)
+	if err != nil {
+		errMsg := fmt.Sprintf("cannot query credentials for enclaveId = %s: %s", enclaveId, err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+	if registeredCredentials == nil || !proto.Equal(registeredCredentials, credentials) {
+		errMsg := fmt.Sprintf("no credentials registered for enclaveId = %s", enclaveId)
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	signedExportMessage, err := t.Enclave.ExportCCKeys(serializedCredentials)
+	if err != nil {
+		errMsg := fmt.Sprintf("Enclave ExportCCKeys function failed: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	return shim.Success([]byte(base64.StdEncoding.EncodeToString(signedExportMessage)))
+}
+
+func (t *EnclaveChaincode) importCCKeys(stub shim.ChaincodeStubInterface) pb.Response {
+	chaincodeParams, err := t.Extractor.GetChaincodeParams(stub)
+	if err != nil {
+		errMsg := fmt.Sprintf("cannot extract chaincode params: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	enclaveId, err := t.Enclave.GetEnclaveId()
+	if err != nil {
+		errMsg := fmt.Sprintf("cannot get enclave id: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	// get export message for this enclave from ercc
+	signedExportMessage, err := t.Ercc.GetKeyExport(stub, chaincodeParams.ChannelId, chaincodeParams.ChaincodeId, enclaveId)
+	if err != nil {
+		errMsg := fmt.Sprintf("cannot get key export for enclaveId = %s: %s", enclaveId, err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	signedCCKeyRegistrationMessage, err := t.Enclave.ImportCCKeys(signedExportMessage)
+	if err != nil {
+		errMsg := fmt.Sprintf("Enclave ImportCCKeys function failed: %s", err.Error())
+		logger.Errorf(errMsg)
+		return shim.Error(errMsg)
+	}
+
+	return shim.Success([]byte(base64.StdEncoding.EncodeToString(signedCCKeyRegistrationMessage)))
+}
+
 func (t *EnclaveChaincode) invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	var errMsg string
 
@@ -150,6 +256,15 @@ func (t *EnclaveChaincode) endorse(stub shim.ChaincodeStubInterface) pb.Response
 		return shim.Error(errMsg)
 	}
 
+	// refuse responses of revoked enclaves
+	revoked, err := t.Ercc.QueryEnclaveRevoked(stub, chaincodeParams.ChannelId, chaincodeParams.ChaincodeId, responseMsg.EnclaveId)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("cannot query revocation status: %s", err.Error()))
+	}
+	if revoked {
+		return shim.Error(fmt.Sprintf("enclave %s has been revoked", responseMsg.EnclaveId))
+	}
+
 	logger.Infof("try to get credentials from ERCC for channel: %s ccId: %s EnclaveId: %s ", chaincodeParams.ChannelId, chaincodeParams.ChaincodeId, responseMsg.EnclaveId)
 
 	// get corresponding enclave credentials from ercc
@@ -185,9 +300,26 @@ func (t *EnclaveChaincode) endorse(stub shim.ChaincodeStubInterface) pb.Response
 	logger.Debugf("Replaying rwset")
 	err = t.Validator.ReplayReadWrites(stub, responseMsg.FpcRwSet)
 	if err != nil {
+		// let clients recognize read conflicts, which may be resolved by rerunning the transaction
+		var conflict *endorsement.ReadConflictError
+		if errors.As(err, &conflict) {
+			return pb.Response{Status: utils.ReadConflictStatus, Message: err.Error()}
+		}
 		return shim.Error(err.Error())
 	}
 
+	// commit the (encrypted) chaincode event
+	if event := responseMsg.GetEvent(); event != nil {
+		logger.Debugf("Setting chaincode event %s", event.GetEventName())
+		eventBytes, err := protoutil.Marshal(event)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := stub.SetEvent(event.GetEventName(), eventBytes); err != nil {
+			return shim.Error(fmt.Sprintf("cannot set chaincode event: %s", err.Error()))
+		}
+	}
+
 	logger.Debugf("Endorsement successful")
 	return shim.Success([]byte("OK")) // make sure we have a non-empty return on success so we can distinguish success from failure in cli ...
 }
@@ -10,7 +10,9 @@ import (
 	"github.com/hyperledger/fabric-private-chaincode/ecc/chaincode/fakes"
 	"github.com/hyperledger/fabric-private-chaincode/internal/endorsement"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/types/known/anypb"
 )
@@ -123,6 +125,158 @@ func TestInitEnclave(t *testing.T) {
 	assert.EqualValues(t, expectedCreds, p)
 }
 
+func TestGenerateCCKeys(t *testing.T) {
+	stub := &fakes.ChaincodeStub{}
+	stub.GetFunctionAndParametersReturns("__generateCCKeys", nil)
+	ec, _, ex, _ := newFakes()
+	ecc := newECC(ec, nil, ex, nil)
+	expectedErr := fmt.Errorf("some error")
+
+	// error when generating keys
+	ec.GenerateCCKeysReturns(nil, expectedErr)
+	r := ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("Enclave GenerateCCKeys function failed: %s", expectedErr), r)
+
+	// no error
+	expectedMsg := []byte("someSignedCCKeyRegistrationMessage")
+	ec.GenerateCCKeysReturns(expectedMsg, nil)
+	r = ecc.Invoke(stub)
+	assert.EqualValues(t, shim.OK, r.Status)
+	p, err := base64.StdEncoding.DecodeString(string(r.Payload))
+	assert.NoError(t, err)
+	assert.EqualValues(t, expectedMsg, p)
+}
+
+func TestExportCCKeys(t *testing.T) {
+	stub := &fakes.ChaincodeStub{}
+	stub.GetFunctionAndParametersReturns("__exportCCKeys", nil)
+	ec, _, ex, ercc := newFakes()
+	ecc := newECC(ec, nil, ex, ercc)
+	expectedErr := fmt.Errorf("some error")
+	expectedCCParams := &protos.CCParameters{
+		ChaincodeId: "someCCID",
+		ChannelId:   "someChannel",
+	}
+	attestedData := &protos.AttestedData{
+		EnclaveVk: []byte("someEnclaveVk"),
+		CcParams:  expectedCCParams,
+	}
+	serializedAttestedData, _ := anypb.New(attestedData)
+	expectedCred := &protos.Credentials{
+		SerializedAttestedData: serializedAttestedData,
+		Attestation:            []byte("someAttestation"),
+	}
+	expectedCreds := utils.MarshalOrPanic(expectedCred)
+	expectedEnclaveId := utils.GetEnclaveId(attestedData)
+
+	// error getting credentials
+	ex.GetSerializedCredentialsReturns(nil, expectedErr)
+	r := ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("getting credentials failed: %s", expectedErr), r)
+
+	// invalid credentials
+	ex.GetSerializedCredentialsReturns([]byte("invalid credentials"), nil)
+	r = ecc.Invoke(stub)
+	assert.EqualValues(t, shim.ERROR, r.Status)
+	assert.Contains(t, r.Message, "invalid credentials")
+
+	// error getting chaincode params
+	ex.GetSerializedCredentialsReturns(expectedCreds, nil)
+	ex.GetChaincodeParamsReturns(nil, expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot extract chaincode params: %s", expectedErr), r)
+
+	// error querying credentials at ercc
+	ex.GetChaincodeParamsReturns(expectedCCParams, nil)
+	ercc.QueryEnclaveCredentialsReturns(nil, expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot query credentials for enclaveId = %s: %s", expectedEnclaveId, expectedErr), r)
+	_, channelId, chaincodeId, enclaveId := ercc.QueryEnclaveCredentialsArgsForCall(0)
+	assert.Equal(t, expectedCCParams.ChannelId, channelId)
+	assert.Equal(t, expectedCCParams.ChaincodeId, chaincodeId)
+	assert.Equal(t, expectedEnclaveId, enclaveId)
+
+	// enclave not registered
+	ercc.QueryEnclaveCredentialsReturns(nil, nil)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("no credentials registered for enclaveId = %s", expectedEnclaveId), r)
+
+	// registered credentials differ, e.g., a forged attestation
+	ercc.QueryEnclaveCredentialsReturns(&protos.Credentials{
+		SerializedAttestedData: serializedAttestedData,
+		Attestation:            []byte("someOtherAttestation"),
+	}, nil)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("no credentials registered for enclaveId = %s", expectedEnclaveId), r)
+	assert.Equal(t, 0, ec.ExportCCKeysCallCount())
+
+	// error when exporting keys
+	ercc.QueryEnclaveCredentialsReturns(expectedCred, nil)
+	ec.ExportCCKeysReturns(nil, expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("Enclave ExportCCKeys function failed: %s", expectedErr), r)
+
+	// no error
+	expectedMsg := []byte("someSignedExportMessage")
+	ec.ExportCCKeysReturns(expectedMsg, nil)
+	r = ecc.Invoke(stub)
+	assert.EqualValues(t, shim.OK, r.Status)
+	p, err := base64.StdEncoding.DecodeString(string(r.Payload))
+	assert.NoError(t, err)
+	assert.EqualValues(t, expectedMsg, p)
+	assert.Equal(t, expectedCreds, ec.ExportCCKeysArgsForCall(1))
+}
+
+func TestImportCCKeys(t *testing.T) {
+	stub := &fakes.ChaincodeStub{}
+	stub.GetFunctionAndParametersReturns("__importCCKeys", nil)
+	ec, _, ex, ercc := newFakes()
+	ecc := newECC(ec, nil, ex, ercc)
+	expectedErr := fmt.Errorf("some error")
+	expectedCCParams := &protos.CCParameters{
+		ChaincodeId: "someCCID",
+		ChannelId:   "someChannel",
+	}
+	expectedExportMsg := []byte("someSignedExportMessage")
+
+	// error getting chaincode params
+	ex.GetChaincodeParamsReturns(nil, expectedErr)
+	r := ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot extract chaincode params: %s", expectedErr), r)
+
+	// error getting enclave id
+	ex.GetChaincodeParamsReturns(expectedCCParams, nil)
+	ec.GetEnclaveIdReturns("", expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot get enclave id: %s", expectedErr), r)
+
+	// error getting key export from ercc
+	ec.GetEnclaveIdReturns("someEnclaveId", nil)
+	ercc.GetKeyExportReturns(nil, expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot get key export for enclaveId = someEnclaveId: %s", expectedErr), r)
+
+	// error when importing keys
+	ercc.GetKeyExportReturns(expectedExportMsg, nil)
+	ec.ImportCCKeysReturns(nil, expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("Enclave ImportCCKeys function failed: %s", expectedErr), r)
+
+	// no error
+	expectedMsg := []byte("someSignedCCKeyRegistrationMessage")
+	ec.ImportCCKeysReturns(expectedMsg, nil)
+	r = ecc.Invoke(stub)
+	assert.EqualValues(t, shim.OK, r.Status)
+	p, err := base64.StdEncoding.DecodeString(string(r.Payload))
+	assert.NoError(t, err)
+	assert.EqualValues(t, expectedMsg, p)
+	_, channelId, chaincodeId, enclaveId := ercc.GetKeyExportArgsForCall(1)
+	assert.Equal(t, "someChannel", channelId)
+	assert.Equal(t, "someCCID", chaincodeId)
+	assert.Equal(t, "someEnclaveId", enclaveId)
+	assert.Equal(t, expectedExportMsg, ec.ImportCCKeysArgsForCall(1))
+}
+
 func TestInvokeEnclave(t *testing.T) {
 	stub := &fakes.ChaincodeStub{}
 	stub.GetFunctionAndParametersReturns("__invoke", nil)
@@ -194,6 +348,19 @@ func TestEndorse(t *testing.T) {
 	r = ecc.Invoke(stub)
 	expectError(t, fmt.Sprintf("cannot extract chaincode response message: %s", expectedErr), r)
 
+	// queryEnclaveRevoked returns error
+	ex.GetChaincodeParamsReturns(expectedCCParams, nil)
+	ex.GetChaincodeResponseMessagesReturns(expectedSignedResp, expectedResp, nil)
+	ercc.QueryEnclaveRevokedReturns(false, expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot query revocation status: %s", expectedErr), r)
+
+	// enclave revoked
+	ercc.QueryEnclaveRevokedReturns(true, nil)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("enclave %s has been revoked", expectedResp.EnclaveId), r)
+	ercc.QueryEnclaveRevokedReturns(false, nil)
+
 	// queryEnclaveCredentials returns error
 	ex.GetChaincodeParamsReturns(expectedCCParams, nil)
 	ex.GetChaincodeResponseMessagesReturns(expectedSignedResp, expectedResp, nil)
@@ -251,6 +418,11 @@ func TestEndorse(t *testing.T) {
 	r = ecc.Invoke(stub)
 	expectError(t, expectedErr.Error(), r)
 
+	// read conflict when checking rwset
+	val.ReplayReadWritesReturns(&endorsement.ReadConflictError{})
+	r = ecc.Invoke(stub)
+	assert.EqualValues(t, utils.ReadConflictStatus, r.Status)
+
 	// no error
 	ex.GetChaincodeParamsReturns(expectedCCParams, nil)
 	ex.GetChaincodeResponseMessagesReturns(expectedSignedResp, expectedResp, nil)
@@ -260,6 +432,25 @@ func TestEndorse(t *testing.T) {
 	r = ecc.Invoke(stub)
 	assert.EqualValues(t, shim.OK, r.Status)
 	assert.EqualValues(t, []byte("OK"), r.Payload)
+	assert.Zero(t, stub.SetEventCallCount())
+
+	// error when setting event
+	expectedEvent := &protos.FPCEvent{
+		EventName:        "someEvent",
+		EncryptedPayload: []byte("someEncryptedPayload"),
+	}
+	expectedResp.Event = expectedEvent
+	stub.SetEventReturns(expectedErr)
+	r = ecc.Invoke(stub)
+	expectError(t, fmt.Sprintf("cannot set chaincode event: %s", expectedErr), r)
+
+	// no error with event
+	stub.SetEventReturns(nil)
+	r = ecc.Invoke(stub)
+	assert.EqualValues(t, shim.OK, r.Status)
+	name, payload := stub.SetEventArgsForCall(1)
+	assert.Equal(t, expectedEvent.EventName, name)
+	assert.Equal(t, protoutil.MarshalOrPanic(expectedEvent), payload)
 }
 
 func expectError(t *testing.T, errorMsg string, r peer.Response) {
@@ -21,9 +21,9 @@ type Enclave interface {
 	// GetEnclaveId returns the EnclaveId hosted by the peer
 	GetEnclaveId() (string, error)
 
-	// key distribution (Post-MVP Feature)
+	// key distribution
 
-	// GenerateCCKeys returns a signed CCKeyRegistration Message including
+	// GenerateCCKeys generates new chaincode keys and returns a signed CCKeyRegistration Message
 	// The output parameters is a serialized protobuf
 	GenerateCCKeys() (signedCCKeyRegistrationMessage []byte, err error)
 
@@ -31,9 +31,9 @@ type Enclave interface {
 	// The input and output parameters are serialized protobufs
 	ExportCCKeys(credentials []byte) (signedExportMessage []byte, err error)
 
-	// ImportCCKeys imports chaincode secrets
-	// The output parameters is a serialized protobuf
-	ImportCCKeys() (signedCCKeyRegistrationMessage []byte, err error)
+	// ImportCCKeys imports chaincode secrets exported by another enclave
+	// The input and output parameters are serialized protobufs
+	ImportCCKeys(signedExportMessage []byte) (signedCCKeyRegistrationMessage []byte, err error)
 
 	// ChaincodeInvoke invokes fpc chaincode inside enclave
 	// chaincodeRequestMessage and chaincodeResponseMessage are serialized protobuf
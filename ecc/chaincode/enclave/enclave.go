@@ -100,7 +100,7 @@ func (e *EnclaveStub) ExportCCKeys(credentials []byte) ([]byte, error) {
 	panic("implement me")
 }
 
-func (e *EnclaveStub) ImportCCKeys() ([]byte, error) {
+func (e *EnclaveStub) ImportCCKeys(signedExportMessage []byte) ([]byte, error) {
 	panic("implement me")
 }
 
@@ -20,6 +20,7 @@ import (
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/pkg/errors"
@@ -35,6 +36,8 @@ type MockEnclaveStub struct {
 	publicKey    []byte
 	enclaveId    string
 	ccPrivateKey []byte
+	ccPublicKey  []byte
+	ccParams     *protos.CCParameters
 }
 
 func NewEnclaveStub() *MockEnclaveStub {
@@ -71,6 +74,8 @@ func (m *MockEnclaveStub) Init(serializedChaincodeParams, serializedHostParamsBy
 		return nil, err
 	}
 	m.ccPrivateKey = ccPrivateKey
+	m.ccPublicKey = ccPublicKey
+	m.ccParams = chaincodeParams
 
 	// calculate enclave id
 	m.enclaveId, _ = m.GetEnclaveId()
@@ -92,9 +97,32 @@ func (m *MockEnclaveStub) Init(serializedChaincodeParams, serializedHostParamsBy
 	return proto.Marshal(credentials)
 }
 
-func (m MockEnclaveStub) GenerateCCKeys() ([]byte, error) {
-	panic("implement me")
-	// -> *protos.SignedCCKeyRegistrationMessage
+func (m *MockEnclaveStub) GenerateCCKeys() ([]byte, error) {
+	// note that the mock enclave already creates its chaincode keys during Init
+	ccParamsHash, err := utils.GetCCParamsHash(m.ccParams)
+	if err != nil {
+		return nil, err
+	}
+
+	enclaveId := sha256.Sum256(m.publicKey)
+	serializedRegistrationMessage, err := anypb.New(&protos.CCKeyRegistrationMessage{
+		CcParamsHash: ccParamsHash,
+		ChaincodeEk:  m.ccPublicKey,
+		EnclaveId:    enclaveId[:],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := m.csp.SignMessage(m.privateKey, serializedRegistrationMessage.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&protos.SignedCCKeyRegistrationMessage{
+		SerializedCckeyRegMsg: serializedRegistrationMessage,
+		Signature:             sig,
+	})
 }
 
 func (m MockEnclaveStub) ExportCCKeys(credentials []byte) ([]byte, error) {
@@ -102,9 +130,9 @@ func (m MockEnclaveStub) ExportCCKeys(credentials []byte) ([]byte, error) {
 	// credentials *protos.Credentials -> *protos.SignedExportMessage,
 }
 
-func (m MockEnclaveStub) ImportCCKeys() ([]byte, error) {
+func (m MockEnclaveStub) ImportCCKeys(signedExportMessage []byte) ([]byte, error) {
 	panic("implement me")
-	// -> *protos.SignedCCKeyRegistrationMessage
+	// signedExportMessage *protos.SignedExportMessage -> *protos.SignedCCKeyRegistrationMessage
 }
 
 func (m *MockEnclaveStub) GetEnclaveId() (string, error) {
@@ -8,7 +8,9 @@ SPDX-License-Identifier: Apache-2.0
 package ercc
 
 import (
+	"encoding/base64"
 	"fmt"
+	"strconv"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
@@ -17,6 +19,8 @@ import (
 
 type Stub interface {
 	QueryEnclaveCredentials(stub shim.ChaincodeStubInterface, channelId, chaincodeId, enclaveId string) (*protos.Credentials, error)
+	GetKeyExport(stub shim.ChaincodeStubInterface, channelId, chaincodeId, enclaveId string) (signedExportMessage []byte, err error)
+	QueryEnclaveRevoked(stub shim.ChaincodeStubInterface, channelId, chaincodeId, enclaveId string) (bool, error)
 }
 
 type StubImpl struct {
@@ -33,3 +37,25 @@ func (ercc *StubImpl) QueryEnclaveCredentials(stub shim.ChaincodeStubInterface,
 
 	return utils.UnmarshalCredentials(string(resp.Payload))
 }
+
+func (ercc *StubImpl) GetKeyExport(stub shim.ChaincodeStubInterface, channelId, chaincodeId, enclaveId string) ([]byte, error) {
+	args := [][]byte{[]byte("getKeyExport"), []byte(chaincodeId), []byte(enclaveId)}
+
+	resp := stub.InvokeChaincode("ercc", args, channelId)
+	if resp.Status != shim.OK {
+		return nil, fmt.Errorf("error: %s", resp.Message)
+	}
+
+	return base64.StdEncoding.DecodeString(string(resp.Payload))
+}
+
+func (ercc *StubImpl) QueryEnclaveRevoked(stub shim.ChaincodeStubInterface, channelId, chaincodeId, enclaveId string) (bool, error) {
+	args := [][]byte{[]byte("queryEnclaveRevoked"), []byte(chaincodeId), []byte(enclaveId)}
+
+	resp := stub.InvokeChaincode("ercc", args, channelId)
+	if resp.Status != shim.OK {
+		return false, fmt.Errorf("error: %s", resp.Message)
+	}
+
+	return strconv.ParseBool(string(resp.Payload))
+}
@@ -59,9 +59,10 @@ type EnclaveStub struct {
 		result1 string
 		result2 error
 	}
-	ImportCCKeysStub        func() ([]byte, error)
+	ImportCCKeysStub        func([]byte) ([]byte, error)
 	importCCKeysMutex       sync.RWMutex
 	importCCKeysArgsForCall []struct {
+		arg1 []byte
 	}
 	importCCKeysReturns struct {
 		result1 []byte
@@ -341,17 +342,23 @@ func (fake *EnclaveStub) GetEnclaveIdReturnsOnCall(i int, result1 string, result
 	}{result1, result2}
 }
 
-func (fake *EnclaveStub) ImportCCKeys() ([]byte, error) {
+func (fake *EnclaveStub) ImportCCKeys(arg1 []byte) ([]byte, error) {
+	var arg1Copy []byte
+	if arg1 != nil {
+		arg1Copy = make([]byte, len(arg1))
+		copy(arg1Copy, arg1)
+	}
 	fake.importCCKeysMutex.Lock()
 	ret, specificReturn := fake.importCCKeysReturnsOnCall[len(fake.importCCKeysArgsForCall)]
 	fake.importCCKeysArgsForCall = append(fake.importCCKeysArgsForCall, struct {
-	}{})
+		arg1 []byte
+	}{arg1Copy})
 	stub := fake.ImportCCKeysStub
 	fakeReturns := fake.importCCKeysReturns
-	fake.recordInvocation("ImportCCKeys", []interface{}{})
+	fake.recordInvocation("ImportCCKeys", []interface{}{arg1Copy})
 	fake.importCCKeysMutex.Unlock()
 	if stub != nil {
-		return stub()
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -365,12 +372,19 @@ func (fake *EnclaveStub) ImportCCKeysCallCount() int {
 	return len(fake.importCCKeysArgsForCall)
 }
 
-func (fake *EnclaveStub) ImportCCKeysCalls(stub func() ([]byte, error)) {
+func (fake *EnclaveStub) ImportCCKeysCalls(stub func([]byte) ([]byte, error)) {
 	fake.importCCKeysMutex.Lock()
 	defer fake.importCCKeysMutex.Unlock()
 	fake.ImportCCKeysStub = stub
 }
 
+func (fake *EnclaveStub) ImportCCKeysArgsForCall(i int) []byte {
+	fake.importCCKeysMutex.RLock()
+	defer fake.importCCKeysMutex.RUnlock()
+	argsForCall := fake.importCCKeysArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *EnclaveStub) ImportCCKeysReturns(result1 []byte, result2 error) {
 	fake.importCCKeysMutex.Lock()
 	defer fake.importCCKeysMutex.Unlock()
@@ -9,6 +9,22 @@ import (
 )
 
 type ErccStub struct {
+	GetKeyExportStub        func(shim.ChaincodeStubInterface, string, string, string) ([]byte, error)
+	getKeyExportMutex       sync.RWMutex
+	getKeyExportArgsForCall []struct {
+		arg1 shim.ChaincodeStubInterface
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	getKeyExportReturns struct {
+		result1 []byte
+		result2 error
+	}
+	getKeyExportReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	QueryEnclaveCredentialsStub        func(shim.ChaincodeStubInterface, string, string, string) (*protos.Credentials, error)
 	queryEnclaveCredentialsMutex       sync.RWMutex
 	queryEnclaveCredentialsArgsForCall []struct {
@@ -25,10 +41,93 @@ type ErccStub struct {
 		result1 *protos.Credentials
 		result2 error
 	}
+	QueryEnclaveRevokedStub        func(shim.ChaincodeStubInterface, string, string, string) (bool, error)
+	queryEnclaveRevokedMutex       sync.RWMutex
+	queryEnclaveRevokedArgsForCall []struct {
+		arg1 shim.ChaincodeStubInterface
+		arg2 string
+		arg3 string
+		arg4 string
+	}
+	queryEnclaveRevokedReturns struct {
+		result1 bool
+		result2 error
+	}
+	queryEnclaveRevokedReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *ErccStub) GetKeyExport(arg1 shim.ChaincodeStubInterface, arg2 string, arg3 string, arg4 string) ([]byte, error) {
+	fake.getKeyExportMutex.Lock()
+	ret, specificReturn := fake.getKeyExportReturnsOnCall[len(fake.getKeyExportArgsForCall)]
+	fake.getKeyExportArgsForCall = append(fake.getKeyExportArgsForCall, struct {
+		arg1 shim.ChaincodeStubInterface
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetKeyExportStub
+	fakeReturns := fake.getKeyExportReturns
+	fake.recordInvocation("GetKeyExport", []interface{}{arg1, arg2, arg3, arg4})
+	fake.getKeyExportMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *ErccStub) GetKeyExportCallCount() int {
+	fake.getKeyExportMutex.RLock()
+	defer fake.getKeyExportMutex.RUnlock()
+	return len(fake.getKeyExportArgsForCall)
+}
+
+func (fake *ErccStub) GetKeyExportCalls(stub func(shim.ChaincodeStubInterface, string, string, string) ([]byte, error)) {
+	fake.getKeyExportMutex.Lock()
+	defer fake.getKeyExportMutex.Unlock()
+	fake.GetKeyExportStub = stub
+}
+
+func (fake *ErccStub) GetKeyExportArgsForCall(i int) (shim.ChaincodeStubInterface, string, string, string) {
+	fake.getKeyExportMutex.RLock()
+	defer fake.getKeyExportMutex.RUnlock()
+	argsForCall := fake.getKeyExportArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *ErccStub) GetKeyExportReturns(result1 []byte, result2 error) {
+	fake.getKeyExportMutex.Lock()
+	defer fake.getKeyExportMutex.Unlock()
+	fake.GetKeyExportStub = nil
+	fake.getKeyExportReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *ErccStub) GetKeyExportReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.getKeyExportMutex.Lock()
+	defer fake.getKeyExportMutex.Unlock()
+	fake.GetKeyExportStub = nil
+	if fake.getKeyExportReturnsOnCall == nil {
+		fake.getKeyExportReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.getKeyExportReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *ErccStub) QueryEnclaveCredentials(arg1 shim.ChaincodeStubInterface, arg2 string, arg3 string, arg4 string) (*protos.Credentials, error) {
 	fake.queryEnclaveCredentialsMutex.Lock()
 	ret, specificReturn := fake.queryEnclaveCredentialsReturnsOnCall[len(fake.queryEnclaveCredentialsArgsForCall)]
@@ -96,11 +195,82 @@ func (fake *ErccStub) QueryEnclaveCredentialsReturnsOnCall(i int, result1 *proto
 	}{result1, result2}
 }
 
+func (fake *ErccStub) QueryEnclaveRevoked(arg1 shim.ChaincodeStubInterface, arg2 string, arg3 string, arg4 string) (bool, error) {
+	fake.queryEnclaveRevokedMutex.Lock()
+	ret, specificReturn := fake.queryEnclaveRevokedReturnsOnCall[len(fake.queryEnclaveRevokedArgsForCall)]
+	fake.queryEnclaveRevokedArgsForCall = append(fake.queryEnclaveRevokedArgsForCall, struct {
+		arg1 shim.ChaincodeStubInterface
+		arg2 string
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.QueryEnclaveRevokedStub
+	fakeReturns := fake.queryEnclaveRevokedReturns
+	fake.recordInvocation("QueryEnclaveRevoked", []interface{}{arg1, arg2, arg3, arg4})
+	fake.queryEnclaveRevokedMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *ErccStub) QueryEnclaveRevokedCallCount() int {
+	fake.queryEnclaveRevokedMutex.RLock()
+	defer fake.queryEnclaveRevokedMutex.RUnlock()
+	return len(fake.queryEnclaveRevokedArgsForCall)
+}
+
+func (fake *ErccStub) QueryEnclaveRevokedCalls(stub func(shim.ChaincodeStubInterface, string, string, string) (bool, error)) {
+	fake.queryEnclaveRevokedMutex.Lock()
+	defer fake.queryEnclaveRevokedMutex.Unlock()
+	fake.QueryEnclaveRevokedStub = stub
+}
+
+func (fake *ErccStub) QueryEnclaveRevokedArgsForCall(i int) (shim.ChaincodeStubInterface, string, string, string) {
+	fake.queryEnclaveRevokedMutex.RLock()
+	defer fake.queryEnclaveRevokedMutex.RUnlock()
+	argsForCall := fake.queryEnclaveRevokedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *ErccStub) QueryEnclaveRevokedReturns(result1 bool, result2 error) {
+	fake.queryEnclaveRevokedMutex.Lock()
+	defer fake.queryEnclaveRevokedMutex.Unlock()
+	fake.QueryEnclaveRevokedStub = nil
+	fake.queryEnclaveRevokedReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *ErccStub) QueryEnclaveRevokedReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.queryEnclaveRevokedMutex.Lock()
+	defer fake.queryEnclaveRevokedMutex.Unlock()
+	fake.QueryEnclaveRevokedStub = nil
+	if fake.queryEnclaveRevokedReturnsOnCall == nil {
+		fake.queryEnclaveRevokedReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.queryEnclaveRevokedReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *ErccStub) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.getKeyExportMutex.RLock()
+	defer fake.getKeyExportMutex.RUnlock()
 	fake.queryEnclaveCredentialsMutex.RLock()
 	defer fake.queryEnclaveCredentialsMutex.RUnlock()
+	fake.queryEnclaveRevokedMutex.RLock()
+	defer fake.queryEnclaveRevokedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
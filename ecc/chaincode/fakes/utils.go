@@ -76,6 +76,19 @@ type Extractors struct {
 		result1 []byte
 		result2 error
 	}
+	GetSerializedCredentialsStub        func(shim.ChaincodeStubInterface) ([]byte, error)
+	getSerializedCredentialsMutex       sync.RWMutex
+	getSerializedCredentialsArgsForCall []struct {
+		arg1 shim.ChaincodeStubInterface
+	}
+	getSerializedCredentialsReturns struct {
+		result1 []byte
+		result2 error
+	}
+	getSerializedCredentialsReturnsOnCall map[int]struct {
+		result1 []byte
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -403,6 +416,70 @@ func (fake *Extractors) GetSerializedChaincodeRequestReturnsOnCall(i int, result
 	}{result1, result2}
 }
 
+func (fake *Extractors) GetSerializedCredentials(arg1 shim.ChaincodeStubInterface) ([]byte, error) {
+	fake.getSerializedCredentialsMutex.Lock()
+	ret, specificReturn := fake.getSerializedCredentialsReturnsOnCall[len(fake.getSerializedCredentialsArgsForCall)]
+	fake.getSerializedCredentialsArgsForCall = append(fake.getSerializedCredentialsArgsForCall, struct {
+		arg1 shim.ChaincodeStubInterface
+	}{arg1})
+	stub := fake.GetSerializedCredentialsStub
+	fakeReturns := fake.getSerializedCredentialsReturns
+	fake.recordInvocation("GetSerializedCredentials", []interface{}{arg1})
+	fake.getSerializedCredentialsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *Extractors) GetSerializedCredentialsCallCount() int {
+	fake.getSerializedCredentialsMutex.RLock()
+	defer fake.getSerializedCredentialsMutex.RUnlock()
+	return len(fake.getSerializedCredentialsArgsForCall)
+}
+
+func (fake *Extractors) GetSerializedCredentialsCalls(stub func(shim.ChaincodeStubInterface) ([]byte, error)) {
+	fake.getSerializedCredentialsMutex.Lock()
+	defer fake.getSerializedCredentialsMutex.Unlock()
+	fake.GetSerializedCredentialsStub = stub
+}
+
+func (fake *Extractors) GetSerializedCredentialsArgsForCall(i int) shim.ChaincodeStubInterface {
+	fake.getSerializedCredentialsMutex.RLock()
+	defer fake.getSerializedCredentialsMutex.RUnlock()
+	argsForCall := fake.getSerializedCredentialsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *Extractors) GetSerializedCredentialsReturns(result1 []byte, result2 error) {
+	fake.getSerializedCredentialsMutex.Lock()
+	defer fake.getSerializedCredentialsMutex.Unlock()
+	fake.GetSerializedCredentialsStub = nil
+	fake.getSerializedCredentialsReturns = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Extractors) GetSerializedCredentialsReturnsOnCall(i int, result1 []byte, result2 error) {
+	fake.getSerializedCredentialsMutex.Lock()
+	defer fake.getSerializedCredentialsMutex.Unlock()
+	fake.GetSerializedCredentialsStub = nil
+	if fake.getSerializedCredentialsReturnsOnCall == nil {
+		fake.getSerializedCredentialsReturnsOnCall = make(map[int]struct {
+			result1 []byte
+			result2 error
+		})
+	}
+	fake.getSerializedCredentialsReturnsOnCall[i] = struct {
+		result1 []byte
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *Extractors) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -416,6 +493,8 @@ func (fake *Extractors) Invocations() map[string][][]interface{} {
 	defer fake.getInitEnclaveMessageMutex.RUnlock()
 	fake.getSerializedChaincodeRequestMutex.RLock()
 	defer fake.getSerializedChaincodeRequestMutex.RUnlock()
+	fake.getSerializedCredentialsMutex.RLock()
+	defer fake.getSerializedCredentialsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
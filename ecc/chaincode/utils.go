@@ -24,6 +24,7 @@ type Extractors interface {
 	GetChaincodeResponseMessages(stub shim.ChaincodeStubInterface) (*protos.SignedChaincodeResponseMessage, *protos.ChaincodeResponseMessage, error)
 	GetChaincodeParams(stub shim.ChaincodeStubInterface) (*protos.CCParameters, error)
 	GetHostParams(stub shim.ChaincodeStubInterface) (*protos.HostParameters, error)
+	GetSerializedCredentials(stub shim.ChaincodeStubInterface) ([]byte, error)
 }
 
 type ExtractorImpl struct {
@@ -60,6 +61,19 @@ func (s *ExtractorImpl) GetSerializedChaincodeRequest(stub shim.ChaincodeStubInt
 	return chaincodeRequestMessage, nil
 }
 
+func (s *ExtractorImpl) GetSerializedCredentials(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	if len(stub.GetStringArgs()) < 2 {
+		return nil, fmt.Errorf("credentials missing")
+	}
+
+	credentials, err := base64.StdEncoding.DecodeString(stub.GetStringArgs()[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
 func (s *ExtractorImpl) GetChaincodeResponseMessages(stub shim.ChaincodeStubInterface) (*protos.SignedChaincodeResponseMessage, *protos.ChaincodeResponseMessage, error) {
 	if len(stub.GetStringArgs()) < 2 {
 		return nil, nil, fmt.Errorf("initEnclaveMessage missing")
@@ -17,11 +17,13 @@ EGO_CONFIG_FILE ?= $(FPC_PATH)/ecc_go/enclave.json
 ECC_MAIN_FILES ?= main.go
 ECC_BINARY ?= ecc
 ECC_BUNDLE ?= $(ECC_BINARY)-bundle
+# the enclave binary built with ego-go includes the EGo attestation and sealing support
+ECC_GOTAGS ?= -tags ego
 
 build: ecc docker env
 
 ecc: ecc_dependencies
-	ego-go build $(GOTAGS) -o $(ECC_BINARY) $(ECC_MAIN_FILES)
+	ego-go build $(GOTAGS) $(ECC_GOTAGS) -o $(ECC_BINARY) $(ECC_MAIN_FILES)
 	cp $(EGO_CONFIG_FILE) ./enclave.json
 	ego sign
 	ego uniqueid $(ECC_BINARY) > mrenclave
@@ -7,13 +7,37 @@ SPDX-License-Identifier: Apache-2.0
 package attestation
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/ego"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/simulation"
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
-func Issue(attestedData *anypb.Any) ([]byte, error) {
-	issuer := simulation.NewSimulationIssuer()
+// SimulatedMrenclaveEnvKey is the environment variable to set the (fake) mrenclave of simulated attestations.
+// If not set, the mrenclave defaults to the chaincode version the enclave is initialized with.
+const SimulatedMrenclaveEnvKey = "FPC_SIM_MRENCLAVE"
+
+// attestationParams contains the attestation parameters as passed to `__initEnclave` (see sgx.AttestationParams)
+type attestationParams struct {
+	AttestationType string `json:"attestation_type"`
+}
+
+// Issue creates an attestation over the attested data using the issuer selected by the attestation type
+// in the (base64 encoded json) attestation params. If no attestation params are given, a simulated
+// attestation is created.
+func Issue(serializedAttestationParams []byte, attestedData *anypb.Any) ([]byte, error) {
+	issuer, err := getIssuer(serializedAttestationParams, attestedData)
+	if err != nil {
+		return nil, err
+	}
+
 	att, err := issuer.Issue(attestedData.Value)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot get attestation")
@@ -21,3 +45,87 @@ func Issue(attestedData *anypb.Any) ([]byte, error) {
 
 	return att, nil
 }
+
+func getIssuer(serializedAttestationParams []byte, attestedData *anypb.Any) (*types.Issuer, error) {
+	params := &attestationParams{}
+	if len(serializedAttestationParams) > 0 {
+		jsonParams, err := base64.StdEncoding.DecodeString(string(serializedAttestationParams))
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot decode attestation params")
+		}
+		if err := json.Unmarshal(jsonParams, params); err != nil {
+			return nil, errors.Wrap(err, "cannot unmarshal attestation params")
+		}
+	}
+
+	switch params.AttestationType {
+	case "", simulation.SimulationType:
+		mrenclave, err := simulatedMrenclave(attestedData)
+		if err != nil {
+			return nil, err
+		}
+		return simulation.NewSimulationIssuer(mrenclave), nil
+	case ego.EgoType:
+		return ego.NewEgoIssuer(), nil
+	default:
+		return nil, errors.Errorf("unsupported attestation type '%s'", params.AttestationType)
+	}
+}
+
+// Verify verifies that the attestation of the credentials was issued by an enclave with the expected mrenclave over
+// the attested data of the credentials. Only attestations of the given attestation type are accepted; that is, an
+// enclave only trusts other enclaves attested the same way as itself (see AttestationType).
+func Verify(attestationType string, credentials *protos.Credentials, expectedMrenclave string) error {
+	att := &types.Attestation{}
+	if err := json.Unmarshal(credentials.GetAttestation(), att); err != nil {
+		return errors.Wrap(err, "cannot unmarshal attestation")
+	}
+
+	if att.Type != attestationType {
+		return errors.Errorf("unexpected attestation type '%s'", att.Type)
+	}
+
+	var verifier *types.Verifier
+	switch att.Type {
+	case simulation.SimulationType:
+		verifier = simulation.NewSimulationVerifier()
+	case ego.EgoType:
+		verifier = ego.NewEgoVerifier()
+	default:
+		return errors.Errorf("unsupported attestation type '%s'", att.Type)
+	}
+
+	// the converters of the supported types do not alter the attestation, i.e., the attestation is the evidence
+	return verifier.Verify(&types.Evidence{Type: att.Type, Data: att.Data}, &types.ValidationValues{
+		Statement: credentials.GetSerializedAttestedData().GetValue(),
+		Mrenclave: expectedMrenclave,
+	})
+}
+
+// AttestationType returns the attestation type of the serialized credentials
+func AttestationType(credentialsBytes []byte) (string, error) {
+	credentials := &protos.Credentials{}
+	if err := proto.Unmarshal(credentialsBytes, credentials); err != nil {
+		return "", errors.Wrap(err, "cannot unmarshal credentials")
+	}
+
+	att := &types.Attestation{}
+	if err := json.Unmarshal(credentials.GetAttestation(), att); err != nil {
+		return "", errors.Wrap(err, "cannot unmarshal attestation")
+	}
+
+	return att.Type, nil
+}
+
+func simulatedMrenclave(attestedData *anypb.Any) (string, error) {
+	if mrenclave, ok := os.LookupEnv(SimulatedMrenclaveEnvKey); ok {
+		return mrenclave, nil
+	}
+
+	data := &protos.AttestedData{}
+	if err := attestedData.UnmarshalTo(data); err != nil {
+		return "", errors.Wrap(err, "cannot unmarshal attested data")
+	}
+
+	return data.GetCcParams().GetVersion(), nil
+}
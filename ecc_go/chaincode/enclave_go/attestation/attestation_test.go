@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/simulation"
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func params(attestationType string) []byte {
+	jsonParams, _ := json.Marshal(&attestationParams{AttestationType: attestationType})
+	return []byte(base64.StdEncoding.EncodeToString(jsonParams))
+}
+
+func TestIssue(t *testing.T) {
+	attestedData, _ := anypb.New(&protos.AttestedData{CcParams: &protos.CCParameters{Version: "someMrenclave"}})
+	verifier := simulation.NewSimulationVerifier()
+	verify := func(att []byte, mrenclave string) error {
+		a := &types.Attestation{}
+		assert.NoError(t, json.Unmarshal(att, a))
+		assert.Equal(t, "simulated", a.Type)
+		return verifier.Verify(&types.Evidence{Type: a.Type, Data: a.Data}, &types.ValidationValues{
+			Statement: attestedData.Value,
+			Mrenclave: mrenclave,
+		})
+	}
+
+	// simulated attestation binds the chaincode version as mrenclave by default
+	for _, p := range [][]byte{nil, params("simulated")} {
+		att, err := Issue(p, attestedData)
+		assert.NoError(t, err)
+		assert.NoError(t, verify(att, "someMrenclave"))
+	}
+
+	t.Setenv(SimulatedMrenclaveEnvKey, "otherMrenclave")
+	att, err := Issue(nil, attestedData)
+	assert.NoError(t, err)
+	assert.NoError(t, verify(att, "otherMrenclave"))
+	assert.Error(t, verify(att, "someMrenclave"))
+
+	// ego requires the ego build tag
+	_, err = Issue(params("ego"), attestedData)
+	assert.Error(t, err)
+
+	_, err = Issue(params("unknown"), attestedData)
+	assert.EqualError(t, err, "unsupported attestation type 'unknown'")
+
+	_, err = Issue([]byte("not base64"), attestedData)
+	assert.Error(t, err)
+}
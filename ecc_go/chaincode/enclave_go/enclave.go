@@ -9,6 +9,7 @@ SPDX-License-Identifier: Apache-2.0
 package enclave_go
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
@@ -16,8 +17,10 @@ import (
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go/attestation"
+	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go/sealing"
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/bccsp/factory"
@@ -38,7 +41,11 @@ type EnclaveStub struct {
 	hostParams           *protos.HostParameters
 	chaincodeParams      *protos.CCParameters
 	fabricCryptoProvider bccsp.BCCSP
-	stubProvider         func(shim.ChaincodeStubInterface, *pb.ChaincodeInput, *readWriteSet, StateEncryptionFunctions) shim.ChaincodeStubInterface
+	stubProvider         func(shim.ChaincodeStubInterface, *pb.ChaincodeInput, map[string][]byte, *readWriteSet, *chaincodeEvent, StateEncryptionFunctions) shim.ChaincodeStubInterface
+	readYourWrites       bool
+	deterministic        bool
+	sealer               sealing.Sealer
+	credentials          []byte
 }
 
 func NewEnclaveStub(cc shim.Chaincode) *EnclaveStub {
@@ -47,52 +54,67 @@ func NewEnclaveStub(cc shim.Chaincode) *EnclaveStub {
 	}
 	cryptoProvider := factory.GetDefault()
 
-	return &EnclaveStub{
+	enclaveStub := &EnclaveStub{
 		csp:                  crypto.GetDefaultCSP(),
 		ccRef:                cc,
 		fabricCryptoProvider: cryptoProvider,
-		stubProvider: func(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, rwset *readWriteSet, sep StateEncryptionFunctions) shim.ChaincodeStubInterface {
-			return NewFpcStubInterface(stub, input, rwset, sep)
-		},
 	}
+	enclaveStub.stubProvider = func(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, transient map[string][]byte, rwset *readWriteSet, event *chaincodeEvent, sep StateEncryptionFunctions) shim.ChaincodeStubInterface {
+		fpcStub := NewFpcStubInterface(stub, input, transient, rwset, event, sep)
+		fpcStub.readYourWrites = enclaveStub.readYourWrites
+		return fpcStub
+	}
+	return enclaveStub
+}
+
+// EnableReadYourWrites lets the chaincode observe its own pending writes and deletes within a single invocation.
+// By default, as in Fabric, reads always return the committed state.
+func (e *EnclaveStub) EnableReadYourWrites() {
+	e.readYourWrites = true
+}
+
+// EnableDeterministicResponses makes the encryption of the response, the event payload, and the written state values
+// deterministic for a given request. Thus, enclaves that execute the same request on the same state produce the
+// same ChaincodeResponseMessage except for the enclave_id, which allows comparing the results of several enclaves.
+// Note that deterministic encryption reveals whether a value is written multiple times within the same request.
+func (e *EnclaveStub) EnableDeterministicResponses() {
+	e.deterministic = true
 }
 
 func (e *EnclaveStub) Init(serializedChaincodeParams, serializedHostParamsBytes, serializedAttestationParams []byte) ([]byte, error) {
 	logger.Debug("Init enclave")
 
-	var err error
-
-	// generate new enclave identity
-	e.identity, err = NewEnclaveIdentity(e.csp)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot create new enclave identity")
+	hostParams := &protos.HostParameters{}
+	if err := proto.Unmarshal(serializedHostParamsBytes, hostParams); err != nil {
+		return nil, err
 	}
 
-	// as we currently support a single enclave instance per chaincode, we also generate a new chaincode identity here
-	// this needs to be refactored once multi enclave support will be integrated
-	e.ccKeys, err = NewChaincodeKeys(e.csp)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot create new enclave identity")
+	chaincodeParams := &protos.CCParameters{}
+	if err := proto.Unmarshal(serializedChaincodeParams, chaincodeParams); err != nil {
+		return nil, err
 	}
 
-	e.hostParams = &protos.HostParameters{}
-	if err := proto.Unmarshal(serializedHostParamsBytes, e.hostParams); err != nil {
-		return nil, err
+	// if the enclave was restored from its sealed state, we reuse the existing identity (and thus registration)
+	// as long as the enclave is initialized for the same chaincode and host
+	if e.sealer != nil && e.credentials != nil &&
+		proto.Equal(e.chaincodeParams, chaincodeParams) && proto.Equal(e.hostParams, hostParams) {
+		logger.Infof("Reuse sealed enclave %s", e.identity.GetEnclaveId())
+		return e.credentials, nil
 	}
 
-	e.chaincodeParams = &protos.CCParameters{}
-	if err := proto.Unmarshal(serializedChaincodeParams, e.chaincodeParams); err != nil {
-		return nil, err
+	// generate new enclave identity
+	identity, err := NewEnclaveIdentity(e.csp)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create new enclave identity")
 	}
 
 	serializedAttestedData, _ := anypb.New(&protos.AttestedData{
-		EnclaveVk:   e.identity.GetPublicKey(),
-		CcParams:    e.chaincodeParams,
-		HostParams:  e.hostParams,
-		ChaincodeEk: e.ccKeys.GetPublicKey(),
+		EnclaveVk:  identity.GetPublicKey(),
+		CcParams:   chaincodeParams,
+		HostParams: hostParams,
 	})
 
-	att, err := attestation.Issue(serializedAttestedData)
+	att, err := attestation.Issue(serializedAttestationParams, serializedAttestedData)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create attestation")
 	}
@@ -104,22 +126,268 @@ func (e *EnclaveStub) Init(serializedChaincodeParams, serializedHostParamsBytes,
 
 	logger.Infof("Create credentials: %s", credentials)
 
-	return proto.Marshal(credentials)
+	credentialsBytes, err := proto.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	// note that chaincode keys are not created here; they are either generated via GenerateCCKeys
+	// or received from another enclave via ImportCCKeys. Hence, the chaincode_ek is not part of the attested data
+	// but bound to the attestation via the SignedCCKeyRegistrationMessage signed with the attested enclave_vk
+	// (see crypto.VerifyCCKeyRegistrationMessage)
+	if err := e.seal(identity, credentialsBytes, nil); err != nil {
+		return nil, err
+	}
+
+	e.identity = identity
+	e.credentials = credentialsBytes
+	e.chaincodeParams = chaincodeParams
+	e.hostParams = hostParams
+	e.ccKeys = nil
+
+	return credentialsBytes, nil
 }
 
-func (e EnclaveStub) GenerateCCKeys() ([]byte, error) {
-	panic("implement me")
-	// -> *protos.SignedCCKeyRegistrationMessage
+// GenerateCCKeys creates new chaincode keys and returns a serialized SignedCCKeyRegistrationMessage, which binds the
+// chaincode_ek to the enclave credentials
+func (e *EnclaveStub) GenerateCCKeys() ([]byte, error) {
+	logger.Debug("GenerateCCKeys")
+
+	if e.identity == nil {
+		return nil, fmt.Errorf("enclave not yet initialized")
+	}
+
+	if e.ccKeys != nil {
+		return nil, fmt.Errorf("chaincode keys already exist")
+	}
+
+	ccKeys, err := NewChaincodeKeys(e.csp)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create new chaincode keys")
+	}
+
+	signedRegistrationMessage, err := e.newSignedCCKeyRegistrationMessage(ccKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.seal(e.identity, e.credentials, ccKeys); err != nil {
+		return nil, err
+	}
+
+	e.ccKeys = ccKeys
+
+	return proto.Marshal(signedRegistrationMessage)
 }
 
-func (e EnclaveStub) ExportCCKeys(credentials []byte) ([]byte, error) {
-	panic("implement me")
-	// credentials *protos.Credentials -> *protos.SignedExportMessage,
+// ExportCCKeys encrypts the chaincode keys for the enclave identified by the given serialized credentials
+// and returns a serialized SignedExportMessage. The keys are only exported if the attestation of the receiver
+// shows that it runs the same chaincode (see verifyEnclave).
+func (e *EnclaveStub) ExportCCKeys(credentialsBytes []byte) ([]byte, error) {
+	logger.Debug("ExportCCKeys")
+
+	if e.identity == nil {
+		return nil, fmt.Errorf("enclave not yet initialized")
+	}
+
+	if e.ccKeys == nil {
+		return nil, fmt.Errorf("chaincode keys not available")
+	}
+
+	credentials := &protos.Credentials{}
+	if err := proto.Unmarshal(credentialsBytes, credentials); err != nil {
+		return nil, errors.Wrap(err, "invalid credentials")
+	}
+
+	// only export to enclaves running the same chaincode
+	attestedData, err := e.verifyEnclave(credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot verify receiver")
+	}
+
+	ccParamsHash, err := utils.GetCCParamsHash(e.chaincodeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	ccKeysBytes, err := proto.Marshal(e.ccKeys.ToProto())
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypt chaincode keys for receiver
+	encryptedCCKeys, err := crypto.EciesEncryptMessage(attestedData.GetEnclaveVk(), ccKeysBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encrypt chaincode keys for receiver")
+	}
+
+	serializedExportMessage, err := anypb.New(&protos.ExportMessage{
+		CcParamsHash:      ccParamsHash,
+		ChaincodeEk:       e.ccKeys.GetPublicKey(),
+		CckeysEnc:         encryptedCCKeys,
+		ReceiverEnclaveVk: attestedData.GetEnclaveVk(),
+		SenderEnclaveVk:   e.identity.GetPublicKey(),
+		SenderCredentials: e.credentials,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := e.identity.Sign(serializedExportMessage.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&protos.SignedExportMessage{
+		SerializedExportMsgBytes: serializedExportMessage,
+		Signature:                sig,
+	})
 }
 
-func (e EnclaveStub) ImportCCKeys() ([]byte, error) {
-	panic("implement me")
-	// -> *protos.SignedCCKeyRegistrationMessage
+// ImportCCKeys receives the chaincode keys from a serialized SignedExportMessage created by another enclave
+// and returns a serialized SignedCCKeyRegistrationMessage. The keys are only imported if the export message is signed
+// with the attested key of an enclave running the same chaincode, as shown by the sender credentials in the message.
+func (e *EnclaveStub) ImportCCKeys(signedExportMessageBytes []byte) ([]byte, error) {
+	logger.Debug("ImportCCKeys")
+
+	if e.identity == nil {
+		return nil, fmt.Errorf("enclave not yet initialized")
+	}
+
+	if e.ccKeys != nil {
+		return nil, fmt.Errorf("chaincode keys already exist")
+	}
+
+	signedExportMessage, err := utils.UnmarshalSignedExportMessage(signedExportMessageBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	exportMessage, err := utils.UnmarshalExportMessage(signedExportMessage.GetSerializedExportMsgBytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(exportMessage.GetReceiverEnclaveVk(), e.identity.GetPublicKey()) {
+		return nil, fmt.Errorf("export message is not addressed to this enclave")
+	}
+
+	ccParamsHash, err := utils.GetCCParamsHash(e.chaincodeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(exportMessage.GetCcParamsHash(), ccParamsHash) {
+		return nil, fmt.Errorf("cc_params_hash of export message does not match")
+	}
+
+	if len(exportMessage.GetSenderCredentials()) == 0 {
+		return nil, fmt.Errorf("sender credentials missing")
+	}
+
+	senderCredentials := &protos.Credentials{}
+	if err := proto.Unmarshal(exportMessage.GetSenderCredentials(), senderCredentials); err != nil {
+		return nil, errors.Wrap(err, "invalid sender credentials")
+	}
+
+	// only import from enclaves running the same chaincode
+	senderAttestedData, err := e.verifyEnclave(senderCredentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot verify sender")
+	}
+
+	if !bytes.Equal(senderAttestedData.GetEnclaveVk(), exportMessage.GetSenderEnclaveVk()) {
+		return nil, fmt.Errorf("sender_enclave_vk does not match sender credentials")
+	}
+
+	if err := e.csp.VerifyMessage(senderAttestedData.GetEnclaveVk(), signedExportMessage.GetSerializedExportMsgBytes().GetValue(), signedExportMessage.GetSignature()); err != nil {
+		return nil, errors.Wrap(err, "export message signature verification failed")
+	}
+
+	ccKeysBytes, err := e.identity.DecryptMessage(exportMessage.GetCckeysEnc())
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decrypt chaincode keys")
+	}
+
+	ccKeysProto := &protos.CCKeys{}
+	if err := proto.Unmarshal(ccKeysBytes, ccKeysProto); err != nil {
+		return nil, errors.Wrap(err, "invalid chaincode keys")
+	}
+
+	if !bytes.Equal(ccKeysProto.GetChaincodeEk(), exportMessage.GetChaincodeEk()) {
+		return nil, fmt.Errorf("chaincode_ek does not match exported chaincode keys")
+	}
+
+	ccKeys, err := NewChaincodeKeysFromProto(e.csp, ccKeysProto)
+	if err != nil {
+		return nil, err
+	}
+
+	signedRegistrationMessage, err := e.newSignedCCKeyRegistrationMessage(ccKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.seal(e.identity, e.credentials, ccKeys); err != nil {
+		return nil, err
+	}
+
+	e.ccKeys = ccKeys
+
+	return proto.Marshal(signedRegistrationMessage)
+}
+
+// verifyEnclave checks that the credentials belong to an enclave of the same chaincode and returns its attested data.
+// That is, the credentials must contain the same cc_params, and their attestation must be issued by an enclave with the
+// mrenclave of the chaincode (i.e., the chaincode version) using the same attestation type as this enclave.
+func (e *EnclaveStub) verifyEnclave(credentials *protos.Credentials) (*protos.AttestedData, error) {
+	attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+	if err != nil {
+		return nil, err
+	}
+
+	if !proto.Equal(attestedData.GetCcParams(), e.chaincodeParams) {
+		return nil, fmt.Errorf("cc_params do not match")
+	}
+
+	attestationType, err := attestation.AttestationType(e.credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := attestation.Verify(attestationType, credentials, e.chaincodeParams.GetVersion()); err != nil {
+		return nil, errors.Wrap(err, "invalid attestation")
+	}
+
+	return attestedData, nil
+}
+
+func (e *EnclaveStub) newSignedCCKeyRegistrationMessage(ccKeys *ChaincodeKeys) (*protos.SignedCCKeyRegistrationMessage, error) {
+	ccParamsHash, err := utils.GetCCParamsHash(e.chaincodeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	enclaveId := sha256.Sum256(e.identity.GetPublicKey())
+
+	serializedRegistrationMessage, err := anypb.New(&protos.CCKeyRegistrationMessage{
+		CcParamsHash: ccParamsHash,
+		ChaincodeEk:  ccKeys.GetPublicKey(),
+		EnclaveId:    enclaveId[:],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := e.identity.Sign(serializedRegistrationMessage.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	return &protos.SignedCCKeyRegistrationMessage{
+		SerializedCckeyRegMsg: serializedRegistrationMessage,
+		Signature:             sig,
+	}, nil
 }
 
 func (e *EnclaveStub) GetEnclaveId() (string, error) {
@@ -133,6 +401,10 @@ func (e *EnclaveStub) GetEnclaveId() (string, error) {
 func (e *EnclaveStub) ChaincodeInvoke(stub shim.ChaincodeStubInterface, chaincodeRequestMessageBytes []byte) ([]byte, error) {
 	logger.Debug("ChaincodeInvoke")
 
+	if e.ccKeys == nil {
+		return nil, fmt.Errorf("chaincode keys not available")
+	}
+
 	signedProposal, err := stub.GetSignedProposal()
 	if err != nil {
 		return nil, err
@@ -161,12 +433,25 @@ func (e *EnclaveStub) ChaincodeInvoke(stub shim.ChaincodeStubInterface, chaincod
 		return nil, errors.Wrap(err, "cannot decrypt chaincode request")
 	}
 
+	chaincodeRequestMessageHash := sha256.Sum256(chaincodeRequestMessageBytes)
+
+	// in deterministic mode, all encryptions of this invocation are bound to the request
+	csp := e.csp
+	var sep StateEncryptionFunctions = e.ccKeys
+	if e.deterministic {
+		csp = crypto.NewDeterministicCSP(e.csp, chaincodeRequestMessageHash[:])
+		sep = e.ccKeys.withCSP(csp)
+	}
+
 	// create a new instance of a FPC RWSet that we pass to the stub and later return with the response
 	rwset := NewReadWriteSet()
 
+	// the chaincode event (if any) is also returned with the response
+	event := newChaincodeEvent(csp, keyTransportMessage.GetResponseEncryptionKey())
+
 	// Invoke chaincode
 	// we wrap the stub with our FpcStubInterface
-	fpcStub := e.stubProvider(stub, cleartextChaincodeRequest.GetInput(), rwset, e.ccKeys)
+	fpcStub := e.stubProvider(stub, cleartextChaincodeRequest.GetInput(), cleartextChaincodeRequest.GetTransientMap(), rwset, event, sep)
 	ccResponse := e.ccRef.Invoke(fpcStub)
 
 	// marshal chaincode response
@@ -176,22 +461,21 @@ func (e *EnclaveStub) ChaincodeInvoke(stub shim.ChaincodeStubInterface, chaincod
 	}
 
 	//encrypt response
-	encryptedResponse, err := e.csp.EncryptMessage(keyTransportMessage.GetResponseEncryptionKey(), ccResponseBytes)
+	encryptedResponse, err := csp.EncryptMessage(keyTransportMessage.GetResponseEncryptionKey(), ccResponseBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	chaincodeRequestMessageHash := sha256.Sum256(chaincodeRequestMessageBytes)
-
 	response := &protos.ChaincodeResponseMessage{
 		EncryptedResponse:           encryptedResponse,
 		FpcRwSet:                    rwset.ToFPCKVSet(),
 		EnclaveId:                   e.identity.GetEnclaveId(),
 		Proposal:                    signedProposal,
 		ChaincodeRequestMessageHash: chaincodeRequestMessageHash[:],
+		Event:                       event.toFPCEvent(),
 	}
 
-	responseBytes, err := proto.Marshal(response)
+	responseBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(response)
 	if err != nil {
 		return nil, err
 	}
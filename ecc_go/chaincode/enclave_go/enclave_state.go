@@ -12,6 +12,8 @@ import (
 	"strings"
 
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/pkg/errors"
 )
 
 type EnclaveIdentity struct {
@@ -28,21 +30,30 @@ type EnclaveIdentityFunctions interface {
 }
 
 func NewEnclaveIdentity(csp crypto.CSP) (*EnclaveIdentity, error) {
-	var err error
-	e := &EnclaveIdentity{}
-	e.csp = csp
-
 	// create enclave keys
-	e.publicKey, e.privateKey, err = csp.NewECDSAKeys()
+	publicKey, privateKey, err := csp.NewECDSAKeys()
 	if err != nil {
 		return nil, err
 	}
 
-	// calculate enclave id
-	pubHash := sha256.Sum256(e.publicKey)
-	e.enclaveId = strings.ToUpper(hex.EncodeToString(pubHash[:]))
+	return NewEnclaveIdentityFromKeys(csp, publicKey, privateKey)
+}
+
+// NewEnclaveIdentityFromKeys returns the enclave identity for existing enclave keys, e.g., restored from sealed storage
+func NewEnclaveIdentityFromKeys(csp crypto.CSP, publicKey, privateKey []byte) (*EnclaveIdentity, error) {
+	if len(publicKey) == 0 || len(privateKey) == 0 {
+		return nil, errors.New("incomplete enclave keys")
+	}
 
-	return e, nil
+	// calculate enclave id
+	pubHash := sha256.Sum256(publicKey)
+
+	return &EnclaveIdentity{
+		csp:        csp,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		enclaveId:  strings.ToUpper(hex.EncodeToString(pubHash[:])),
+	}, nil
 }
 
 func (e *EnclaveIdentity) Sign(msg []byte) (signature []byte, err error) {
@@ -50,6 +61,11 @@ func (e *EnclaveIdentity) Sign(msg []byte) (signature []byte, err error) {
 	return
 }
 
+// DecryptMessage decrypts a message encrypted for this enclave using crypto.EciesEncryptMessage with the enclave public key
+func (e *EnclaveIdentity) DecryptMessage(ciphertext []byte) (plaintext []byte, err error) {
+	return crypto.EciesDecryptMessage(e.privateKey, ciphertext)
+}
+
 func (e *EnclaveIdentity) GetPublicKey() []byte {
 	return e.publicKey
 }
@@ -96,6 +112,30 @@ func NewChaincodeKeys(csp crypto.CSP) (*ChaincodeKeys, error) {
 	return c, nil
 }
 
+// NewChaincodeKeysFromProto restores chaincode keys as received from another enclave via key export
+func NewChaincodeKeysFromProto(csp crypto.CSP, keys *protos.CCKeys) (*ChaincodeKeys, error) {
+	if len(keys.GetChaincodeEk()) == 0 || len(keys.GetChaincodeDk()) == 0 || len(keys.GetStateKey()) == 0 {
+		return nil, errors.New("incomplete chaincode keys")
+	}
+
+	return &ChaincodeKeys{
+		csp:          csp,
+		ccPrivateKey: keys.GetChaincodeDk(),
+		ccPublicKey:  keys.GetChaincodeEk(),
+		stateKey:     keys.GetStateKey(),
+	}, nil
+}
+
+// ToProto returns the chaincode keys as proto message; note that the result contains secret key material
+// and must only leave the enclave encrypted for another enclave
+func (c *ChaincodeKeys) ToProto() *protos.CCKeys {
+	return &protos.CCKeys{
+		StateKey:    c.stateKey,
+		ChaincodeDk: c.ccPrivateKey,
+		ChaincodeEk: c.ccPublicKey,
+	}
+}
+
 func (c *ChaincodeKeys) GetPublicKey() []byte {
 	return c.ccPublicKey
 }
@@ -104,6 +144,16 @@ func (c *ChaincodeKeys) PkDecryptMessage(ciphertext []byte) (plaintext []byte, e
 	return c.csp.PkDecryptMessage(c.ccPrivateKey, ciphertext)
 }
 
+// withCSP returns a copy of the chaincode keys that uses the given CSP
+func (c *ChaincodeKeys) withCSP(csp crypto.CSP) *ChaincodeKeys {
+	return &ChaincodeKeys{
+		csp:          csp,
+		ccPrivateKey: c.ccPrivateKey,
+		ccPublicKey:  c.ccPublicKey,
+		stateKey:     c.stateKey,
+	}
+}
+
 func (c *ChaincodeKeys) EncryptState(plaintext []byte) (ciphertext []byte, err error) {
 	return c.csp.EncryptMessage(c.stateKey, plaintext)
 }
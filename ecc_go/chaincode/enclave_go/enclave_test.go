@@ -0,0 +1,311 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package enclave_go
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go/attestation"
+	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go/sealing"
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func newTestEnclave(t *testing.T, ccParams *protos.CCParameters) (*EnclaveStub, []byte) {
+	e := NewEnclaveStub(nil)
+	credentials, err := e.Init(utils.MarshalOrPanic(ccParams), utils.MarshalOrPanic(&protos.HostParameters{}), nil)
+	require.NoError(t, err)
+	return e, credentials
+}
+
+// newTestEnclaveWithMrenclave returns an enclave of the chaincode whose (simulated) attestation shows another mrenclave
+// than the chaincode version, e.g., an enclave running a modified chaincode
+func newTestEnclaveWithMrenclave(t *testing.T, ccParams *protos.CCParameters, mrenclave string) (*EnclaveStub, []byte) {
+	t.Setenv(attestation.SimulatedMrenclaveEnvKey, mrenclave)
+	defer os.Unsetenv(attestation.SimulatedMrenclaveEnvKey)
+	return newTestEnclave(t, ccParams)
+}
+
+// checkCCKeyRegistrationMessage checks that the chaincode_ek of the registration message is bound to the credentials
+func checkCCKeyRegistrationMessage(t *testing.T, e *EnclaveStub, credentialsBytes, signedMsgBytes []byte) *protos.CCKeyRegistrationMessage {
+	credentials := &protos.Credentials{}
+	require.NoError(t, proto.Unmarshal(credentialsBytes, credentials))
+	attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+	require.NoError(t, err)
+
+	signedMsg, err := utils.UnmarshalSignedCCKeyRegistrationMessage(signedMsgBytes)
+	require.NoError(t, err)
+	msg, err := crypto.VerifyCCKeyRegistrationMessage(attestedData, signedMsg)
+	require.NoError(t, err)
+	assert.Equal(t, e.ccKeys.GetPublicKey(), msg.GetChaincodeEk())
+
+	return msg
+}
+
+func TestKeyDistribution(t *testing.T) {
+	ccParams := &protos.CCParameters{
+		ChaincodeId: "someChaincode",
+		Version:     "someMrEnclave",
+		Sequence:    1,
+		ChannelId:   "someChannel",
+	}
+
+	sender, senderCredentials := newTestEnclave(t, ccParams)
+	receiver, receiverCredentials := newTestEnclave(t, ccParams)
+
+	// export fails without keys
+	_, err := sender.ExportCCKeys(receiverCredentials)
+	assert.EqualError(t, err, "chaincode keys not available")
+
+	// generate keys
+	signedRegMsg, err := sender.GenerateCCKeys()
+	require.NoError(t, err)
+	checkCCKeyRegistrationMessage(t, sender, senderCredentials, signedRegMsg)
+
+	// keys can only be generated once
+	_, err = sender.GenerateCCKeys()
+	assert.EqualError(t, err, "chaincode keys already exist")
+
+	// export fails for enclave of other chaincode
+	_, otherCredentials := newTestEnclave(t, &protos.CCParameters{ChaincodeId: "otherChaincode"})
+	_, err = sender.ExportCCKeys(otherCredentials)
+	assert.EqualError(t, err, "cannot verify receiver: cc_params do not match")
+
+	// export fails for enclave with another mrenclave
+	_, rogueCredentials := newTestEnclaveWithMrenclave(t, ccParams, "otherMrEnclave")
+	_, err = sender.ExportCCKeys(rogueCredentials)
+	assert.ErrorContains(t, err, "cannot verify receiver: invalid attestation: MRENCLAVE_MISMATCH")
+
+	// export fails for enclave that is not attested, e.g., credentials with the attestation of another enclave
+	forgedCredentials := &protos.Credentials{}
+	require.NoError(t, proto.Unmarshal(receiverCredentials, forgedCredentials))
+	senderCredentialsProto := &protos.Credentials{}
+	require.NoError(t, proto.Unmarshal(senderCredentials, senderCredentialsProto))
+	forgedCredentials.Attestation = senderCredentialsProto.GetAttestation()
+	_, err = sender.ExportCCKeys(utils.MarshalOrPanic(forgedCredentials))
+	assert.ErrorContains(t, err, "cannot verify receiver: invalid attestation: STATEMENT_MISMATCH")
+
+	// export keys
+	signedExportMsg, err := sender.ExportCCKeys(receiverCredentials)
+	require.NoError(t, err)
+
+	// import fails at enclave that is not the receiver
+	other, _ := newTestEnclave(t, ccParams)
+	_, err = other.ImportCCKeys(signedExportMsg)
+	assert.EqualError(t, err, "export message is not addressed to this enclave")
+
+	// import fails with invalid signature
+	tamperedMsg := &protos.SignedExportMessage{}
+	require.NoError(t, proto.Unmarshal(signedExportMsg, tamperedMsg))
+	tamperedMsg.Signature = []byte("invalid signature")
+	_, err = receiver.ImportCCKeys(utils.MarshalOrPanic(tamperedMsg))
+	assert.ErrorContains(t, err, "export message signature verification failed")
+
+	// import fails without sender credentials or with credentials of another enclave
+	tamperExportMsg := func(tamper func(msg *protos.ExportMessage)) []byte {
+		signedMsg := &protos.SignedExportMessage{}
+		require.NoError(t, proto.Unmarshal(signedExportMsg, signedMsg))
+		msg, err := utils.UnmarshalExportMessage(signedMsg.GetSerializedExportMsgBytes())
+		require.NoError(t, err)
+		tamper(msg)
+		signedMsg.SerializedExportMsgBytes, err = anypb.New(msg)
+		require.NoError(t, err)
+		return utils.MarshalOrPanic(signedMsg)
+	}
+	_, err = receiver.ImportCCKeys(tamperExportMsg(func(msg *protos.ExportMessage) { msg.SenderCredentials = nil }))
+	assert.EqualError(t, err, "sender credentials missing")
+
+	_, err = receiver.ImportCCKeys(tamperExportMsg(func(msg *protos.ExportMessage) { msg.SenderCredentials = receiverCredentials }))
+	assert.EqualError(t, err, "sender_enclave_vk does not match sender credentials")
+
+	// import fails from enclave with another mrenclave
+	rogue, _ := newTestEnclaveWithMrenclave(t, ccParams, "otherMrEnclave")
+	_, err = rogue.GenerateCCKeys()
+	require.NoError(t, err)
+	rogueExportMsg, err := rogue.ExportCCKeys(receiverCredentials)
+	require.NoError(t, err)
+	_, err = receiver.ImportCCKeys(rogueExportMsg)
+	assert.ErrorContains(t, err, "cannot verify sender: invalid attestation: MRENCLAVE_MISMATCH")
+
+	// import keys
+	signedRegMsg, err = receiver.ImportCCKeys(signedExportMsg)
+	require.NoError(t, err)
+	msg := checkCCKeyRegistrationMessage(t, receiver, receiverCredentials, signedRegMsg)
+	assert.Equal(t, sender.ccKeys.GetPublicKey(), msg.GetChaincodeEk())
+
+	// receiver can decrypt state written by sender
+	ciphertext, err := sender.ccKeys.EncryptState([]byte("some state"))
+	require.NoError(t, err)
+	plaintext, err := receiver.ccKeys.DecryptState(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some state"), plaintext)
+
+	// keys can only be imported once
+	_, err = receiver.ImportCCKeys(signedExportMsg)
+	assert.EqualError(t, err, "chaincode keys already exist")
+}
+
+func TestAttestedCcEncryptionKeyWithKeyDistribution(t *testing.T) {
+	ccParams := &protos.CCParameters{
+		ChaincodeId: "someChaincode",
+		Version:     "someMrEnclave",
+		Sequence:    1,
+		ChannelId:   "someChannel",
+	}
+
+	// the enclaves are provisioned via the key distribution protocol, as registered at ERCC
+	sender, senderCredentials := newTestEnclave(t, ccParams)
+	receiver, receiverCredentials := newTestEnclave(t, ccParams)
+	senderRegMsg, err := sender.GenerateCCKeys()
+	require.NoError(t, err)
+	signedExportMsg, err := sender.ExportCCKeys(receiverCredentials)
+	require.NoError(t, err)
+	receiverRegMsg, err := receiver.ImportCCKeys(signedExportMsg)
+	require.NoError(t, err)
+
+	credentialsList := []*protos.Credentials{}
+	registrations := map[string][]byte{}
+	register := func(e *EnclaveStub, credentialsBytes, signedRegMsg []byte) {
+		credentials := &protos.Credentials{}
+		require.NoError(t, proto.Unmarshal(credentialsBytes, credentials))
+		credentialsList = append(credentialsList, credentials)
+		enclaveId, err := e.GetEnclaveId()
+		require.NoError(t, err)
+		registrations[enclaveId] = signedRegMsg
+	}
+	register(sender, senderCredentials, senderRegMsg)
+	register(receiver, receiverCredentials, receiverRegMsg)
+
+	provider := &crypto.EncryptionProviderImpl{
+		CSP: crypto.GetDefaultCSP(),
+		GetCcCredentials: func() ([]*protos.Credentials, error) {
+			return credentialsList, nil
+		},
+		VerifyCredentials: func(credentials *protos.Credentials) error {
+			return nil
+		},
+		GetCcKeyRegistration: func(enclaveId string) (*protos.SignedCCKeyRegistrationMessage, error) {
+			if registrations[enclaveId] == nil {
+				return nil, nil
+			}
+			return utils.UnmarshalSignedCCKeyRegistrationMessage(registrations[enclaveId])
+		},
+		KeyPins: crypto.NewKeyPins(),
+	}
+
+	// the request encrypted with the key bound to the credentials can be decrypted by the enclaves
+	conceal := func() (*protos.ChaincodeRequestMessage, error) {
+		ctx, err := provider.NewEncryptionContext()
+		if err != nil {
+			return nil, err
+		}
+		request, err := ctx.Conceal("someFunction", []string{"someArg"})
+		require.NoError(t, err)
+		requestBytes, err := base64.StdEncoding.DecodeString(request)
+		require.NoError(t, err)
+		requestMsg := &protos.ChaincodeRequestMessage{}
+		require.NoError(t, proto.Unmarshal(requestBytes, requestMsg))
+		return requestMsg, nil
+	}
+	requestMsg, err := conceal()
+	require.NoError(t, err)
+	for _, e := range []*EnclaveStub{sender, receiver} {
+		keyTransportMsg, err := e.extractKeyTransportMessage(requestMsg)
+		require.NoError(t, err)
+		request, err := e.extractCleartextChaincodeRequest(requestMsg, keyTransportMsg)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("someFunction"), []byte("someArg")}, request.GetInput().GetArgs())
+	}
+
+	// an enclave that is not provisioned yet is ignored
+	other, otherCredentials := newTestEnclave(t, ccParams)
+	register(other, otherCredentials, nil)
+	_, err = conceal()
+	assert.NoError(t, err)
+
+	// the registration message of another enclave is rejected
+	otherId, err := other.GetEnclaveId()
+	require.NoError(t, err)
+	registrations[otherId] = senderRegMsg
+	_, err = conceal()
+	assert.ErrorContains(t, err, "invalid cc key registration of enclave "+otherId)
+
+	// an enclave that generated other keys for the same chaincode is detected
+	otherRegMsg, err := other.GenerateCCKeys()
+	require.NoError(t, err)
+	registrations[otherId] = otherRegMsg
+	_, err = conceal()
+	assert.EqualError(t, err, "enclaves attest different chaincode encryption keys")
+}
+
+func TestSealing(t *testing.T) {
+	ccParams := &protos.CCParameters{
+		ChaincodeId: "someChaincode",
+		Version:     "someMrEnclave",
+		Sequence:    1,
+		ChannelId:   "someChannel",
+	}
+	sealer := sealing.NewFileSealer(filepath.Join(t.TempDir(), "sealed"))
+
+	// nothing to restore
+	e := NewEnclaveStub(nil)
+	e.EnableSealing(sealer)
+	assert.Nil(t, e.identity)
+
+	credentials, err := e.Init(utils.MarshalOrPanic(ccParams), utils.MarshalOrPanic(&protos.HostParameters{}), nil)
+	require.NoError(t, err)
+	_, err = e.GenerateCCKeys()
+	require.NoError(t, err)
+
+	// restart restores identity and chaincode keys
+	restarted := NewEnclaveStub(nil)
+	restarted.EnableSealing(sealer)
+	require.NotNil(t, restarted.identity)
+	assert.Equal(t, e.identity.GetEnclaveId(), restarted.identity.GetEnclaveId())
+	require.NotNil(t, restarted.ccKeys)
+	assert.Equal(t, e.ccKeys.GetPublicKey(), restarted.ccKeys.GetPublicKey())
+
+	ciphertext, err := e.ccKeys.EncryptState([]byte("some state"))
+	require.NoError(t, err)
+	plaintext, err := restarted.ccKeys.DecryptState(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some state"), plaintext)
+
+	// re-init with the same parameters reuses the existing credentials and keys
+	restartedCredentials, err := restarted.Init(utils.MarshalOrPanic(ccParams), utils.MarshalOrPanic(&protos.HostParameters{}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, credentials, restartedCredentials)
+	assert.NotNil(t, restarted.ccKeys)
+
+	// re-init with other parameters creates a new enclave and replaces the sealed state
+	otherCCParams := proto.Clone(ccParams).(*protos.CCParameters)
+	otherCCParams.Sequence = 2
+	otherCredentials, err := restarted.Init(utils.MarshalOrPanic(otherCCParams), utils.MarshalOrPanic(&protos.HostParameters{}), nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, credentials, otherCredentials)
+	assert.Nil(t, restarted.ccKeys)
+
+	restarted = NewEnclaveStub(nil)
+	restarted.EnableSealing(sealer)
+	require.NotNil(t, restarted.identity)
+	assert.NotEqual(t, e.identity.GetEnclaveId(), restarted.identity.GetEnclaveId())
+	assert.Nil(t, restarted.ccKeys)
+
+	// invalid sealed state is ignored
+	require.NoError(t, sealer.Seal([]byte("invalid state")))
+	restarted = NewEnclaveStub(nil)
+	restarted.EnableSealing(sealer)
+	assert.Nil(t, restarted.identity)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package enclave_go
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+)
+
+// chaincodeEvent records the encrypted chaincode event of an invocation.
+// As in Fabric, a transaction has at most one event and setting a new event overwrites the previous one.
+type chaincodeEvent struct {
+	mu                    sync.Mutex
+	csp                   crypto.CSP
+	responseEncryptionKey []byte
+	event                 *protos.FPCEvent
+}
+
+func newChaincodeEvent(csp crypto.CSP, responseEncryptionKey []byte) *chaincodeEvent {
+	return &chaincodeEvent{
+		csp:                   csp,
+		responseEncryptionKey: responseEncryptionKey,
+	}
+}
+
+// set encrypts the payload with the given event key or, if no event key id is given, with the response encryption key
+func (e *chaincodeEvent) set(name string, payload []byte, eventKeyId string, eventKey []byte) error {
+	if name == "" {
+		return fmt.Errorf("event name can not be empty string")
+	}
+
+	key := e.responseEncryptionKey
+	if eventKeyId != "" {
+		key = eventKey
+	}
+
+	encryptedPayload, err := e.csp.EncryptMessage(key, payload)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt event payload: %s", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.event = &protos.FPCEvent{
+		EventName:        name,
+		EncryptedPayload: encryptedPayload,
+		EventKeyId:       eventKeyId,
+	}
+
+	return nil
+}
+
+func (e *chaincodeEvent) toFPCEvent() *protos.FPCEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.event
+}
@@ -7,9 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package enclave_go
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
 )
 
@@ -17,6 +19,9 @@ type ReadWriteSet interface {
 	AddRead(key string, hash []byte)
 	AddWrite(key string, value []byte)
 	AddDelete(key string)
+	AddRangeQuery(rangeQuery *rangeQuery)
+	GetWrite(key string) *kvrwset.KVWrite
+	GetWrites(filter func(key string) bool) []*kvrwset.KVWrite
 	ToFPCKVSet() *protos.FPCKVSet
 }
 
@@ -29,10 +34,86 @@ type write struct {
 	kvwrite *kvrwset.KVWrite
 }
 
+// rangeQuery records the results of a range query while they are consumed by the chaincode
+type rangeQuery struct {
+	mu        sync.Mutex
+	startKey  string
+	endKey    string
+	lastKey   string
+	numReads  int
+	exhausted bool
+	pageSize  int32
+	hasher    *utils.RangeQueryHasher
+}
+
+func newRangeQuery(startKey, endKey string) *rangeQuery {
+	return &rangeQuery{
+		startKey: startKey,
+		endKey:   endKey,
+		hasher:   utils.NewRangeQueryHasher(),
+	}
+}
+
+// newPaginatedRangeQuery returns a range query for a single page, where the start key is given by the bookmark (if set)
+func newPaginatedRangeQuery(startKey, endKey string, pageSize int32, bookmark string) *rangeQuery {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	rq := newRangeQuery(startKey, endKey)
+	rq.pageSize = pageSize
+	return rq
+}
+
+func (rq *rangeQuery) addRead(key string, hash []byte) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	rq.hasher.Add(key, hash)
+	rq.lastKey = key
+	rq.numReads++
+}
+
+func (rq *rangeQuery) setExhausted() {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	rq.exhausted = true
+}
+
+// toRangeQueryInfo returns the RangeQueryInfo of this range query or nil if no results were consumed
+func (rq *rangeQuery) toRangeQueryInfo() *kvrwset.RangeQueryInfo {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	// a full page does not exhaust the range as the remaining keys belong to the next page
+	if rq.pageSize > 0 && rq.numReads >= int(rq.pageSize) {
+		rq.exhausted = false
+	}
+
+	endKey := rq.endKey
+	if !rq.exhausted {
+		if rq.numReads == 0 {
+			return nil
+		}
+		// as in Fabric, if the iterator is not exhausted, the range only covers the keys up to (and including) the last key read
+		endKey = rq.lastKey
+	}
+
+	return &kvrwset.RangeQueryInfo{
+		StartKey:     rq.startKey,
+		EndKey:       endKey,
+		ItrExhausted: rq.exhausted,
+		ReadsInfo: &kvrwset.RangeQueryInfo_ReadsMerkleHashes{
+			ReadsMerkleHashes: &kvrwset.QueryReadsMerkleSummary{
+				MaxLevelHashes: [][]byte{rq.hasher.Sum()},
+			},
+		},
+	}
+}
+
 type readWriteSet struct {
-	mu     sync.Mutex
-	reads  map[string]read
-	writes map[string]write
+	mu           sync.Mutex
+	reads        map[string]read
+	writes       map[string]write
+	rangeQueries []*rangeQuery
 }
 
 func NewReadWriteSet() *readWriteSet {
@@ -77,6 +158,39 @@ func (rwset *readWriteSet) AddDelete(key string) {
 	}
 }
 
+func (rwset *readWriteSet) AddRangeQuery(rangeQuery *rangeQuery) {
+	rwset.mu.Lock()
+	defer rwset.mu.Unlock()
+	rwset.rangeQueries = append(rwset.rangeQueries, rangeQuery)
+}
+
+// GetWrite returns the pending write (or delete) of the given key or nil if the key has not been written
+func (rwset *readWriteSet) GetWrite(key string) *kvrwset.KVWrite {
+	rwset.mu.Lock()
+	defer rwset.mu.Unlock()
+	w, ok := rwset.writes[key]
+	if !ok {
+		return nil
+	}
+	return w.kvwrite
+}
+
+// GetWrites returns the pending writes (and deletes) of all keys matching the filter, sorted by key
+func (rwset *readWriteSet) GetWrites(filter func(key string) bool) []*kvrwset.KVWrite {
+	rwset.mu.Lock()
+	defer rwset.mu.Unlock()
+	var writes []*kvrwset.KVWrite
+	for key, w := range rwset.writes {
+		if filter(key) {
+			writes = append(writes, w.kvwrite)
+		}
+	}
+	sort.Slice(writes, func(i, j int) bool {
+		return writes[i].Key < writes[j].Key
+	})
+	return writes
+}
+
 func (rwset *readWriteSet) ToFPCKVSet() *protos.FPCKVSet {
 	rwset.mu.Lock()
 	defer rwset.mu.Unlock()
@@ -88,16 +202,33 @@ func (rwset *readWriteSet) ToFPCKVSet() *protos.FPCKVSet {
 		ReadValueHashes: [][]byte{},
 	}
 
-	// fill with reads
-	for _, read := range rwset.reads {
+	// fill with reads; note that reads and writes are sorted by key so that the FPCKVSet is canonical
+	reads := make([]read, 0, len(rwset.reads))
+	for _, r := range rwset.reads {
+		reads = append(reads, r)
+	}
+	sort.Slice(reads, func(i, j int) bool {
+		return reads[i].kvread.Key < reads[j].kvread.Key
+	})
+	for _, read := range reads {
 		fpcKVSet.RwSet.Reads = append(fpcKVSet.RwSet.Reads, read.kvread)
 		fpcKVSet.ReadValueHashes = append(fpcKVSet.ReadValueHashes, read.hash)
 	}
 
+	// fill with range queries
+	for _, rangeQuery := range rwset.rangeQueries {
+		if rqi := rangeQuery.toRangeQueryInfo(); rqi != nil {
+			fpcKVSet.RwSet.RangeQueriesInfo = append(fpcKVSet.RwSet.RangeQueriesInfo, rqi)
+		}
+	}
+
 	// fill with writes
 	for _, write := range rwset.writes {
 		fpcKVSet.RwSet.Writes = append(fpcKVSet.RwSet.Writes, write.kvwrite)
 	}
+	sort.Slice(fpcKVSet.RwSet.Writes, func(i, j int) bool {
+		return fpcKVSet.RwSet.Writes[i].Key < fpcKVSet.RwSet.Writes[j].Key
+	})
 
 	return fpcKVSet
 }
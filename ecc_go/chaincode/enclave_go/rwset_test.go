@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package enclave_go
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestToFPCKVSetIsSorted(t *testing.T) {
+	rwset := NewReadWriteSet()
+	for _, k := range []string{"keyC", "keyA", "keyB"} {
+		rwset.AddRead(k, []byte("hash"+k))
+		rwset.AddWrite(k, []byte("value"+k))
+	}
+	rwset.AddDelete("key0")
+
+	fpcKVSet := rwset.ToFPCKVSet()
+	var reads, hashes, writes []string
+	for i, r := range fpcKVSet.RwSet.Reads {
+		reads = append(reads, r.Key)
+		hashes = append(hashes, string(fpcKVSet.ReadValueHashes[i]))
+	}
+	for _, w := range fpcKVSet.RwSet.Writes {
+		writes = append(writes, w.Key)
+	}
+	assert.Equal(t, []string{"keyA", "keyB", "keyC"}, reads)
+	assert.Equal(t, []string{"hashkeyA", "hashkeyB", "hashkeyC"}, hashes)
+	assert.Equal(t, []string{"key0", "keyA", "keyB", "keyC"}, writes)
+}
+
+func TestDeterministicWrites(t *testing.T) {
+	ccKeys, err := NewChaincodeKeys(NewEnclaveStub(nil).csp)
+	require.NoError(t, err)
+
+	execute := func(sep StateEncryptionFunctions) []byte {
+		rwset := NewReadWriteSet()
+		stub := NewFpcStubInterface(nil, &pb.ChaincodeInput{}, nil, rwset, nil, sep)
+		for _, k := range []string{"keyC", "keyA", "keyB"} {
+			require.NoError(t, stub.PutState(k, []byte("value"+k)))
+		}
+		fpcKVSetBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(rwset.ToFPCKVSet())
+		require.NoError(t, err)
+		return fpcKVSetBytes
+	}
+
+	// by default, state encryption is randomized
+	assert.NotEqual(t, execute(ccKeys), execute(ccKeys))
+
+	// deterministic state encryption results in the same rwset for the same request
+	context := []byte("some request hash")
+	assert.Equal(t,
+		execute(ccKeys.withCSP(crypto.NewDeterministicCSP(ccKeys.csp, context))),
+		execute(ccKeys.withCSP(crypto.NewDeterministicCSP(ccKeys.csp, context))))
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package enclave_go
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go/sealing"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// EnableSealing persists the enclave identity, credentials, and chaincode keys using the given sealer and
+// restores a previously sealed enclave state. A restored enclave can serve invocations right away, and
+// re-initializing it for the same chaincode and host returns the existing credentials.
+// If the sealed state cannot be restored, the enclave starts without state and must be initialized as usual.
+func (e *EnclaveStub) EnableSealing(sealer sealing.Sealer) {
+	e.sealer = sealer
+	if err := e.restore(); err != nil {
+		logger.Warningf("Cannot restore sealed enclave state: %s", err)
+	}
+}
+
+func (e *EnclaveStub) restore() error {
+	data, err := e.sealer.Unseal()
+	if errors.Is(err, sealing.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state := &protos.SealedEnclaveState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return errors.Wrap(err, "invalid sealed enclave state")
+	}
+
+	identity, err := NewEnclaveIdentityFromKeys(e.csp, state.GetEnclaveVk(), state.GetEnclaveSk())
+	if err != nil {
+		return err
+	}
+
+	credentials := &protos.Credentials{}
+	if err := proto.Unmarshal(state.GetCredentials(), credentials); err != nil {
+		return errors.Wrap(err, "invalid sealed credentials")
+	}
+
+	attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(attestedData.GetEnclaveVk(), identity.GetPublicKey()) {
+		return fmt.Errorf("sealed credentials do not match the enclave identity")
+	}
+
+	var ccKeys *ChaincodeKeys
+	if state.GetCcKeys() != nil {
+		ccKeys, err = NewChaincodeKeysFromProto(e.csp, state.GetCcKeys())
+		if err != nil {
+			return err
+		}
+	}
+
+	e.identity = identity
+	e.credentials = state.GetCredentials()
+	e.chaincodeParams = attestedData.GetCcParams()
+	e.hostParams = attestedData.GetHostParams()
+	e.ccKeys = ccKeys
+
+	logger.Infof("Restored sealed enclave %s", identity.GetEnclaveId())
+
+	return nil
+}
+
+// seal persists the given enclave state if sealing is enabled
+func (e *EnclaveStub) seal(identity *EnclaveIdentity, credentials []byte, ccKeys *ChaincodeKeys) error {
+	if e.sealer == nil {
+		return nil
+	}
+
+	state := &protos.SealedEnclaveState{
+		EnclaveSk:   identity.privateKey,
+		EnclaveVk:   identity.publicKey,
+		Credentials: credentials,
+	}
+	if ccKeys != nil {
+		state.CcKeys = ccKeys.ToProto()
+	}
+
+	data, err := proto.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := e.sealer.Seal(data); err != nil {
+		return errors.Wrap(err, "cannot seal enclave state")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+//go:build ego
+// +build ego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sealing
+
+import (
+	"github.com/edgelesssys/ego/ecrypto"
+	"github.com/pkg/errors"
+)
+
+// EgoSealer seals the data with the SGX seal key of the enclave (i.e., bound to MRENCLAVE) using EGo
+// and stores the sealed data in a file on the host.
+// Note that the sealed data cannot be unsealed by a different enclave binary, e.g., after a chaincode upgrade.
+type EgoSealer struct {
+	path string
+}
+
+func NewEgoSealer(path string) *EgoSealer {
+	return &EgoSealer{path: path}
+}
+
+func (s *EgoSealer) Seal(data []byte) error {
+	sealed, err := ecrypto.SealWithUniqueKey(data, nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot seal data")
+	}
+	return writeFile(s.path, sealed)
+}
+
+func (s *EgoSealer) Unseal() ([]byte, error) {
+	sealed, err := readFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ecrypto.Unseal(sealed, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot unseal data")
+	}
+	return data, nil
+}
@@ -0,0 +1,25 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sealing
+
+// FileSealer stores the data in a file without encryption.
+// It provides no confidentiality and must only be used for testing and in simulation mode.
+type FileSealer struct {
+	path string
+}
+
+func NewFileSealer(path string) *FileSealer {
+	return &FileSealer{path: path}
+}
+
+func (s *FileSealer) Seal(data []byte) error {
+	return writeFile(s.path, data)
+}
+
+func (s *FileSealer) Unseal() ([]byte, error) {
+	return readFile(s.path)
+}
@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sealing
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSealer(t *testing.T) {
+	s := NewFileSealer(filepath.Join(t.TempDir(), "state", "sealed"))
+
+	data, err := s.Unseal()
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, data)
+
+	assert.NoError(t, s.Seal([]byte("some data")))
+	data, err = s.Unseal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("some data"), data)
+
+	// sealing replaces the previous data
+	assert.NoError(t, s.Seal([]byte("other data")))
+	data, err = s.Unseal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("other data"), data)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package sealing persists enclave secrets across enclave restarts.
+package sealing
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by Unseal if no data has been sealed yet
+var ErrNotFound = errors.New("no sealed data found")
+
+// Sealer persists data such that it can only be recovered by the enclave that sealed it
+type Sealer interface {
+	// Seal persists the given data and replaces any previously sealed data
+	Seal(data []byte) error
+	// Unseal returns the sealed data or ErrNotFound if no data has been sealed yet
+	Unseal() ([]byte, error)
+}
+
+// writeFile atomically replaces the content of the file at path
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
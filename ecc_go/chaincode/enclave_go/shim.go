@@ -9,6 +9,8 @@ package enclave_go
 
 import (
 	"fmt"
+	"strings"
+
 	//lint:ignore SA1019 the package is needed to unmarshall the header
 	protoV1 "github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
@@ -21,18 +23,24 @@ import (
 )
 
 type FpcStubInterface struct {
-	stub  shim.ChaincodeStubInterface
-	input *pb.ChaincodeInput
-	rwset ReadWriteSet
-	sep   StateEncryptionFunctions
+	stub      shim.ChaincodeStubInterface
+	input     *pb.ChaincodeInput
+	transient map[string][]byte
+	rwset     ReadWriteSet
+	event     *chaincodeEvent
+	sep       StateEncryptionFunctions
+	// readYourWrites lets reads observe the pending writes and deletes of the current invocation
+	readYourWrites bool
 }
 
-func NewFpcStubInterface(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, rwset *readWriteSet, sep StateEncryptionFunctions) *FpcStubInterface {
+func NewFpcStubInterface(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, transient map[string][]byte, rwset *readWriteSet, event *chaincodeEvent, sep StateEncryptionFunctions) *FpcStubInterface {
 	return &FpcStubInterface{
-		stub:  stub,
-		input: input,
-		sep:   sep,
-		rwset: rwset,
+		stub:      stub,
+		input:     input,
+		transient: transient,
+		sep:       sep,
+		rwset:     rwset,
+		event:     event,
 	}
 }
 
@@ -97,6 +105,16 @@ func (f *FpcStubInterface) GetState(key string) ([]byte, error) {
 }
 
 func (f *FpcStubInterface) GetPublicState(key string) ([]byte, error) {
+	if f.readYourWrites {
+		// note that reading a pending write does not depend on the committed state and is thus not recorded in the rwset
+		if w := f.rwset.GetWrite(key); w != nil {
+			if w.IsDelete {
+				return nil, nil
+			}
+			return w.Value, nil
+		}
+	}
+
 	value, err := f.stub.GetState(key)
 	if err != nil {
 		return nil, err
@@ -140,11 +158,31 @@ func (f *FpcStubInterface) GetStateValidationParameter(key string) ([]byte, erro
 }
 
 func (f *FpcStubInterface) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
-	panic("not implemented") // TODO: Implement
+	iterator, err := f.stub.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// note that the range query is recorded as RangeQueryInfo so that the validator can replay the range
+	rangeQuery := newRangeQuery(startKey, endKey)
+	f.rwset.AddRangeQuery(rangeQuery)
+
+	return f.mergePendingWrites(newRangeQueryIterator(iterator, rangeQuery, f.sep.DecryptState), func(key string) bool {
+		return inRange(key, startKey, endKey)
+	}, f.sep.DecryptState), nil
 }
 
 func (f *FpcStubInterface) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
-	panic("not implemented") // TODO: Implement
+	iterator, metadata, err := f.stub.GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// note that the page is recorded as RangeQueryInfo so that the validator can replay the range covered by this page
+	rangeQuery := newPaginatedRangeQuery(startKey, endKey, pageSize, bookmark)
+	f.rwset.AddRangeQuery(rangeQuery)
+
+	return newRangeQueryIterator(iterator, rangeQuery, f.sep.DecryptState), metadata, nil
 }
 
 func (f *FpcStubInterface) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
@@ -153,7 +191,7 @@ func (f *FpcStubInterface) GetStateByPartialCompositeKey(objectType string, keys
 		return nil, err
 	}
 
-	return newFpcIterator(iterator, f.rwset.AddRead, f.sep.DecryptState), nil
+	return f.mergePendingCompositeKeyWrites(newFpcIterator(iterator, f.rwset.AddRead, f.sep.DecryptState), objectType, keys, f.sep.DecryptState)
 }
 
 func (f *FpcStubInterface) GetPublicStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
@@ -163,11 +201,39 @@ func (f *FpcStubInterface) GetPublicStateByPartialCompositeKey(objectType string
 	}
 
 	// note that we do not pass the state decryption function here
-	return newFpcIterator(iterator, f.rwset.AddRead, nil), nil
+	return f.mergePendingCompositeKeyWrites(newFpcIterator(iterator, f.rwset.AddRead, nil), objectType, keys, nil)
 }
 
 func (f *FpcStubInterface) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
-	panic("not implemented") // TODO: Implement
+	iterator, metadata, err := f.stub.GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// as with GetStateByPartialCompositeKey, the reads of this page are recorded individually
+	return newFpcIterator(iterator, f.rwset.AddRead, f.sep.DecryptState), metadata, nil
+}
+
+// mergePendingWrites merges the pending writes matching the filter into the query results if read-your-own-writes is enabled.
+// Note that paginated queries always return the committed state only, as merging would break the page boundaries.
+func (f *FpcStubInterface) mergePendingWrites(iterator shim.StateQueryIteratorInterface, filter func(key string) bool, decryptFunction func(ciphertext []byte) (plaintext []byte, err error)) shim.StateQueryIteratorInterface {
+	if !f.readYourWrites {
+		return iterator
+	}
+	return newMergeIterator(iterator, f.rwset.GetWrites(filter), decryptFunction)
+}
+
+func (f *FpcStubInterface) mergePendingCompositeKeyWrites(iterator shim.StateQueryIteratorInterface, objectType string, keys []string, decryptFunction func(ciphertext []byte) (plaintext []byte, err error)) (shim.StateQueryIteratorInterface, error) {
+	if !f.readYourWrites {
+		return iterator, nil
+	}
+	prefix, err := f.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return f.mergePendingWrites(iterator, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}, decryptFunction), nil
 }
 
 func (f *FpcStubInterface) CreateCompositeKey(objectType string, attributes []string) (string, error) {
@@ -238,8 +304,10 @@ func (f *FpcStubInterface) GetCreator() ([]byte, error) {
 	return f.stub.GetCreator()
 }
 
+// GetTransient returns the transient data passed by the client as part of the encrypted chaincode request.
+// Note that GetTransient does not return the transient map of the __invoke proposal.
 func (f *FpcStubInterface) GetTransient() (map[string][]byte, error) {
-	return nil, fmt.Errorf("function not yet supported")
+	return f.transient, nil
 }
 
 func (f *FpcStubInterface) GetBinding() ([]byte, error) {
@@ -271,6 +339,23 @@ func (f *FpcStubInterface) GetTxTimestamp() (*timestamp.Timestamp, error) {
 	return chdr.GetTimestamp(), nil
 }
 
+// SetEvent sets a chaincode event whose payload is encrypted with the response encryption key of the invoking client.
+// That is, only the invoking client can decrypt the event; see SetEventWithKey for events addressed to other listeners.
 func (f *FpcStubInterface) SetEvent(name string, payload []byte) error {
-	return fmt.Errorf("function not yet supported")
+	if f.event == nil {
+		return fmt.Errorf("function not yet supported")
+	}
+	return f.event.set(name, payload, "", nil)
+}
+
+// SetEventWithKey sets a chaincode event whose payload is encrypted with a chaincode-defined (symmetric) event key.
+// The event key id is attached to the event in cleartext so that listeners, who obtained the event key from the chaincode, can select the key to decrypt the event.
+func (f *FpcStubInterface) SetEventWithKey(name string, payload []byte, eventKeyId string, eventKey []byte) error {
+	if f.event == nil {
+		return fmt.Errorf("function not yet supported")
+	}
+	if eventKeyId == "" {
+		return fmt.Errorf("event key id can not be empty string")
+	}
+	return f.event.set(name, payload, eventKeyId, eventKey)
 }
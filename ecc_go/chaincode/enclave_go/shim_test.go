@@ -0,0 +1,402 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package enclave_go
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-private-chaincode/internal/endorsement"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFpcStub(t *testing.T) (*FpcStubInterface, *readWriteSet, *shimtest.MockStub, *ChaincodeKeys) {
+	state := shimtest.NewMockStub("someChaincode", nil)
+	state.MockTransactionStart("someTxId")
+
+	ccKeys, err := NewChaincodeKeys(NewEnclaveStub(nil).csp)
+	require.NoError(t, err)
+
+	for _, k := range []string{"keyA", "keyB", "keyD"} {
+		encValue, err := ccKeys.EncryptState([]byte("value" + k))
+		require.NoError(t, err)
+		require.NoError(t, state.PutState(k, encValue))
+	}
+
+	rwset := NewReadWriteSet()
+	return NewFpcStubInterface(state, &pb.ChaincodeInput{}, nil, rwset, nil, ccKeys), rwset, state, ccKeys
+}
+
+func TestGetStateByRange(t *testing.T) {
+	validator := endorsement.NewValidator()
+
+	// iterate the full range
+	stub, rwset, state, ccKeys := newTestFpcStub(t)
+	iter, err := stub.GetStateByRange("keyA", "keyC")
+	require.NoError(t, err)
+
+	var keys []string
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("value"+kv.Key), kv.Value)
+		keys = append(keys, kv.Key)
+	}
+	require.NoError(t, iter.Close())
+	assert.Equal(t, []string{"keyA", "keyB"}, keys)
+
+	fpcKVSet := rwset.ToFPCKVSet()
+	require.Len(t, fpcKVSet.RwSet.RangeQueriesInfo, 1)
+	rqi := fpcKVSet.RwSet.RangeQueriesInfo[0]
+	assert.Equal(t, "keyA", rqi.StartKey)
+	assert.Equal(t, "keyC", rqi.EndKey)
+	assert.True(t, rqi.ItrExhausted)
+	assert.Empty(t, fpcKVSet.RwSet.Reads)
+
+	assert.NoError(t, validator.ReplayReadWrites(state, fpcKVSet))
+
+	// phantom key in range
+	encValue, err := ccKeys.EncryptState([]byte("valuekeyAA"))
+	require.NoError(t, err)
+	require.NoError(t, state.PutState("keyAA", encValue))
+	assert.EqualError(t, validator.ReplayReadWrites(state, fpcKVSet), "range query hash mismatch for range start=keyA end=keyC")
+
+	// stop iteration early
+	stub, rwset, state, _ = newTestFpcStub(t)
+	iter, err = stub.GetStateByRange("keyA", "keyZ")
+	require.NoError(t, err)
+	require.True(t, iter.HasNext())
+	kv, err := iter.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "keyA", kv.Key)
+	require.NoError(t, iter.Close())
+
+	fpcKVSet = rwset.ToFPCKVSet()
+	require.Len(t, fpcKVSet.RwSet.RangeQueriesInfo, 1)
+	rqi = fpcKVSet.RwSet.RangeQueriesInfo[0]
+	assert.Equal(t, "keyA", rqi.EndKey)
+	assert.False(t, rqi.ItrExhausted)
+
+	// keys after the last key read are not covered
+	require.NoError(t, state.PutState("keyC", []byte("some value")))
+	assert.NoError(t, validator.ReplayReadWrites(state, fpcKVSet))
+
+	// range without results consumed is not recorded
+	stub, rwset, _, _ = newTestFpcStub(t)
+	_, err = stub.GetStateByRange("keyA", "keyC")
+	require.NoError(t, err)
+	assert.Empty(t, rwset.ToFPCKVSet().RwSet.RangeQueriesInfo)
+}
+
+// paginatedMockStub adds range query pagination to the MockStub
+type paginatedMockStub struct {
+	*shimtest.MockStub
+}
+
+func (s *paginatedMockStub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	iter, err := s.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Close()
+
+	page := &skvsIterator{}
+	metadata := &pb.QueryResponseMetadata{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(page.results) == int(pageSize) {
+			metadata.Bookmark = kv.Key
+			break
+		}
+		page.results = append(page.results, kv)
+	}
+	metadata.FetchedRecordsCount = int32(len(page.results))
+
+	return page, metadata, nil
+}
+
+func readAll(t *testing.T, iter shim.StateQueryIteratorInterface) []string {
+	var keys []string
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		require.NoError(t, err)
+		keys = append(keys, kv.Key)
+	}
+	require.NoError(t, iter.Close())
+	return keys
+}
+
+func TestGetStateByRangeWithPagination(t *testing.T) {
+	validator := endorsement.NewValidator()
+
+	// first page
+	_, rwset, state, ccKeys := newTestFpcStub(t)
+	stub := NewFpcStubInterface(&paginatedMockStub{state}, &pb.ChaincodeInput{}, nil, rwset, nil, ccKeys)
+	iter, metadata, err := stub.GetStateByRangeWithPagination("keyA", "keyZ", 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyA", "keyB"}, readAll(t, iter))
+	assert.Equal(t, "keyD", metadata.Bookmark)
+	assert.EqualValues(t, 2, metadata.FetchedRecordsCount)
+
+	// a full page only covers the keys up to the last key read
+	fpcKVSet := rwset.ToFPCKVSet()
+	require.Len(t, fpcKVSet.RwSet.RangeQueriesInfo, 1)
+	rqi := fpcKVSet.RwSet.RangeQueriesInfo[0]
+	assert.Equal(t, "keyA", rqi.StartKey)
+	assert.Equal(t, "keyB", rqi.EndKey)
+	assert.False(t, rqi.ItrExhausted)
+
+	require.NoError(t, state.PutState("keyC", []byte("some value")))
+	assert.NoError(t, validator.ReplayReadWrites(state, fpcKVSet))
+
+	// last page
+	_, rwset, state, ccKeys = newTestFpcStub(t)
+	stub = NewFpcStubInterface(&paginatedMockStub{state}, &pb.ChaincodeInput{}, nil, rwset, nil, ccKeys)
+	iter, metadata, err = stub.GetStateByRangeWithPagination("keyA", "keyZ", 2, "keyD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyD"}, readAll(t, iter))
+	assert.Empty(t, metadata.Bookmark)
+
+	fpcKVSet = rwset.ToFPCKVSet()
+	require.Len(t, fpcKVSet.RwSet.RangeQueriesInfo, 1)
+	rqi = fpcKVSet.RwSet.RangeQueriesInfo[0]
+	assert.Equal(t, "keyD", rqi.StartKey)
+	assert.Equal(t, "keyZ", rqi.EndKey)
+	assert.True(t, rqi.ItrExhausted)
+	assert.NoError(t, validator.ReplayReadWrites(state, fpcKVSet))
+
+	// phantom key in last page
+	require.NoError(t, state.PutState("keyE", []byte("some value")))
+	assert.Error(t, validator.ReplayReadWrites(state, fpcKVSet))
+}
+
+func TestSkvsPagination(t *testing.T) {
+	state := shimtest.NewMockStub("someChaincode", nil)
+	state.MockTransactionStart("someTxId")
+	ccKeys, err := NewChaincodeKeys(NewEnclaveStub(nil).csp)
+	require.NoError(t, err)
+
+	fpcStub := NewFpcStubInterface(state, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), nil, ccKeys)
+	compKeyA, err := fpcStub.CreateCompositeKey("asset", []string{"a"})
+	require.NoError(t, err)
+	compKeyB, err := fpcStub.CreateCompositeKey("asset", []string{"b"})
+	require.NoError(t, err)
+
+	allData, err := json.Marshal(map[string][]byte{
+		"keyA": []byte("valueA"), "keyB": []byte("valueB"), "keyD": []byte("valueD"),
+		compKeyA: []byte("assetA"), compKeyB: []byte("assetB"),
+	})
+	require.NoError(t, err)
+	encValue, err := ccKeys.EncryptState(allData)
+	require.NoError(t, err)
+	require.NoError(t, state.PutState(SKVSKey, encValue))
+
+	stub := NewSkvsStubInterface(state, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), nil, ccKeys)
+
+	iter, err := stub.GetStateByRange("keyA", "keyC")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyA", "keyB"}, readAll(t, iter))
+
+	iter, metadata, err := stub.GetStateByRangeWithPagination("keyA", "", 2, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyA", "keyB"}, readAll(t, iter))
+	assert.Equal(t, "keyD", metadata.Bookmark)
+
+	iter, metadata, err = stub.GetStateByRangeWithPagination("keyA", "", 2, metadata.Bookmark)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyD"}, readAll(t, iter))
+	assert.Empty(t, metadata.Bookmark)
+
+	iter, metadata, err = stub.GetStateByPartialCompositeKeyWithPagination("asset", []string{}, 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{compKeyA}, readAll(t, iter))
+	assert.Equal(t, compKeyB, metadata.Bookmark)
+
+	iter, metadata, err = stub.GetStateByPartialCompositeKeyWithPagination("asset", []string{}, 1, metadata.Bookmark)
+	require.NoError(t, err)
+	assert.Equal(t, []string{compKeyB}, readAll(t, iter))
+	assert.Empty(t, metadata.Bookmark)
+}
+
+func TestSetEvent(t *testing.T) {
+	csp := NewEnclaveStub(nil).csp
+	responseKey, err := csp.NewSymmetricKey()
+	require.NoError(t, err)
+	eventKey, err := csp.NewSymmetricKey()
+	require.NoError(t, err)
+
+	// events not supported without event
+	stub := NewFpcStubInterface(nil, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), nil, nil)
+	assert.EqualError(t, stub.SetEvent("someEvent", []byte("some payload")), "function not yet supported")
+
+	event := newChaincodeEvent(csp, responseKey)
+	stub = NewFpcStubInterface(nil, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), event, nil)
+	assert.Nil(t, event.toFPCEvent())
+
+	assert.EqualError(t, stub.SetEvent("", []byte("some payload")), "event name can not be empty string")
+
+	// event for the invoking client
+	require.NoError(t, stub.SetEvent("someEvent", []byte("some payload")))
+	fpcEvent := event.toFPCEvent()
+	assert.Equal(t, "someEvent", fpcEvent.GetEventName())
+	assert.Empty(t, fpcEvent.GetEventKeyId())
+	payload, err := csp.DecryptMessage(responseKey, fpcEvent.GetEncryptedPayload())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("some payload"), payload)
+
+	// event for other listeners overwrites the previous event
+	assert.EqualError(t, stub.SetEventWithKey("otherEvent", []byte("other payload"), "", eventKey), "event key id can not be empty string")
+	require.NoError(t, stub.SetEventWithKey("otherEvent", []byte("other payload"), "someKeyId", eventKey))
+	fpcEvent = event.toFPCEvent()
+	assert.Equal(t, "otherEvent", fpcEvent.GetEventName())
+	assert.Equal(t, "someKeyId", fpcEvent.GetEventKeyId())
+	payload, err = csp.DecryptMessage(eventKey, fpcEvent.GetEncryptedPayload())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("other payload"), payload)
+}
+
+func TestGetTransient(t *testing.T) {
+	transient := map[string][]byte{"secret": []byte("some secret")}
+
+	stub := NewFpcStubInterface(nil, &pb.ChaincodeInput{}, transient, NewReadWriteSet(), nil, nil)
+	m, err := stub.GetTransient()
+	assert.NoError(t, err)
+	assert.Equal(t, transient, m)
+
+	stub = NewFpcStubInterface(nil, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), nil, nil)
+	m, err = stub.GetTransient()
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+}
+
+func TestReadYourWrites(t *testing.T) {
+	validator := endorsement.NewValidator()
+
+	// by default, reads return the committed state
+	stub, _, _, _ := newTestFpcStub(t)
+	require.NoError(t, stub.PutState("keyA", []byte("newValue")))
+	value, err := stub.GetState("keyA")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("valuekeyA"), value)
+
+	stub, rwset, state, ccKeys := newTestFpcStub(t)
+	stub.readYourWrites = true
+
+	// pending writes and deletes are visible
+	require.NoError(t, stub.PutState("keyA", []byte("newValue")))
+	require.NoError(t, stub.PutState("keyC", []byte("valuekeyC")))
+	require.NoError(t, stub.DelState("keyB"))
+
+	value, err = stub.GetState("keyA")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("newValue"), value)
+	value, err = stub.GetState("keyB")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = stub.GetState("keyD")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("valuekeyD"), value)
+
+	// only the committed read is recorded
+	fpcKVSet := rwset.ToFPCKVSet()
+	require.Len(t, fpcKVSet.RwSet.Reads, 1)
+	assert.Equal(t, "keyD", fpcKVSet.RwSet.Reads[0].Key)
+
+	// range queries merge the pending writes
+	iter, err := stub.GetStateByRange("keyA", "keyZ")
+	require.NoError(t, err)
+	var values []string
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		require.NoError(t, err)
+		values = append(values, kv.Key+"="+string(kv.Value))
+	}
+	require.NoError(t, iter.Close())
+	assert.Equal(t, []string{"keyA=newValue", "keyC=valuekeyC", "keyD=valuekeyD"}, values)
+
+	// the range query still covers the committed state
+	fpcKVSet = rwset.ToFPCKVSet()
+	require.Len(t, fpcKVSet.RwSet.RangeQueriesInfo, 1)
+	assert.True(t, fpcKVSet.RwSet.RangeQueriesInfo[0].ItrExhausted)
+	assert.NoError(t, validator.ReplayReadWrites(state, fpcKVSet))
+
+	// composite key queries merge the pending writes
+	compKeyA, err := state.CreateCompositeKey("asset", []string{"a"})
+	require.NoError(t, err)
+	encValue, err := ccKeys.EncryptState([]byte("assetA"))
+	require.NoError(t, err)
+	require.NoError(t, state.PutState(compKeyA, encValue))
+
+	compKeyB, err := stub.CreateCompositeKey("asset", []string{"b"})
+	require.NoError(t, err)
+	require.NoError(t, stub.PutState(compKeyB, []byte("assetB")))
+
+	iter, err = stub.GetStateByPartialCompositeKey("asset", []string{})
+	require.NoError(t, err)
+	values = nil
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		require.NoError(t, err)
+		values = append(values, string(kv.Value))
+	}
+	assert.Equal(t, []string{"assetA", "assetB"}, values)
+
+	// a pending delete hides the committed key
+	fpcCompKeyA, err := stub.CreateCompositeKey("asset", []string{"a"})
+	require.NoError(t, err)
+	require.NoError(t, stub.DelState(fpcCompKeyA))
+	iter, err = stub.GetStateByPartialCompositeKey("asset", []string{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{compKeyB}, readAll(t, iter))
+}
+
+func TestSkvsReadYourWrites(t *testing.T) {
+	state := shimtest.NewMockStub("someChaincode", nil)
+	state.MockTransactionStart("someTxId")
+	ccKeys, err := NewChaincodeKeys(NewEnclaveStub(nil).csp)
+	require.NoError(t, err)
+
+	allData, err := json.Marshal(map[string][]byte{"keyA": []byte("valueA"), "keyB": []byte("valueB")})
+	require.NoError(t, err)
+	encValue, err := ccKeys.EncryptState(allData)
+	require.NoError(t, err)
+	require.NoError(t, state.PutState(SKVSKey, encValue))
+
+	// by default, reads return the state at the beginning of the invocation
+	stub := NewSkvsStubInterface(state, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), nil, ccKeys)
+	require.NoError(t, stub.PutState("keyA", []byte("newValue")))
+	value, err := stub.GetState("keyA")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("valueA"), value)
+
+	stub = NewSkvsStubInterface(state, &pb.ChaincodeInput{}, nil, NewReadWriteSet(), nil, ccKeys)
+	stub.readYourWrites = true
+	require.NoError(t, stub.PutState("keyA", []byte("newValue")))
+	require.NoError(t, stub.PutState("keyC", []byte("valueC")))
+	require.NoError(t, stub.DelState("keyB"))
+
+	value, err = stub.GetState("keyA")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("newValue"), value)
+	value, err = stub.GetState("keyB")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	iter, err := stub.GetStateByRange("keyA", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keyA", "keyC"}, readAll(t, iter))
+}
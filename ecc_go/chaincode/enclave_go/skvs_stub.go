@@ -13,8 +13,10 @@ import (
 
 func NewSkvsStub(cc shim.Chaincode) *EnclaveStub {
 	enclaveStub := NewEnclaveStub(cc)
-	enclaveStub.stubProvider = func(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, rwset *readWriteSet, sep StateEncryptionFunctions) shim.ChaincodeStubInterface {
-		return NewSkvsStubInterface(stub, input, rwset, sep)
+	enclaveStub.stubProvider = func(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, transient map[string][]byte, rwset *readWriteSet, event *chaincodeEvent, sep StateEncryptionFunctions) shim.ChaincodeStubInterface {
+		skvsStub := NewSkvsStubInterface(stub, input, transient, rwset, event, sep)
+		skvsStub.readYourWrites = enclaveStub.readYourWrites
+		return skvsStub
 	}
 	return enclaveStub
 }
@@ -9,8 +9,11 @@ package enclave_go
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 )
 
@@ -23,8 +26,8 @@ type SkvsStubInterface struct {
 	key        string
 }
 
-func NewSkvsStubInterface(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, rwset *readWriteSet, sep StateEncryptionFunctions) *SkvsStubInterface {
-	fpcStub := NewFpcStubInterface(stub, input, rwset, sep)
+func NewSkvsStubInterface(stub shim.ChaincodeStubInterface, input *pb.ChaincodeInput, transient map[string][]byte, rwset *readWriteSet, event *chaincodeEvent, sep StateEncryptionFunctions) *SkvsStubInterface {
+	fpcStub := NewFpcStubInterface(stub, input, transient, rwset, event, sep)
 	skvsStub := &SkvsStubInterface{
 		FpcStubInterface: fpcStub,
 		allDataOld:       make(map[string][]byte),
@@ -72,14 +75,23 @@ func (s *SkvsStubInterface) initSKVS() error {
 }
 
 func (s *SkvsStubInterface) GetState(key string) ([]byte, error) {
-	value, found := s.allDataOld[key]
+	value, found := s.data()[key]
 	if !found {
-		logger.Errorf("skvs allDataOld key: %s, not found", key)
+		logger.Errorf("skvs key: %s, not found", key)
 		return nil, nil
 	}
 	return value, nil
 }
 
+// data returns the local SKVS copy that is visible to reads; that is, allDataNew if read-your-own-writes is enabled
+// and allDataOld otherwise
+func (s *SkvsStubInterface) data() map[string][]byte {
+	if s.readYourWrites {
+		return s.allDataNew
+	}
+	return s.allDataOld
+}
+
 func (s *SkvsStubInterface) PutState(key string, value []byte) error {
 
 	s.allDataNew[key] = value
@@ -109,9 +121,99 @@ func (s *SkvsStubInterface) DelState(key string) error {
 }
 
 func (s *SkvsStubInterface) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
-	panic("not implemented") // TODO: Implement
+	// note that all data has been read (and recorded in the rwset) with the single SKVS key,
+	// so the range query is evaluated over the local copy and does not need to be recorded separately
+	keys := s.sortedKeys(func(k string) bool {
+		return inRange(k, startKey, endKey)
+	})
+
+	return s.newSkvsIterator(keys), nil
 }
 
 func (s *SkvsStubInterface) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
-	panic("not implemented") // TODO: Implement
+	if bookmark != "" {
+		startKey = bookmark
+	}
+
+	keys := s.sortedKeys(func(k string) bool {
+		return inRange(k, startKey, endKey)
+	})
+
+	return s.paginate(keys, pageSize)
+}
+
+func (s *SkvsStubInterface) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	prefix, err := s.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := s.sortedKeys(func(k string) bool {
+		return strings.HasPrefix(k, prefix) && k >= bookmark
+	})
+
+	return s.paginate(keys, pageSize)
+}
+
+// sortedKeys returns the keys of the local SKVS copy that match the filter in sorted order
+func (s *SkvsStubInterface) sortedKeys(filter func(key string) bool) []string {
+	var keys []string
+	for k := range s.data() {
+		if filter(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paginate returns an iterator over the first page of keys; the bookmark is the first key of the next page
+func (s *SkvsStubInterface) paginate(keys []string, pageSize int32) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	bookmark := ""
+	if pageSize > 0 && len(keys) > int(pageSize) {
+		bookmark = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+
+	metadata := &pb.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(keys)),
+		Bookmark:            bookmark,
+	}
+
+	return s.newSkvsIterator(keys), metadata, nil
+}
+
+func (s *SkvsStubInterface) newSkvsIterator(keys []string) *skvsIterator {
+	results := make([]*queryresult.KV, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, &queryresult.KV{Key: k, Value: s.data()[k]})
+	}
+	return &skvsIterator{results: results}
+}
+
+func inRange(key, startKey, endKey string) bool {
+	return key >= startKey && (endKey == "" || key < endKey)
+}
+
+// skvsIterator iterates over query results of the local SKVS copy
+type skvsIterator struct {
+	results []*queryresult.KV
+	next    int
+}
+
+func (i *skvsIterator) HasNext() bool {
+	return i.next < len(i.results)
+}
+
+func (i *skvsIterator) Next() (*queryresult.KV, error) {
+	if !i.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	q := i.results[i.next]
+	i.next++
+	return q, nil
+}
+
+func (i *skvsIterator) Close() error {
+	return nil
 }
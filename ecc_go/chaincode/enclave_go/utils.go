@@ -8,10 +8,12 @@ package enclave_go
 
 import (
 	"crypto/sha256"
+	"fmt"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
 )
 
 func hash(value []byte) []byte {
@@ -71,3 +73,105 @@ func (i *fpcIterator) Next() (*queryresult.KV, error) {
 		Value:     decValue,
 	}, nil
 }
+
+// rangeQueryIterator records the consumed results of a range query
+type rangeQueryIterator struct {
+	*fpcIterator
+	rangeQuery *rangeQuery
+}
+
+func newRangeQueryIterator(iterator shim.StateQueryIteratorInterface, rangeQuery *rangeQuery, decryptFunction func(ciphertext []byte) (plaintext []byte, err error)) *rangeQueryIterator {
+	return &rangeQueryIterator{
+		fpcIterator: newFpcIterator(iterator, rangeQuery.addRead, decryptFunction),
+		rangeQuery:  rangeQuery,
+	}
+}
+
+func (i *rangeQueryIterator) HasNext() bool {
+	hasNext := i.fpcIterator.HasNext()
+	if !hasNext {
+		i.rangeQuery.setExhausted()
+	}
+	return hasNext
+}
+
+// mergeIterator merges the pending writes of the current invocation into the results of a query iterator.
+// A pending write replaces the committed value of the same key, and a pending delete hides the key.
+// Note that the committed results are still consumed through the underlying iterator so that the reads are recorded.
+type mergeIterator struct {
+	iterator        shim.StateQueryIteratorInterface
+	writes          []*kvrwset.KVWrite
+	decryptFunction func(ciphertext []byte) (plaintext []byte, err error)
+	committed       *queryresult.KV
+	next            *queryresult.KV
+	err             error
+}
+
+func newMergeIterator(iterator shim.StateQueryIteratorInterface, writes []*kvrwset.KVWrite, decryptFunction func(ciphertext []byte) (plaintext []byte, err error)) *mergeIterator {
+	return &mergeIterator{
+		iterator:        iterator,
+		writes:          writes,
+		decryptFunction: decryptFunction,
+	}
+}
+
+func (i *mergeIterator) HasNext() bool {
+	i.fetch()
+	return i.next != nil || i.err != nil
+}
+
+func (i *mergeIterator) Next() (*queryresult.KV, error) {
+	i.fetch()
+	if i.err != nil {
+		err := i.err
+		i.err = nil
+		return nil, err
+	}
+	if i.next == nil {
+		return nil, fmt.Errorf("no more results")
+	}
+	q := i.next
+	i.next = nil
+	return q, nil
+}
+
+func (i *mergeIterator) Close() error {
+	return i.iterator.Close()
+}
+
+// fetch determines the next result unless it is already available
+func (i *mergeIterator) fetch() {
+	for i.next == nil && i.err == nil {
+		if i.committed == nil && i.iterator.HasNext() {
+			i.committed, i.err = i.iterator.Next()
+			if i.err != nil {
+				return
+			}
+		}
+
+		if len(i.writes) == 0 || (i.committed != nil && i.committed.Key < i.writes[0].Key) {
+			// no pending write before the next committed result
+			i.next, i.committed = i.committed, nil
+			return
+		}
+
+		w := i.writes[0]
+		i.writes = i.writes[1:]
+		if i.committed != nil && i.committed.Key == w.Key {
+			// the committed value is replaced by the pending write
+			i.committed = nil
+		}
+		if w.IsDelete {
+			continue
+		}
+
+		value := w.Value
+		if i.decryptFunction != nil {
+			value, i.err = i.decryptFunction(w.Value)
+			if i.err != nil {
+				return
+			}
+		}
+		i.next = &queryresult.KV{Key: w.Key, Value: value}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/hyperledger/fabric-private-chaincode/ecc/chaincode"
 	"github.com/hyperledger/fabric-private-chaincode/ecc/chaincode/ercc"
 	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go"
+	"github.com/hyperledger/fabric-private-chaincode/ecc_go/chaincode/enclave_go/sealing"
 	"github.com/hyperledger/fabric-private-chaincode/internal/endorsement"
 )
 
@@ -35,3 +36,36 @@ func WithSKVS() BuildOption {
 		ecc.Enclave = enclave_go.NewSkvsStub(cc)
 	}
 }
+
+// WithReadYourWrites enables read-your-own-writes semantics within a single invocation; that is, GetState and
+// the (non-paginated) range and composite key queries observe the pending writes and deletes of the invocation.
+// Note that WithReadYourWrites must be given after WithSKVS.
+func WithReadYourWrites() BuildOption {
+	return func(ecc *chaincode.EnclaveChaincode, cc shim.Chaincode) {
+		if e, ok := ecc.Enclave.(*enclave_go.EnclaveStub); ok {
+			e.EnableReadYourWrites()
+		}
+	}
+}
+
+// WithDeterministicResponses makes the chaincode response deterministic for a given request and state, so that the
+// responses of several endorsing enclaves can be compared byte for byte.
+// Note that WithDeterministicResponses must be given after WithSKVS.
+func WithDeterministicResponses() BuildOption {
+	return func(ecc *chaincode.EnclaveChaincode, cc shim.Chaincode) {
+		if e, ok := ecc.Enclave.(*enclave_go.EnclaveStub); ok {
+			e.EnableDeterministicResponses()
+		}
+	}
+}
+
+// WithSealing persists the enclave identity and chaincode keys with the given sealer, so that the enclave keeps its
+// registration and can read its encrypted state after a restart.
+// Note that WithSealing must be given after WithSKVS.
+func WithSealing(sealer sealing.Sealer) BuildOption {
+	return func(ecc *chaincode.EnclaveChaincode, cc shim.Chaincode) {
+		if e, ok := ecc.Enclave.(*enclave_go.EnclaveStub); ok {
+			e.EnableSealing(sealer)
+		}
+	}
+}
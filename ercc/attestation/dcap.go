@@ -0,0 +1,13 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import "github.com/hyperledger/fabric-private-chaincode/internal/attestation/dcap"
+
+func init() {
+	registry.add(dcap.NewDcapVerifier())
+}
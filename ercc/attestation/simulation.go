@@ -6,8 +6,15 @@ SPDX-License-Identifier: Apache-2.0
 
 package attestation
 
-import "github.com/hyperledger/fabric-private-chaincode/internal/attestation/simulation"
+import (
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/simulation"
+)
 
 func init() {
+	// legacy simulated attestations (as created by the C++ enclave in simulation mode) are accepted unless disabled
+	if simulation.AcceptLegacyAttestation() {
+		registry.add(simulation.NewLegacySimulationVerifier())
+		return
+	}
 	registry.add(simulation.NewSimulationVerifier())
 }
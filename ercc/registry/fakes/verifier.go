@@ -8,11 +8,12 @@ import (
 )
 
 type CredentialVerifier struct {
-	VerifyCredentialsStub        func(*protos.Credentials, string) error
+	VerifyCredentialsStub        func(*protos.Credentials, string, *protos.AttestationVerificationPolicy) error
 	verifyCredentialsMutex       sync.RWMutex
 	verifyCredentialsArgsForCall []struct {
 		arg1 *protos.Credentials
 		arg2 string
+		arg3 *protos.AttestationVerificationPolicy
 	}
 	verifyCredentialsReturns struct {
 		result1 error
@@ -24,19 +25,20 @@ type CredentialVerifier struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *CredentialVerifier) VerifyCredentials(arg1 *protos.Credentials, arg2 string) error {
+func (fake *CredentialVerifier) VerifyCredentials(arg1 *protos.Credentials, arg2 string, arg3 *protos.AttestationVerificationPolicy) error {
 	fake.verifyCredentialsMutex.Lock()
 	ret, specificReturn := fake.verifyCredentialsReturnsOnCall[len(fake.verifyCredentialsArgsForCall)]
 	fake.verifyCredentialsArgsForCall = append(fake.verifyCredentialsArgsForCall, struct {
 		arg1 *protos.Credentials
 		arg2 string
-	}{arg1, arg2})
+		arg3 *protos.AttestationVerificationPolicy
+	}{arg1, arg2, arg3})
 	stub := fake.VerifyCredentialsStub
 	fakeReturns := fake.verifyCredentialsReturns
-	fake.recordInvocation("VerifyCredentials", []interface{}{arg1, arg2})
+	fake.recordInvocation("VerifyCredentials", []interface{}{arg1, arg2, arg3})
 	fake.verifyCredentialsMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -50,17 +52,17 @@ func (fake *CredentialVerifier) VerifyCredentialsCallCount() int {
 	return len(fake.verifyCredentialsArgsForCall)
 }
 
-func (fake *CredentialVerifier) VerifyCredentialsCalls(stub func(*protos.Credentials, string) error) {
+func (fake *CredentialVerifier) VerifyCredentialsCalls(stub func(*protos.Credentials, string, *protos.AttestationVerificationPolicy) error) {
 	fake.verifyCredentialsMutex.Lock()
 	defer fake.verifyCredentialsMutex.Unlock()
 	fake.VerifyCredentialsStub = stub
 }
 
-func (fake *CredentialVerifier) VerifyCredentialsArgsForCall(i int) (*protos.Credentials, string) {
+func (fake *CredentialVerifier) VerifyCredentialsArgsForCall(i int) (*protos.Credentials, string, *protos.AttestationVerificationPolicy) {
 	fake.verifyCredentialsMutex.RLock()
 	defer fake.verifyCredentialsMutex.RUnlock()
 	argsForCall := fake.verifyCredentialsArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *CredentialVerifier) VerifyCredentialsReturns(result1 error) {
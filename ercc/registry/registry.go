@@ -10,15 +10,21 @@ SPDX-License-Identifier: Apache-2.0
 package registry
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation"
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
 )
 
 var logger = flogging.MustGetLogger("ercc")
@@ -142,7 +148,36 @@ func (rs *Contract) QueryListProvisionedEnclaves(ctx contractapi.TransactionCont
 	return enclaveIds, err
 }
 
-// QueryChaincodeEndPoints returns the chaincode endpoints for given chaincode id
+// QueryCCKeyRegistration returns the (base64 encoded) signed cc key registration message with which an enclave
+// was provisioned with the chaincode keys via RegisterCCKeys. If the enclave is not provisioned or was provisioned
+// with its registration, as it includes the chaincode encryption key in its attested data, an empty string is returned.
+func (rs *Contract) QueryCCKeyRegistration(ctx contractapi.TransactionContextInterface, chaincodeId string, enclaveId string) (string, error) {
+	provisionedKey, err := ctx.GetStub().CreateCompositeKey("namespaces/provisioned", []string{chaincodeId, enclaveId})
+	if err != nil {
+		return "", err
+	}
+
+	provisioned, err := ctx.GetStub().GetState(provisionedKey)
+	if err != nil {
+		return "", err
+	}
+	if len(provisioned) == 0 {
+		return "", nil
+	}
+
+	// enclaves provisioned with their registration store their credentials instead of a cc key registration message
+	credentialsBase64, err := rs.QueryEnclaveCredentials(ctx, chaincodeId, enclaveId)
+	if err != nil {
+		return "", err
+	}
+	if string(provisioned) == credentialsBase64 {
+		return "", nil
+	}
+
+	return string(provisioned), nil
+}
+
+// QueryChaincodeEndPoints returns the chaincode endpoints of all provisioned enclaves for given chaincode id
 // (if more than one, they are concatenated with a ",")
 func (rs *Contract) QueryChaincodeEndPoints(ctx contractapi.TransactionContextInterface, chaincodeId string) (string, error) {
 	iter, err := ctx.GetStub().GetStateByPartialCompositeKey("namespaces/credentials", []string{chaincodeId})
@@ -163,6 +198,23 @@ func (rs *Contract) QueryChaincodeEndPoints(ctx contractapi.TransactionContextIn
 		if err != nil {
 			return "", err
 		}
+		// only provisioned enclaves can serve requests
+		_, res, err := ctx.GetStub().SplitCompositeKey(q.Key)
+		if err != nil {
+			return "", err
+		}
+		k, err := ctx.GetStub().CreateCompositeKey("namespaces/provisioned", []string{chaincodeId, res[1]})
+		if err != nil {
+			return "", err
+		}
+		p, err := ctx.GetStub().GetState(k)
+		if err != nil {
+			return "", err
+		}
+		if p == nil {
+			continue
+		}
+
 		credentialsBase64 := string(q.Value)
 		credentials, err := utils.UnmarshalCredentials(credentialsBase64)
 		if err != nil {
@@ -183,62 +235,69 @@ func (rs *Contract) QueryChaincodeEndPoints(ctx contractapi.TransactionContextIn
 	return peerEndpoints, nil
 }
 
-// QueryChaincodeEncryptionKey returns the chaincode encryption key for a given chaincode id
+// QueryChaincodeEncryptionKey returns the chaincode encryption key for a given chaincode id.
+// The chaincode encryption key is set by the first successful RegisterCCKeys call for this chaincode or, for enclaves
+// that include the chaincode encryption key in their attested data, by the first successful RegisterEnclave call.
+// If no chaincode encryption key is set, the key attested by a registered enclave is returned (if any).
+// If no chaincode keys have been registered yet, an empty string is returned.
 func (rs *Contract) QueryChaincodeEncryptionKey(ctx contractapi.TransactionContextInterface, chaincodeId string) (string, error) {
-	// NOTE: This is a (momentary) short-cut over the FPC and FPC Lite specification in `docs/design/fabric-v2+/fpc-registration.puml` and `docs/design/fabric-v2+/fpc-key-dist.puml`.  See also `common/enclave/cc_data.cpp` and `protos/fpc/fpc.proto`
-	// TODO: remove short cut (see also RegisterEnclave and RegisterCCKeys (Post-MVP)
-
-	// retrieve the enclave id
-	iter, err := ctx.GetStub().GetStateByPartialCompositeKey("namespaces/credentials", []string{chaincodeId})
-	if iter != nil {
-		defer iter.Close()
-	}
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/chaincode_ek", []string{chaincodeId})
 	if err != nil {
 		return "", err
 	}
 
-	// pick the first one from the list
-	q, err := iter.Next()
-	if err != nil {
-		return "", err
-	}
-	_, res, err := ctx.GetStub().SplitCompositeKey(q.Key)
+	chaincodeEKBytes, err := ctx.GetStub().GetState(key)
 	if err != nil {
-		logger.Debugf("no split")
 		return "", err
 	}
-	enclaveId := res[1]
 
-	// recreate composite key of credentials
-	k, err := ctx.GetStub().CreateCompositeKey("namespaces/credentials", []string{chaincodeId, enclaveId})
-	if err != nil {
-		return "", err
+	if len(chaincodeEKBytes) == 0 {
+		chaincodeEKBytes, err = getAttestedChaincodeEk(ctx, chaincodeId)
+		if err != nil {
+			return "", err
+		}
 	}
 
-	// get credentials from state
-	credentialsBase64, err := ctx.GetStub().GetState(k)
-	if err != nil {
-		return "", err
-	}
+	// b64 encoded chaincode key
+	b64ChaincodeEK := base64.StdEncoding.EncodeToString(chaincodeEKBytes)
+	logger.Debugf("QueryChaincodeEncryptionKey: EK: '%s' / EK b64: '%s'", string(chaincodeEKBytes), b64ChaincodeEK)
 
-	// retrieve chaincode ek from credentials
-	credentials, err := utils.UnmarshalCredentials(string(credentialsBase64))
+	return b64ChaincodeEK, nil
+}
+
+// getAttestedChaincodeEk returns the chaincode encryption key of the first registered enclave of the chaincode
+// that includes the key in its attested data, or nil if there is no such enclave
+func getAttestedChaincodeEk(ctx contractapi.TransactionContextInterface, chaincodeId string) ([]byte, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey("namespaces/credentials", []string{chaincodeId})
+	if iter != nil {
+		defer iter.Close()
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var attestedData protos.AttestedData
-	if err := credentials.SerializedAttestedData.UnmarshalTo(&attestedData); err != nil {
-		return "", err
-	}
+	for iter != nil && iter.HasNext() {
+		q, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
 
-	chaincodeEKBytes := attestedData.GetChaincodeEk()
+		credentials, err := utils.UnmarshalCredentials(string(q.Value))
+		if err != nil {
+			return nil, err
+		}
 
-	// b64 encoded chaincode key
-	b64ChaincodeEK := base64.StdEncoding.EncodeToString(chaincodeEKBytes)
-	logger.Debugf("QueryChaincodeEncryptionKey: EK: '%s' / EK b64: '%s'", string(chaincodeEKBytes), b64ChaincodeEK)
+		attestedData, err := utils.UnmarshalAttestedData(credentials.SerializedAttestedData)
+		if err != nil {
+			return nil, err
+		}
 
-	return b64ChaincodeEK, nil
+		if len(attestedData.GetChaincodeEk()) > 0 {
+			return attestedData.GetChaincodeEk(), nil
+		}
+	}
+
+	return nil, nil
 }
 
 // RegisterEnclave register a new FPC chaincode enclave instance
@@ -274,15 +333,19 @@ func (rs *Contract) RegisterEnclave(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	// check if one enclave is already registered
-	registeredCredentialsList, _ := rs.QueryListEnclaveCredentials(ctx, chaincodeId)
-	if registeredCredentialsList != nil {
-		return fmt.Errorf("an enclave is already registered for chaincode %s", chaincodeId)
+	// revoked enclaves cannot register again
+	revoked, err := rs.QueryEnclaveRevoked(ctx, chaincodeId, enclaveId)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("enclave %s has been revoked", enclaveId)
 	}
 
-	// TODO perform the (enclave) endorsement policy specific tests (MVP/Post-MVP)
-	// - MVP (designated chaincode): verify no other enclave (of other peers) is registered or fail
-	// - Post-MVP: check consistency with potentially existing enclaves
+	// check the FPC deployment policy and consistency with already registered enclaves
+	if err := checkDeploymentPolicy(ctx, attestedData, enclaveId); err != nil {
+		return err
+	}
 
 	// All check passed, now register enclave
 	logger.Debugf("Registering credentials at key %s", key)
@@ -291,14 +354,22 @@ func (rs *Contract) RegisterEnclave(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("cannot store credentials: %s", err)
 	}
 
-	// Due to MVP short-cut (see QueryChaincodeEncryptionKey) we already declare chaincode/enclave as provisioned
-	// TODO: this has to go to RegisterCCKeys and ImportCCKeys (Post-MVP)
-	provisionedKey, err := ctx.GetStub().CreateCompositeKey("namespaces/provisioned", []string{chaincodeId, enclaveId})
-	if err != nil {
-		return fmt.Errorf("cannot create provisionedKey: %s", err)
-	}
-	if err := ctx.GetStub().PutState(provisionedKey, []byte("a SignedCCKeyRegistrationMessage")); err != nil {
-		return fmt.Errorf("cannot store provisionedKey: %s", err)
+	// enclaves that include the chaincode encryption key in their attested data (i.e., the C++ enclaves) create their
+	// chaincode keys at initialization and do not support the key distribution protocol. Such an enclave is
+	// provisioned with its registration, for which the attested data serves as cc key registration.
+	// Other enclaves are provisioned with the chaincode keys via RegisterCCKeys.
+	if len(attestedData.ChaincodeEk) > 0 {
+		if err := registerChaincodeEk(ctx, chaincodeId, attestedData.ChaincodeEk); err != nil {
+			return err
+		}
+
+		provisionedKey, err := ctx.GetStub().CreateCompositeKey("namespaces/provisioned", []string{chaincodeId, enclaveId})
+		if err != nil {
+			return fmt.Errorf("cannot create provisionedKey: %s", err)
+		}
+		if err := ctx.GetStub().PutState(provisionedKey, []byte(credentialsBase64)); err != nil {
+			return fmt.Errorf("cannot store provisionedKey: %s", err)
+		}
 	}
 
 	logger.Debugf("RegisterEnclave successful")
@@ -330,9 +401,16 @@ func checkAttestedData(ctx contractapi.TransactionContextInterface, v attestatio
 		return fmt.Errorf("sequence does not match chaincode definition")
 	}
 
-	// check that attestation evidence contains expectedMrEnclave as defined in chaincode definition
-	if err := v.VerifyCredentials(credentials, expectedMrEnclave); err != nil {
-		return fmt.Errorf("evidence verification failed: %s", err)
+	policy, err := getVerificationPolicy(ctx, attestedData.CcParams.ChaincodeId)
+	if err != nil {
+		return err
+	}
+
+	// check that attestation evidence contains expectedMrEnclave as defined in chaincode definition, or an enclave
+	// accepted by the allowed mrenclaves or signers of the policy (see types.CheckEnclaveIdentity),
+	// and satisfies the verification policy; rejections keep their structured reason (see types.RejectionError)
+	if err := v.VerifyCredentials(credentials, expectedMrEnclave, policy); err != nil {
+		return errors.Wrap(err, "evidence verification failed")
 	}
 
 	// next check peer (enclave host) identity is covered by the attestation
@@ -340,46 +418,836 @@ func checkAttestedData(ctx contractapi.TransactionContextInterface, v attestatio
 		return errors.New("host params are empty")
 	}
 
+	// check that registration transaction creator has same mspid as the enclave owner
+	if err := checkCreator(ctx, ie, attestedData); err != nil {
+		return err
+	}
+
+	// TODO add more checks (POST-MVP)
+	// - channel_hash should correspond to peers view of channel id
+	// - TLCC_MRENCLAVE matches the version baked into ERCC
+
+	return nil
+}
+
+// SetDeploymentPolicy sets the (base64-encoded) FPCDeploymentPolicy for a chaincode.
+// The policy restricts which organizations may host enclaves for the chaincode and how many enclaves can be registered.
+// The policy must be requested by admins of a majority of the channel organizations, where each call records the vote of the
+// creator's organization for the given policy. Note that the policy only applies to subsequent enclave registrations.
+func (rs *Contract) SetDeploymentPolicy(ctx contractapi.TransactionContextInterface, chaincodeId string, policyBase64 string) error {
+	logger.Debugf("SetDeploymentPolicy")
+
+	policyBytes, err := base64.StdEncoding.DecodeString(policyBase64)
+	if err != nil {
+		return errors.Wrap(err, "invalid deployment policy bytes")
+	}
+
+	policy := &protos.FPCDeploymentPolicy{}
+	if err := proto.Unmarshal(policyBytes, policy); err != nil {
+		return errors.Wrap(err, "invalid deployment policy")
+	}
+
+	if policy.GetMaxEnclaves() > 0 && policy.GetMaxEnclavesPerMsp() > policy.GetMaxEnclaves() {
+		return fmt.Errorf("max_enclaves_per_msp must not exceed max_enclaves")
+	}
+
+	// check that the chaincode exists
+	if _, err := utils.GetChaincodeDefinition(chaincodeId, ctx.GetStub()); err != nil {
+		return fmt.Errorf("cannot get chaincode definition: %s", err)
+	}
+
+	return rs.setPolicy(ctx, "namespaces/deployment_policy", chaincodeId, policyBytes)
+}
+
+// QueryDeploymentPolicy returns the (base64-encoded) FPCDeploymentPolicy for a chaincode.
+// If no policy is set, an empty string is returned.
+func (rs *Contract) QueryDeploymentPolicy(ctx contractapi.TransactionContextInterface, chaincodeId string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/deployment_policy", []string{chaincodeId})
+	if err != nil {
+		return "", err
+	}
+
+	policyBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(policyBytes), nil
+}
+
+// SetVerificationPolicy sets the (base64-encoded) AttestationVerificationPolicy for a chaincode.
+// The policy defines the TCB status and advisory IDs of enclave platforms that are accepted by the attestation verifiers.
+// As the deployment policy, the policy must be requested by admins of a majority of the channel organizations and only
+// applies to subsequent enclave registrations.
+func (rs *Contract) SetVerificationPolicy(ctx contractapi.TransactionContextInterface, chaincodeId string, policyBase64 string) error {
+	logger.Debugf("SetVerificationPolicy")
+
+	policyBytes, err := base64.StdEncoding.DecodeString(policyBase64)
+	if err != nil {
+		return errors.Wrap(err, "invalid verification policy bytes")
+	}
+
+	policy := &protos.AttestationVerificationPolicy{}
+	if err := proto.Unmarshal(policyBytes, policy); err != nil {
+		return errors.Wrap(err, "invalid verification policy")
+	}
+
+	// check that the chaincode exists
+	if _, err := utils.GetChaincodeDefinition(chaincodeId, ctx.GetStub()); err != nil {
+		return fmt.Errorf("cannot get chaincode definition: %s", err)
+	}
+
+	return rs.setPolicy(ctx, "namespaces/verification_policy", chaincodeId, policyBytes)
+}
+
+// setPolicy stores a policy of a chaincode in the given namespace once admins of a majority of the channel organizations
+// requested the same policy. Each call records the vote of the creator's organization for the policy.
+func (rs *Contract) setPolicy(ctx contractapi.TransactionContextInterface, namespace, chaincodeId string, policyBytes []byte) error {
 	creatorIdentityBytes, err := ctx.GetStub().GetCreator()
 	if err != nil {
 		return err
 	}
 
-	// check that registration transaction creator has same mspid as the enclave owner
-	if err := ie.EvaluateCreatorIdentity(creatorIdentityBytes, attestedData.HostParams.PeerMspId); err != nil {
+	mspId, err := rs.IEvaluator.EvaluateAdminIdentity(creatorIdentityBytes, ctx.GetStub())
+	if err != nil {
 		return fmt.Errorf("creator identity evaluation failed: %s", err)
 	}
 
-	// TODO add more checks (POST-MVP)
-	// - channel_hash should correspond to peers view of channel id
-	// - TLCC_MRENCLAVE matches the version baked into ERCC
-	// - validate FPC deployment (restriction) policy
+	orgs, err := utils.GetChannelMSPIDs(ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("cannot get channel organizations: %s", err)
+	}
+	if !contains(orgs, mspId) {
+		return fmt.Errorf("msp %s is not a member of channel %s", mspId, ctx.GetStub().GetChannelID())
+	}
+
+	// votes are recorded per policy; votes for other policies are discarded once a policy is set
+	policyHash := sha256.Sum256(policyBytes)
+	approved, err := recordApproval(ctx, "namespaces/policy_votes", []string{namespace, chaincodeId, hex.EncodeToString(policyHash[:])}, mspId, orgs)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		logger.Debugf("recorded vote of %s for %s of chaincode %s", mspId, namespace, chaincodeId)
+		return nil
+	}
+	if err := removeApprovals(ctx, "namespaces/policy_votes", []string{namespace, chaincodeId}); err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(namespace, []string{chaincodeId})
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutState(key, policyBytes); err != nil {
+		return fmt.Errorf("cannot store policy: %s", err)
+	}
 
 	return nil
 }
 
-// RegisterCCKeys  registers a CCKeyRegistration message that confirms that an enclave is provisioned with the chaincode encryption key.
-// This method is used during the key generation and key distribution protocol. In particular, during key generation,
-// this call sets the chaincode_ek for a chaincode if no chaincode_ek is set yet.
-func (rs *Contract) RegisterCCKeys(ctx contractapi.TransactionContextInterface, ccKeyRegistrationMessageBase64 string) error {
-	// TODO: Implement me once we remove spec-short-cut,see QueryChaincodeEncryptionKey & RegisterEnclave (Post-MVP)
+// removeApprovals removes all approvals of the proposals identified by the attributes
+func removeApprovals(ctx contractapi.TransactionContextInterface, namespace string, attributes []string) error {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(namespace, attributes)
+	if iter != nil {
+		defer iter.Close()
+	}
+	if err != nil {
+		return err
+	}
 
-	//input msg CCKeyRegistrationMessage
+	var keys []string
+	for iter != nil && iter.HasNext() {
+		q, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, q.Key)
+	}
 
-	return fmt.Errorf("not implemented yet")
+	for _, k := range keys {
+		if err := ctx.GetStub().DelState(k); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// PutKeyExport register key export (Post-MVP feature)
-func (rs *Contract) PutKeyExport(ctx contractapi.TransactionContextInterface, exportMessageBase64 string) error {
-	// input msg ExportMessage
-	// TODO implement me (Post-MVP)
-	return fmt.Errorf("not implemented yet")
+// QueryVerificationPolicy returns the (base64-encoded) AttestationVerificationPolicy for a chaincode.
+// If no policy is set, an empty string is returned.
+func (rs *Contract) QueryVerificationPolicy(ctx contractapi.TransactionContextInterface, chaincodeId string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/verification_policy", []string{chaincodeId})
+	if err != nil {
+		return "", err
+	}
+
+	policyBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(policyBytes), nil
 }
 
-// GetKeyExport retrieve key export (Post-MVP feature)
-func (rs *Contract) GetKeyExport(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) (string, error) {
-	//input chaincodeId string, enclaveId string
-	//return *ExportMessage or  error
-	// TODO implement me (Post-MVP)
-	return "", fmt.Errorf("not implemented yet")
+// getVerificationPolicy returns the verification policy of a chaincode or nil, if no policy is set,
+// in which case the verifiers apply their default policy
+func getVerificationPolicy(ctx contractapi.TransactionContextInterface, chaincodeId string) (*protos.AttestationVerificationPolicy, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/verification_policy", []string{chaincodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	policyBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policyBytes) == 0 {
+		return nil, nil
+	}
+
+	policy := &protos.AttestationVerificationPolicy{}
+	if err := proto.Unmarshal(policyBytes, policy); err != nil {
+		return nil, errors.Wrap(err, "invalid verification policy")
+	}
+
+	return policy, nil
+}
+
+func getDeploymentPolicy(ctx contractapi.TransactionContextInterface, chaincodeId string) (*protos.FPCDeploymentPolicy, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/deployment_policy", []string{chaincodeId})
+	if err != nil {
+		return nil, err
+	}
+
+	policyBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// if no policy is set, we use an empty policy, which does not restrict the deployment
+	policy := &protos.FPCDeploymentPolicy{}
+	if err := proto.Unmarshal(policyBytes, policy); err != nil {
+		return nil, errors.Wrap(err, "invalid deployment policy")
+	}
+
+	return policy, nil
+}
+
+// checkDeploymentPolicy checks that a new enclave satisfies the FPC deployment policy of the chaincode
+// and is consistent with the enclaves already registered for the chaincode.
+// Enclaves registered for an earlier chaincode definition (sequence) cannot endorse anymore and are therefore ignored.
+func checkDeploymentPolicy(ctx contractapi.TransactionContextInterface, attestedData *protos.AttestedData, enclaveId string) error {
+	chaincodeId := attestedData.CcParams.ChaincodeId
+	mspId := attestedData.HostParams.PeerMspId
+
+	policy, err := getDeploymentPolicy(ctx, chaincodeId)
+	if err != nil {
+		return err
+	}
+
+	if len(policy.GetAllowedMspIds()) > 0 && !contains(policy.GetAllowedMspIds(), mspId) {
+		return fmt.Errorf("msp %s is not allowed to host an enclave for chaincode %s", mspId, chaincodeId)
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey("namespaces/credentials", []string{chaincodeId})
+	if iter != nil {
+		defer iter.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	var numEnclaves, numEnclavesOfMsp uint32
+	for iter != nil && iter.HasNext() {
+		q, err := iter.Next()
+		if err != nil {
+			return err
+		}
+
+		_, res, err := ctx.GetStub().SplitCompositeKey(q.Key)
+		if err != nil {
+			return err
+		}
+
+		if len(res) == 2 && res[1] == enclaveId {
+			return fmt.Errorf("enclave %s is already registered", enclaveId)
+		}
+
+		credentials, err := utils.UnmarshalCredentials(string(q.Value))
+		if err != nil {
+			return err
+		}
+
+		registered, err := utils.UnmarshalAttestedData(credentials.SerializedAttestedData)
+		if err != nil {
+			return err
+		}
+
+		if registered.GetCcParams().GetSequence() != attestedData.CcParams.Sequence {
+			continue
+		}
+
+		// a peer hosts at most one enclave per chaincode
+		if registered.GetHostParams().GetPeerEndpoint() == attestedData.HostParams.PeerEndpoint {
+			return fmt.Errorf("an enclave is already registered for peer endpoint %s", attestedData.HostParams.PeerEndpoint)
+		}
+
+		numEnclaves++
+		if registered.GetHostParams().GetPeerMspId() == mspId {
+			numEnclavesOfMsp++
+		}
+	}
+
+	if policy.GetMaxEnclaves() > 0 && numEnclaves >= policy.GetMaxEnclaves() {
+		return fmt.Errorf("maximum number of enclaves (%d) reached for chaincode %s", policy.GetMaxEnclaves(), chaincodeId)
+	}
+
+	if policy.GetMaxEnclavesPerMsp() > 0 && numEnclavesOfMsp >= policy.GetMaxEnclavesPerMsp() {
+		return fmt.Errorf("maximum number of enclaves (%d) reached for msp %s", policy.GetMaxEnclavesPerMsp(), mspId)
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterCCKeys  registers a CCKeyRegistration message that confirms that an enclave is provisioned with the chaincode encryption key.
+// This method is used during the key generation and key distribution protocol. In particular, during key generation,
+// this call sets the chaincode_ek for a chaincode if no chaincode_ek is set yet.
+// The message must be issued by an enclave registered for the given chaincode.
+func (rs *Contract) RegisterCCKeys(ctx contractapi.TransactionContextInterface, chaincodeId string, signedCCKeyRegistrationMessageBase64 string) error {
+	logger.Debugf("RegisterCCKeys")
+
+	signedMsgBytes, err := base64.StdEncoding.DecodeString(signedCCKeyRegistrationMessageBase64)
+	if err != nil {
+		return errors.Wrap(err, "invalid cckey registration message bytes")
+	}
+
+	signedMsg, err := utils.UnmarshalSignedCCKeyRegistrationMessage(signedMsgBytes)
+	if err != nil {
+		return err
+	}
+
+	msg, err := utils.UnmarshalCCKeyRegistrationMessage(signedMsg.GetSerializedCckeyRegMsg())
+	if err != nil {
+		return err
+	}
+
+	// check that the enclave is registered
+	enclaveId := strings.ToUpper(hex.EncodeToString(msg.GetEnclaveId()))
+	attestedData, err := getRegisteredEnclave(ctx, chaincodeId, enclaveId)
+	if err != nil {
+		return err
+	}
+	if attestedData == nil {
+		return fmt.Errorf("enclave %s not registered for chaincode %s", enclaveId, chaincodeId)
+	}
+
+	if err := checkEnclaveSignature(attestedData.EnclaveVk, signedMsg.GetSerializedCckeyRegMsg().GetValue(), signedMsg.GetSignature()); err != nil {
+		return err
+	}
+
+	if err := checkCCParamsHash(ctx, chaincodeId, msg.GetCcParamsHash()); err != nil {
+		return err
+	}
+
+	// check that registration transaction creator has same mspid as the enclave owner
+	if err := checkCreator(ctx, rs.IEvaluator, attestedData); err != nil {
+		return err
+	}
+
+	if err := registerChaincodeEk(ctx, chaincodeId, msg.GetChaincodeEk()); err != nil {
+		return err
+	}
+
+	// store registration message as proof that the enclave is provisioned with the chaincode keys
+	provisionedKey, err := ctx.GetStub().CreateCompositeKey("namespaces/provisioned", []string{chaincodeId, enclaveId})
+	if err != nil {
+		return fmt.Errorf("cannot create provisionedKey: %s", err)
+	}
+	if err := ctx.GetStub().PutState(provisionedKey, []byte(signedCCKeyRegistrationMessageBase64)); err != nil {
+		return fmt.Errorf("cannot store provisionedKey: %s", err)
+	}
+
+	logger.Debugf("RegisterCCKeys successful")
+
+	return nil
+}
+
+// registerChaincodeEk sets the chaincode_ek of a chaincode if no chaincode_ek is set yet; otherwise the chaincode_ek must match
+func registerChaincodeEk(ctx contractapi.TransactionContextInterface, chaincodeId string, chaincodeEk []byte) error {
+	ekKey, err := ctx.GetStub().CreateCompositeKey("namespaces/chaincode_ek", []string{chaincodeId})
+	if err != nil {
+		return err
+	}
+
+	registeredEk, err := ctx.GetStub().GetState(ekKey)
+	if err != nil {
+		return err
+	}
+
+	if len(registeredEk) == 0 {
+		logger.Debugf("Registering chaincode_ek for chaincode %s", chaincodeId)
+		if err := ctx.GetStub().PutState(ekKey, chaincodeEk); err != nil {
+			return fmt.Errorf("cannot store chaincode_ek: %s", err)
+		}
+	} else if !bytes.Equal(registeredEk, chaincodeEk) {
+		return fmt.Errorf("chaincode_ek does not match registered chaincode_ek")
+	}
+
+	return nil
+}
+
+// PutKeyExport registers a SignedExportMessage, which contains the chaincode keys encrypted for a registered receiver enclave.
+// The receiver enclave retrieves the message via GetKeyExport. Sender and receiver must be registered for the given chaincode.
+func (rs *Contract) PutKeyExport(ctx contractapi.TransactionContextInterface, chaincodeId string, signedExportMessageBase64 string) error {
+	logger.Debugf("PutKeyExport")
+
+	signedMsgBytes, err := base64.StdEncoding.DecodeString(signedExportMessageBase64)
+	if err != nil {
+		return errors.Wrap(err, "invalid export message bytes")
+	}
+
+	signedMsg, err := utils.UnmarshalSignedExportMessage(signedMsgBytes)
+	if err != nil {
+		return err
+	}
+
+	msg, err := utils.UnmarshalExportMessage(signedMsg.GetSerializedExportMsgBytes())
+	if err != nil {
+		return err
+	}
+
+	// check that the sender is a registered enclave
+	senderId := utils.GetEnclaveId(&protos.AttestedData{EnclaveVk: msg.GetSenderEnclaveVk()})
+	senderAttestedData, err := getRegisteredEnclave(ctx, chaincodeId, senderId)
+	if err != nil {
+		return err
+	}
+	if senderAttestedData == nil {
+		return fmt.Errorf("sender enclave %s not registered for chaincode %s", senderId, chaincodeId)
+	}
+
+	// check that the receiver is a registered enclave of the same chaincode
+	receiverId := utils.GetEnclaveId(&protos.AttestedData{EnclaveVk: msg.GetReceiverEnclaveVk()})
+	receiverCredentialsBase64, err := rs.QueryEnclaveCredentials(ctx, chaincodeId, receiverId)
+	if err != nil {
+		return err
+	}
+	if len(receiverCredentialsBase64) == 0 {
+		return fmt.Errorf("receiver enclave %s not registered for chaincode %s", receiverId, chaincodeId)
+	}
+
+	if err := checkEnclaveSignature(msg.GetSenderEnclaveVk(), signedMsg.GetSerializedExportMsgBytes().GetValue(), signedMsg.GetSignature()); err != nil {
+		return err
+	}
+
+	if err := checkCCParamsHash(ctx, chaincodeId, msg.GetCcParamsHash()); err != nil {
+		return err
+	}
+
+	// check that the sender is provisioned with the chaincode keys it exports
+	provisionedKey, err := ctx.GetStub().CreateCompositeKey("namespaces/provisioned", []string{chaincodeId, senderId})
+	if err != nil {
+		return err
+	}
+	provisioned, err := ctx.GetStub().GetState(provisionedKey)
+	if err != nil {
+		return err
+	}
+	if len(provisioned) == 0 {
+		return fmt.Errorf("sender enclave %s is not provisioned", senderId)
+	}
+
+	ekKey, err := ctx.GetStub().CreateCompositeKey("namespaces/chaincode_ek", []string{chaincodeId})
+	if err != nil {
+		return err
+	}
+	registeredEk, err := ctx.GetStub().GetState(ekKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(registeredEk, msg.GetChaincodeEk()) {
+		return fmt.Errorf("chaincode_ek does not match registered chaincode_ek")
+	}
+
+	// check that export transaction creator has same mspid as the receiver enclave owner;
+	// note that the sender enclave may be hosted by another organization
+	receiverCredentials, err := utils.UnmarshalCredentials(receiverCredentialsBase64)
+	if err != nil {
+		return err
+	}
+	receiverAttestedData, err := utils.UnmarshalAttestedData(receiverCredentials.SerializedAttestedData)
+	if err != nil {
+		return err
+	}
+	if err := checkCreator(ctx, rs.IEvaluator, receiverAttestedData); err != nil {
+		return err
+	}
+
+	exportKey, err := ctx.GetStub().CreateCompositeKey("namespaces/exported", []string{chaincodeId, receiverId})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(exportKey, []byte(signedExportMessageBase64)); err != nil {
+		return fmt.Errorf("cannot store export message: %s", err)
+	}
+
+	logger.Debugf("PutKeyExport successful")
+
+	return nil
+}
+
+// GetKeyExport returns the (base64-encoded) SignedExportMessage registered for a given chaincode and receiver enclave id
+func (rs *Contract) GetKeyExport(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/exported", []string{chaincodeId, enclaveId})
+	if err != nil {
+		return "", err
+	}
+
+	signedExportMessageBase64, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(signedExportMessageBase64) == 0 {
+		return "", fmt.Errorf("no key export found for enclave %s", enclaveId)
+	}
+
+	return string(signedExportMessageBase64), nil
+}
+
+// DeregisterEnclave removes a registered enclave from the enclave registry, that is, its credentials, its cc key registration, and its key export.
+// Only the organization hosting the enclave can deregister it.
+// Note that a deregistered enclave may register again; use RevokeEnclave to permanently exclude an enclave.
+func (rs *Contract) DeregisterEnclave(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) error {
+	logger.Debugf("DeregisterEnclave")
+
+	attestedData, err := getRegisteredEnclave(ctx, chaincodeId, enclaveId)
+	if err != nil {
+		return err
+	}
+	if attestedData == nil {
+		return fmt.Errorf("enclave %s not registered for chaincode %s", enclaveId, chaincodeId)
+	}
+
+	// check that deregister transaction creator has same mspid as the enclave owner
+	if err := checkCreator(ctx, rs.IEvaluator, attestedData); err != nil {
+		return err
+	}
+
+	return removeEnclave(ctx, chaincodeId, enclaveId)
+}
+
+// RevokeEnclave revokes an enclave of a chaincode, for instance, if its enclave key has leaked.
+// A revoked enclave is removed from the enclave registry, added to the revocation list, and cannot register again.
+// Moreover, ECC refuses to endorse responses signed by a revoked enclave.
+//
+// A revocation requested by an admin of the organization hosting the enclave takes effect immediately.
+// Otherwise, the revocation must be requested by admins of a majority of the organizations of the chaincode, where each call
+// records the vote of the creator's organization. The organizations are given by the allowed MSP IDs of the FPC deployment policy
+// or, if the policy does not restrict them, by the organizations hosting enclaves for the chaincode.
+func (rs *Contract) RevokeEnclave(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) error {
+	logger.Debugf("RevokeEnclave")
+
+	revoked, err := rs.QueryEnclaveRevoked(ctx, chaincodeId, enclaveId)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("enclave %s is already revoked", enclaveId)
+	}
+
+	creatorIdentityBytes, err := ctx.GetStub().GetCreator()
+	if err != nil {
+		return err
+	}
+
+	// only admins can revoke enclaves
+	mspId, err := rs.IEvaluator.EvaluateAdminIdentity(creatorIdentityBytes, ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("creator identity evaluation failed: %s", err)
+	}
+
+	// an admin of the enclave owner can revoke its enclave directly
+	attestedData, err := getRegisteredEnclave(ctx, chaincodeId, enclaveId)
+	if err != nil {
+		return err
+	}
+	if attestedData != nil && attestedData.GetHostParams().GetPeerMspId() == mspId {
+		return revokeEnclave(ctx, chaincodeId, enclaveId)
+	}
+
+	// otherwise, we record the vote of the creator's organization
+	orgs, err := getRevocationOrgs(ctx, chaincodeId)
+	if err != nil {
+		return err
+	}
+	if !contains(orgs, mspId) {
+		return fmt.Errorf("msp %s is not authorized to revoke enclaves of chaincode %s", mspId, chaincodeId)
+	}
+
+	approved, err := recordApproval(ctx, "namespaces/revocation_votes", []string{chaincodeId, enclaveId}, mspId, orgs)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		logger.Debugf("recorded revocation vote of %s for enclave %s", mspId, enclaveId)
+		return nil
+	}
+
+	return revokeEnclave(ctx, chaincodeId, enclaveId)
+}
+
+// recordApproval records the approval of a proposal, identified by the attributes, by the creator's organization and returns
+// true if admins of a majority of the organizations approved the proposal. In this case, all approvals of the proposal are
+// removed; otherwise, the approval is stored.
+func recordApproval(ctx contractapi.TransactionContextInterface, namespace string, attributes []string, mspId string, orgs []string) (bool, error) {
+	voteKey, err := ctx.GetStub().CreateCompositeKey(namespace, append(append([]string{}, attributes...), mspId))
+	if err != nil {
+		return false, err
+	}
+
+	// note that our own vote is not visible to the range query within this transaction
+	votes := 1
+	var otherVoteKeys []string
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey(namespace, attributes)
+	if iter != nil {
+		defer iter.Close()
+	}
+	if err != nil {
+		return false, err
+	}
+	for iter != nil && iter.HasNext() {
+		q, err := iter.Next()
+		if err != nil {
+			return false, err
+		}
+
+		_, res, err := ctx.GetStub().SplitCompositeKey(q.Key)
+		if err != nil {
+			return false, err
+		}
+
+		if q.Key != voteKey {
+			otherVoteKeys = append(otherVoteKeys, q.Key)
+			if len(res) == len(attributes)+1 && contains(orgs, res[len(attributes)]) {
+				votes++
+			}
+		}
+	}
+
+	if votes <= len(orgs)/2 {
+		logger.Debugf("%d of %d organizations approved %v", votes, len(orgs), attributes)
+		return false, ctx.GetStub().PutState(voteKey, []byte(ctx.GetStub().GetTxID()))
+	}
+
+	// majority reached, cleanup votes
+	for _, k := range otherVoteKeys {
+		if err := ctx.GetStub().DelState(k); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// QueryListRevokedEnclaves returns the revocation list, i.e., the enclave ids of all revoked enclaves for a given chaincode id.
+func (rs *Contract) QueryListRevokedEnclaves(ctx contractapi.TransactionContextInterface, chaincodeId string) ([]string, error) {
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey("namespaces/revoked", []string{chaincodeId})
+	if iter != nil {
+		defer iter.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var enclaveIds []string
+	for iter != nil && iter.HasNext() {
+		q, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, res, err := ctx.GetStub().SplitCompositeKey(q.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		enclaveIds = append(enclaveIds, res[1])
+	}
+
+	return enclaveIds, nil
+}
+
+// QueryEnclaveRevoked returns true if the enclave with the given enclave id is revoked for a given chaincode id.
+func (rs *Contract) QueryEnclaveRevoked(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/revoked", []string{chaincodeId, enclaveId})
+	if err != nil {
+		return false, err
+	}
+
+	v, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, err
+	}
+
+	return v != nil, nil
+}
+
+// getRegisteredEnclave returns the attested data of a registered enclave or nil if the enclave is not registered for the chaincode
+func getRegisteredEnclave(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) (*protos.AttestedData, error) {
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/credentials", []string{chaincodeId, enclaveId})
+	if err != nil {
+		return nil, err
+	}
+
+	credentialsBase64, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if credentialsBase64 == nil {
+		return nil, nil
+	}
+
+	credentials, err := utils.UnmarshalCredentials(string(credentialsBase64))
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.UnmarshalAttestedData(credentials.SerializedAttestedData)
+}
+
+// getRevocationOrgs returns the organizations that can jointly revoke enclaves of a chaincode
+func getRevocationOrgs(ctx contractapi.TransactionContextInterface, chaincodeId string) ([]string, error) {
+	policy, err := getDeploymentPolicy(ctx, chaincodeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(policy.GetAllowedMspIds()) > 0 {
+		return policy.GetAllowedMspIds(), nil
+	}
+
+	iter, err := ctx.GetStub().GetStateByPartialCompositeKey("namespaces/credentials", []string{chaincodeId})
+	if iter != nil {
+		defer iter.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var orgs []string
+	for iter != nil && iter.HasNext() {
+		q, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		credentials, err := utils.UnmarshalCredentials(string(q.Value))
+		if err != nil {
+			return nil, err
+		}
+
+		attestedData, err := utils.UnmarshalAttestedData(credentials.SerializedAttestedData)
+		if err != nil {
+			return nil, err
+		}
+
+		if mspId := attestedData.GetHostParams().GetPeerMspId(); !contains(orgs, mspId) {
+			orgs = append(orgs, mspId)
+		}
+	}
+
+	if len(orgs) == 0 {
+		return nil, fmt.Errorf("no organizations found to revoke enclaves of chaincode %s", chaincodeId)
+	}
+
+	return orgs, nil
+}
+
+// removeEnclave removes all registry entries of an enclave
+func removeEnclave(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) error {
+	for _, namespace := range []string{"namespaces/credentials", "namespaces/provisioned", "namespaces/exported"} {
+		key, err := ctx.GetStub().CreateCompositeKey(namespace, []string{chaincodeId, enclaveId})
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("cannot remove %s: %s", key, err)
+		}
+	}
+
+	return nil
+}
+
+// revokeEnclave removes an enclave from the registry and adds it to the revocation list
+func revokeEnclave(ctx contractapi.TransactionContextInterface, chaincodeId, enclaveId string) error {
+	if err := removeEnclave(ctx, chaincodeId, enclaveId); err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey("namespaces/revoked", []string{chaincodeId, enclaveId})
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.GetStub().PutState(key, []byte(ctx.GetStub().GetTxID())); err != nil {
+		return fmt.Errorf("cannot store revocation: %s", err)
+	}
+
+	return nil
+}
+
+func checkEnclaveSignature(enclaveVk, msg, signature []byte) error {
+	if err := crypto.GetDefaultCSP().VerifyMessage(enclaveVk, msg, signature); err != nil {
+		return fmt.Errorf("enclave signature verification failed: %s", err)
+	}
+	return nil
+}
+
+// checkCCParamsHash checks that the cc_params_hash corresponds to the current chaincode definition
+func checkCCParamsHash(ctx contractapi.TransactionContextInterface, chaincodeId string, ccParamsHash []byte) error {
+	ccDef, err := utils.GetChaincodeDefinition(chaincodeId, ctx.GetStub())
+	if err != nil {
+		return fmt.Errorf("cannot get chaincode definition: %s", err)
+	}
+
+	expectedHash, err := utils.GetCCParamsHash(&protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     ccDef.Version,
+		Sequence:    ccDef.Sequence,
+		ChannelId:   ctx.GetStub().GetChannelID(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(expectedHash, ccParamsHash) {
+		return fmt.Errorf("cc_params_hash does not match chaincode definition")
+	}
+
+	return nil
+}
+
+func checkCreator(ctx contractapi.TransactionContextInterface, ie utils.IdentityEvaluatorInterface, attestedData *protos.AttestedData) error {
+	creatorIdentityBytes, err := ctx.GetStub().GetCreator()
+	if err != nil {
+		return err
+	}
+
+	if err := ie.EvaluateCreatorIdentity(creatorIdentityBytes, attestedData.GetHostParams().GetPeerMspId()); err != nil {
+		return fmt.Errorf("creator identity evaluation failed: %s", err)
+	}
+
+	return nil
 }
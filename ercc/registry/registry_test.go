@@ -8,21 +8,29 @@ SPDX-License-Identifier: Apache-2.0
 package registry_test
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"testing"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/hyperledger/fabric-private-chaincode/ercc/registry"
 	"github.com/hyperledger/fabric-private-chaincode/ercc/registry/fakes"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation"
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric-protos-go/peer/lifecycle"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -347,3 +355,830 @@ func TestQueryEnclaveCredentials(t *testing.T) {
 	require.Empty(t, resp)
 	require.NoError(t, err)
 }
+
+type testEnclave struct {
+	vk         []byte
+	sk         []byte
+	id         string
+	hostParams *protos.HostParameters
+	// chaincodeEk is included in the attested data, if set
+	chaincodeEk []byte
+}
+
+func newTestEnclave(t *testing.T) *testEnclave {
+	vk, sk, err := crypto.NewGoCrypto().NewECDSAKeys()
+	require.NoError(t, err)
+	id := utils.GetEnclaveId(&protos.AttestedData{EnclaveVk: vk})
+	return &testEnclave{vk: vk, sk: sk, id: id, hostParams: &protos.HostParameters{
+		PeerMspId:    someMspId,
+		PeerEndpoint: "peer-" + id[:8] + ":7051",
+	}}
+}
+
+func (e *testEnclave) sign(t *testing.T, msg []byte) []byte {
+	sig, err := crypto.NewGoCrypto().SignMessage(e.sk, msg)
+	require.NoError(t, err)
+	return sig
+}
+
+func (e *testEnclave) credentials(ccParams *protos.CCParameters) string {
+	serializedAttestedData, _ := anypb.New(&protos.AttestedData{
+		EnclaveVk:   e.vk,
+		CcParams:    ccParams,
+		HostParams:  e.hostParams,
+		ChaincodeEk: e.chaincodeEk,
+	})
+	return toBase64(&protos.Credentials{
+		Evidence:               []byte("some mock evidence"),
+		SerializedAttestedData: serializedAttestedData,
+	})
+}
+
+func (e *testEnclave) ccKeyRegistrationMessage(t *testing.T, ccParamsHash, chaincodeEk []byte) string {
+	enclaveId := sha256.Sum256(e.vk)
+	serializedMsg, err := anypb.New(&protos.CCKeyRegistrationMessage{
+		CcParamsHash: ccParamsHash,
+		ChaincodeEk:  chaincodeEk,
+		EnclaveId:    enclaveId[:],
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(&protos.SignedCCKeyRegistrationMessage{
+		SerializedCckeyRegMsg: serializedMsg,
+		Signature:             e.sign(t, serializedMsg.GetValue()),
+	}))
+}
+
+func (e *testEnclave) exportMessage(t *testing.T, receiver *testEnclave, ccParamsHash, chaincodeEk []byte) string {
+	serializedMsg, err := anypb.New(&protos.ExportMessage{
+		CcParamsHash:      ccParamsHash,
+		ChaincodeEk:       chaincodeEk,
+		CckeysEnc:         []byte("some encrypted keys"),
+		ReceiverEnclaveVk: receiver.vk,
+		SenderEnclaveVk:   e.vk,
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(&protos.SignedExportMessage{
+		SerializedExportMsgBytes: serializedMsg,
+		Signature:                e.sign(t, serializedMsg.GetValue()),
+	}))
+}
+
+// newStatefulStub returns a fake chaincode stub that keeps its state in memory
+func newStatefulStub() (*fakes.ChaincodeStub, *shimtest.MockStub) {
+	state := shimtest.NewMockStub("ercc", nil)
+	state.MockTransactionStart("someTxId")
+
+	chaincodeStub := &fakes.ChaincodeStub{}
+	chaincodeStub.GetStateStub = state.GetState
+	chaincodeStub.PutStateStub = state.PutState
+	chaincodeStub.DelStateStub = state.DelState
+	chaincodeStub.CreateCompositeKeyStub = state.CreateCompositeKey
+	chaincodeStub.SplitCompositeKeyStub = state.SplitCompositeKey
+	chaincodeStub.GetStateByPartialCompositeKeyStub = state.GetStateByPartialCompositeKey
+	chaincodeStub.GetChannelIDReturns(channelId)
+	chaincodeStub.GetTxIDReturns("someTxId")
+	chaincodeStub.GetCreatorReturns([]byte("fake creator"), nil)
+	setChannelOrgs(chaincodeStub, someMspId)
+	return chaincodeStub, state
+}
+
+// setChannelOrgs lets the stub return a channel config with the given organizations when invoking cscc
+// and the chaincode definition otherwise
+func setChannelOrgs(chaincodeStub *fakes.ChaincodeStub, mspIds ...string) {
+	groups := map[string]*common.ConfigGroup{}
+	for _, mspId := range mspIds {
+		mspConfig := protoutil.MarshalOrPanic(&msp.MSPConfig{Config: protoutil.MarshalOrPanic(&msp.FabricMSPConfig{Name: mspId})})
+		groups[mspId] = &common.ConfigGroup{Values: map[string]*common.ConfigValue{"MSP": {Value: mspConfig}}}
+	}
+	channelConfig := protoutil.MarshalOrPanic(&common.Config{
+		ChannelGroup: &common.ConfigGroup{Groups: map[string]*common.ConfigGroup{"Application": {Groups: groups}}},
+	})
+	ccDef := protoutil.MarshalOrPanic(&lifecycle.QueryChaincodeDefinitionResult{
+		Version:  mrenclave,
+		Sequence: 1,
+	})
+
+	chaincodeStub.InvokeChaincodeCalls(func(name string, args [][]byte, channel string) pb.Response {
+		if name == "cscc" {
+			return shim.Success(channelConfig)
+		}
+		return shim.Success(ccDef)
+	})
+}
+
+func putCredentials(t *testing.T, state *shimtest.MockStub, e *testEnclave, ccParams *protos.CCParameters) {
+	key, err := state.CreateCompositeKey("namespaces/credentials", []string{ccParams.ChaincodeId, e.id})
+	require.NoError(t, err)
+	require.NoError(t, state.PutState(key, []byte(e.credentials(ccParams))))
+}
+
+func TestRegisterCCKeys(t *testing.T) {
+	chaincodeStub, state := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.IEvaluator = id
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+	ccParamsHash, err := utils.GetCCParamsHash(ccParams)
+	require.NoError(t, err)
+	chaincodeEk := []byte("some chaincode ek")
+
+	enclave := newTestEnclave(t)
+	otherEnclave := newTestEnclave(t)
+
+	// no chaincode ek registered yet
+	ek, err := ercc.QueryChaincodeEncryptionKey(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, ek)
+
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, "some bytes")
+	require.Contains(t, err.Error(), "invalid cckey registration message bytes")
+
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, "")
+	require.EqualError(t, err, "SignedCCKeyRegistrationMessage is empty")
+
+	// enclave not registered
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, enclave.ccKeyRegistrationMessage(t, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, fmt.Sprintf("enclave %s not registered for chaincode %s", enclave.id, chaincodeId))
+
+	putCredentials(t, state, enclave, ccParams)
+	putCredentials(t, state, otherEnclave, ccParams)
+
+	// message signed by other enclave
+	b, _ := base64.StdEncoding.DecodeString(enclave.ccKeyRegistrationMessage(t, ccParamsHash, chaincodeEk))
+	signedMsg, err := utils.UnmarshalSignedCCKeyRegistrationMessage(b)
+	require.NoError(t, err)
+	signedMsg.Signature = otherEnclave.sign(t, signedMsg.GetSerializedCckeyRegMsg().GetValue())
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(signedMsg)))
+	require.Contains(t, err.Error(), "enclave signature verification failed")
+
+	// wrong cc params hash
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, enclave.ccKeyRegistrationMessage(t, []byte("wrong hash"), chaincodeEk))
+	require.EqualError(t, err, "cc_params_hash does not match chaincode definition")
+
+	// creator mismatch
+	id.EvaluateCreatorIdentityReturns(fmt.Errorf("msp does not match"))
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, enclave.ccKeyRegistrationMessage(t, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, "creator identity evaluation failed: msp does not match")
+	id.EvaluateCreatorIdentityReturns(nil)
+
+	// not provisioned yet
+	registration, err := ercc.QueryCCKeyRegistration(transactionContext, chaincodeId, enclave.id)
+	require.NoError(t, err)
+	require.Empty(t, registration)
+
+	// success
+	signedMsgBase64 := enclave.ccKeyRegistrationMessage(t, ccParamsHash, chaincodeEk)
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, signedMsgBase64)
+	require.NoError(t, err)
+
+	registration, err = ercc.QueryCCKeyRegistration(transactionContext, chaincodeId, enclave.id)
+	require.NoError(t, err)
+	require.Equal(t, signedMsgBase64, registration)
+
+	ek, err = ercc.QueryChaincodeEncryptionKey(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, base64.StdEncoding.EncodeToString(chaincodeEk), ek)
+
+	provisioned, err := ercc.QueryListProvisionedEnclaves(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, []string{enclave.id}, provisioned)
+
+	// other enclave must register the same chaincode ek
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, otherEnclave.ccKeyRegistrationMessage(t, ccParamsHash, []byte("other chaincode ek")))
+	require.EqualError(t, err, "chaincode_ek does not match registered chaincode_ek")
+
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, otherEnclave.ccKeyRegistrationMessage(t, ccParamsHash, chaincodeEk))
+	require.NoError(t, err)
+
+	provisioned, err = ercc.QueryListProvisionedEnclaves(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{enclave.id, otherEnclave.id}, provisioned)
+}
+
+func TestRegisterEnclaveWithAttestedChaincodeEk(t *testing.T) {
+	chaincodeStub, state := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+
+	ercc := registry.Contract{}
+	ercc.Verifier = &fakes.CredentialVerifier{}
+	ercc.IEvaluator = &fakes.IdentityEvaluator{}
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+
+	// enclaves registered without the chaincode_ek being set (e.g., by an earlier ERCC version) are still served
+	legacy := newTestEnclave(t)
+	legacy.chaincodeEk = []byte("some legacy chaincode ek")
+	putCredentials(t, state, legacy, &protos.CCParameters{ChaincodeId: chaincodeId})
+	ek, err := ercc.QueryChaincodeEncryptionKey(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, base64.StdEncoding.EncodeToString(legacy.chaincodeEk), ek)
+	require.NoError(t, ercc.DeregisterEnclave(transactionContext, chaincodeId, legacy.id))
+
+	// an enclave attesting its chaincode_ek is provisioned with its registration
+	enclave := newTestEnclave(t)
+	enclave.chaincodeEk = []byte("some chaincode ek")
+	err = ercc.RegisterEnclave(transactionContext, enclave.credentials(ccParams))
+	require.NoError(t, err)
+
+	ek, err = ercc.QueryChaincodeEncryptionKey(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, base64.StdEncoding.EncodeToString(enclave.chaincodeEk), ek)
+
+	provisioned, err := ercc.QueryListProvisionedEnclaves(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, []string{enclave.id}, provisioned)
+
+	// there is no separate cc key registration message
+	registration, err := ercc.QueryCCKeyRegistration(transactionContext, chaincodeId, enclave.id)
+	require.NoError(t, err)
+	require.Empty(t, registration)
+
+	endpoints, err := ercc.QueryChaincodeEndPoints(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, enclave.hostParams.PeerEndpoint, endpoints)
+
+	// other enclaves must attest the same chaincode_ek
+	otherEnclave := newTestEnclave(t)
+	otherEnclave.chaincodeEk = []byte("other chaincode ek")
+	err = ercc.RegisterEnclave(transactionContext, otherEnclave.credentials(ccParams))
+	require.EqualError(t, err, "chaincode_ek does not match registered chaincode_ek")
+}
+
+func TestKeyExport(t *testing.T) {
+	chaincodeStub, state := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.IEvaluator = id
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+	ccParamsHash, err := utils.GetCCParamsHash(ccParams)
+	require.NoError(t, err)
+	chaincodeEk := []byte("some chaincode ek")
+
+	sender := newTestEnclave(t)
+	receiver := newTestEnclave(t)
+
+	// no export available
+	resp, err := ercc.GetKeyExport(transactionContext, chaincodeId, receiver.id)
+	require.Empty(t, resp)
+	require.EqualError(t, err, fmt.Sprintf("no key export found for enclave %s", receiver.id))
+
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, "")
+	require.EqualError(t, err, "SignedExportMessage is empty")
+
+	// sender not registered
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, fmt.Sprintf("sender enclave %s not registered for chaincode %s", sender.id, chaincodeId))
+
+	// receiver not registered
+	putCredentials(t, state, sender, ccParams)
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, fmt.Sprintf("receiver enclave %s not registered for chaincode %s", receiver.id, chaincodeId))
+
+	// receiver registered for other chaincode
+	putCredentials(t, state, receiver, &protos.CCParameters{ChaincodeId: "otherChaincode"})
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, fmt.Sprintf("receiver enclave %s not registered for chaincode %s", receiver.id, chaincodeId))
+
+	putCredentials(t, state, receiver, ccParams)
+
+	// message signed by receiver
+	b, _ := base64.StdEncoding.DecodeString(sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk))
+	signedMsg, err := utils.UnmarshalSignedExportMessage(b)
+	require.NoError(t, err)
+	signedMsg.Signature = receiver.sign(t, signedMsg.GetSerializedExportMsgBytes().GetValue())
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(signedMsg)))
+	require.Contains(t, err.Error(), "enclave signature verification failed")
+
+	// wrong cc params hash
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, []byte("wrong hash"), chaincodeEk))
+	require.EqualError(t, err, "cc_params_hash does not match chaincode definition")
+
+	// sender not provisioned
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, fmt.Sprintf("sender enclave %s is not provisioned", sender.id))
+
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, sender.ccKeyRegistrationMessage(t, ccParamsHash, chaincodeEk))
+	require.NoError(t, err)
+
+	// wrong chaincode ek
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, ccParamsHash, []byte("other chaincode ek")))
+	require.EqualError(t, err, "chaincode_ek does not match registered chaincode_ek")
+
+	// creator mismatch
+	id.EvaluateCreatorIdentityReturns(fmt.Errorf("msp does not match"))
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk))
+	require.EqualError(t, err, "creator identity evaluation failed: msp does not match")
+	id.EvaluateCreatorIdentityReturns(nil)
+
+	// success
+	exportMsg := sender.exportMessage(t, receiver, ccParamsHash, chaincodeEk)
+	err = ercc.PutKeyExport(transactionContext, chaincodeId, exportMsg)
+	require.NoError(t, err)
+
+	resp, err = ercc.GetKeyExport(transactionContext, chaincodeId, receiver.id)
+	require.NoError(t, err)
+	require.Equal(t, exportMsg, resp)
+}
+
+func TestDeploymentPolicy(t *testing.T) {
+	chaincodeStub, _ := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	verifier := &fakes.CredentialVerifier{}
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.Verifier = verifier
+	ercc.IEvaluator = id
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+	otherMspId := "other org"
+
+	// no policy set
+	resp, err := ercc.QueryDeploymentPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, "some bytes")
+	require.Contains(t, err.Error(), "invalid deployment policy bytes")
+
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(
+		&protos.FPCDeploymentPolicy{MaxEnclaves: 1, MaxEnclavesPerMsp: 2})))
+	require.EqualError(t, err, "max_enclaves_per_msp must not exceed max_enclaves")
+
+	policy := base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(&protos.FPCDeploymentPolicy{
+		AllowedMspIds:     []string{someMspId, otherMspId},
+		MaxEnclaves:       3,
+		MaxEnclavesPerMsp: 2,
+	}))
+	id.EvaluateAdminIdentityReturns(someMspId, nil)
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, policy)
+	require.NoError(t, err)
+
+	resp, err = ercc.QueryDeploymentPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, policy, resp)
+
+	// first enclave
+	e1 := newTestEnclave(t)
+	err = ercc.RegisterEnclave(transactionContext, e1.credentials(ccParams))
+	require.NoError(t, err)
+
+	// same enclave again
+	err = ercc.RegisterEnclave(transactionContext, e1.credentials(ccParams))
+	require.EqualError(t, err, fmt.Sprintf("enclave %s is already registered", e1.id))
+
+	// other enclave on the same peer
+	e2 := newTestEnclave(t)
+	e2.hostParams.PeerEndpoint = e1.hostParams.PeerEndpoint
+	err = ercc.RegisterEnclave(transactionContext, e2.credentials(ccParams))
+	require.EqualError(t, err, fmt.Sprintf("an enclave is already registered for peer endpoint %s", e1.hostParams.PeerEndpoint))
+
+	// msp not allowed
+	e3 := newTestEnclave(t)
+	e3.hostParams.PeerMspId = "unknown org"
+	err = ercc.RegisterEnclave(transactionContext, e3.credentials(ccParams))
+	require.EqualError(t, err, fmt.Sprintf("msp unknown org is not allowed to host an enclave for chaincode %s", chaincodeId))
+
+	// second enclave of same msp
+	e4 := newTestEnclave(t)
+	err = ercc.RegisterEnclave(transactionContext, e4.credentials(ccParams))
+	require.NoError(t, err)
+
+	// max enclaves per msp reached
+	e5 := newTestEnclave(t)
+	err = ercc.RegisterEnclave(transactionContext, e5.credentials(ccParams))
+	require.EqualError(t, err, fmt.Sprintf("maximum number of enclaves (2) reached for msp %s", someMspId))
+
+	// enclave of other msp
+	e6 := newTestEnclave(t)
+	e6.hostParams.PeerMspId = otherMspId
+	err = ercc.RegisterEnclave(transactionContext, e6.credentials(ccParams))
+	require.NoError(t, err)
+
+	// max enclaves reached
+	e7 := newTestEnclave(t)
+	e7.hostParams.PeerMspId = otherMspId
+	err = ercc.RegisterEnclave(transactionContext, e7.credentials(ccParams))
+	require.EqualError(t, err, fmt.Sprintf("maximum number of enclaves (3) reached for chaincode %s", chaincodeId))
+
+	credentialsList, err := ercc.QueryListEnclaveCredentials(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Len(t, credentialsList, 3)
+
+	// only provisioned enclaves are returned as endpoints
+	endpoints, err := ercc.QueryChaincodeEndPoints(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, endpoints)
+
+	ccParamsHash, err := utils.GetCCParamsHash(ccParams)
+	require.NoError(t, err)
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, e1.ccKeyRegistrationMessage(t, ccParamsHash, []byte("some chaincode ek")))
+	require.NoError(t, err)
+
+	endpoints, err = ercc.QueryChaincodeEndPoints(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, e1.hostParams.PeerEndpoint, endpoints)
+
+	// enclaves of an earlier chaincode definition are not counted
+	chaincodeStub.InvokeChaincodeReturns(shim.Success(protoutil.MarshalOrPanic(
+		&lifecycle.QueryChaincodeDefinitionResult{
+			Version:  mrenclave,
+			Sequence: 2,
+		})))
+	ccParams.Sequence = 2
+	err = ercc.RegisterEnclave(transactionContext, e5.credentials(ccParams))
+	require.NoError(t, err)
+
+}
+
+func TestDeploymentPolicyApproval(t *testing.T) {
+	chaincodeStub, _ := newStatefulStub()
+	setChannelOrgs(chaincodeStub, "org1", "org2", "org3")
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.IEvaluator = id
+
+	policy := base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(&protos.FPCDeploymentPolicy{AllowedMspIds: []string{"org1"}}))
+	otherPolicy := base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(&protos.FPCDeploymentPolicy{AllowedMspIds: []string{"org2"}}))
+
+	// only admins of channel members can vote
+	id.EvaluateAdminIdentityReturns("", fmt.Errorf("creator is not an admin"))
+	err := ercc.SetDeploymentPolicy(transactionContext, chaincodeId, policy)
+	require.EqualError(t, err, "creator identity evaluation failed: creator is not an admin")
+
+	id.EvaluateAdminIdentityReturns("org4", nil)
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, policy)
+	require.EqualError(t, err, fmt.Sprintf("msp org4 is not a member of channel %s", channelId))
+
+	// first vote
+	id.EvaluateAdminIdentityReturns("org1", nil)
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, policy)
+	require.NoError(t, err)
+
+	resp, err := ercc.QueryDeploymentPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	// votes for another policy are counted separately
+	id.EvaluateAdminIdentityReturns("org2", nil)
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, otherPolicy)
+	require.NoError(t, err)
+
+	resp, err = ercc.QueryDeploymentPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	// second vote reaches majority
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, policy)
+	require.NoError(t, err)
+
+	resp, err = ercc.QueryDeploymentPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, policy, resp)
+
+	// votes for other policies are discarded once a policy is set
+	id.EvaluateAdminIdentityReturns("org3", nil)
+	err = ercc.SetDeploymentPolicy(transactionContext, chaincodeId, otherPolicy)
+	require.NoError(t, err)
+
+	resp, err = ercc.QueryDeploymentPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, policy, resp)
+}
+
+func TestVerificationPolicy(t *testing.T) {
+	chaincodeStub, _ := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	verifier := &fakes.CredentialVerifier{}
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.Verifier = verifier
+	ercc.IEvaluator = id
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+
+	// no policy set
+	resp, err := ercc.QueryVerificationPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	err = ercc.RegisterEnclave(transactionContext, newTestEnclave(t).credentials(ccParams))
+	require.NoError(t, err)
+	_, _, policy := verifier.VerifyCredentialsArgsForCall(0)
+	require.Nil(t, policy)
+
+	err = ercc.SetVerificationPolicy(transactionContext, chaincodeId, "some bytes")
+	require.Contains(t, err.Error(), "invalid verification policy bytes")
+
+	expectedPolicy := &protos.AttestationVerificationPolicy{
+		AcceptedTcbStatus:  []string{types.TcbUpToDate, types.TcbSWHardeningNeeded},
+		AllowedAdvisoryIds: []string{"INTEL-SA-00615"},
+	}
+	policyBase64 := base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(expectedPolicy))
+
+	// a non-admin cannot set the policy
+	id.EvaluateAdminIdentityReturns("", fmt.Errorf("creator is not an admin"))
+	err = ercc.SetVerificationPolicy(transactionContext, chaincodeId, policyBase64)
+	require.EqualError(t, err, "creator identity evaluation failed: creator is not an admin")
+
+	resp, err = ercc.QueryVerificationPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	// an admin of another organization cannot set the policy
+	id.EvaluateAdminIdentityReturns("other org", nil)
+	err = ercc.SetVerificationPolicy(transactionContext, chaincodeId, policyBase64)
+	require.EqualError(t, err, fmt.Sprintf("msp other org is not a member of channel %s", channelId))
+
+	// the admin of the only channel member sets the policy
+	id.EvaluateAdminIdentityReturns(someMspId, nil)
+	err = ercc.SetVerificationPolicy(transactionContext, chaincodeId, policyBase64)
+	require.NoError(t, err)
+
+	resp, err = ercc.QueryVerificationPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, policyBase64, resp)
+
+	// the policy is passed to the verifier
+	err = ercc.RegisterEnclave(transactionContext, newTestEnclave(t).credentials(ccParams))
+	require.NoError(t, err)
+	_, mrenclaveArg, policy := verifier.VerifyCredentialsArgsForCall(1)
+	require.Equal(t, mrenclave, mrenclaveArg)
+	require.True(t, proto.Equal(expectedPolicy, policy))
+
+	// structured rejection reasons are preserved
+	verifier.VerifyCredentialsReturns(types.Reject(types.RejectTcbStatus, "tcb status OutOfDate not accepted"))
+	err = ercc.RegisterEnclave(transactionContext, newTestEnclave(t).credentials(ccParams))
+	require.EqualError(t, err, "evidence verification failed: TCB_STATUS_NOT_ACCEPTED: tcb status OutOfDate not accepted")
+	rejection := &types.RejectionError{}
+	require.ErrorAs(t, err, &rejection)
+	require.Equal(t, types.RejectTcbStatus, rejection.Reason)
+}
+
+func TestVerificationPolicyAllowedMrenclaves(t *testing.T) {
+	chaincodeStub, _ := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	verifier := &fakes.CredentialVerifier{}
+
+	id := &fakes.IdentityEvaluator{}
+	id.EvaluateAdminIdentityReturns(someMspId, nil)
+
+	ercc := registry.Contract{}
+	ercc.Verifier = verifier
+	ercc.IEvaluator = id
+
+	otherMrenclave := "some other mrenclave"
+	policy := &protos.AttestationVerificationPolicy{AllowedMrenclaves: []string{otherMrenclave}}
+	err := ercc.SetVerificationPolicy(transactionContext, chaincodeId, base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(policy)))
+	require.NoError(t, err)
+
+	// the version of the cc params must always match the chaincode definition, the policy does not apply here
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     otherMrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+	err = ercc.RegisterEnclave(transactionContext, newTestEnclave(t).credentials(ccParams))
+	require.EqualError(t, err, "mrenclave does not match chaincode definition")
+	require.Equal(t, 0, verifier.VerifyCredentialsCallCount())
+
+	// the evidence is verified against the mrenclave of the chaincode definition and the policy,
+	// which decides whether the enclave identity contained in the evidence is accepted
+	ccParams.Version = mrenclave
+	err = ercc.RegisterEnclave(transactionContext, newTestEnclave(t).credentials(ccParams))
+	require.NoError(t, err)
+	_, mrenclaveArg, policyArg := verifier.VerifyCredentialsArgsForCall(0)
+	require.Equal(t, mrenclave, mrenclaveArg)
+	require.True(t, proto.Equal(policy, policyArg))
+}
+
+func TestVerificationPolicyAllowedSignersApproval(t *testing.T) {
+	chaincodeStub, _ := newStatefulStub()
+	setChannelOrgs(chaincodeStub, "org1", "org2")
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.Verifier = &fakes.CredentialVerifier{}
+	ercc.IEvaluator = id
+
+	// accepting other enclave builds requires the same approval as any other verification policy
+	policy := base64.StdEncoding.EncodeToString(protoutil.MarshalOrPanic(&protos.AttestationVerificationPolicy{
+		AllowedSigners: []*protos.EnclaveSignerPolicy{{Mrsigner: "some mrsigner", IsvProdId: 1, MinIsvSvn: 2}},
+	}))
+
+	id.EvaluateAdminIdentityReturns("", fmt.Errorf("creator is not an admin"))
+	err := ercc.SetVerificationPolicy(transactionContext, chaincodeId, policy)
+	require.EqualError(t, err, "creator identity evaluation failed: creator is not an admin")
+
+	// a single organization cannot accept other enclave builds
+	id.EvaluateAdminIdentityReturns("org1", nil)
+	err = ercc.SetVerificationPolicy(transactionContext, chaincodeId, policy)
+	require.NoError(t, err)
+
+	resp, err := ercc.QueryVerificationPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	id.EvaluateAdminIdentityReturns("org2", nil)
+	err = ercc.SetVerificationPolicy(transactionContext, chaincodeId, policy)
+	require.NoError(t, err)
+
+	resp, err = ercc.QueryVerificationPolicy(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Equal(t, policy, resp)
+}
+
+func TestDeregisterEnclave(t *testing.T) {
+	chaincodeStub, state := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.Verifier = &fakes.CredentialVerifier{}
+	ercc.IEvaluator = id
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+	ccParamsHash, err := utils.GetCCParamsHash(ccParams)
+	require.NoError(t, err)
+
+	enclave := newTestEnclave(t)
+
+	err = ercc.DeregisterEnclave(transactionContext, chaincodeId, enclave.id)
+	require.EqualError(t, err, fmt.Sprintf("enclave %s not registered for chaincode %s", enclave.id, chaincodeId))
+
+	putCredentials(t, state, enclave, ccParams)
+	err = ercc.RegisterCCKeys(transactionContext, chaincodeId, enclave.ccKeyRegistrationMessage(t, ccParamsHash, []byte("some chaincode ek")))
+	require.NoError(t, err)
+
+	// only the owner can deregister
+	id.EvaluateCreatorIdentityReturns(fmt.Errorf("msp does not match"))
+	err = ercc.DeregisterEnclave(transactionContext, chaincodeId, enclave.id)
+	require.EqualError(t, err, "creator identity evaluation failed: msp does not match")
+	id.EvaluateCreatorIdentityReturns(nil)
+
+	err = ercc.DeregisterEnclave(transactionContext, chaincodeId, enclave.id)
+	require.NoError(t, err)
+
+	resp, err := ercc.QueryEnclaveCredentials(transactionContext, chaincodeId, enclave.id)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	provisioned, err := ercc.QueryListProvisionedEnclaves(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, provisioned)
+
+	// a deregistered enclave can register again
+	err = ercc.RegisterEnclave(transactionContext, enclave.credentials(ccParams))
+	require.NoError(t, err)
+}
+
+func TestRevokeEnclave(t *testing.T) {
+	chaincodeStub, state := newStatefulStub()
+	transactionContext := &fakes.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	id := &fakes.IdentityEvaluator{}
+
+	ercc := registry.Contract{}
+	ercc.Verifier = &fakes.CredentialVerifier{}
+	ercc.IEvaluator = id
+
+	ccParams := &protos.CCParameters{
+		ChaincodeId: chaincodeId,
+		Version:     mrenclave,
+		Sequence:    1,
+		ChannelId:   channelId,
+	}
+
+	orgs := []string{"org1", "org2", "org3", "org4"}
+	enclaves := make([]*testEnclave, len(orgs))
+	for i, org := range orgs {
+		enclaves[i] = newTestEnclave(t)
+		enclaves[i].hostParams.PeerMspId = org
+		putCredentials(t, state, enclaves[i], ccParams)
+	}
+
+	revoked, err := ercc.QueryListRevokedEnclaves(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.Empty(t, revoked)
+
+	// a non-admin of the owner cannot revoke its enclave
+	id.EvaluateCreatorIdentityReturns(nil)
+	id.EvaluateAdminIdentityReturns("", fmt.Errorf("creator is not an admin"))
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[0].id)
+	require.EqualError(t, err, "creator identity evaluation failed: creator is not an admin")
+
+	isRevoked, err := ercc.QueryEnclaveRevoked(transactionContext, chaincodeId, enclaves[0].id)
+	require.NoError(t, err)
+	require.False(t, isRevoked)
+
+	// an admin of the owner revokes its enclave
+	id.EvaluateAdminIdentityReturns("org1", nil)
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[0].id)
+	require.NoError(t, err)
+	_, stub := id.EvaluateAdminIdentityArgsForCall(1)
+	require.Equal(t, chaincodeStub, stub)
+
+	isRevoked, err = ercc.QueryEnclaveRevoked(transactionContext, chaincodeId, enclaves[0].id)
+	require.NoError(t, err)
+	require.True(t, isRevoked)
+
+	resp, err := ercc.QueryEnclaveCredentials(transactionContext, chaincodeId, enclaves[0].id)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[0].id)
+	require.EqualError(t, err, fmt.Sprintf("enclave %s is already revoked", enclaves[0].id))
+
+	// revoked enclave cannot register again
+	err = ercc.RegisterEnclave(transactionContext, enclaves[0].credentials(ccParams))
+	require.EqualError(t, err, fmt.Sprintf("enclave %s has been revoked", enclaves[0].id))
+
+	// other organizations need a majority to revoke; org1 does not host enclaves anymore
+	id.EvaluateAdminIdentityReturns("", fmt.Errorf("creator is not an admin"))
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[1].id)
+	require.EqualError(t, err, "creator identity evaluation failed: creator is not an admin")
+
+	id.EvaluateAdminIdentityReturns("org1", nil)
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[1].id)
+	require.EqualError(t, err, fmt.Sprintf("msp org1 is not authorized to revoke enclaves of chaincode %s", chaincodeId))
+
+	// first vote
+	id.EvaluateAdminIdentityReturns("org3", nil)
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[1].id)
+	require.NoError(t, err)
+
+	isRevoked, err = ercc.QueryEnclaveRevoked(transactionContext, chaincodeId, enclaves[1].id)
+	require.NoError(t, err)
+	require.False(t, isRevoked)
+
+	// voting again does not count twice
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[1].id)
+	require.NoError(t, err)
+
+	isRevoked, err = ercc.QueryEnclaveRevoked(transactionContext, chaincodeId, enclaves[1].id)
+	require.NoError(t, err)
+	require.False(t, isRevoked)
+
+	// second vote reaches majority
+	id.EvaluateAdminIdentityReturns("org4", nil)
+	err = ercc.RevokeEnclave(transactionContext, chaincodeId, enclaves[1].id)
+	require.NoError(t, err)
+
+	isRevoked, err = ercc.QueryEnclaveRevoked(transactionContext, chaincodeId, enclaves[1].id)
+	require.NoError(t, err)
+	require.True(t, isRevoked)
+
+	revoked, err = ercc.QueryListRevokedEnclaves(transactionContext, chaincodeId)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{enclaves[0].id, enclaves[1].id}, revoked)
+}
@@ -135,6 +135,11 @@ ledger_init() {
     kill -0 ${ORDERER_PID} || die "Orderer quit too quickly: (for log see ${ORDERER_LOG_OUT} & ${ORDERER_LOG_ERR})"
 
     # 3. start peer
+    # - in simulation mode, the C++ enclaves create legacy simulated attestations, which ERCC accepts
+    #   by default (deprecated). We accept them explicitly for this local (test) ledger unless defined otherwise.
+    if [ "${SGX_MODE:-SIM}" = "SIM" ]; then
+        export FPC_SIM_ACCEPT_LEGACY_ATTESTATION=${FPC_SIM_ACCEPT_LEGACY_ATTESTATION:-true}
+    fi
     LD_LIBRARY_PATH=${LD_LIBRARY_PATH:+"$LD_LIBRARY_PATH:"}${FPC_PATH}/tlcc/enclave/lib \
 		   ${PEER_CMD} node start 1>${PEER_LOG_OUT} 2>${PEER_LOG_ERR} &
     export PEER_PID=$!
@@ -512,7 +512,9 @@ EOF
     echo "Registering with Enclave Registry"
     try $RUN ${FABRIC_BIN_DIR}/peer chaincode invoke -o ${ORDERER_ADDR} -C ${CHAN_ID} -n ${ERCC_ID} -c '{"Args":["RegisterEnclave", "'${CC_CREDS_CONV_B64}'"]}' --waitForEvent
 
-    # NOTE: the chaincode encryption key is retrieved here for testing purposes
+    # NOTE: fpc-c enclaves include their chaincode encryption key in the attested data and are provisioned
+    # with their registration, i.e., they do not support the key generation and distribution of the Go enclaves.
+    # The chaincode encryption key is retrieved here for testing purposes
     echo "Querying Chaincode Encryption Key"
     try_out_r $RUN ${FABRIC_BIN_DIR}/peer chaincode query -o ${ORDERER_ADDR} -C ${CHAN_ID} -n ${ERCC_ID} -c '{"Args":["QueryChaincodeEncryptionKey", "'${CC_ID}'"]}'
     CC_EK_B64="${RESPONSE}"
@@ -11,6 +11,8 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/dcap"
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/ego"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/epid"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/simulation"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
@@ -76,6 +78,8 @@ func NewDefaultCredentialConverter() *CredentialConverter {
 		simulation.NewSimulationConverter(),
 		epid.NewEpidLinkableConverter(),
 		epid.NewEpidUnlinkableConverter(),
+		ego.NewEgoConverter(),
+		dcap.NewDcapConverter(),
 	)
 }
 
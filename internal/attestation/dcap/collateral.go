@@ -0,0 +1,235 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	tcbSignerCommonName = "Intel SGX TCB Signing"
+	intelOrganization   = "Intel Corporation"
+)
+
+// Collateral contains the information fetched from a PCCS that is required to verify a quote.
+// The signed structures (TCB info and QE identity) are kept exactly as received.
+type Collateral struct {
+	PckCrl                string `json:"pck_crl"`
+	PckCrlIssuerChain     string `json:"pck_crl_issuer_chain"`
+	RootCaCrl             string `json:"root_ca_crl"`
+	TcbInfo               string `json:"tcb_info"`
+	TcbInfoIssuerChain    string `json:"tcb_info_issuer_chain"`
+	QeIdentity            string `json:"qe_identity"`
+	QeIdentityIssuerChain string `json:"qe_identity_issuer_chain"`
+}
+
+type tcbInfoResponse struct {
+	TcbInfo   json.RawMessage `json:"tcbInfo"`
+	Signature string          `json:"signature"`
+}
+
+type tcbInfo struct {
+	Id         string     `json:"id"`
+	Version    int        `json:"version"`
+	NextUpdate time.Time  `json:"nextUpdate"`
+	Fmspc      string     `json:"fmspc"`
+	PceId      string     `json:"pceId"`
+	TcbLevels  []tcbLevel `json:"tcbLevels"`
+}
+
+type tcbLevel struct {
+	Tcb struct {
+		SgxTcbComponents []struct {
+			Svn int `json:"svn"`
+		} `json:"sgxtcbcomponents"`
+		PceSvn int `json:"pcesvn"`
+	} `json:"tcb"`
+	TcbStatus   string   `json:"tcbStatus"`
+	AdvisoryIDs []string `json:"advisoryIDs"`
+}
+
+type qeIdentityResponse struct {
+	EnclaveIdentity json.RawMessage `json:"enclaveIdentity"`
+	Signature       string          `json:"signature"`
+}
+
+type qeIdentity struct {
+	Id             string    `json:"id"`
+	Version        int       `json:"version"`
+	NextUpdate     time.Time `json:"nextUpdate"`
+	MiscSelect     string    `json:"miscselect"`
+	MiscSelectMask string    `json:"miscselectMask"`
+	Attributes     string    `json:"attributes"`
+	AttributesMask string    `json:"attributesMask"`
+	MrSigner       string    `json:"mrsigner"`
+	IsvProdId      uint16    `json:"isvprodid"`
+	TcbLevels      []struct {
+		Tcb struct {
+			IsvSvn uint16 `json:"isvsvn"`
+		} `json:"tcb"`
+		TcbStatus   string   `json:"tcbStatus"`
+		AdvisoryIDs []string `json:"advisoryIDs"`
+	} `json:"tcbLevels"`
+}
+
+// TcbResult is the outcome of the TCB evaluation of a platform or quoting enclave
+type TcbResult struct {
+	Status      string
+	AdvisoryIDs []string
+}
+
+// verifySignedBody verifies the hex encoded signature over body with the leaf certificate of the issuer chain,
+// which must be the Intel SGX TCB Signing certificate
+func verifySignedBody(body []byte, signature string, issuerChainPEM string, roots *x509.CertPool, now time.Time, rootCrl *x509.RevocationList) error {
+	signer, err := verifyCertChain([]byte(issuerChainPEM), roots, now, rootCrl)
+	if err != nil {
+		return errors.Wrap(err, "invalid issuer chain")
+	}
+	if !isTcbSigner(signer) {
+		return fmt.Errorf("unexpected signer '%s'", signer.Subject)
+	}
+
+	pub, ok := signer.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported signer key")
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "cannot decode signature")
+	}
+
+	digest := sha256.Sum256(body)
+	if !verifySignature(pub, digest[:], sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// isTcbSigner returns true if the certificate is the Intel SGX TCB Signing certificate, as opposed to, e.g., a PCK CA
+// or PCK certificate issued by the same root
+func isTcbSigner(cert *x509.Certificate) bool {
+	return cert.Subject.CommonName == tcbSignerCommonName &&
+		len(cert.Subject.Organization) == 1 && cert.Subject.Organization[0] == intelOrganization
+}
+
+// verifyTcbInfo verifies the TCB info and evaluates the TCB level of the platform described by the PCK certificate
+func verifyTcbInfo(c *Collateral, pck *PckExtensions, roots *x509.CertPool, now time.Time, rootCrl *x509.RevocationList) (*TcbResult, error) {
+	resp := &tcbInfoResponse{}
+	if err := json.Unmarshal([]byte(c.TcbInfo), resp); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal tcb info")
+	}
+	if err := verifySignedBody(resp.TcbInfo, resp.Signature, c.TcbInfoIssuerChain, roots, now, rootCrl); err != nil {
+		return nil, errors.Wrap(err, "cannot verify tcb info")
+	}
+
+	info := &tcbInfo{}
+	if err := json.Unmarshal(resp.TcbInfo, info); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal tcb info")
+	}
+	if now.After(info.NextUpdate) {
+		return nil, fmt.Errorf("tcb info expired")
+	}
+	if !strings.EqualFold(info.Fmspc, pck.Fmspc) || !strings.EqualFold(info.PceId, pck.PceId) {
+		return nil, fmt.Errorf("tcb info does not match platform")
+	}
+
+	// tcb levels are sorted in descending order; the first level the platform satisfies applies
+	for _, level := range info.TcbLevels {
+		if len(level.Tcb.SgxTcbComponents) != len(pck.TcbComponents) {
+			return nil, fmt.Errorf("invalid tcb level")
+		}
+
+		satisfied := pck.PceSvn >= level.Tcb.PceSvn
+		for i, comp := range level.Tcb.SgxTcbComponents {
+			satisfied = satisfied && pck.TcbComponents[i] >= comp.Svn
+		}
+
+		if satisfied {
+			return &TcbResult{Status: level.TcbStatus, AdvisoryIDs: level.AdvisoryIDs}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no matching tcb level")
+}
+
+// verifyQeIdentity verifies the QE identity, checks that the QE report matches it, and evaluates the TCB level of the QE
+func verifyQeIdentity(c *Collateral, qeReport *ReportBody, roots *x509.CertPool, now time.Time, rootCrl *x509.RevocationList) (*TcbResult, error) {
+	resp := &qeIdentityResponse{}
+	if err := json.Unmarshal([]byte(c.QeIdentity), resp); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal qe identity")
+	}
+	if err := verifySignedBody(resp.EnclaveIdentity, resp.Signature, c.QeIdentityIssuerChain, roots, now, rootCrl); err != nil {
+		return nil, errors.Wrap(err, "cannot verify qe identity")
+	}
+
+	id := &qeIdentity{}
+	if err := json.Unmarshal(resp.EnclaveIdentity, id); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal qe identity")
+	}
+	if now.After(id.NextUpdate) {
+		return nil, fmt.Errorf("qe identity expired")
+	}
+
+	if !strings.EqualFold(hex.EncodeToString(qeReport.MrSigner), id.MrSigner) || qeReport.IsvProdId != id.IsvProdId {
+		return nil, fmt.Errorf("qe report does not match qe identity")
+	}
+
+	// miscselect is given as hex encoded (big endian) integer
+	miscSelect := make([]byte, 4)
+	binary.BigEndian.PutUint32(miscSelect, qeReport.MiscSelect)
+	if err := checkMasked(miscSelect, id.MiscSelect, id.MiscSelectMask); err != nil {
+		return nil, errors.Wrap(err, "qe miscselect does not match")
+	}
+	if err := checkMasked(qeReport.Attributes, id.Attributes, id.AttributesMask); err != nil {
+		return nil, errors.Wrap(err, "qe attributes do not match")
+	}
+
+	// tcb levels are sorted in descending order; the first level the qe satisfies applies
+	for _, level := range id.TcbLevels {
+		if qeReport.IsvSvn >= level.Tcb.IsvSvn {
+			return &TcbResult{Status: level.TcbStatus, AdvisoryIDs: level.AdvisoryIDs}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("qe tcb level not supported")
+}
+
+// checkMasked checks that value & mask equals expected (both hex encoded)
+func checkMasked(value []byte, expectedHex, maskHex string) error {
+	expected, err := hex.DecodeString(expectedHex)
+	if err != nil {
+		return err
+	}
+	mask, err := hex.DecodeString(maskHex)
+	if err != nil {
+		return err
+	}
+	if len(expected) != len(value) || len(mask) != len(value) {
+		return fmt.Errorf("invalid length")
+	}
+
+	masked := make([]byte, len(value))
+	for i := range value {
+		masked[i] = value[i] & mask[i]
+	}
+	if !bytes.Equal(masked, expected) {
+		return fmt.Errorf("mismatch")
+	}
+	return nil
+}
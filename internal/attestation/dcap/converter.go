@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/pkg/errors"
+)
+
+const DcapType = "dcap"
+
+// Evidence is a DCAP quote together with the collateral required for its verification
+type Evidence struct {
+	Quote      string      `json:"quote"`
+	Collateral *Collateral `json:"collateral"`
+}
+
+// NewDcapConverter creates a new attestation converter for Intel SGX DCAP (ECDSA) attestation.
+// The converter attaches the quote collateral fetched from a PCCS (see NewPCCSClient).
+func NewDcapConverter(opts ...PCCSClientOption) *types.Converter {
+	return &types.Converter{
+		Type: DcapType,
+		Converter: func(attestationBytes []byte) (evidenceBytes []byte, err error) {
+			return convert(NewPCCSClient(opts...), string(attestationBytes))
+		},
+	}
+}
+
+func convert(pccs *PCCSClient, quoteBase64 string) ([]byte, error) {
+	quoteBytes, err := base64.StdEncoding.DecodeString(quoteBase64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode quote")
+	}
+
+	quote, err := ParseQuote(quoteBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse quote")
+	}
+
+	chain, err := parseCertChain(quote.PckCertChainPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse pck certificate chain")
+	}
+
+	pck, err := parsePckExtensions(chain[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := pckCAType(chain[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// the pck ca certificate points to the crl of the root ca
+	var rootCaCrlUrl string
+	if len(chain) > 1 && len(chain[1].CRLDistributionPoints) > 0 {
+		rootCaCrlUrl = chain[1].CRLDistributionPoints[0]
+	}
+
+	collateral, err := pccs.GetCollateral(pck.Fmspc, ca, rootCaCrlUrl)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get collateral")
+	}
+
+	evidenceBytes, err := json.Marshal(&Evidence{
+		Quote:      quoteBase64,
+		Collateral: collateral,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal evidence")
+	}
+
+	return evidenceBytes, nil
+}
@@ -0,0 +1,390 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	statement = []byte("someStatement")
+	mrenclave = []byte("01234567890123456789012345678901")
+)
+
+// evidence converts a quote of the platform using the local pccs stand-in
+func evidence(t *testing.T, p *platform, quote []byte) *types.Evidence {
+	server := p.pccs(t)
+	converter := NewDcapConverter(WithUrl(server.URL))
+	evidenceBytes, err := converter.Converter([]byte(base64.StdEncoding.EncodeToString(quote)))
+	require.NoError(t, err)
+	return &types.Evidence{Type: DcapType, Data: string(evidenceBytes)}
+}
+
+func verifier(p *platform, opts ...VerifierOption) *types.Verifier {
+	opts = append([]VerifierOption{WithRootCAs(p.roots()), WithTime(func() time.Time { return fixtureTime })}, opts...)
+	return NewDcapVerifier(opts...)
+}
+
+func expected(mrenclave []byte) *types.ValidationValues {
+	return &types.ValidationValues{Statement: statement, Mrenclave: hex.EncodeToString(mrenclave)}
+}
+
+func TestParseQuote(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5})
+	quoteBytes := p.quote(t, statement, mrenclave, false)
+
+	quote, err := ParseQuote(quoteBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, mrenclave, quote.IsvReport.MrEnclave)
+	assert.False(t, quote.IsvReport.Debug())
+	assert.Equal(t, qeMrSigner, quote.QeReport.MrSigner)
+	assert.Equal(t, qeAuthData, quote.QeAuthData)
+
+	chain, err := parseCertChain(quote.PckCertChainPEM)
+	assert.NoError(t, err)
+	assert.Len(t, chain, 3)
+
+	pck, err := parsePckExtensions(chain[0])
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(fmspc), pck.Fmspc)
+	assert.Equal(t, 11, pck.PceSvn)
+	assert.Equal(t, 5, pck.TcbComponents[15])
+
+	_, err = ParseQuote(quoteBytes[:100])
+	assert.EqualError(t, err, "quote too short")
+
+	_, err = ParseQuote(quoteBytes[:len(quoteBytes)-1])
+	assert.EqualError(t, err, "quote too short")
+
+	invalidVersion := append([]byte{2, 0}, quoteBytes[2:]...)
+	_, err = ParseQuote(invalidVersion)
+	assert.EqualError(t, err, "unsupported quote version 2")
+
+	tdxQuote := append([]byte{}, quoteBytes...)
+	tdxQuote[4] = 0x81
+	_, err = ParseQuote(tdxQuote)
+	assert.EqualError(t, err, "unsupported tee type 129")
+
+	otherVendor := append([]byte{}, quoteBytes...)
+	otherVendor[12] ^= 0xff
+	_, err = ParseQuote(otherVendor)
+	assert.Contains(t, err.Error(), "unsupported qe vendor id")
+}
+
+// TestParseQuoteLayout checks the parser against the absolute offsets of the quote (version 3) format as specified
+// in the Intel SGX ECDSA Quote Library API, independently of the quote fixture
+func TestParseQuoteLayout(t *testing.T) {
+	chain := []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n")
+	authData := []byte("authData")
+	quoteBytes := make([]byte, 1014+len(authData)+6+len(chain))
+	put := func(offset int, b ...byte) {
+		copy(quoteBytes[offset:], b)
+	}
+	fill := func(offset, n int, v byte) {
+		for i := 0; i < n; i++ {
+			quoteBytes[offset+i] = v
+		}
+	}
+
+	// header
+	put(0, 3, 0)       // version
+	put(2, 2, 0)       // attestation key type
+	put(4, 0, 0, 0, 0) // tee type
+	put(8, 7, 0)       // qe svn
+	put(10, 9, 0)      // pce svn
+	put(12, intelQeVendorId...)
+
+	// isv report body
+	fill(48, 16, 0x01)  // cpu svn
+	put(64, 0x05, 0, 0) // miscselect
+	fill(96, 16, 0x02)  // attributes
+	fill(112, 32, 0x03) // mrenclave
+	fill(176, 32, 0x04) // mrsigner
+	put(304, 0x11, 0)   // isv prod id
+	put(306, 0x12, 0)   // isv svn
+	fill(368, 64, 0x05) // report data
+
+	// signature data
+	binary.LittleEndian.PutUint32(quoteBytes[432:], uint32(len(quoteBytes)-436))
+	fill(436, 64, 0x06)     // isv report signature
+	fill(500, 64, 0x07)     // attestation key
+	fill(564+64, 32, 0x08)  // qe mrenclave
+	fill(564+128, 32, 0x09) // qe mrsigner
+	put(564+256, 0x01, 0)   // qe isv prod id
+	fill(948, 64, 0x0a)     // qe report signature
+	binary.LittleEndian.PutUint16(quoteBytes[1012:], uint16(len(authData)))
+	put(1014, authData...)
+	offset := 1014 + len(authData)
+	put(offset, 5, 0) // certification data type
+	binary.LittleEndian.PutUint32(quoteBytes[offset+2:], uint32(len(chain)))
+	put(offset+6, chain...)
+
+	quote, err := ParseQuote(quoteBytes)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(7), quote.QeSvn)
+	assert.Equal(t, uint16(9), quote.PceSvn)
+	assert.Equal(t, bytes.Repeat([]byte{0x01}, 16), quote.IsvReport.CpuSvn)
+	assert.Equal(t, uint32(5), quote.IsvReport.MiscSelect)
+	assert.Equal(t, bytes.Repeat([]byte{0x02}, 16), quote.IsvReport.Attributes)
+	assert.Equal(t, bytes.Repeat([]byte{0x03}, 32), quote.IsvReport.MrEnclave)
+	assert.Equal(t, bytes.Repeat([]byte{0x04}, 32), quote.IsvReport.MrSigner)
+	assert.Equal(t, uint16(0x11), quote.IsvReport.IsvProdId)
+	assert.Equal(t, uint16(0x12), quote.IsvReport.IsvSvn)
+	assert.Equal(t, bytes.Repeat([]byte{0x05}, 64), quote.IsvReport.ReportData)
+	assert.Equal(t, bytes.Repeat([]byte{0x06}, 64), quote.IsvReportSignature)
+	assert.Equal(t, bytes.Repeat([]byte{0x07}, 64), quote.AttestationKey)
+	assert.Equal(t, bytes.Repeat([]byte{0x08}, 32), quote.QeReport.MrEnclave)
+	assert.Equal(t, bytes.Repeat([]byte{0x09}, 32), quote.QeReport.MrSigner)
+	assert.Equal(t, uint16(1), quote.QeReport.IsvProdId)
+	assert.Equal(t, bytes.Repeat([]byte{0x0a}, 64), quote.QeReportSignature)
+	assert.Equal(t, authData, quote.QeAuthData)
+	assert.Equal(t, chain, quote.PckCertChainPEM)
+	assert.Equal(t, quoteBytes[:432], quote.signedIsvReportBytes)
+}
+
+func TestRootCAs(t *testing.T) {
+	block, _ := pem.Decode([]byte(intelSgxRootCA))
+	require.NotNil(t, block)
+	intelRoot, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "Intel SGX Root CA", intelRoot.Subject.CommonName)
+	assert.NoError(t, intelRoot.CheckSignatureFrom(intelRoot))
+	fingerprint := sha256.Sum256(intelRoot.Raw)
+	assert.Equal(t, "44a0196b2b99f889b8e149e95b807a350e7424964399e885a7cbb8ccfab674d3", hex.EncodeToString(fingerprint[:]))
+
+	// without configuration, the pinned Intel root CA is used
+	t.Setenv(RootCAPathEnvKey, "")
+	roots, err := loadRootCAs()
+	assert.NoError(t, err)
+	assert.True(t, roots.Equal(defaultRootCAs()))
+
+	// a configured root CA replaces the Intel root CA
+	p := newPlatform(t, platformOptions{})
+	path := t.TempDir() + "/root_ca.pem"
+	require.NoError(t, writeFile(path, pemChain(p.root)))
+	t.Setenv(RootCAPathEnvKey, path)
+	roots, err = loadRootCAs()
+	assert.NoError(t, err)
+	assert.True(t, roots.Equal(p.roots()))
+
+	// an invalid configuration is not replaced by the Intel root CA
+	t.Setenv(RootCAPathEnvKey, t.TempDir()+"/missing.pem")
+	_, err = loadRootCAs()
+	assert.Error(t, err)
+}
+
+func TestDcapAttestation(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	e := &Evidence{}
+	assert.NoError(t, json.Unmarshal([]byte(ev.Data), e))
+	assert.NotEmpty(t, e.Collateral.TcbInfo)
+	assert.NotEmpty(t, e.Collateral.RootCaCrl)
+
+	v := verifier(p)
+	assert.NoError(t, v.Verify(ev, expected(mrenclave)))
+
+	// other statement
+	err := v.Verify(ev, &types.ValidationValues{Statement: []byte("otherStatement"), Mrenclave: hex.EncodeToString(mrenclave)})
+	assert.EqualError(t, err, "STATEMENT_MISMATCH: report data does not match statement")
+
+	// other mrenclave
+	err = v.Verify(ev, expected([]byte("otherMrenclave")))
+	rejection := &types.RejectionError{}
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, types.RejectMrenclaveMismatch, rejection.Reason)
+
+	// untrusted root
+	err = verifier(newPlatform(t, platformOptions{})).Verify(ev, expected(mrenclave))
+	assert.Contains(t, err.Error(), "invalid certificate chain")
+
+	// expired collateral
+	err = NewDcapVerifier(WithRootCAs(p.roots()), WithTime(func() time.Time { return fixtureTime.Add(48 * time.Hour) })).Verify(ev, expected(mrenclave))
+	assert.Error(t, err)
+
+	// tampered quote
+	quote, _ := base64.StdEncoding.DecodeString(e.Quote)
+	quote[headerSize+64] ^= 0xff
+	e.Quote = base64.StdEncoding.EncodeToString(quote)
+	tampered, _ := json.Marshal(e)
+	err = v.Verify(&types.Evidence{Type: DcapType, Data: string(tampered)}, expected(mrenclave))
+	assert.EqualError(t, err, "INVALID_EVIDENCE: invalid quote signature")
+
+	// tampered tcb info
+	e = &Evidence{}
+	assert.NoError(t, json.Unmarshal([]byte(ev.Data), e))
+	e.Collateral.TcbInfo = e.Collateral.TcbInfo[:20] + "1" + e.Collateral.TcbInfo[21:]
+	tampered, _ = json.Marshal(e)
+	err = v.Verify(&types.Evidence{Type: DcapType, Data: string(tampered)}, expected(mrenclave))
+	assert.Error(t, err)
+}
+
+func TestDcapAttestationDebugEnclave(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, true))
+
+	err := verifier(p).Verify(ev, expected(mrenclave))
+	assert.EqualError(t, err, "DEBUG_ENCLAVE: report is from a debug enclave")
+}
+
+func TestDcapAttestationTcbStatus(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 3})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	err := verifier(p).Verify(ev, expected(mrenclave))
+	assert.EqualError(t, err, "TCB_STATUS_NOT_ACCEPTED: tcb status OutOfDate not accepted (advisories: [INTEL-SA-00828])")
+
+	// accept out of date platforms, but not the advisory
+	values := expected(mrenclave)
+	values.Policy = &protos.AttestationVerificationPolicy{AcceptedTcbStatus: []string{types.TcbUpToDate, types.TcbOutOfDate}}
+	err = verifier(p).Verify(ev, values)
+	assert.EqualError(t, err, "ADVISORY_NOT_ALLOWED: advisory INTEL-SA-00828 not allowed for tcb status OutOfDate")
+
+	values.Policy.AllowedAdvisoryIds = []string{"INTEL-SA-00828"}
+	err = verifier(p).Verify(ev, values)
+	assert.NoError(t, err)
+
+	// platform below all tcb levels
+	p = newPlatform(t, platformOptions{tcbComponentSvn: 1})
+	ev = evidence(t, p, p.quote(t, statement, mrenclave, false))
+	err = verifier(p).Verify(ev, expected(mrenclave))
+	assert.EqualError(t, err, "INVALID_EVIDENCE: no matching tcb level")
+}
+
+func TestDcapAttestationQeTcbStatus(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5, qeOutOfDate: true})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	err := verifier(p).Verify(ev, expected(mrenclave))
+	assert.EqualError(t, err, "quoting enclave: TCB_STATUS_NOT_ACCEPTED: tcb status OutOfDate not accepted (advisories: [INTEL-SA-00615])")
+	rejection := &types.RejectionError{}
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, types.RejectTcbStatus, rejection.Reason)
+
+	// accept out of date platforms, but not the advisory
+	values := expected(mrenclave)
+	values.Policy = &protos.AttestationVerificationPolicy{AcceptedTcbStatus: []string{types.TcbUpToDate, types.TcbOutOfDate}}
+	err = verifier(p).Verify(ev, values)
+	assert.EqualError(t, err, "quoting enclave: ADVISORY_NOT_ALLOWED: advisory INTEL-SA-00615 not allowed for tcb status OutOfDate")
+
+	values.Policy.AllowedAdvisoryIds = []string{"INTEL-SA-00615"}
+	err = verifier(p).Verify(ev, values)
+	assert.NoError(t, err)
+}
+
+func TestDcapAttestationIdentityPolicy(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	// the chaincode definition refers to another mrenclave
+	values := expected([]byte("otherMrenclave"))
+	err := verifier(p).Verify(ev, values)
+	assert.Contains(t, err.Error(), "MRENCLAVE_MISMATCH")
+
+	// allowed mrenclave
+	values.Policy = &protos.AttestationVerificationPolicy{AllowedMrenclaves: []string{hex.EncodeToString(mrenclave)}}
+	assert.NoError(t, verifier(p).Verify(ev, values))
+
+	// signer policy
+	signer := &protos.EnclaveSignerPolicy{Mrsigner: hex.EncodeToString(isvMrSigner), IsvProdId: uint32(isvProdId), MinIsvSvn: uint32(isvSvn)}
+	values.Policy = &protos.AttestationVerificationPolicy{AllowedSigners: []*protos.EnclaveSignerPolicy{signer}}
+	assert.NoError(t, verifier(p).Verify(ev, values))
+
+	// enclave svn too low
+	signer.MinIsvSvn = uint32(isvSvn) + 1
+	err = verifier(p).Verify(ev, values)
+	assert.Contains(t, err.Error(), "MRENCLAVE_MISMATCH")
+
+	// other product
+	signer.MinIsvSvn = 0
+	signer.IsvProdId = uint32(isvProdId) + 1
+	err = verifier(p).Verify(ev, values)
+	assert.Contains(t, err.Error(), "MRENCLAVE_MISMATCH")
+}
+
+func TestDcapAttestationRevokedPck(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5, revokePck: true})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	err := verifier(p).Verify(ev, expected(mrenclave))
+	assert.Contains(t, err.Error(), "is revoked")
+}
+
+func TestDcapAttestationPckCrlOfOtherIssuer(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5, otherPckCrlIssuer: true})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	err := verifier(p).Verify(ev, expected(mrenclave))
+	assert.Contains(t, err.Error(), "does not match pck issuer")
+}
+
+func TestDcapAttestationTcbSigner(t *testing.T) {
+	// collateral signed by a certificate of the Intel PKI other than the TCB signing certificate
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5, tcbSignerName: "Intel SGX PCK Certificate"})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+
+	err := verifier(p).Verify(ev, expected(mrenclave))
+	assert.Contains(t, err.Error(), "unexpected signer")
+}
+
+func TestDcapConverterPCCSErrors(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5})
+	quote := base64.StdEncoding.EncodeToString(p.quote(t, statement, mrenclave, false))
+
+	// pccs not reachable
+	_, err := NewDcapConverter(WithUrl("http://127.0.0.1:0")).Converter([]byte(quote))
+	assert.Error(t, err)
+
+	// unknown endpoint
+	server := p.pccs(t)
+	_, err = NewDcapConverter(WithUrl(server.URL + "/unknown")).Converter([]byte(quote))
+	assert.Contains(t, err.Error(), "404")
+
+	// endpoint via environment
+	t.Setenv(PCCSUrlEnvKey, server.URL)
+	_, err = NewDcapConverter().Converter([]byte(quote))
+	assert.NoError(t, err)
+
+	// invalid quote
+	_, err = NewDcapConverter(WithUrl(server.URL)).Converter([]byte("bm90IGEgcXVvdGU="))
+	assert.Error(t, err)
+}
+
+func TestDcapRootCAFromEnvironment(t *testing.T) {
+	p := newPlatform(t, platformOptions{tcbComponentSvn: 5})
+	ev := evidence(t, p, p.quote(t, statement, mrenclave, false))
+	v := NewDcapVerifier(WithTime(func() time.Time { return fixtureTime }))
+
+	// pinned intel root ca does not trust the fixture
+	t.Setenv(RootCAPathEnvKey, "")
+	err := v.Verify(ev, expected(mrenclave))
+	assert.Contains(t, err.Error(), "invalid certificate chain")
+
+	t.Setenv(RootCAPathEnvKey, t.TempDir()+"/missing.pem")
+	err = v.Verify(ev, expected(mrenclave))
+	assert.Contains(t, err.Error(), "cannot load root ca")
+
+	path := t.TempDir() + "/root_ca.pem"
+	require.NoError(t, writeFile(path, pemChain(p.root)))
+	t.Setenv(RootCAPathEnvKey, path)
+	assert.NoError(t, v.Verify(ev, expected(mrenclave)))
+}
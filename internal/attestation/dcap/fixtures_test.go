@@ -0,0 +1,364 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fixtureTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fmspc       = []byte{0x00, 0x90, 0x6e, 0xd5, 0x00, 0x00}
+	pceId       = []byte{0x00, 0x00}
+	qeMrSigner  = make([]byte, 32)
+	qeAuthData  = make([]byte, 32)
+
+	// identity of the enclave signer
+	isvMrSigner = []byte("abcdefghijabcdefghijabcdefghij01")
+	isvProdId   = uint16(1)
+	isvSvn      = uint16(2)
+)
+
+// platform is a stand-in for an SGX platform along with the Intel PKI
+type platform struct {
+	root, pckCa, tcbSigner, pck             *x509.Certificate
+	pckCrlIssuer                            *x509.Certificate
+	rootKey, pckCaKey, tcbSignerKey, pckKey *ecdsa.PrivateKey
+	attestationKey                          *ecdsa.PrivateKey
+
+	rootCrl, pckCrl []byte
+	tcbInfo         string
+	qeIdentity      string
+}
+
+type platformOptions struct {
+	tcbComponentSvn int
+	revokePck       bool
+	qeOutOfDate     bool
+	// tcbSignerName replaces the common name of the TCB signing certificate
+	tcbSignerName string
+	// otherPckCrlIssuer issues the pck crl by another CA than the issuer of the pck certificate
+	otherPckCrlIssuer bool
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func newCert(t *testing.T, template, parent *x509.Certificate, pub *ecdsa.PublicKey, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	template.NotBefore = fixtureTime.Add(-time.Hour)
+	template.NotAfter = fixtureTime.Add(24 * time.Hour)
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func caTemplate(serial int64, cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"Intel Corporation"}},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+}
+
+func sgxExtensionValue(t *testing.T, tcbComponentSvn int, pceSvn int) []byte {
+	marshal := func(v interface{}) asn1.RawValue {
+		b, err := asn1.Marshal(v)
+		require.NoError(t, err)
+		return asn1.RawValue{FullBytes: b}
+	}
+	sub := func(oid asn1.ObjectIdentifier, n int) asn1.ObjectIdentifier {
+		return append(append(asn1.ObjectIdentifier{}, oid...), n)
+	}
+
+	var tcb []sgxExtension
+	cpuSvn := make([]byte, 16)
+	for i := 1; i <= 16; i++ {
+		tcb = append(tcb, sgxExtension{Id: sub(oidTcb, i), Value: marshal(tcbComponentSvn)})
+		cpuSvn[i-1] = byte(tcbComponentSvn)
+	}
+	tcb = append(tcb, sgxExtension{Id: sub(oidTcb, pceSvnComponent), Value: marshal(pceSvn)})
+	tcb = append(tcb, sgxExtension{Id: sub(oidTcb, cpuSvnComponent), Value: marshal(cpuSvn)})
+
+	value, err := asn1.Marshal([]sgxExtension{
+		{Id: sub(oidSgxExtensions, 1), Value: marshal(make([]byte, 16))}, // ppid
+		{Id: oidTcb, Value: marshal(tcb)},
+		{Id: oidPceId, Value: marshal(pceId)},
+		{Id: oidFmspc, Value: marshal(fmspc)},
+	})
+	require.NoError(t, err)
+	return value
+}
+
+func newCrl(t *testing.T, issuer *x509.Certificate, key *ecdsa.PrivateKey, revoked ...*x509.Certificate) []byte {
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: fixtureTime.Add(-time.Hour),
+		NextUpdate: fixtureTime.Add(24 * time.Hour),
+	}
+	for _, c := range revoked {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   c.SerialNumber,
+			RevocationTime: fixtureTime.Add(-time.Hour),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, issuer, key)
+	require.NoError(t, err)
+	return der
+}
+
+// signBody returns the body as signed PCCS json structure {"<name>":<body>,"signature":"<hex>"}
+func signBody(t *testing.T, name string, body interface{}, key *ecdsa.PrivateKey) string {
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	digest := sha256.Sum256(raw)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signed, err := json.Marshal(map[string]interface{}{
+		name:        json.RawMessage(raw),
+		"signature": hex.EncodeToString(rawSignature(r, s)),
+	})
+	require.NoError(t, err)
+	return string(signed)
+}
+
+func rawSignature(r, s *big.Int) []byte {
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig
+}
+
+func newPlatform(t *testing.T, opts platformOptions) *platform {
+	p := &platform{
+		rootKey:        newKey(t),
+		pckCaKey:       newKey(t),
+		tcbSignerKey:   newKey(t),
+		pckKey:         newKey(t),
+		attestationKey: newKey(t),
+	}
+
+	p.root = newCert(t, caTemplate(1, "Intel SGX Root CA"), nil, &p.rootKey.PublicKey, p.rootKey)
+	pckCaTemplate := caTemplate(2, "Intel SGX PCK Processor CA")
+	pckCaTemplate.CRLDistributionPoints = []string{"http://crl.invalid/root.crl"}
+	p.pckCa = newCert(t, pckCaTemplate, p.root, &p.pckCaKey.PublicKey, p.rootKey)
+	tcbSignerName := "Intel SGX TCB Signing"
+	if len(opts.tcbSignerName) > 0 {
+		tcbSignerName = opts.tcbSignerName
+	}
+	p.tcbSigner = newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: tcbSignerName, Organization: []string{"Intel Corporation"}},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, p.root, &p.tcbSignerKey.PublicKey, p.rootKey)
+	p.pck = newCert(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(4),
+		Subject:         pkix.Name{CommonName: "Intel SGX PCK Certificate"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: oidSgxExtensions, Value: sgxExtensionValue(t, opts.tcbComponentSvn, 11)}},
+	}, p.pckCa, &p.pckKey.PublicKey, p.pckCaKey)
+
+	p.rootCrl = newCrl(t, p.root, p.rootKey)
+	p.pckCrlIssuer = p.pckCa
+	switch {
+	case opts.otherPckCrlIssuer:
+		platformCaKey := newKey(t)
+		p.pckCrlIssuer = newCert(t, caTemplate(5, "Intel SGX PCK Platform CA"), p.root, &platformCaKey.PublicKey, p.rootKey)
+		p.pckCrl = newCrl(t, p.pckCrlIssuer, platformCaKey)
+	case opts.revokePck:
+		p.pckCrl = newCrl(t, p.pckCa, p.pckCaKey, p.pck)
+	default:
+		p.pckCrl = newCrl(t, p.pckCa, p.pckCaKey)
+	}
+
+	level := func(svn int, status string, advisories ...string) map[string]interface{} {
+		var components []map[string]int
+		for i := 0; i < 16; i++ {
+			components = append(components, map[string]int{"svn": svn})
+		}
+		return map[string]interface{}{
+			"tcb":         map[string]interface{}{"sgxtcbcomponents": components, "pcesvn": 11},
+			"tcbDate":     "2023-08-09T00:00:00Z",
+			"tcbStatus":   status,
+			"advisoryIDs": advisories,
+		}
+	}
+	p.tcbInfo = signBody(t, "tcbInfo", map[string]interface{}{
+		"id":         "SGX",
+		"version":    3,
+		"issueDate":  fixtureTime.Add(-time.Hour),
+		"nextUpdate": fixtureTime.Add(24 * time.Hour),
+		"fmspc":      hex.EncodeToString(fmspc),
+		"pceId":      hex.EncodeToString(pceId),
+		"tcbLevels":  []interface{}{level(5, types.TcbUpToDate), level(2, types.TcbOutOfDate, "INTEL-SA-00828")},
+	}, p.tcbSignerKey)
+
+	// the qe of the platform has isvsvn 8
+	qeTcbLevels := []interface{}{
+		map[string]interface{}{"tcb": map[string]int{"isvsvn": 8}, "tcbStatus": types.TcbUpToDate},
+	}
+	if opts.qeOutOfDate {
+		qeTcbLevels = []interface{}{
+			map[string]interface{}{"tcb": map[string]int{"isvsvn": 9}, "tcbStatus": types.TcbUpToDate},
+			map[string]interface{}{"tcb": map[string]int{"isvsvn": 6}, "tcbStatus": types.TcbOutOfDate, "advisoryIDs": []string{"INTEL-SA-00615"}},
+		}
+	}
+	p.qeIdentity = signBody(t, "enclaveIdentity", map[string]interface{}{
+		"id":             "QE",
+		"version":        2,
+		"issueDate":      fixtureTime.Add(-time.Hour),
+		"nextUpdate":     fixtureTime.Add(24 * time.Hour),
+		"miscselect":     "00000000",
+		"miscselectMask": "FFFFFFFF",
+		"attributes":     "11000000000000000000000000000000",
+		"attributesMask": "FBFFFFFFFFFFFFFF0000000000000000",
+		"mrsigner":       hex.EncodeToString(qeMrSigner),
+		"isvprodid":      1,
+		"tcbLevels":      qeTcbLevels,
+	}, p.tcbSignerKey)
+
+	return p
+}
+
+func pemChain(certs ...*x509.Certificate) []byte {
+	var chain []byte
+	for _, c := range certs {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return chain
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	return rawSignature(r, s)
+}
+
+func newReportBody(mrenclave, mrsigner []byte, attributes byte, isvProdId, isvSvn uint16, reportData []byte) []byte {
+	b := make([]byte, reportBodySize)
+	b[48] = attributes
+	copy(b[64:96], mrenclave)
+	copy(b[128:160], mrsigner)
+	binary.LittleEndian.PutUint16(b[256:258], isvProdId)
+	binary.LittleEndian.PutUint16(b[258:260], isvSvn)
+	copy(b[320:384], reportData)
+	return b
+}
+
+// quote creates a DCAP quote for an enclave with the given mrenclave and statement
+func (p *platform) quote(t *testing.T, statement, mrenclave []byte, debug bool) []byte {
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint16(header[0:2], quoteVersion)
+	binary.LittleEndian.PutUint16(header[2:4], attestationKeyEc)
+	binary.LittleEndian.PutUint32(header[4:8], teeTypeSgx)
+	copy(header[12:28], intelQeVendorId)
+
+	attributes := byte(0x5)
+	if debug {
+		attributes |= attributeDebug
+	}
+	statementHash := sha256.Sum256(statement)
+	isvReport := newReportBody(mrenclave, isvMrSigner, attributes, isvProdId, isvSvn, statementHash[:])
+
+	attestationKey := make([]byte, publicKeySize)
+	p.attestationKey.X.FillBytes(attestationKey[:32])
+	p.attestationKey.Y.FillBytes(attestationKey[32:])
+
+	qeReportData := sha256.Sum256(append(append([]byte{}, attestationKey...), qeAuthData...))
+	qeReport := newReportBody(make([]byte, 32), qeMrSigner, 0x11, 1, 8, qeReportData[:])
+
+	chain := pemChain(p.pck, p.pckCa, p.root)
+
+	var sigData []byte
+	sigData = append(sigData, sign(t, p.attestationKey, append(append([]byte{}, header...), isvReport...))...)
+	sigData = append(sigData, attestationKey...)
+	sigData = append(sigData, qeReport...)
+	sigData = append(sigData, sign(t, p.pckKey, qeReport)...)
+	sigData = binary.LittleEndian.AppendUint16(sigData, uint16(len(qeAuthData)))
+	sigData = append(sigData, qeAuthData...)
+	sigData = binary.LittleEndian.AppendUint16(sigData, certDataPckChain)
+	sigData = binary.LittleEndian.AppendUint32(sigData, uint32(len(chain)))
+	sigData = append(sigData, chain...)
+
+	quote := append(append([]byte{}, header...), isvReport...)
+	quote = binary.LittleEndian.AppendUint32(quote, uint32(len(sigData)))
+	return append(quote, sigData...)
+}
+
+func (p *platform) roots() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(p.root)
+	return roots
+}
+
+// pccs starts a local PCCS stand-in serving the collateral of the platform
+func (p *platform) pccs(t *testing.T) *httptest.Server {
+	issuerChain := url.QueryEscape(string(pemChain(p.tcbSigner, p.root)))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pckcrl", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ca") != processorCA {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(pckCrlIssuerChainHeader, url.QueryEscape(string(pemChain(p.pckCrlIssuer, p.root))))
+		_, _ = w.Write([]byte(hex.EncodeToString(p.pckCrl)))
+	})
+	mux.HandleFunc("/tcb", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fmspc") != hex.EncodeToString(fmspc) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(tcbInfoIssuerChainHeader, issuerChain)
+		_, _ = w.Write([]byte(p.tcbInfo))
+	})
+	mux.HandleFunc("/qe/identity", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(qeIdentityIssuerChainHeader, issuerChain)
+		_, _ = w.Write([]byte(p.qeIdentity))
+	})
+	mux.HandleFunc("/rootcacrl", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: p.rootCrl}))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeFile(path string, data []byte) error {
+	return os.WriteFile(path, data, 0600)
+}
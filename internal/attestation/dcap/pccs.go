@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPCCSUrl is the Intel Provisioning Certification Service, which is PCCS compatible
+	DefaultPCCSUrl = "https://api.trustedservices.intel.com/sgx/certification/v4"
+
+	// PCCSUrlEnvKey is the environment variable to override the PCCS endpoint used by the converter
+	PCCSUrlEnvKey = "PCCS_URL"
+
+	pckCrlIssuerChainHeader     = "SGX-PCK-CRL-Issuer-Chain"
+	tcbInfoIssuerChainHeader    = "TCB-Info-Issuer-Chain"
+	qeIdentityIssuerChainHeader = "SGX-Enclave-Identity-Issuer-Chain"
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type PCCSClient struct {
+	url        string
+	httpClient HTTPClient
+}
+
+type PCCSClientOption func(*PCCSClient)
+
+// WithUrl option allows to override the default PCCS endpoint (DefaultPCCSUrl)
+func WithUrl(url string) PCCSClientOption {
+	return func(c *PCCSClient) {
+		c.url = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithHttpClient option allows to use a custom http client, e.g., to trust the TLS certificate of a local PCCS
+func WithHttpClient(client HTTPClient) PCCSClientOption {
+	return func(c *PCCSClient) {
+		c.httpClient = client
+	}
+}
+
+// NewPCCSClient returns a new PCCSClient instance. The endpoint is read from PCCS_URL if set, otherwise
+// DefaultPCCSUrl is used. Optionally, PCCSClientOption can be provided to change the behavior of the PCCSClient.
+func NewPCCSClient(opts ...PCCSClientOption) *PCCSClient {
+	client := &PCCSClient{
+		url: DefaultPCCSUrl,
+	}
+	if u := os.Getenv(PCCSUrlEnvKey); len(u) != 0 {
+		WithUrl(u)(client)
+	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	// create default http client if not provided via options
+	if client.httpClient == nil {
+		client.httpClient = &http.Client{}
+	}
+
+	return client
+}
+
+// GetCollateral fetches the collateral for the platform identified by fmspc and the type of its PCK CA.
+// If the endpoint does not serve the root CA CRL (e.g., Intel PCS), it is fetched from rootCaCrlUrl instead.
+func (p *PCCSClient) GetCollateral(fmspc, ca, rootCaCrlUrl string) (*Collateral, error) {
+	c := &Collateral{}
+	var err error
+
+	if c.PckCrl, c.PckCrlIssuerChain, err = p.get(p.url+"/pckcrl?ca="+neturl.QueryEscape(ca), pckCrlIssuerChainHeader); err != nil {
+		return nil, errors.Wrap(err, "cannot get pck crl")
+	}
+	if c.TcbInfo, c.TcbInfoIssuerChain, err = p.get(p.url+"/tcb?fmspc="+neturl.QueryEscape(fmspc), tcbInfoIssuerChainHeader); err != nil {
+		return nil, errors.Wrap(err, "cannot get tcb info")
+	}
+	if c.QeIdentity, c.QeIdentityIssuerChain, err = p.get(p.url+"/qe/identity", qeIdentityIssuerChainHeader); err != nil {
+		return nil, errors.Wrap(err, "cannot get qe identity")
+	}
+	if c.RootCaCrl, _, err = p.get(p.url+"/rootcacrl", ""); err != nil {
+		if len(rootCaCrlUrl) == 0 {
+			return nil, errors.Wrap(err, "cannot get root ca crl")
+		}
+		if c.RootCaCrl, _, err = p.get(rootCaCrlUrl, ""); err != nil {
+			return nil, errors.Wrap(err, "cannot get root ca crl")
+		}
+	}
+
+	return c, nil
+}
+
+// get performs a GET request and returns the body and the (url decoded) issuer chain header
+func (p *PCCSClient) get(url, issuerChainHeader string) (body string, issuerChain string, err error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot create http request")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot perform http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return "", "", fmt.Errorf("request failed! Reason: %d %s. Request ID: %s", resp.StatusCode, resp.Status, resp.Header.Get("Request-ID"))
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot read response")
+	}
+
+	if len(issuerChainHeader) != 0 {
+		issuerChain, err = neturl.QueryUnescape(resp.Header.Get(issuerChainHeader))
+		if err != nil {
+			return "", "", errors.Wrap(err, "cannot decode issuer chain")
+		}
+		if len(issuerChain) == 0 {
+			return "", "", fmt.Errorf("missing %s header", issuerChainHeader)
+		}
+	}
+
+	return string(bodyBytes), issuerChain, nil
+}
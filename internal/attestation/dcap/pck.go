@@ -0,0 +1,243 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	oidSgxExtensions = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1}
+	oidTcb           = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2}
+	oidPceId         = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 3}
+	oidFmspc         = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 4}
+)
+
+const (
+	pceSvnComponent = 17
+	cpuSvnComponent = 18
+
+	processorCA = "processor"
+	platformCA  = "platform"
+)
+
+type sgxExtension struct {
+	Id    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// PckExtensions contains the SGX specific extensions of a PCK certificate
+type PckExtensions struct {
+	Fmspc         string
+	PceId         string
+	CpuSvn        []byte
+	PceSvn        int
+	TcbComponents [16]int
+}
+
+func parsePckExtensions(cert *x509.Certificate) (*PckExtensions, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSgxExtensions) {
+			continue
+		}
+
+		var entries []sgxExtension
+		if _, err := asn1.Unmarshal(ext.Value, &entries); err != nil {
+			return nil, errors.Wrap(err, "cannot parse sgx extensions")
+		}
+
+		pck := &PckExtensions{}
+		for _, e := range entries {
+			switch {
+			case e.Id.Equal(oidFmspc):
+				pck.Fmspc = hex.EncodeToString(e.Value.Bytes)
+			case e.Id.Equal(oidPceId):
+				pck.PceId = hex.EncodeToString(e.Value.Bytes)
+			case e.Id.Equal(oidTcb):
+				if err := parseTcbExtension(e.Value.FullBytes, pck); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if len(pck.Fmspc) == 0 || len(pck.CpuSvn) == 0 {
+			return nil, fmt.Errorf("incomplete sgx extensions")
+		}
+		return pck, nil
+	}
+
+	return nil, fmt.Errorf("no sgx extensions found")
+}
+
+func parseTcbExtension(b []byte, pck *PckExtensions) error {
+	var components []sgxExtension
+	if _, err := asn1.Unmarshal(b, &components); err != nil {
+		return errors.Wrap(err, "cannot parse tcb extension")
+	}
+
+	for _, c := range components {
+		if len(c.Id) != len(oidTcb)+1 || !c.Id[:len(oidTcb)].Equal(oidTcb) {
+			continue
+		}
+
+		switch n := c.Id[len(oidTcb)]; {
+		case n == cpuSvnComponent:
+			pck.CpuSvn = c.Value.Bytes
+		default:
+			var svn int
+			if _, err := asn1.Unmarshal(c.Value.FullBytes, &svn); err != nil {
+				return errors.Wrap(err, "cannot parse tcb component")
+			}
+			if n == pceSvnComponent {
+				pck.PceSvn = svn
+			} else if n >= 1 && n <= 16 {
+				pck.TcbComponents[n-1] = svn
+			}
+		}
+	}
+
+	return nil
+}
+
+// pckCAType returns the type of the CA that issued the PCK certificate
+func pckCAType(cert *x509.Certificate) (string, error) {
+	issuer := strings.ToLower(cert.Issuer.CommonName)
+	switch {
+	case strings.Contains(issuer, processorCA):
+		return processorCA, nil
+	case strings.Contains(issuer, platformCA):
+		return platformCA, nil
+	default:
+		return "", fmt.Errorf("unknown pck issuer '%s'", cert.Issuer.CommonName)
+	}
+}
+
+// parseCertChain parses a PEM encoded certificate chain, starting with the leaf certificate
+func parseCertChain(chainPEM []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, chainPEM = pem.Decode(chainPEM)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse certificate")
+		}
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("empty certificate chain")
+	}
+	return chain, nil
+}
+
+// verifyCertChain verifies the PEM encoded certificate chain against the trusted roots and
+// the given CRLs and returns the leaf certificate
+func verifyCertChain(chainPEM []byte, roots *x509.CertPool, now time.Time, crls ...*x509.RevocationList) (*x509.Certificate, error) {
+	chain, err := parseCertChain(chainPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+
+	verifiedChains, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid certificate chain")
+	}
+
+	for _, c := range verifiedChains[0] {
+		if err := checkRevocation(c, verifiedChains[0], crls, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return chain[0], nil
+}
+
+// checkRevocation checks that the certificate is not revoked by any CRL of its issuer
+func checkRevocation(cert *x509.Certificate, chain []*x509.Certificate, crls []*x509.RevocationList, now time.Time) error {
+	for _, crl := range crls {
+		if crl.Issuer.String() != cert.Issuer.String() {
+			continue
+		}
+
+		issuer := findIssuer(cert, chain)
+		if issuer == nil {
+			return fmt.Errorf("no issuer for crl '%s'", crl.Issuer)
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			return errors.Wrap(err, "invalid crl signature")
+		}
+		if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+			return fmt.Errorf("crl of '%s' expired", crl.Issuer)
+		}
+
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return fmt.Errorf("certificate '%s' is revoked", cert.Subject)
+			}
+		}
+	}
+
+	return nil
+}
+
+func findIssuer(cert *x509.Certificate, chain []*x509.Certificate) *x509.Certificate {
+	for _, c := range chain {
+		if c.Subject.String() == cert.Issuer.String() && cert.CheckSignatureFrom(c) == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseCRL parses a CRL given as PEM, DER, or hex encoded DER
+func parseCRL(crlBytes []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(crlBytes); block != nil {
+		crlBytes = block.Bytes
+	} else if decoded, err := hex.DecodeString(strings.TrimSpace(string(crlBytes))); err == nil {
+		crlBytes = decoded
+	}
+
+	crl, err := x509.ParseRevocationList(crlBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse crl")
+	}
+	return crl, nil
+}
+
+// verifySignature verifies a raw (r||s) ECDSA P-256 signature with the given public key
+func verifySignature(pub *ecdsa.PublicKey, digest, signature []byte) bool {
+	if len(signature) != signatureSize {
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(pub, digest, r, s)
+}
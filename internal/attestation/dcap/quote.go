@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	quoteVersion     = 3
+	attestationKeyEc = 2 // ECDSA-256-with-P-256 curve
+	certDataPckChain = 5 // concatenated PCK certificate chain (PEM)
+	teeTypeSgx       = 0
+
+	headerSize     = 48
+	reportBodySize = 384
+	signatureSize  = 64
+	publicKeySize  = 64
+
+	attributeDebug = 0x2
+)
+
+// intelQeVendorId identifies the Intel quoting enclave
+var intelQeVendorId = []byte{0x93, 0x9a, 0x72, 0x33, 0xf7, 0x9c, 0x4c, 0xa9, 0x94, 0x0a, 0x0d, 0xb3, 0x95, 0x7f, 0x06, 0x07}
+
+// ReportBody is an SGX enclave report body
+type ReportBody struct {
+	Raw        []byte
+	CpuSvn     []byte
+	MiscSelect uint32
+	Attributes []byte
+	MrEnclave  []byte
+	MrSigner   []byte
+	IsvProdId  uint16
+	IsvSvn     uint16
+	ReportData []byte
+}
+
+// Debug returns true if the report is from a debug enclave
+func (r *ReportBody) Debug() bool {
+	return r.Attributes[0]&attributeDebug != 0
+}
+
+// Quote is an SGX DCAP quote (version 3) using an ECDSA P-256 attestation key
+type Quote struct {
+	Header               []byte
+	QeSvn                uint16
+	PceSvn               uint16
+	IsvReport            *ReportBody
+	IsvReportSignature   []byte
+	AttestationKey       []byte
+	QeReport             *ReportBody
+	QeReportSignature    []byte
+	QeAuthData           []byte
+	PckCertChainPEM      []byte
+	signedIsvReportBytes []byte
+}
+
+func parseReportBody(b []byte) *ReportBody {
+	return &ReportBody{
+		Raw:        b,
+		CpuSvn:     b[0:16],
+		MiscSelect: binary.LittleEndian.Uint32(b[16:20]),
+		Attributes: b[48:64],
+		MrEnclave:  b[64:96],
+		MrSigner:   b[128:160],
+		IsvProdId:  binary.LittleEndian.Uint16(b[256:258]),
+		IsvSvn:     binary.LittleEndian.Uint16(b[258:260]),
+		ReportData: b[320:384],
+	}
+}
+
+// reader consumes a byte slice and remembers if it ran out of data
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data) < n {
+		r.err = fmt.Errorf("quote too short")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// ParseQuote parses a raw SGX DCAP quote
+func ParseQuote(quoteBytes []byte) (*Quote, error) {
+	r := &reader{data: quoteBytes}
+	header := r.next(headerSize)
+	reportBody := r.next(reportBodySize)
+	signatureDataLen := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	version := binary.LittleEndian.Uint16(header[0:2])
+	if version != quoteVersion {
+		return nil, fmt.Errorf("unsupported quote version %d", version)
+	}
+	if keyType := binary.LittleEndian.Uint16(header[2:4]); keyType != attestationKeyEc {
+		return nil, fmt.Errorf("unsupported attestation key type %d", keyType)
+	}
+	if teeType := binary.LittleEndian.Uint32(header[4:8]); teeType != teeTypeSgx {
+		return nil, fmt.Errorf("unsupported tee type %d", teeType)
+	}
+	if qeVendorId := header[12:28]; !bytes.Equal(qeVendorId, intelQeVendorId) {
+		return nil, fmt.Errorf("unsupported qe vendor id %x", qeVendorId)
+	}
+
+	q := &Quote{
+		Header:               header,
+		QeSvn:                binary.LittleEndian.Uint16(header[8:10]),
+		PceSvn:               binary.LittleEndian.Uint16(header[10:12]),
+		IsvReport:            parseReportBody(reportBody),
+		signedIsvReportBytes: quoteBytes[:headerSize+reportBodySize],
+	}
+
+	r = &reader{data: r.next(int(signatureDataLen))}
+	q.IsvReportSignature = r.next(signatureSize)
+	q.AttestationKey = r.next(publicKeySize)
+	qeReport := r.next(reportBodySize)
+	q.QeReportSignature = r.next(signatureSize)
+	q.QeAuthData = r.next(int(r.uint16()))
+	certDataType := r.uint16()
+	q.PckCertChainPEM = r.next(int(r.uint32()))
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if certDataType != certDataPckChain {
+		return nil, fmt.Errorf("unsupported certification data type %d", certDataType)
+	}
+	q.QeReport = parseReportBody(qeReport)
+
+	return q, nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// RootCAPathEnvKey is the environment variable pointing to a PEM encoded root CA certificate that replaces the
+// pinned Intel SGX Root CA, e.g., for testing
+const RootCAPathEnvKey = "DCAP_ROOT_CA_PATH"
+
+// loadRootCAs loads the trusted root CA from DCAP_ROOT_CA_PATH.
+// If env var not set, the pinned Intel SGX Root CA is returned
+func loadRootCAs() (*x509.CertPool, error) {
+	path := os.Getenv(RootCAPathEnvKey)
+	if len(path) == 0 {
+		return defaultRootCAs(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", path)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no certificate found in %s", path)
+	}
+
+	return roots, nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dcap
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/pkg/errors"
+)
+
+type dcapVerifier struct {
+	roots *x509.CertPool
+	now   func() time.Time
+}
+
+type VerifierOption func(*dcapVerifier)
+
+// WithRootCAs option allows to set the trusted root CAs. If not set, the pinned Intel SGX Root CA is used, unless
+// replaced by the root CA at DCAP_ROOT_CA_PATH
+func WithRootCAs(roots *x509.CertPool) VerifierOption {
+	return func(v *dcapVerifier) {
+		v.roots = roots
+	}
+}
+
+// WithTime option allows to set the time used for the validity checks. Mainly used for testing with recorded fixtures
+func WithTime(now func() time.Time) VerifierOption {
+	return func(v *dcapVerifier) {
+		v.now = now
+	}
+}
+
+// NewDcapVerifier creates a new attestation verifier for Intel SGX DCAP (ECDSA) attestation.
+// The TCB status of the platform and of the quoting enclave are checked against the policy of the validation values
+// (see types.CheckTcbStatus).
+func NewDcapVerifier(opts ...VerifierOption) *types.Verifier {
+	v := &dcapVerifier{
+		now: time.Now,
+	}
+
+	// apply options
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return &types.Verifier{
+		Type:   DcapType,
+		Verify: v.verify,
+	}
+}
+
+func (v *dcapVerifier) verify(evidence *types.Evidence, expectedValidationValues *types.ValidationValues) error {
+	e := &Evidence{}
+	if err := json.Unmarshal([]byte(evidence.Data), e); err != nil {
+		return types.Reject(types.RejectInvalidEvidence, "cannot unmarshal dcap evidence: %s", err)
+	}
+
+	report, tcb, qeTcb, err := v.verifyEvidence(e)
+	if err != nil {
+		return types.Reject(types.RejectInvalidEvidence, "%s", err)
+	}
+
+	if err := types.CheckTcbStatus(expectedValidationValues.Policy, tcb.Status, tcb.AdvisoryIDs); err != nil {
+		return err
+	}
+	if err := types.CheckTcbStatus(expectedValidationValues.Policy, qeTcb.Status, qeTcb.AdvisoryIDs); err != nil {
+		return errors.WithMessage(err, "quoting enclave")
+	}
+
+	return checkReport(report, expectedValidationValues)
+}
+
+// verifyEvidence verifies the quote and its collateral and returns the enclave report along with the TCB status
+// of the platform and of the quoting enclave
+func (v *dcapVerifier) verifyEvidence(e *Evidence) (*ReportBody, *TcbResult, *TcbResult, error) {
+	if e.Collateral == nil {
+		return nil, nil, nil, fmt.Errorf("missing collateral")
+	}
+
+	roots := v.roots
+	if roots == nil {
+		var err error
+		if roots, err = loadRootCAs(); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "cannot load root ca")
+		}
+	}
+	now := v.now()
+
+	quoteBytes, err := base64.StdEncoding.DecodeString(e.Quote)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "cannot decode quote")
+	}
+	quote, err := ParseQuote(quoteBytes)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "cannot parse quote")
+	}
+
+	rootCrl, err := parseCRL([]byte(e.Collateral.RootCaCrl))
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid root ca crl")
+	}
+
+	// the pck crl is issued by the pck ca, which must chain to a trusted root
+	pckCa, err := verifyCertChain([]byte(e.Collateral.PckCrlIssuerChain), roots, now, rootCrl)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid pck crl issuer chain")
+	}
+	pckCrl, err := parseCRL([]byte(e.Collateral.PckCrl))
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid pck crl")
+	}
+	if err := pckCrl.CheckSignatureFrom(pckCa); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid pck crl signature")
+	}
+
+	// pck certificate
+	pckCert, err := verifyCertChain(quote.PckCertChainPEM, roots, now, rootCrl, pckCrl)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid pck certificate")
+	}
+	// the revocation of the pck certificate is only checked by the crl of its issuer
+	if pckCrl.Issuer.String() != pckCert.Issuer.String() {
+		return nil, nil, nil, fmt.Errorf("pck crl of '%s' does not match pck issuer '%s'", pckCrl.Issuer, pckCert.Issuer)
+	}
+	pckKey, ok := pckCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported pck key")
+	}
+	pck, err := parsePckExtensions(pckCert)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the qe report is signed by the pck and binds the attestation key
+	digest := sha256.Sum256(quote.QeReport.Raw)
+	if !verifySignature(pckKey, digest[:], quote.QeReportSignature) {
+		return nil, nil, nil, fmt.Errorf("invalid qe report signature")
+	}
+	expectedQeReportData := sha256.Sum256(append(append([]byte{}, quote.AttestationKey...), quote.QeAuthData...))
+	if !bytes.Equal(quote.QeReport.ReportData[:32], expectedQeReportData[:]) || !isZero(quote.QeReport.ReportData[32:]) {
+		return nil, nil, nil, fmt.Errorf("qe report does not bind attestation key")
+	}
+	qeTcb, err := verifyQeIdentity(e.Collateral, quote.QeReport, roots, now, rootCrl)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the enclave report is signed by the attestation key
+	attestationKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(quote.AttestationKey[:32]),
+		Y:     new(big.Int).SetBytes(quote.AttestationKey[32:]),
+	}
+	digest = sha256.Sum256(quote.signedIsvReportBytes)
+	if !verifySignature(attestationKey, digest[:], quote.IsvReportSignature) {
+		return nil, nil, nil, fmt.Errorf("invalid quote signature")
+	}
+
+	tcb, err := verifyTcbInfo(e.Collateral, pck, roots, now, rootCrl)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return quote.IsvReport, tcb, qeTcb, nil
+}
+
+func checkReport(report *ReportBody, expectedValidationValues *types.ValidationValues) error {
+	if report.Debug() {
+		return types.Reject(types.RejectDebugEnclave, "report is from a debug enclave")
+	}
+
+	// the report data starts with the hash of the statement
+	hash := sha256.Sum256(expectedValidationValues.Statement)
+	if !bytes.Equal(report.ReportData[:len(hash)], hash[:]) {
+		return types.Reject(types.RejectStatementMismatch, "report data does not match statement")
+	}
+
+	return types.CheckEnclaveIdentity(expectedValidationValues, &types.EnclaveIdentity{
+		Mrenclave: hex.EncodeToString(report.MrEnclave),
+		Mrsigner:  hex.EncodeToString(report.MrSigner),
+		IsvProdId: uint32(report.IsvProdId),
+		IsvSvn:    uint32(report.IsvSvn),
+	})
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
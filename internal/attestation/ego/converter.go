@@ -0,0 +1,23 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ego
+
+import (
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+)
+
+// NewEgoConverter creates a new attestation converter for EGo/OpenEnclave remote reports.
+// A remote report is self-contained evidence, thus, no conversion is required.
+func NewEgoConverter() *types.Converter {
+	return &types.Converter{
+		Type: EgoType,
+		Converter: func(attestationBytes []byte) (evidenceBytes []byte, err error) {
+			// NO-OP
+			return attestationBytes, nil
+		},
+	}
+}
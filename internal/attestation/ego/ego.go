@@ -0,0 +1,42 @@
+//go:build ego
+// +build ego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ego
+
+import (
+	"errors"
+
+	"github.com/edgelesssys/ego/attestation"
+	"github.com/edgelesssys/ego/eclient"
+	"github.com/edgelesssys/ego/enclave"
+)
+
+func getRemoteReport(reportData []byte) ([]byte, error) {
+	return enclave.GetRemoteReport(reportData)
+}
+
+func verifyRemoteReport(reportBytes []byte) (*Report, error) {
+	// a report of a platform whose TCB is not up to date is still returned, so that the TCB status can be
+	// checked against the verification policy
+	report, err := eclient.VerifyRemoteReport(reportBytes)
+	if err != nil && !errors.Is(err, attestation.ErrTCBLevelInvalid) {
+		return nil, err
+	}
+
+	return &Report{
+		Data:            report.Data,
+		UniqueID:        report.UniqueID,
+		SignerID:        report.SignerID,
+		ProductID:       report.ProductID,
+		SecurityVersion: report.SecurityVersion,
+		Debug:           report.Debug,
+		TcbStatus:       report.TCBStatus.String(),
+		AdvisoryIDs:     report.TCBAdvisories,
+	}, nil
+}
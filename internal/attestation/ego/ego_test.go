@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ego
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	mrenclave = []byte("someMrenclave")
+	mrsigner  = []byte("someMrsigner")
+)
+
+// fakeReport "signs" a report by prefixing the report data with the mrenclave
+func fakeReport(reportData []byte) ([]byte, error) {
+	return append(append([]byte{}, mrenclave...), reportData...), nil
+}
+
+func fakeVerify(debug bool) VerifyReportFunction {
+	return func(reportBytes []byte) (*Report, error) {
+		if len(reportBytes) < len(mrenclave) {
+			return nil, fmt.Errorf("invalid signature")
+		}
+		return &Report{
+			Data:            reportBytes[len(mrenclave):],
+			UniqueID:        reportBytes[:len(mrenclave)],
+			SignerID:        mrsigner,
+			ProductID:       []byte{0x02, 0x00},
+			SecurityVersion: 3,
+			Debug:           debug,
+			TcbStatus:       types.TcbUpToDate,
+		}, nil
+	}
+}
+
+func issueEvidence(t *testing.T, statement []byte) *types.Evidence {
+	attestationBytes, err := newIssuer(fakeReport).Issue(statement)
+	assert.NoError(t, err)
+
+	att := &types.Attestation{}
+	assert.NoError(t, json.Unmarshal(attestationBytes, att))
+	assert.Equal(t, EgoType, att.Type)
+
+	evidenceBytes, err := NewEgoConverter().Converter([]byte(att.Data))
+	assert.NoError(t, err)
+
+	return &types.Evidence{Type: att.Type, Data: string(evidenceBytes)}
+}
+
+func TestEgoAttestation(t *testing.T) {
+	statement := []byte("someStatement")
+	evidence := issueEvidence(t, statement)
+	expected := &types.ValidationValues{
+		Statement: statement,
+		Mrenclave: hex.EncodeToString(mrenclave),
+	}
+
+	verifier := newVerifier(fakeVerify(false))
+	assert.NoError(t, verifier.Verify(evidence, expected))
+
+	// other statement
+	err := verifier.Verify(evidence, &types.ValidationValues{Statement: []byte("otherStatement"), Mrenclave: expected.Mrenclave})
+	assert.EqualError(t, err, "STATEMENT_MISMATCH: report data does not match statement")
+
+	// other mrenclave
+	err = verifier.Verify(evidence, &types.ValidationValues{Statement: statement, Mrenclave: "abcd"})
+	assert.Contains(t, err.Error(), "MRENCLAVE_MISMATCH")
+
+	// debug enclave
+	err = newVerifier(fakeVerify(true)).Verify(evidence, expected)
+	assert.EqualError(t, err, "DEBUG_ENCLAVE: report is from a debug enclave")
+
+	// invalid report
+	err = verifier.Verify(&types.Evidence{Type: EgoType, Data: "MA=="}, expected)
+	assert.Contains(t, err.Error(), "INVALID_EVIDENCE: invalid remote report")
+}
+
+func TestEgoAttestationTcbStatus(t *testing.T) {
+	statement := []byte("someStatement")
+	evidence := issueEvidence(t, statement)
+	verifier := newVerifier(func(reportBytes []byte) (*Report, error) {
+		report, err := fakeVerify(false)(reportBytes)
+		report.TcbStatus = types.TcbSWHardeningNeeded
+		report.AdvisoryIDs = []string{"INTEL-SA-00615"}
+		return report, err
+	})
+	expected := &types.ValidationValues{
+		Statement: statement,
+		Mrenclave: hex.EncodeToString(mrenclave),
+	}
+
+	err := verifier.Verify(evidence, expected)
+	assert.Contains(t, err.Error(), "TCB_STATUS_NOT_ACCEPTED")
+
+	expected.Policy = &protos.AttestationVerificationPolicy{
+		AcceptedTcbStatus:  []string{types.TcbUpToDate, types.TcbSWHardeningNeeded},
+		AllowedAdvisoryIds: []string{"INTEL-SA-00615"},
+	}
+	assert.NoError(t, verifier.Verify(evidence, expected))
+}
+
+func TestEgoAttestationSignerPolicy(t *testing.T) {
+	statement := []byte("someStatement")
+	evidence := issueEvidence(t, statement)
+	verifier := newVerifier(fakeVerify(false))
+	signer := &protos.EnclaveSignerPolicy{Mrsigner: hex.EncodeToString(mrsigner), IsvProdId: 2, MinIsvSvn: 3}
+	expected := &types.ValidationValues{
+		Statement: statement,
+		Mrenclave: "abcd",
+		Policy:    &protos.AttestationVerificationPolicy{AllowedSigners: []*protos.EnclaveSignerPolicy{signer}},
+	}
+	assert.NoError(t, verifier.Verify(evidence, expected))
+
+	signer.MinIsvSvn = 4
+	err := verifier.Verify(evidence, expected)
+	assert.Contains(t, err.Error(), "MRENCLAVE_MISMATCH")
+
+	signer.MinIsvSvn = 0
+	signer.Mrsigner = "abcd"
+	err = verifier.Verify(evidence, expected)
+	assert.Contains(t, err.Error(), "MRENCLAVE_MISMATCH")
+}
+
+func TestEgoNotSupported(t *testing.T) {
+	_, err := NewEgoIssuer().Issue([]byte("someStatement"))
+	assert.Error(t, err)
+
+	err = NewEgoVerifier().Verify(&types.Evidence{Type: EgoType, Data: "MA=="}, &types.ValidationValues{})
+	assert.Error(t, err)
+}
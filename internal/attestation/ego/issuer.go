@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ego
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/pkg/errors"
+)
+
+// EgoType is the attestation type of EGo/OpenEnclave remote reports
+const EgoType = "ego"
+
+// GetReportFunction returns a remote report with the given report data embedded
+type GetReportFunction func(reportData []byte) ([]byte, error)
+
+// NewEgoIssuer creates a new attestation issuer producing EGo/OpenEnclave remote reports.
+// Note that this issuer requires the chaincode to be built with the `ego` build tag and to run inside an enclave.
+func NewEgoIssuer() *types.Issuer {
+	return newIssuer(getRemoteReport)
+}
+
+func newIssuer(getReport GetReportFunction) *types.Issuer {
+	return &types.Issuer{
+		Type: EgoType,
+		Issue: func(customData []byte) ([]byte, error) {
+			// the report binds the hash of the statement
+			hash := sha256.Sum256(customData)
+			report, err := getReport(hash[:])
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot get remote report")
+			}
+
+			return json.Marshal(&types.Attestation{
+				Type: EgoType,
+				Data: base64.StdEncoding.EncodeToString(report),
+			})
+		},
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !ego
+// +build !ego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ego
+
+import "fmt"
+
+var errNotSupported = fmt.Errorf("ego attestation not supported; build with `-tags ego`")
+
+func getRemoteReport(reportData []byte) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+func verifyRemoteReport(reportBytes []byte) (*Report, error) {
+	return nil, errNotSupported
+}
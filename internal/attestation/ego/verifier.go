@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ego
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+)
+
+// Report contains the information of a verified remote report that is relevant for FPC
+type Report struct {
+	Data            []byte
+	UniqueID        []byte
+	SignerID        []byte
+	ProductID       []byte
+	SecurityVersion uint
+	Debug           bool
+	TcbStatus       string
+	AdvisoryIDs     []string
+}
+
+// VerifyReportFunction verifies the signature of a remote report and returns its content.
+// Note that the report is returned even if the platform TCB is not up to date; the TCB status is checked by the verifier.
+type VerifyReportFunction func(reportBytes []byte) (*Report, error)
+
+// NewEgoVerifier creates a new attestation verifier for EGo/OpenEnclave remote reports.
+// Note that the verification of the report signature requires the verifier to be built with the `ego` build tag.
+func NewEgoVerifier() *types.Verifier {
+	return newVerifier(verifyRemoteReport)
+}
+
+func newVerifier(verifyReport VerifyReportFunction) *types.Verifier {
+	return &types.Verifier{
+		Type: EgoType,
+		Verify: func(evidence *types.Evidence, expectedValidationValues *types.ValidationValues) error {
+			reportBytes, err := base64.StdEncoding.DecodeString(evidence.Data)
+			if err != nil {
+				return types.Reject(types.RejectInvalidEvidence, "cannot decode remote report: %s", err)
+			}
+
+			report, err := verifyReport(reportBytes)
+			if err != nil {
+				return types.Reject(types.RejectInvalidEvidence, "invalid remote report: %s", err)
+			}
+
+			if err := types.CheckTcbStatus(expectedValidationValues.Policy, report.TcbStatus, report.AdvisoryIDs); err != nil {
+				return err
+			}
+
+			return checkReport(report, expectedValidationValues)
+		},
+	}
+}
+
+func checkReport(report *Report, expectedValidationValues *types.ValidationValues) error {
+	if report.Debug {
+		return types.Reject(types.RejectDebugEnclave, "report is from a debug enclave")
+	}
+
+	// the report data starts with the hash of the statement
+	hash := sha256.Sum256(expectedValidationValues.Statement)
+	if len(report.Data) < len(hash) || !bytes.Equal(report.Data[:len(hash)], hash[:]) {
+		return types.Reject(types.RejectStatementMismatch, "report data does not match statement")
+	}
+
+	// the product id is a little endian integer
+	var isvProdId uint32
+	if len(report.ProductID) >= 2 {
+		isvProdId = uint32(binary.LittleEndian.Uint16(report.ProductID))
+	}
+
+	return types.CheckEnclaveIdentity(expectedValidationValues, &types.EnclaveIdentity{
+		Mrenclave: hex.EncodeToString(report.UniqueID),
+		Mrsigner:  hex.EncodeToString(report.SignerID),
+		IsvProdId: isvProdId,
+		IsvSvn:    uint32(report.SecurityVersion),
+	})
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package epid
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultReportCacheTTL is the default time an IAS report is cached
+const DefaultReportCacheTTL = time.Hour
+
+type cacheEntry struct {
+	reportJson string
+	expiry     time.Time
+}
+
+// ReportCache caches IAS reports by quote. Since a report reflects the TCB status of the platform at the time of
+// the request, entries expire after a TTL.
+type ReportCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]*cacheEntry
+}
+
+// NewReportCache returns a new ReportCache whose entries expire after ttl
+func NewReportCache(ttl time.Duration) *ReportCache {
+	return &ReportCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+func (c *ReportCache) get(key string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	if c.now().After(entry.expiry) {
+		delete(c.entries, key)
+		return "", false
+	}
+
+	return entry.reportJson, true
+}
+
+func (c *ReportCache) put(key string, reportJson string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// drop expired entries
+	now := c.now()
+	for k, entry := range c.entries {
+		if now.After(entry.expiry) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = &cacheEntry{reportJson: reportJson, expiry: now.Add(c.ttl)}
+}
@@ -17,6 +17,9 @@ const (
 	LinkableType   = "epid-linkable"
 )
 
+// reportCache is shared by all converters, so that repeated conversions of the same quote do not contact IAS
+var reportCache = NewReportCache(DefaultReportCacheTTL)
+
 // NewEpidUnlinkableConverter creates a new attestation converter for Intel SGX EPID (unlinkable) attestation
 func NewEpidUnlinkableConverter() *types.Converter {
 	return &types.Converter{
@@ -41,7 +44,17 @@ func newEpidConverter() types.ConvertFunction {
 			return nil, errors.Wrap(err, "cannot load IAS API key")
 		}
 
-		ias := NewIASClient(apiKey)
+		reportRoots, err := loadReportRootCAs()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load IAS report root CA")
+		}
+
+		tlsRoots, err := loadTLSRootCAs()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot load IAS TLS CA")
+		}
+
+		ias := NewIASClient(apiKey, WithReportRootCAs(reportRoots), WithTLSRootCAs(tlsRoots), WithReportCache(reportCache))
 		evidence, err := ias.RequestAttestationReport(string(attestationBytes))
 		if err != nil {
 			return nil, errors.Wrap(err, "cannot convert epid attestation")
@@ -8,16 +8,34 @@ package epid
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"time"
 
+	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/pkg/errors"
 )
 
+var logger = flogging.MustGetLogger("fpc.attestation.epid")
+
 const DefaultIASUrl = "https://api.trustedservices.intel.com/sgx/dev/attestation/v4/report"
 
+const (
+	// DefaultTimeout is the default timeout of a single request to IAS
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxRetries is the default number of retries after a failed request to IAS
+	DefaultMaxRetries = 3
+	// DefaultBackoff is the default delay before the first retry; the delay doubles with each further retry
+	DefaultBackoff = 500 * time.Millisecond
+)
+
 type IntelAttestationService interface {
 	RequestAttestationReport(quoteBase64 string) (reportJson string, err error)
 }
@@ -58,6 +76,18 @@ type IASClient struct {
 	url        string
 	apiKey     string
 	httpClient HTTPClient
+
+	timeout    time.Duration
+	proxy      *url.URL
+	tlsRootCAs *x509.CertPool
+
+	maxRetries int
+	backoff    time.Duration
+	sleep      func(time.Duration)
+
+	// roots used to verify the report signing certificate; defaults to the pinned Intel SGX Attestation Report Signing CA
+	reportRootCAs *x509.CertPool
+	cache         *ReportCache
 }
 
 type IASClientOption func(*IASClient)
@@ -69,20 +99,71 @@ func WithUrl(url string) IASClientOption {
 	}
 }
 
-// WithHttpClient option allows to use a custom http client. Mainly used for testing
+// WithHttpClient option allows to use a custom http client. Mainly used for testing.
+// Note that the timeout, proxy, and TLS options are ignored when using a custom http client
 func WithHttpClient(client HTTPClient) IASClientOption {
 	return func(c *IASClient) {
 		c.httpClient = client
 	}
 }
 
+// WithTimeout option allows to override the timeout of a single request (DefaultTimeout)
+func WithTimeout(timeout time.Duration) IASClientOption {
+	return func(c *IASClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries option allows to override the number of retries (DefaultMaxRetries) and the initial backoff
+// (DefaultBackoff) for failed requests. Only network errors, 429, and 5xx responses are retried
+func WithRetries(maxRetries int, backoff time.Duration) IASClientOption {
+	return func(c *IASClient) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// WithProxy option allows to send requests via a HTTP(S) proxy.
+// By default, the proxy is taken from the environment (HTTPS_PROXY, NO_PROXY)
+func WithProxy(proxy *url.URL) IASClientOption {
+	return func(c *IASClient) {
+		c.proxy = proxy
+	}
+}
+
+// WithTLSRootCAs option allows to trust custom CAs for the TLS connection to IAS (e.g., of a TLS-intercepting proxy)
+func WithTLSRootCAs(roots *x509.CertPool) IASClientOption {
+	return func(c *IASClient) {
+		c.tlsRootCAs = roots
+	}
+}
+
+// WithReportRootCAs option sets the trusted root CAs for the verification of the report signing certificate.
+// If not set, the pinned Intel SGX Attestation Report Signing CA is used
+func WithReportRootCAs(roots *x509.CertPool) IASClientOption {
+	return func(c *IASClient) {
+		c.reportRootCAs = roots
+	}
+}
+
+// WithReportCache option allows to cache attestation reports by quote
+func WithReportCache(cache *ReportCache) IASClientOption {
+	return func(c *IASClient) {
+		c.cache = cache
+	}
+}
+
 // NewIASClient returns a new IASClient instance using DefaultIASUrl as IAS endpoint
 // This method requires an API Key as input in order to authenticate with the IAS.
 // Optionally, IASClientOption can be provided to change the behavior of the IASClient.
 func NewIASClient(apiKey string, opts ...IASClientOption) *IASClient {
 	client := &IASClient{
-		url:    DefaultIASUrl,
-		apiKey: apiKey,
+		url:        DefaultIASUrl,
+		apiKey:     apiKey,
+		timeout:    DefaultTimeout,
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+		sleep:      time.Sleep,
 	}
 
 	// apply options
@@ -90,9 +171,20 @@ func NewIASClient(apiKey string, opts ...IASClientOption) *IASClient {
 		opt(client)
 	}
 
+	if client.reportRootCAs == nil {
+		client.reportRootCAs = defaultReportRootCAs()
+	}
+
 	// create default http client if not provided via options
 	if client.httpClient == nil {
-		client.httpClient = &http.Client{}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if client.proxy != nil {
+			transport.Proxy = http.ProxyURL(client.proxy)
+		}
+		if client.tlsRootCAs != nil {
+			transport.TLSClientConfig = &tls.Config{RootCAs: client.tlsRootCAs}
+		}
+		client.httpClient = &http.Client{Timeout: client.timeout, Transport: transport}
 	}
 
 	return client
@@ -100,28 +192,74 @@ func NewIASClient(apiKey string, opts ...IASClientOption) *IASClient {
 
 // RequestAttestationReport submits a quote (provided as base64 encoded string) to the Intel Attestation Service (IAS)
 // in order to verify it and generate an attestation report.
-// The report returned by the attestation service is packaged as a IASReport and serialized as json string.
+// The signature of the report returned by the attestation service is verified before the report is packaged as
+// a IASReport and serialized as json string.
 func (i *IASClient) RequestAttestationReport(quoteBase64 string) (reportJson string, err error) {
+	quoteHash := sha256.Sum256([]byte(quoteBase64))
+	cacheKey := hex.EncodeToString(quoteHash[:])
+
+	if i.cache != nil {
+		if reportJson, ok := i.cache.get(cacheKey); ok {
+			logger.Debugf("Use cached IAS report for quote %s", cacheKey)
+			return reportJson, nil
+		}
+	}
 
 	// build request
 	request := &IASRequest{
 		Quote: quoteBase64,
 	}
 
-	report, err := i.requestAttestationReport(request)
+	report, err := i.requestAttestationReportWithRetries(request)
 	if err != nil {
 		return "", errors.Wrap(err, "")
 	}
 
+	if err := verifyReportSignature(report, i.reportRootCAs); err != nil {
+		return "", errors.Wrap(err, "invalid IAS report signature")
+	}
+
 	serializedReport, err := json.Marshal(report)
 	if err != nil {
 		return "", errors.Wrap(err, "cannot marshal IAS report")
 	}
 	reportJson = string(serializedReport)
 
+	if i.cache != nil {
+		i.cache.put(cacheKey, reportJson)
+	}
+
 	return reportJson, nil
 }
 
+// retryableError indicates a failed request that may succeed when retried
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (i *IASClient) requestAttestationReportWithRetries(request *IASRequest) (report *IASReport, err error) {
+	backoff := i.backoff
+	for attempt := 0; ; attempt++ {
+		report, err = i.requestAttestationReport(request)
+		if err == nil {
+			return report, nil
+		}
+
+		retryable := &retryableError{}
+		if !errors.As(err, &retryable) || attempt >= i.maxRetries {
+			return nil, err
+		}
+
+		logger.Warningf("IAS request failed (attempt %d of %d), retry in %s: %s", attempt+1, i.maxRetries+1, backoff, err)
+		i.sleep(backoff)
+		backoff *= 2
+	}
+}
+
 func (i *IASClient) requestAttestationReport(request *IASRequest) (report *IASReport, err error) {
 
 	requestJson, err := json.Marshal(request)
@@ -140,7 +278,7 @@ func (i *IASClient) requestAttestationReport(request *IASRequest) (report *IASRe
 
 	resp, err := i.httpClient.Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot perform http request")
+		return nil, &retryableError{errors.Wrap(err, "cannot perform http request")}
 	}
 	defer resp.Body.Close()
 
@@ -148,7 +286,11 @@ func (i *IASClient) requestAttestationReport(request *IASRequest) (report *IASRe
 
 	// check response status code
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("request failed! Reason: %d %s. Request ID: %s", resp.StatusCode, resp.Status, reportRequestId)
+		err := fmt.Errorf("request failed! Reason: %d %s. Request ID: %s", resp.StatusCode, resp.Status, reportRequestId)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, &retryableError{err}
+		}
+		return nil, err
 	}
 
 	// get header
@@ -157,6 +299,9 @@ func (i *IASClient) requestAttestationReport(request *IASRequest) (report *IASRe
 
 	// get the response body
 	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &retryableError{errors.Wrap(err, "cannot read response body")}
+	}
 
 	report = &IASReport{
 		Signature:    reportSignature,
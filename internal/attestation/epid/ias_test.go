@@ -7,15 +7,29 @@ SPDX-License-Identifier: Apache-2.0
 package epid
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"io"
+	"math/big"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/fakes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //go:generate counterfeiter -o fakes/httpclient.go -fake-name HTTPClient . httpClient
@@ -24,33 +38,103 @@ type httpClient interface {
 	HTTPClient
 }
 
+// reportSigner is a stand-in for the IAS report signing PKI
+type reportSigner struct {
+	root, signingCert *x509.Certificate
+	signingKey        *rsa.PrivateKey
+}
+
+func newCert(t *testing.T, template, parent *x509.Certificate, pub, priv interface{}) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func newReportSigner(t *testing.T) *reportSigner {
+	rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation Report Signing CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	root := newCert(t, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+
+	signingTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Attestation Report Signing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	signingCert := newCert(t, signingTemplate, root, &signingKey.PublicKey, rootKey)
+
+	return &reportSigner{root: root, signingCert: signingCert, signingKey: signingKey}
+}
+
+func (s *reportSigner) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.root)
+	return pool
+}
+
+// response returns an IAS response with the signature and the url-encoded certificate chain in the header
+func (s *reportSigner) response(t *testing.T, body string) *http.Response {
+	digest := sha256.Sum256([]byte(body))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.signingKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.signingCert.Raw})
+	chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.root.Raw})...)
+
+	header := http.Header{}
+	header.Add("X-IASReport-Signature", base64.StdEncoding.EncodeToString(signature))
+	header.Add("X-IASReport-Signing-Certificate", url.PathEscape(string(chain)))
+
+	return &http.Response{
+		StatusCode: 200,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func errorResponse(statusCode int) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+}
+
 func TestIAS(t *testing.T) {
 	dummyQuote := base64.StdEncoding.EncodeToString([]byte("dummyQuote"))
 	dummyApiKey := "some_key"
 	dummyIASUrl := "https://api.fakeias.com/attestation/report"
 
-	expectedSignature := "signature"
-	expectedCertificates := "certs"
 	expectedBody := "some body"
+	signer := newReportSigner(t)
+	resp := signer.response(t, expectedBody)
 
 	expectedReport := &IASReport{
-		Signature:    expectedSignature,
-		Certificates: expectedCertificates,
+		Signature:    resp.Header.Get("X-IASReport-Signature"),
+		Certificates: resp.Header.Get("X-IASReport-Signing-Certificate"),
 		Body:         expectedBody,
 	}
 
-	header := http.Header{}
-	header.Add("X-IASReport-Signature", expectedSignature)
-	header.Add("X-IASReport-Signing-Certificate", expectedCertificates)
-
 	fakeHttpClient := &fakes.HTTPClient{}
-	fakeHttpClient.DoReturns(&http.Response{
-		StatusCode: 200,
-		Header:     header,
-		Body:       io.NopCloser(strings.NewReader(expectedBody)),
-	}, nil)
+	fakeHttpClient.DoReturns(resp, nil)
 
-	iasClient := NewIASClient(dummyApiKey, WithHttpClient(fakeHttpClient), WithUrl(dummyIASUrl))
+	iasClient := NewIASClient(dummyApiKey, WithHttpClient(fakeHttpClient), WithUrl(dummyIASUrl), WithReportRootCAs(signer.roots()))
 	assert.Equal(t, dummyApiKey, iasClient.apiKey)
 	assert.Equal(t, dummyIASUrl, iasClient.url)
 	assert.NotNil(t, iasClient.httpClient)
@@ -71,3 +155,155 @@ func TestIAS(t *testing.T) {
 
 	assert.EqualValues(t, expectedReport, report)
 }
+
+func TestIASDefaultHttpClient(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy:3128")
+	iasClient := NewIASClient("some_key", WithTimeout(5*time.Second), WithProxy(proxy), WithTLSRootCAs(x509.NewCertPool()))
+
+	httpClient, ok := iasClient.httpClient.(*http.Client)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Second, httpClient.Timeout)
+
+	transport := httpClient.Transport.(*http.Transport)
+	assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+	req, _ := http.NewRequest("POST", DefaultIASUrl, nil)
+	proxyUrl, err := transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, proxy, proxyUrl)
+}
+
+func TestIASRetries(t *testing.T) {
+	signer := newReportSigner(t)
+	var backoffs []time.Duration
+
+	newClient := func(fakeHttpClient *fakes.HTTPClient) *IASClient {
+		backoffs = nil
+		iasClient := NewIASClient("some_key", WithHttpClient(fakeHttpClient), WithRetries(3, time.Second), WithReportRootCAs(signer.roots()))
+		iasClient.sleep = func(d time.Duration) { backoffs = append(backoffs, d) }
+		return iasClient
+	}
+
+	// transient errors are retried with exponential backoff
+	fakeHttpClient := &fakes.HTTPClient{}
+	fakeHttpClient.DoReturnsOnCall(0, nil, fmt.Errorf("connection reset"))
+	fakeHttpClient.DoReturnsOnCall(1, errorResponse(http.StatusServiceUnavailable), nil)
+	fakeHttpClient.DoReturnsOnCall(2, signer.response(t, "some body"), nil)
+	_, err := newClient(fakeHttpClient).RequestAttestationReport("someQuote")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fakeHttpClient.DoCallCount())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, backoffs)
+
+	// give up after max retries
+	fakeHttpClient = &fakes.HTTPClient{}
+	fakeHttpClient.DoReturns(nil, fmt.Errorf("connection reset"))
+	_, err = newClient(fakeHttpClient).RequestAttestationReport("someQuote")
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Equal(t, 4, fakeHttpClient.DoCallCount())
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, backoffs)
+
+	// client errors are not retried
+	fakeHttpClient = &fakes.HTTPClient{}
+	fakeHttpClient.DoReturns(errorResponse(http.StatusUnauthorized), nil)
+	_, err = newClient(fakeHttpClient).RequestAttestationReport("someQuote")
+	assert.Contains(t, err.Error(), "request failed! Reason: 401")
+	assert.Equal(t, 1, fakeHttpClient.DoCallCount())
+}
+
+func TestIASReportSignature(t *testing.T) {
+	signer := newReportSigner(t)
+	other := newReportSigner(t)
+
+	request := func(resp *http.Response, opts ...IASClientOption) error {
+		fakeHttpClient := &fakes.HTTPClient{}
+		fakeHttpClient.DoReturns(resp, nil)
+		opts = append([]IASClientOption{WithHttpClient(fakeHttpClient), WithRetries(0, 0)}, opts...)
+		_, err := NewIASClient("some_key", opts...).RequestAttestationReport("someQuote")
+		return err
+	}
+
+	assert.NoError(t, request(signer.response(t, "some body"), WithReportRootCAs(signer.roots())))
+
+	// without configured roots, the Intel root CA is used and the root contained in the report is not trusted
+	err := request(signer.response(t, "some body"))
+	assert.Contains(t, err.Error(), "invalid IAS report signature: invalid certificate chain")
+
+	// untrusted root
+	err = request(signer.response(t, "some body"), WithReportRootCAs(other.roots()))
+	assert.Contains(t, err.Error(), "invalid IAS report signature: invalid certificate chain")
+
+	// tampered body
+	resp := signer.response(t, "some body")
+	resp.Body = io.NopCloser(strings.NewReader("other body"))
+	err = request(resp, WithReportRootCAs(signer.roots()))
+	assert.Contains(t, err.Error(), "signature verification failed")
+
+	// missing certificates
+	resp = signer.response(t, "some body")
+	resp.Header.Del("X-IASReport-Signing-Certificate")
+	err = request(resp, WithReportRootCAs(signer.roots()))
+	assert.Contains(t, err.Error(), "no certificates found")
+}
+
+func TestIASReportCache(t *testing.T) {
+	signer := newReportSigner(t)
+	cache := NewReportCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	fakeHttpClient := &fakes.HTTPClient{}
+	fakeHttpClient.DoReturnsOnCall(0, signer.response(t, "some body"), nil)
+	fakeHttpClient.DoReturnsOnCall(1, signer.response(t, "other body"), nil)
+	fakeHttpClient.DoReturnsOnCall(2, signer.response(t, "new body"), nil)
+	iasClient := NewIASClient("some_key", WithHttpClient(fakeHttpClient), WithReportCache(cache), WithReportRootCAs(signer.roots()))
+
+	first, err := iasClient.RequestAttestationReport("someQuote")
+	assert.NoError(t, err)
+
+	// cached
+	cached, err := iasClient.RequestAttestationReport("someQuote")
+	assert.NoError(t, err)
+	assert.Equal(t, first, cached)
+	assert.Equal(t, 1, fakeHttpClient.DoCallCount())
+
+	// other quote
+	_, err = iasClient.RequestAttestationReport("otherQuote")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fakeHttpClient.DoCallCount())
+
+	// expired
+	now = now.Add(2 * time.Minute)
+	renewed, err := iasClient.RequestAttestationReport("someQuote")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, renewed)
+	assert.Equal(t, 3, fakeHttpClient.DoCallCount())
+}
+
+func TestReportRootCAs(t *testing.T) {
+	block, _ := pem.Decode([]byte(intelReportSigningRootCA))
+	require.NotNil(t, block)
+	intelRoot, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "Intel SGX Attestation Report Signing CA", intelRoot.Subject.CommonName)
+	assert.NoError(t, intelRoot.CheckSignatureFrom(intelRoot))
+
+	// without configuration, the pinned Intel root CA is used
+	t.Setenv(ReportRootCAPathEnvKey, "")
+	t.Setenv("FPC_PATH", t.TempDir())
+	roots, err := loadReportRootCAs()
+	assert.NoError(t, err)
+	assert.True(t, roots.Equal(defaultReportRootCAs()))
+
+	// a configured root CA replaces the Intel root CA
+	signer := newReportSigner(t)
+	path := filepath.Join(t.TempDir(), "root_ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: signer.root.Raw}), 0644))
+	t.Setenv(ReportRootCAPathEnvKey, path)
+	roots, err = loadReportRootCAs()
+	assert.NoError(t, err)
+	assert.True(t, roots.Equal(signer.roots()))
+
+	// an invalid configuration is not replaced by the Intel root CA
+	t.Setenv(ReportRootCAPathEnvKey, filepath.Join(t.TempDir(), "missing.pem"))
+	_, err = loadReportRootCAs()
+	assert.Error(t, err)
+}
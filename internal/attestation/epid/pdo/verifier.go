@@ -66,8 +66,27 @@ func Verify(evidence *types.Evidence, expectedValidationValues *types.Validation
 		return err
 	}
 
+	// with identity rules in the policy, the enclave identity is checked here and the
+	// PDO-based verifier is pinned to the mrenclave contained in the quote
+	mrenclave := expectedValidationValues.Mrenclave
+	if types.HasIdentityRules(expectedValidationValues.Policy) {
+		id, err := epid.EnclaveIdentity(evidence)
+		if err != nil {
+			return err
+		}
+		if err := types.CheckEnclaveIdentity(expectedValidationValues, id); err != nil {
+			return err
+		}
+		mrenclave = id.Mrenclave
+	}
+
 	verifier := &VerifierImpl{}
-	return verifier.VerifyEvidence(evidenceBytes, expectedValidationValues.Statement, expectedValidationValues.Mrenclave)
+	if err := verifier.VerifyEvidence(evidenceBytes, expectedValidationValues.Statement, mrenclave); err != nil {
+		return types.Reject(types.RejectInvalidEvidence, "%s", err)
+	}
+
+	// the quote status is checked against the policy; without a policy, only UpToDate (i.e., OK) is accepted
+	return epid.CheckPolicy(evidence, expectedValidationValues.Policy)
 }
 
 func NewEpidLinkableVerifier() *types.Verifier {
@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package epid
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+)
+
+// quoteStatus maps the IAS quote status to the corresponding TCB status
+var quoteStatus = map[string]string{
+	"OK":                                    types.TcbUpToDate,
+	"SW_HARDENING_NEEDED":                   types.TcbSWHardeningNeeded,
+	"CONFIGURATION_NEEDED":                  types.TcbConfigurationNeeded,
+	"CONFIGURATION_AND_SW_HARDENING_NEEDED": types.TcbConfigurationAndSWHardeningNeeded,
+	"GROUP_OUT_OF_DATE":                     types.TcbOutOfDate,
+	"GROUP_REVOKED":                         types.TcbRevoked,
+}
+
+// offsets of the report body fields within an EPID quote (see sgx_quote_t)
+const (
+	reportBodyOffset = 48
+	mrenclaveOffset  = reportBodyOffset + 64
+	mrsignerOffset   = reportBodyOffset + 128
+	isvProdIdOffset  = reportBodyOffset + 256
+	isvSvnOffset     = reportBodyOffset + 258
+	quoteBodyLen     = reportBodyOffset + 384
+)
+
+func parseBody(evidence *types.Evidence) (*IASResponseBody, error) {
+	report := &IASReport{}
+	if err := json.Unmarshal([]byte(evidence.Data), report); err != nil {
+		return nil, types.Reject(types.RejectInvalidEvidence, "cannot unmarshal IAS report: %s", err)
+	}
+
+	body := &IASResponseBody{}
+	if err := json.Unmarshal([]byte(report.Body), body); err != nil {
+		return nil, types.Reject(types.RejectInvalidEvidence, "cannot unmarshal IAS report body: %s", err)
+	}
+
+	return body, nil
+}
+
+// CheckPolicy checks the quote status and advisory IDs of an (already verified) IAS report against the policy.
+func CheckPolicy(evidence *types.Evidence, policy *protos.AttestationVerificationPolicy) error {
+	body, err := parseBody(evidence)
+	if err != nil {
+		return err
+	}
+
+	status, ok := quoteStatus[body.IsvEnclaveQuoteStatus]
+	if !ok {
+		status = body.IsvEnclaveQuoteStatus
+	}
+
+	return types.CheckTcbStatus(policy, status, body.AdvisoryIDs)
+}
+
+// EnclaveIdentity returns the identity of the enclave as contained in the quote body of an IAS report.
+// Note that the IAS report itself must be verified separately.
+func EnclaveIdentity(evidence *types.Evidence) (*types.EnclaveIdentity, error) {
+	body, err := parseBody(evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := base64.StdEncoding.DecodeString(body.IsvEnclaveQuoteBody)
+	if err != nil {
+		return nil, types.Reject(types.RejectInvalidEvidence, "cannot decode quote body: %s", err)
+	}
+
+	if len(quote) < quoteBodyLen {
+		return nil, types.Reject(types.RejectInvalidEvidence, "quote body too short: %d bytes", len(quote))
+	}
+
+	return &types.EnclaveIdentity{
+		Mrenclave: hex.EncodeToString(quote[mrenclaveOffset : mrenclaveOffset+32]),
+		Mrsigner:  hex.EncodeToString(quote[mrsignerOffset : mrsignerOffset+32]),
+		IsvProdId: uint32(binary.LittleEndian.Uint16(quote[isvProdIdOffset:])),
+		IsvSvn:    uint32(binary.LittleEndian.Uint16(quote[isvSvnOffset:])),
+	}, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package epid
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/stretchr/testify/assert"
+)
+
+func evidence(t *testing.T, status string, advisoryIDs ...string) *types.Evidence {
+	body, err := json.Marshal(&IASResponseBody{IsvEnclaveQuoteStatus: status, AdvisoryIDs: advisoryIDs})
+	assert.NoError(t, err)
+	report, err := json.Marshal(&IASReport{Body: string(body)})
+	assert.NoError(t, err)
+	return &types.Evidence{Type: LinkableType, Data: string(report)}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	policy := &protos.AttestationVerificationPolicy{
+		AcceptedTcbStatus:  []string{types.TcbUpToDate, types.TcbSWHardeningNeeded},
+		AllowedAdvisoryIds: []string{"INTEL-SA-00334"},
+	}
+
+	assert.NoError(t, CheckPolicy(evidence(t, "OK"), nil))
+	assert.NoError(t, CheckPolicy(evidence(t, "SW_HARDENING_NEEDED", "INTEL-SA-00334"), policy))
+
+	// without a policy, only UpToDate is accepted
+	err := CheckPolicy(evidence(t, "SW_HARDENING_NEEDED", "INTEL-SA-00334"), nil)
+	assert.EqualError(t, err, "TCB_STATUS_NOT_ACCEPTED: tcb status SWHardeningNeeded not accepted (advisories: [INTEL-SA-00334])")
+	err = CheckPolicy(evidence(t, "GROUP_OUT_OF_DATE"), &protos.AttestationVerificationPolicy{})
+	assert.EqualError(t, err, "TCB_STATUS_NOT_ACCEPTED: tcb status OutOfDate not accepted (advisories: [])")
+
+	err = CheckPolicy(evidence(t, "SW_HARDENING_NEEDED", "INTEL-SA-00334", "INTEL-SA-00615"), policy)
+	assert.EqualError(t, err, "ADVISORY_NOT_ALLOWED: advisory INTEL-SA-00615 not allowed for tcb status SWHardeningNeeded")
+
+	err = CheckPolicy(evidence(t, "GROUP_OUT_OF_DATE", "INTEL-SA-00334"), policy)
+	rejection := &types.RejectionError{}
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, types.RejectTcbStatus, rejection.Reason)
+
+	err = CheckPolicy(&types.Evidence{Data: "invalid"}, policy)
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, types.RejectInvalidEvidence, rejection.Reason)
+}
+
+func TestEnclaveIdentity(t *testing.T) {
+	mrenclave := []byte("01234567890123456789012345678901")
+	mrsigner := []byte("abcdefghijabcdefghijabcdefghij01")
+
+	quote := make([]byte, quoteBodyLen)
+	copy(quote[mrenclaveOffset:], mrenclave)
+	copy(quote[mrsignerOffset:], mrsigner)
+	binary.LittleEndian.PutUint16(quote[isvProdIdOffset:], 1)
+	binary.LittleEndian.PutUint16(quote[isvSvnOffset:], 2)
+
+	body, err := json.Marshal(&IASResponseBody{IsvEnclaveQuoteBody: base64.StdEncoding.EncodeToString(quote)})
+	assert.NoError(t, err)
+	report, err := json.Marshal(&IASReport{Body: string(body)})
+	assert.NoError(t, err)
+
+	id, err := EnclaveIdentity(&types.Evidence{Type: LinkableType, Data: string(report)})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.EnclaveIdentity{
+		Mrenclave: hex.EncodeToString(mrenclave),
+		Mrsigner:  hex.EncodeToString(mrsigner),
+		IsvProdId: 1,
+		IsvSvn:    2,
+	}, id)
+
+	// quote body too short
+	body, _ = json.Marshal(&IASResponseBody{IsvEnclaveQuoteBody: base64.StdEncoding.EncodeToString(quote[:100])})
+	report, _ = json.Marshal(&IASReport{Body: string(body)})
+	_, err = EnclaveIdentity(&types.Evidence{Type: LinkableType, Data: string(report)})
+	assert.EqualError(t, err, "INVALID_EVIDENCE: quote body too short: 100 bytes")
+}
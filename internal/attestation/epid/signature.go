@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package epid
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// parseReportCertificates parses the (url-encoded) PEM certificates of an IAS report,
+// i.e., the report signing certificate followed by the root CA
+func parseReportCertificates(certificates string) ([]*x509.Certificate, error) {
+	certsPEM, err := url.PathUnescape(certificates)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode certificates")
+	}
+
+	var certs []*x509.Certificate
+	rest := []byte(certsPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+
+	return certs, nil
+}
+
+// verifyReportSignature checks that the report body is signed by the report signing certificate and that the
+// signing certificate chains to one of the roots. Note that the root CA contained in the report is never trusted.
+func verifyReportSignature(report *IASReport, roots *x509.CertPool) error {
+	if roots == nil {
+		return fmt.Errorf("no IAS report signing root CA")
+	}
+
+	certs, err := parseReportCertificates(report.Certificates)
+	if err != nil {
+		return err
+	}
+	signingCert := certs[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = signingCert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid certificate chain")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(report.Signature)
+	if err != nil {
+		return errors.Wrap(err, "cannot decode signature")
+	}
+
+	if err := signingCert.CheckSignature(x509.SHA256WithRSA, []byte(report.Body), signature); err != nil {
+		return errors.Wrap(err, "signature verification failed")
+	}
+
+	return nil
+}
@@ -8,6 +8,7 @@ SPDX-License-Identifier: Apache-2.0
 package epid
 
 import (
+	"crypto/x509"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +17,13 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// ReportRootCAPathEnvKey is the environment variable pointing to the PEM encoded IAS report signing root CA
+	ReportRootCAPathEnvKey = "IAS_ROOT_CA_PATH"
+	// TLSRootCAPathEnvKey is the environment variable pointing to PEM encoded CAs trusted for the TLS connection to IAS
+	TLSRootCAPathEnvKey = "IAS_TLS_CA_PATH"
+)
+
 // loadApiKey tries to load the IAS API Key from environment variable.
 // If env var not set, use loadApiKeyFromCredentialsEnvPath and then loadApiKeyFromFPCConfig as fallback
 func loadApiKey() (string, error) {
@@ -70,3 +78,61 @@ func loadApiKeyFromPath(path string) (string, error) {
 
 	return strings.TrimSuffix(string(data), "\n"), nil
 }
+
+// loadReportRootCAs loads the IAS report signing root CA from IAS_ROOT_CA_PATH.
+// If env var not set, use $FPC_PATH/config/ias/root_ca.pem as fallback; if this file does not exist,
+// the pinned Intel SGX Attestation Report Signing CA is returned
+func loadReportRootCAs() (*x509.CertPool, error) {
+	path := os.Getenv(ReportRootCAPathEnvKey)
+	if len(path) == 0 {
+		fpcPath := os.Getenv("FPC_PATH")
+		if len(fpcPath) == 0 {
+			return defaultReportRootCAs(), nil
+		}
+		path = filepath.Join(fpcPath, "config", "ias", "root_ca.pem")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return defaultReportRootCAs(), nil
+		}
+	}
+
+	return loadCertPool(path)
+}
+
+// loadTLSRootCAs loads additional CAs for the TLS connection to IAS from IAS_TLS_CA_PATH.
+// If env var not set, nil is returned and the system CAs are used
+func loadTLSRootCAs() (*x509.CertPool, error) {
+	path := os.Getenv(TLSRootCAPathEnvKey)
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", path)
+	}
+
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no certificate found in %s", path)
+	}
+
+	return roots, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", path)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no certificate found in %s", path)
+	}
+
+	return roots, nil
+}
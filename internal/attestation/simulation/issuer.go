@@ -6,16 +6,41 @@ SPDX-License-Identifier: Apache-2.0
 
 package simulation
 
-import "github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 
-// NewSimulationIssuer creates a new attestation issuer for Intel SGX simulation mode
-func NewSimulationIssuer() *types.Issuer {
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/pkg/errors"
+)
+
+// NewSimulationIssuer creates a new attestation issuer for Intel SGX simulation mode.
+// The simulated attestation binds the hash of the statement and the given (fake) mrenclave.
+func NewSimulationIssuer(mrenclave string) *types.Issuer {
 	return &types.Issuer{
-		Type:  SimulationType,
-		Issue: issue,
+		Type: SimulationType,
+		Issue: func(customData []byte) ([]byte, error) {
+			return issue(customData, mrenclave)
+		},
 	}
 }
 
-func issue(customData []byte) ([]byte, error) {
-	return []byte("{\"attestation_type\":\"" + SimulationType + "\",\"attestation\":\"MA==\"}"), nil
+func issue(customData []byte, mrenclave string) ([]byte, error) {
+	hash := sha256.Sum256(customData)
+	r := &report{
+		StatementHash: hash[:],
+		Mrenclave:     mrenclave,
+	}
+	r.Mac = r.computeMac()
+
+	reportBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal simulated report")
+	}
+
+	return json.Marshal(&types.Attestation{
+		Type: SimulationType,
+		Data: base64.StdEncoding.EncodeToString(reportBytes),
+	})
 }
@@ -0,0 +1,34 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package simulation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// legacyAttestation is the attestation data of simulated attestations that do not bind a statement
+// (e.g., as produced by the C++ enclave in SGX simulation mode)
+const legacyAttestation = "MA=="
+
+// testKey is used to MAC simulated reports. Note that this key is public, thus, simulated attestations
+// only help to detect mismatches between credentials and evidence but do not provide any security.
+var testKey = []byte("fpc-simulated-attestation-test-key")
+
+// report is the simulated counterpart of an enclave report
+type report struct {
+	StatementHash []byte `json:"statement_hash"`
+	Mrenclave     string `json:"mrenclave"`
+	Mac           []byte `json:"mac"`
+}
+
+func (r *report) computeMac() []byte {
+	mac := hmac.New(sha256.New, testKey)
+	mac.Write(r.StatementHash)
+	mac.Write([]byte(r.Mrenclave))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package simulation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func issueEvidence(t *testing.T, statement []byte, mrenclave string) *types.Evidence {
+	attestationBytes, err := NewSimulationIssuer(mrenclave).Issue(statement)
+	assert.NoError(t, err)
+
+	att := &types.Attestation{}
+	assert.NoError(t, json.Unmarshal(attestationBytes, att))
+	assert.Equal(t, SimulationType, att.Type)
+
+	evidenceBytes, err := NewSimulationConverter().Converter([]byte(att.Data))
+	assert.NoError(t, err)
+
+	return &types.Evidence{Type: att.Type, Data: string(evidenceBytes)}
+}
+
+func TestSimulationAttestation(t *testing.T) {
+	statement := []byte("someStatement")
+	mrenclave := "someMrenclave"
+	evidence := issueEvidence(t, statement, mrenclave)
+	verifier := NewSimulationVerifier()
+
+	err := verifier.Verify(evidence, &types.ValidationValues{Statement: statement, Mrenclave: mrenclave})
+	assert.NoError(t, err)
+
+	// other statement
+	err = verifier.Verify(evidence, &types.ValidationValues{Statement: []byte("otherStatement"), Mrenclave: mrenclave})
+	assert.EqualError(t, err, "STATEMENT_MISMATCH: report data does not match statement")
+
+	// other mrenclave
+	err = verifier.Verify(evidence, &types.ValidationValues{Statement: statement, Mrenclave: "otherMrenclave"})
+	assert.EqualError(t, err, "MRENCLAVE_MISMATCH: expected 'otherMrenclave' but got 'someMrenclave'")
+
+	// tampered report
+	r := &report{}
+	reportBytes, _ := base64.StdEncoding.DecodeString(evidence.Data)
+	assert.NoError(t, json.Unmarshal(reportBytes, r))
+	r.Mrenclave = "otherMrenclave"
+	reportBytes, _ = json.Marshal(r)
+	tampered := &types.Evidence{Type: SimulationType, Data: base64.StdEncoding.EncodeToString(reportBytes)}
+	err = verifier.Verify(tampered, &types.ValidationValues{Statement: statement, Mrenclave: "otherMrenclave"})
+	assert.EqualError(t, err, "INVALID_EVIDENCE: invalid simulated report mac")
+
+	// garbage
+	err = verifier.Verify(&types.Evidence{Type: SimulationType, Data: "not base64"}, &types.ValidationValues{})
+	assert.Error(t, err)
+
+	// legacy attestation is rejected
+	legacy := &types.Evidence{Type: SimulationType, Data: legacyAttestation}
+	err = verifier.Verify(legacy, &types.ValidationValues{Statement: statement})
+	assert.EqualError(t, err, "INVALID_EVIDENCE: legacy simulated attestation without statement binding not accepted")
+}
+
+func TestLegacySimulationAttestation(t *testing.T) {
+	statement := []byte("someStatement")
+	mrenclave := "someMrenclave"
+	verifier := NewLegacySimulationVerifier()
+
+	// legacy attestation is accepted
+	err := verifier.Verify(&types.Evidence{Type: SimulationType, Data: legacyAttestation}, &types.ValidationValues{Statement: statement})
+	assert.NoError(t, err)
+
+	// simulated reports are still checked
+	evidence := issueEvidence(t, statement, mrenclave)
+	err = verifier.Verify(evidence, &types.ValidationValues{Statement: statement, Mrenclave: mrenclave})
+	assert.NoError(t, err)
+	err = verifier.Verify(evidence, &types.ValidationValues{Statement: []byte("otherStatement"), Mrenclave: mrenclave})
+	assert.EqualError(t, err, "STATEMENT_MISMATCH: report data does not match statement")
+}
+
+func TestAcceptLegacyAttestation(t *testing.T) {
+	// accepted by default
+	t.Setenv(AcceptLegacyAttestationEnvKey, "")
+	assert.NoError(t, os.Unsetenv(AcceptLegacyAttestationEnvKey))
+	assert.True(t, AcceptLegacyAttestation())
+
+	t.Setenv(AcceptLegacyAttestationEnvKey, "true")
+	assert.True(t, AcceptLegacyAttestation())
+
+	// invalid values keep the default
+	t.Setenv(AcceptLegacyAttestationEnvKey, "maybe")
+	assert.True(t, AcceptLegacyAttestation())
+
+	// rejected if disabled
+	t.Setenv(AcceptLegacyAttestationEnvKey, "false")
+	assert.False(t, AcceptLegacyAttestation())
+}
@@ -7,15 +7,97 @@ SPDX-License-Identifier: Apache-2.0
 package simulation
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strconv"
+
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric/common/flogging"
 )
 
+var logger = flogging.MustGetLogger("fpc.attestation.simulation")
+
+// AcceptLegacyAttestationEnvKey is the environment variable that, if set to false, lets ERCC reject legacy
+// simulated attestations (see NewLegacySimulationVerifier and AcceptLegacyAttestation)
+const AcceptLegacyAttestationEnvKey = "FPC_SIM_ACCEPT_LEGACY_ATTESTATION"
+
+// AcceptLegacyAttestation returns whether legacy simulated attestations are accepted, i.e., unless
+// AcceptLegacyAttestationEnvKey is set to false. Accepting them by default is deprecated; it keeps the C++ chaincode,
+// whose enclave does not bind the statement in simulation mode, working until the default is turned into an opt-in.
+func AcceptLegacyAttestation() bool {
+	if value, ok := os.LookupEnv(AcceptLegacyAttestationEnvKey); ok {
+		accept, err := strconv.ParseBool(value)
+		if err != nil {
+			logger.Warningf("Ignore invalid value '%s' of %s", value, AcceptLegacyAttestationEnvKey)
+		} else if !accept {
+			return false
+		}
+	}
+
+	logger.Warningf("Accepting legacy simulated attestations without statement binding is deprecated and will be "+
+		"disabled by default in a future release; set %s=false to reject them", AcceptLegacyAttestationEnvKey)
+	return true
+}
+
+// NewSimulationVerifier creates a new attestation verifier for Intel SGX simulation mode.
+// The verifier checks the statement and mrenclave of simulated reports just like for real attestations;
+// a simulated platform is considered UpToDate with respect to the verification policy.
+// Legacy simulated attestations, which do not bind any statement, are rejected.
 func NewSimulationVerifier() *types.Verifier {
 	return &types.Verifier{
 		Type: SimulationType,
-		Verify: func(evidence *types.Evidence, expectedValidationValues *types.ValidationValues) (err error) {
-			// NO-OP
-			return nil
+		Verify: func(evidence *types.Evidence, expectedValidationValues *types.ValidationValues) error {
+			return verify(evidence, expectedValidationValues, false)
 		},
 	}
 }
+
+// NewLegacySimulationVerifier creates a new attestation verifier for Intel SGX simulation mode that, in addition to
+// the simulated reports accepted by NewSimulationVerifier, accepts the legacy simulated attestations of the C++
+// enclave. As these do not bind any statement or mrenclave, this verifier must only be used for testing.
+func NewLegacySimulationVerifier() *types.Verifier {
+	return &types.Verifier{
+		Type: SimulationType,
+		Verify: func(evidence *types.Evidence, expectedValidationValues *types.ValidationValues) error {
+			return verify(evidence, expectedValidationValues, true)
+		},
+	}
+}
+
+func verify(evidence *types.Evidence, expectedValidationValues *types.ValidationValues, acceptLegacy bool) error {
+	if err := types.CheckTcbStatus(expectedValidationValues.Policy, types.TcbUpToDate, nil); err != nil {
+		return err
+	}
+
+	if evidence.Data == legacyAttestation {
+		if !acceptLegacy {
+			return types.Reject(types.RejectInvalidEvidence, "legacy simulated attestation without statement binding not accepted")
+		}
+		logger.Warningf("Accept legacy simulated attestation without statement and mrenclave binding")
+		return nil
+	}
+
+	reportBytes, err := base64.StdEncoding.DecodeString(evidence.Data)
+	if err != nil {
+		return types.Reject(types.RejectInvalidEvidence, "cannot decode simulated report: %s", err)
+	}
+
+	r := &report{}
+	if err := json.Unmarshal(reportBytes, r); err != nil {
+		return types.Reject(types.RejectInvalidEvidence, "cannot unmarshal simulated report: %s", err)
+	}
+
+	if !hmac.Equal(r.Mac, r.computeMac()) {
+		return types.Reject(types.RejectInvalidEvidence, "invalid simulated report mac")
+	}
+
+	hash := sha256.Sum256(expectedValidationValues.Statement)
+	if !hmac.Equal(r.StatementHash, hash[:]) {
+		return types.Reject(types.RejectStatementMismatch, "report data does not match statement")
+	}
+
+	return types.CheckEnclaveIdentity(expectedValidationValues, &types.EnclaveIdentity{Mrenclave: r.Mrenclave})
+}
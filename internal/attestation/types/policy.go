@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+)
+
+// TCB status values as defined by Intel; verifiers map their platform status to these values
+const (
+	TcbUpToDate                          = "UpToDate"
+	TcbSWHardeningNeeded                 = "SWHardeningNeeded"
+	TcbConfigurationNeeded               = "ConfigurationNeeded"
+	TcbConfigurationAndSWHardeningNeeded = "ConfigurationAndSWHardeningNeeded"
+	TcbOutOfDate                         = "OutOfDate"
+	TcbOutOfDateConfigurationNeeded      = "OutOfDateConfigurationNeeded"
+	TcbRevoked                           = "Revoked"
+)
+
+// RejectionReason identifies why a verifier rejected an evidence
+type RejectionReason string
+
+const (
+	RejectInvalidEvidence   RejectionReason = "INVALID_EVIDENCE"
+	RejectStatementMismatch RejectionReason = "STATEMENT_MISMATCH"
+	RejectMrenclaveMismatch RejectionReason = "MRENCLAVE_MISMATCH"
+	RejectDebugEnclave      RejectionReason = "DEBUG_ENCLAVE"
+	RejectTcbStatus         RejectionReason = "TCB_STATUS_NOT_ACCEPTED"
+	RejectAdvisory          RejectionReason = "ADVISORY_NOT_ALLOWED"
+)
+
+// RejectionError is returned by verifiers when an evidence is rejected
+type RejectionError struct {
+	Reason  RejectionReason
+	Message string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+// Reject returns a new RejectionError
+func Reject(reason RejectionReason, format string, args ...interface{}) error {
+	return &RejectionError{
+		Reason:  reason,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// CheckTcbStatus checks the TCB status and advisory IDs of a platform against the policy.
+// If no policy is given or the policy does not define accepted TCB status, only UpToDate is accepted.
+// The advisory IDs of a platform that is not UpToDate must all be allowed by the policy.
+func CheckTcbStatus(policy *protos.AttestationVerificationPolicy, status string, advisoryIDs []string) error {
+	accepted := policy.GetAcceptedTcbStatus()
+	if len(accepted) == 0 {
+		accepted = []string{TcbUpToDate}
+	}
+
+	if !contains(accepted, status) {
+		return Reject(RejectTcbStatus, "tcb status %s not accepted (advisories: %v)", status, advisoryIDs)
+	}
+
+	if status == TcbUpToDate {
+		return nil
+	}
+
+	for _, id := range advisoryIDs {
+		if !contains(policy.GetAllowedAdvisoryIds(), id) {
+			return Reject(RejectAdvisory, "advisory %s not allowed for tcb status %s", id, status)
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// EnclaveIdentity is the identity of an attested enclave as reported by the evidence
+type EnclaveIdentity struct {
+	Mrenclave string
+	Mrsigner  string
+	IsvProdId uint32
+	IsvSvn    uint32
+}
+
+// CheckEnclaveIdentity checks that the enclave identity matches the expected mrenclave or is accepted by the
+// allowed mrenclaves or signers of the policy
+func CheckEnclaveIdentity(expectedValidationValues *ValidationValues, id *EnclaveIdentity) error {
+	if strings.EqualFold(id.Mrenclave, expectedValidationValues.Mrenclave) || mrenclaveAllowed(expectedValidationValues.Policy, id.Mrenclave) {
+		return nil
+	}
+
+	for _, signer := range expectedValidationValues.Policy.GetAllowedSigners() {
+		if len(signer.GetMrsigner()) > 0 && strings.EqualFold(signer.GetMrsigner(), id.Mrsigner) &&
+			signer.GetIsvProdId() == id.IsvProdId && id.IsvSvn >= signer.GetMinIsvSvn() {
+			return nil
+		}
+	}
+
+	return Reject(RejectMrenclaveMismatch, "expected '%s' but got '%s'", expectedValidationValues.Mrenclave, id.Mrenclave)
+}
+
+// mrenclaveAllowed returns true if the mrenclave is explicitly allowed by the policy
+func mrenclaveAllowed(policy *protos.AttestationVerificationPolicy, mrenclave string) bool {
+	for _, m := range policy.GetAllowedMrenclaves() {
+		if strings.EqualFold(m, mrenclave) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIdentityRules returns true if the policy accepts enclaves other than the one of the chaincode definition
+func HasIdentityRules(policy *protos.AttestationVerificationPolicy) bool {
+	return len(policy.GetAllowedMrenclaves()) > 0 || len(policy.GetAllowedSigners()) > 0
+}
@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 
 package types
 
+import "github.com/hyperledger/fabric-private-chaincode/internal/protos"
+
 type ConvertFunction func(attestationBytes []byte) (evidenceBytes []byte, err error)
 
 type Converter struct {
@@ -41,4 +43,6 @@ type Evidence struct {
 type ValidationValues struct {
 	Statement []byte
 	Mrenclave string
+	// Policy defines the accepted platform TCB status; if nil, verifiers apply their default
+	Policy *protos.AttestationVerificationPolicy
 }
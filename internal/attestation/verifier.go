@@ -40,7 +40,7 @@ func (d *verifierDispatcher) Register(verifiers ...*types.Verifier) error {
 func (d *verifierDispatcher) Verify(evidence *types.Evidence, expectedValidationValues *types.ValidationValues) error {
 	verify, ok := d.verifiers[evidence.Type]
 	if !ok {
-		return fmt.Errorf("'%s' type is not registered", evidence.Type)
+		return types.Reject(types.RejectInvalidEvidence, "'%s' type is not registered", evidence.Type)
 	}
 
 	logger.Debugf("Invoke verifier of type '%s'", evidence.Type)
@@ -52,7 +52,7 @@ type CredentialVerifier struct {
 }
 
 type Verifier interface {
-	VerifyCredentials(credentials *protos.Credentials, expectedMrenclave string) (err error)
+	VerifyCredentials(credentials *protos.Credentials, expectedMrenclave string, policy *protos.AttestationVerificationPolicy) (err error)
 }
 
 func NewCredentialVerifier(verifier ...*types.Verifier) *CredentialVerifier {
@@ -66,16 +66,19 @@ func NewCredentialVerifier(verifier ...*types.Verifier) *CredentialVerifier {
 	return &CredentialVerifier{dispatcher: dispatcher}
 }
 
-func (c *CredentialVerifier) VerifyCredentials(credentials *protos.Credentials, expectedMrenclave string) error {
+// VerifyCredentials verifies the evidence of the credentials against the attested data, the expected mrenclave,
+// and the verification policy. If the evidence is rejected, a types.RejectionError is returned.
+func (c *CredentialVerifier) VerifyCredentials(credentials *protos.Credentials, expectedMrenclave string, policy *protos.AttestationVerificationPolicy) error {
 
 	evidence, err := unmarshalEvidence(credentials.Evidence)
 	if err != nil {
-		return err
+		return types.Reject(types.RejectInvalidEvidence, "%s", err)
 	}
 
 	expectedValues := &types.ValidationValues{
-		Statement: credentials.SerializedAttestedData.Value,
+		Statement: credentials.SerializedAttestedData.GetValue(),
 		Mrenclave: expectedMrenclave,
+		Policy:    policy,
 	}
 
 	return c.dispatcher.Verify(evidence, expectedValues)
@@ -11,7 +11,9 @@ import (
 
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/simulation"
 	"github.com/hyperledger/fabric-private-chaincode/internal/attestation/types"
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 func NewDummyVerifier() *types.Verifier {
@@ -56,3 +58,37 @@ func TestVerifier(t *testing.T) {
 	err = d.Register(simulation.NewSimulationVerifier())
 	assert.NoError(t, err)
 }
+
+func TestCredentialVerifierWithSimulation(t *testing.T) {
+	attestedData, _ := anypb.New(&protos.AttestedData{EnclaveVk: []byte("someEnclaveVk")})
+	att, err := simulation.NewSimulationIssuer("someMrenclave").Issue(attestedData.Value)
+	assert.NoError(t, err)
+
+	credentials, err := NewDefaultCredentialConverter().convertCredentials(&protos.Credentials{
+		Attestation:            att,
+		SerializedAttestedData: attestedData,
+	})
+	assert.NoError(t, err)
+
+	v := NewCredentialVerifier(simulation.NewSimulationVerifier())
+	assert.NoError(t, v.VerifyCredentials(credentials, "someMrenclave", nil))
+	assert.Error(t, v.VerifyCredentials(credentials, "otherMrenclave", nil))
+
+	// simulated platforms are up to date
+	policy := &protos.AttestationVerificationPolicy{AcceptedTcbStatus: []string{types.TcbSWHardeningNeeded}}
+	err = v.VerifyCredentials(credentials, "someMrenclave", policy)
+	rejection := &types.RejectionError{}
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, types.RejectTcbStatus, rejection.Reason)
+
+	// credentials with attested data that does not match the evidence
+	otherAttestedData, _ := anypb.New(&protos.AttestedData{EnclaveVk: []byte("otherEnclaveVk")})
+	credentials.SerializedAttestedData = otherAttestedData
+	assert.EqualError(t, v.VerifyCredentials(credentials, "someMrenclave", nil), "STATEMENT_MISMATCH: report data does not match statement")
+
+	// unknown evidence type
+	credentials.Evidence = []byte(`{"attestation_type":"unknown","evidence":""}`)
+	err = v.VerifyCredentials(credentials, "someMrenclave", nil)
+	assert.ErrorAs(t, err, &rejection)
+	assert.Equal(t, types.RejectInvalidEvidence, rejection.Reason)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// KeyPins records the chaincode encryption key accepted for a chaincode definition (i.e., chaincode id and sequence),
+// so that a key once accepted cannot be replaced for the same chaincode definition.
+type KeyPins struct {
+	mutex sync.Mutex
+	pins  map[string][]byte
+}
+
+// NewKeyPins returns an empty KeyPins instance
+func NewKeyPins() *KeyPins {
+	return &KeyPins{pins: make(map[string][]byte)}
+}
+
+// pin pins the key for the chaincode definition or returns an error if another key is already pinned
+func (p *KeyPins) pin(chaincodeId string, sequence int64, key []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	id := fmt.Sprintf("%s/%d", chaincodeId, sequence)
+	if pinned, ok := p.pins[id]; ok {
+		if !bytes.Equal(pinned, key) {
+			return fmt.Errorf("chaincode encryption key does not match pinned key for chaincode %s sequence %d", chaincodeId, sequence)
+		}
+		return nil
+	}
+
+	p.pins[id] = key
+	return nil
+}
+
+// getAttestedCcEncryptionKey returns the chaincode encryption key bound to the verified enclave credentials of the
+// chaincode, i.e., the key contained in the attested data or in a cc key registration message signed by the enclave.
+// Only credentials of the latest chaincode sequence are considered, and all of them must attest the same key.
+func (p EncryptionProviderImpl) getAttestedCcEncryptionKey() ([]byte, error) {
+	if p.VerifyCredentials == nil {
+		return nil, fmt.Errorf("no credential verifier configured")
+	}
+
+	credentialsList, err := p.GetCcCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enclave credentials from ercc: %s", err.Error())
+	}
+
+	var ccParams *protos.CCParameters
+	var key []byte
+	for _, credentials := range credentialsList {
+		attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+		if err != nil {
+			logger.Warningf("Ignore enclave credentials: %s", err)
+			continue
+		}
+
+		if len(attestedData.GetChaincodeEk()) == 0 && p.GetCcKeyRegistration == nil {
+			logger.Debugf("Ignore enclave credentials without chaincode encryption key")
+			continue
+		}
+
+		if ccParams != nil && attestedData.GetCcParams().GetSequence() < ccParams.GetSequence() {
+			continue
+		}
+
+		if err := p.VerifyCredentials(credentials); err != nil {
+			logger.Warningf("Ignore enclave credentials: %s", err)
+			continue
+		}
+
+		enclaveKey, err := p.getEnclaveCcEncryptionKey(attestedData)
+		if err != nil {
+			return nil, err
+		}
+		if enclaveKey == nil {
+			logger.Debugf("Ignore enclave credentials without chaincode encryption key")
+			continue
+		}
+
+		if ccParams != nil && attestedData.GetCcParams().GetSequence() == ccParams.GetSequence() {
+			if !bytes.Equal(key, enclaveKey) {
+				return nil, fmt.Errorf("enclaves attest different chaincode encryption keys")
+			}
+			continue
+		}
+
+		ccParams = attestedData.GetCcParams()
+		key = enclaveKey
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("no verified enclave credentials with chaincode encryption key found")
+	}
+
+	if p.KeyPins != nil {
+		if err := p.KeyPins.pin(ccParams.GetChaincodeId(), ccParams.GetSequence(), key); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// getEnclaveCcEncryptionKey returns the chaincode encryption key of the enclave with the given (verified) attested
+// data, or nil if the enclave is not provisioned with the chaincode keys yet
+func (p EncryptionProviderImpl) getEnclaveCcEncryptionKey(attestedData *protos.AttestedData) ([]byte, error) {
+	if len(attestedData.GetChaincodeEk()) > 0 {
+		return attestedData.GetChaincodeEk(), nil
+	}
+
+	enclaveId := utils.GetEnclaveId(attestedData)
+	signedMsg, err := p.GetCcKeyRegistration(enclaveId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cc key registration of enclave %s from ercc: %s", enclaveId, err.Error())
+	}
+	if signedMsg == nil {
+		return nil, nil
+	}
+
+	// a registration message that is not issued by the enclave is not stored by ercc, thus, it suggests a malicious ercc
+	msg, err := VerifyCCKeyRegistrationMessage(attestedData, signedMsg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cc key registration of enclave %s", enclaveId)
+	}
+
+	return msg.GetChaincodeEk(), nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func credentialsWithKey(t *testing.T, enclaveVk []byte, sequence int64, chaincodeEk []byte) *protos.Credentials {
+	attestedData, err := anypb.New(&protos.AttestedData{
+		EnclaveVk:   enclaveVk,
+		CcParams:    &protos.CCParameters{ChaincodeId: "someChaincode", Sequence: sequence},
+		ChaincodeEk: chaincodeEk,
+	})
+	require.NoError(t, err)
+	return &protos.Credentials{SerializedAttestedData: attestedData}
+}
+
+func TestAttestedCcEncryptionKey(t *testing.T) {
+	csp := GetDefaultCSP()
+	ek1, _, err := csp.NewRSAKeys()
+	require.NoError(t, err)
+	ek2, _, err := csp.NewRSAKeys()
+	require.NoError(t, err)
+
+	var credentialsList []*protos.Credentials
+	rejected := map[string]bool{}
+	provider := &EncryptionProviderImpl{
+		CSP: csp,
+		GetCcEncryptionKey: func() ([]byte, error) {
+			return nil, fmt.Errorf("should not be used")
+		},
+		GetCcCredentials: func() ([]*protos.Credentials, error) {
+			return credentialsList, nil
+		},
+		VerifyCredentials: func(credentials *protos.Credentials) error {
+			if rejected[string(credentials.GetSerializedAttestedData().GetValue())] {
+				return fmt.Errorf("evidence verification failed")
+			}
+			return nil
+		},
+		KeyPins: NewKeyPins(),
+	}
+
+	getKey := func() ([]byte, error) {
+		ctx, err := provider.NewEncryptionContext()
+		if err != nil {
+			return nil, err
+		}
+		return ctx.(*EncryptionContextImpl).chaincodeEncryptionKey, nil
+	}
+
+	// no credentials
+	_, err = getKey()
+	assert.EqualError(t, err, "no verified enclave credentials with chaincode encryption key found")
+
+	// credentials without key are ignored
+	credentialsList = []*protos.Credentials{credentialsWithKey(t, []byte("enclave0"), 1, nil), credentialsWithKey(t, []byte("enclave1"), 1, ek1)}
+	key, err := getKey()
+	assert.NoError(t, err)
+	assert.Equal(t, ek1, key)
+
+	// credentials of a malicious enclave are rejected by the verifier
+	malicious := credentialsWithKey(t, []byte("enclave2"), 1, ek2)
+	rejected[string(malicious.GetSerializedAttestedData().GetValue())] = true
+	credentialsList = append(credentialsList, malicious)
+	key, err = getKey()
+	assert.NoError(t, err)
+	assert.Equal(t, ek1, key)
+
+	// verified enclaves must agree on the key
+	rejected = map[string]bool{}
+	_, err = getKey()
+	assert.EqualError(t, err, "enclaves attest different chaincode encryption keys")
+
+	// the key is pinned for the chaincode sequence
+	credentialsList = []*protos.Credentials{credentialsWithKey(t, []byte("enclave2"), 1, ek2)}
+	_, err = getKey()
+	assert.EqualError(t, err, "chaincode encryption key does not match pinned key for chaincode someChaincode sequence 1")
+
+	// a new sequence may come with a new key; the credentials of the latest sequence are used
+	credentialsList = []*protos.Credentials{credentialsWithKey(t, []byte("enclave1"), 1, ek1), credentialsWithKey(t, []byte("enclave2"), 2, ek2)}
+	key, err = getKey()
+	assert.NoError(t, err)
+	assert.Equal(t, ek2, key)
+
+	// no verifier
+	provider.VerifyCredentials = nil
+	_, err = getKey()
+	assert.EqualError(t, err, "no credential verifier configured")
+}
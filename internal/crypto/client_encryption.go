@@ -8,6 +8,8 @@ SPDX-License-Identifier: Apache-2.0
 package crypto
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 
@@ -27,6 +29,20 @@ type EncryptionProvider interface {
 type EncryptionProviderImpl struct {
 	CSP                CSP
 	GetCcEncryptionKey func() ([]byte, error)
+	// GetEnclaveCredentials returns the credentials of the registered enclave with the given enclave id.
+	// If set, the response of an enclave is only revealed if the enclave is registered and the response is
+	// signed by the enclave.
+	GetEnclaveCredentials func(enclaveId string) (*protos.Credentials, error)
+	// GetCcCredentials returns the credentials of all registered enclaves of the chaincode.
+	// If set, the chaincode encryption key is taken from the attested data of the credentials accepted by
+	// VerifyCredentials instead of using GetCcEncryptionKey, and pinned in KeyPins (if set).
+	GetCcCredentials  func() ([]*protos.Credentials, error)
+	VerifyCredentials func(credentials *protos.Credentials) error
+	// GetCcKeyRegistration returns the signed cc key registration message of the enclave with the given enclave id,
+	// or nil if there is none. If set, the chaincode encryption key of enclaves that do not include the key in their
+	// attested data (i.e., enclaves provisioned via the key distribution protocol) is taken from the verified message.
+	GetCcKeyRegistration func(enclaveId string) (*protos.SignedCCKeyRegistrationMessage, error)
+	KeyPins              *KeyPins
 }
 
 func (p EncryptionProviderImpl) NewEncryptionContext() (EncryptionContext, error) {
@@ -42,12 +58,7 @@ func (p EncryptionProviderImpl) NewEncryptionContext() (EncryptionContext, error
 		return nil, err
 	}
 
-	ccEncryptionKey, err := p.GetCcEncryptionKey()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get chaincode encryption key from ercc: %s", err.Error())
-	}
-	//decode key
-	ccEncryptionKey, err = base64.StdEncoding.DecodeString(string(ccEncryptionKey))
+	ccEncryptionKey, err := p.getCcEncryptionKey()
 	if err != nil {
 		return nil, err
 	}
@@ -57,16 +68,38 @@ func (p EncryptionProviderImpl) NewEncryptionContext() (EncryptionContext, error
 		requestEncryptionKey:   requestEncryptionKey,
 		responseEncryptionKey:  resultEncryptionKey,
 		chaincodeEncryptionKey: ccEncryptionKey,
+		getEnclaveCredentials:  p.GetEnclaveCredentials,
 	}, nil
 }
 
+func (p EncryptionProviderImpl) getCcEncryptionKey() ([]byte, error) {
+	if p.GetCcCredentials != nil {
+		return p.getAttestedCcEncryptionKey()
+	}
+
+	ccEncryptionKey, err := p.GetCcEncryptionKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chaincode encryption key from ercc: %s", err.Error())
+	}
+	//decode key
+	return base64.StdEncoding.DecodeString(string(ccEncryptionKey))
+}
+
 // EncryptionContext defines the interface of an object responsible to encrypt the contents of a transaction invocation
 // and to decrypt the corresponding response.
+// ConcealWithTransient may be used instead of Conceal to additionally pass transient data to the chaincode, and
+// ConcealBytes to pass binary args that are not converted from strings.
 // Conceal and Reveal must be called only once during the lifetime of an object that implements this interface. That is,
 // an EncryptionContext is only valid for a single transaction invocation.
+// RevealEvent may be called in addition to Reveal to decrypt the chaincode event contained in the same response.
+// Before decrypting, Reveal checks that the response refers to the request created by Conceal and, if enclave
+// credentials are available, that the response is signed by a registered enclave.
 type EncryptionContext interface {
 	Conceal(function string, args []string) (string, error)
+	ConcealWithTransient(function string, args []string, transient map[string][]byte) (string, error)
+	ConcealBytes(function string, args [][]byte, transient map[string][]byte) (string, error)
 	Reveal(r []byte) ([]byte, error)
+	RevealEvent(r []byte) (*protos.FPCEvent, []byte, error)
 }
 
 type EncryptionContextImpl struct {
@@ -74,43 +107,133 @@ type EncryptionContextImpl struct {
 	requestEncryptionKey   []byte
 	responseEncryptionKey  []byte
 	chaincodeEncryptionKey []byte
+	getEnclaveCredentials  func(enclaveId string) (*protos.Credentials, error)
+	// hash of the chaincode request message created by Conceal
+	requestHash []byte
 }
 
 func (e *EncryptionContextImpl) Reveal(signedResponseBytesB64 []byte) ([]byte, error) {
-	signedResponseBytes, err := base64.StdEncoding.DecodeString(string(signedResponseBytesB64))
+	signedResponse, response, err := extractChaincodeResponseMessage(signedResponseBytesB64)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := e.verifyResponse(signedResponse, response); err != nil {
+		return nil, errors.Wrap(err, "response verification failed")
+	}
+
+	clearResponseBytes, err := e.csp.DecryptMessage(e.responseEncryptionKey, response.EncryptedResponse)
+	if err != nil {
+		return nil, errors.Wrap(err, "decryption of response failed")
+	}
+
+	return clearResponseBytes, nil
+}
+
+// RevealEvent returns the chaincode event set by the transaction (or nil if there is none) and its decrypted payload.
+// The payload is only decrypted if the event is addressed to the invoking client, that is, it was encrypted using
+// the response encryption key; for events encrypted with a chaincode-defined event key the returned payload is nil.
+func (e *EncryptionContextImpl) RevealEvent(signedResponseBytesB64 []byte) (*protos.FPCEvent, []byte, error) {
+	signedResponse, response, err := extractChaincodeResponseMessage(signedResponseBytesB64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := e.verifyResponse(signedResponse, response); err != nil {
+		return nil, nil, errors.Wrap(err, "response verification failed")
+	}
+
+	event := response.GetEvent()
+	if event == nil || event.GetEventKeyId() != "" {
+		return event, nil, nil
+	}
+
+	clearPayload, err := e.csp.DecryptMessage(e.responseEncryptionKey, event.EncryptedPayload)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "decryption of event payload failed")
+	}
+
+	return event, clearPayload, nil
+}
+
+// verifyResponse checks that the response refers to the request of this context and is signed by a registered enclave
+func (e *EncryptionContextImpl) verifyResponse(signedResponse *protos.SignedChaincodeResponseMessage, response *protos.ChaincodeResponseMessage) error {
+	if e.requestHash != nil && !bytes.Equal(e.requestHash, response.GetChaincodeRequestMessageHash()) {
+		return fmt.Errorf("chaincode request message hash mismatch")
+	}
+
+	if e.getEnclaveCredentials == nil {
+		return nil
+	}
+
+	enclaveId := response.GetEnclaveId()
+	credentials, err := e.getEnclaveCredentials(enclaveId)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get credentials of enclave %s", enclaveId)
+	}
+
+	attestedData, err := utils.UnmarshalAttestedData(credentials.GetSerializedAttestedData())
+	if err != nil {
+		return errors.Wrap(err, "invalid enclave credentials")
+	}
+
+	if attestedData.GetEnclaveVk() == nil {
+		return fmt.Errorf("no enclave verification key")
+	}
+
+	if signedResponse.GetSignature() == nil {
+		return fmt.Errorf("no enclave signature")
+	}
+
+	if err := e.csp.VerifyMessage(attestedData.GetEnclaveVk(), signedResponse.GetChaincodeResponseMessage(), signedResponse.GetSignature()); err != nil {
+		return fmt.Errorf("enclave signature verification failed")
+	}
+
+	return nil
+}
+
+func extractChaincodeResponseMessage(signedResponseBytesB64 []byte) (*protos.SignedChaincodeResponseMessage, *protos.ChaincodeResponseMessage, error) {
+	signedResponseBytes, err := base64.StdEncoding.DecodeString(string(signedResponseBytesB64))
+	if err != nil {
+		return nil, nil, err
+	}
+
 	signedResponse, err := utils.UnmarshalSignedChaincodeResponseMessage(signedResponseBytes)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to extract signed response message")
+		return nil, nil, errors.Wrap(err, "failed to extract signed response message")
 	}
 
 	responseBytes := signedResponse.GetChaincodeResponseMessage()
 	if responseBytes == nil {
-		return nil, fmt.Errorf("no chaincode response message")
+		return nil, nil, fmt.Errorf("no chaincode response message")
 	}
 
 	response, err := utils.UnmarshalChaincodeResponseMessage(responseBytes)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to extract response message")
+		return nil, nil, errors.Wrap(err, "failed to extract response message")
 	}
 
-	clearResponseBytes, err := e.csp.DecryptMessage(e.responseEncryptionKey, response.EncryptedResponse)
-	if err != nil {
-		return nil, errors.Wrap(err, "decryption of response failed")
-	}
-
-	return clearResponseBytes, nil
+	return signedResponse, response, nil
 }
 
 func (e *EncryptionContextImpl) Conceal(function string, args []string) (string, error) {
-	args = append([]string{function}, args...)
+	return e.ConcealWithTransient(function, args, nil)
+}
+
+// ConcealWithTransient encrypts the chaincode request as Conceal does. The transient data is encrypted along with
+// the function and args; thus, it is only visible to the chaincode enclave via GetTransient.
+func (e *EncryptionContextImpl) ConcealWithTransient(function string, args []string, transient map[string][]byte) (string, error) {
 	bytes := make([][]byte, len(args))
 	for i, v := range args {
 		bytes[i] = []byte(v)
 	}
+	return e.ConcealBytes(function, bytes, transient)
+}
+
+// ConcealBytes encrypts the chaincode request as ConcealWithTransient does but takes the args as bytes; thus, binary
+// args such as serialized protobuf messages are passed to the chaincode as they are.
+func (e *EncryptionContextImpl) ConcealBytes(function string, args [][]byte, transient map[string][]byte) (string, error) {
+	bytes := append([][]byte{[]byte(function)}, args...)
 
 	// prepare KeyTransportMessage
 	keyTransport := &protos.KeyTransportMessage{
@@ -130,9 +253,10 @@ func (e *EncryptionContextImpl) Conceal(function string, args []string) (string,
 
 	// prepare CleartextChaincodeRequest
 	ccRequest := &protos.CleartextChaincodeRequest{
-		Input: &peer.ChaincodeInput{Args: bytes},
+		Input:        &peer.ChaincodeInput{Args: bytes},
+		TransientMap: transient,
 	}
-	logger.Debugf("prepping chaincode params: %s", ccRequest)
+	logger.Debugf("prepping chaincode params: %s", ccRequest.GetInput())
 
 	serializedCcRequest, err := utils.MarshallProto(ccRequest)
 	if err != nil {
@@ -155,5 +279,9 @@ func (e *EncryptionContextImpl) Conceal(function string, args []string) (string,
 		return "", err
 	}
 
+	// the enclave includes the hash of the request in its response
+	requestHash := sha256.Sum256(serializedEncryptedCcRequest)
+	e.requestHash = requestHash[:]
+
 	return base64.StdEncoding.EncodeToString(serializedEncryptedCcRequest), nil
 }
@@ -8,6 +8,7 @@ SPDX-License-Identifier: Apache-2.0
 package crypto
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"testing"
@@ -16,6 +17,8 @@ import (
 	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 func TestNewEncryptionContext(t *testing.T) {
@@ -100,6 +103,68 @@ func TestConceal(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestConcealWithTransient(t *testing.T) {
+	transient := map[string][]byte{"secret": []byte("some secret")}
+
+	pubKey, _, err := GetDefaultCSP().NewRSAKeys()
+	assert.NoError(t, err)
+	requestEncryptionKey, err := GetDefaultCSP().NewSymmetricKey()
+	assert.NoError(t, err)
+
+	ctx := &EncryptionContextImpl{
+		csp:                    GetDefaultCSP(),
+		requestEncryptionKey:   requestEncryptionKey,
+		chaincodeEncryptionKey: pubKey,
+	}
+
+	request, err := ctx.ConcealWithTransient("some function", []string{"some", "args"}, transient)
+	assert.NoError(t, err)
+
+	// the transient data is part of the encrypted request
+	requestBytes, err := base64.StdEncoding.DecodeString(request)
+	assert.NoError(t, err)
+	requestMsg := &protos.ChaincodeRequestMessage{}
+	assert.NoError(t, proto.Unmarshal(requestBytes, requestMsg))
+	cleartextRequestBytes, err := GetDefaultCSP().DecryptMessage(requestEncryptionKey, requestMsg.GetEncryptedRequest())
+	assert.NoError(t, err)
+	cleartextRequest := &protos.CleartextChaincodeRequest{}
+	assert.NoError(t, proto.Unmarshal(cleartextRequestBytes, cleartextRequest))
+	assert.Equal(t, transient, cleartextRequest.GetTransientMap())
+	assert.Equal(t, [][]byte{[]byte("some function"), []byte("some"), []byte("args")}, cleartextRequest.GetInput().GetArgs())
+}
+
+func TestConcealBytes(t *testing.T) {
+	pubKey, _, err := GetDefaultCSP().NewRSAKeys()
+	assert.NoError(t, err)
+	requestEncryptionKey, err := GetDefaultCSP().NewSymmetricKey()
+	assert.NoError(t, err)
+
+	ctx := &EncryptionContextImpl{
+		csp:                    GetDefaultCSP(),
+		requestEncryptionKey:   requestEncryptionKey,
+		chaincodeEncryptionKey: pubKey,
+	}
+
+	binaryArg := []byte{0x00, 0xff, 0xfe, 0x0a}
+	request, err := ctx.ConcealBytes("some function", [][]byte{binaryArg, nil}, nil)
+	assert.NoError(t, err)
+
+	// the args are passed as they are
+	requestBytes, err := base64.StdEncoding.DecodeString(request)
+	assert.NoError(t, err)
+	requestMsg := &protos.ChaincodeRequestMessage{}
+	assert.NoError(t, proto.Unmarshal(requestBytes, requestMsg))
+	cleartextRequestBytes, err := GetDefaultCSP().DecryptMessage(requestEncryptionKey, requestMsg.GetEncryptedRequest())
+	assert.NoError(t, err)
+	cleartextRequest := &protos.CleartextChaincodeRequest{}
+	assert.NoError(t, proto.Unmarshal(cleartextRequestBytes, cleartextRequest))
+	args := cleartextRequest.GetInput().GetArgs()
+	assert.Len(t, args, 3)
+	assert.Equal(t, []byte("some function"), args[0])
+	assert.Equal(t, binaryArg, args[1])
+	assert.Empty(t, args[2])
+}
+
 func TestReveal(t *testing.T) {
 	msg := []byte("some response")
 
@@ -158,3 +223,134 @@ func TestReveal(t *testing.T) {
 	assert.Equal(t, resp, msg)
 	assert.NoError(t, err)
 }
+
+func TestRevealVerification(t *testing.T) {
+	csp := GetDefaultCSP()
+	msg := []byte("some response")
+
+	ccPubKey, _, err := csp.NewRSAKeys()
+	assert.NoError(t, err)
+	enclaveVk, enclaveSk, err := csp.NewECDSAKeys()
+	assert.NoError(t, err)
+	attestedData, err := anypb.New(&protos.AttestedData{EnclaveVk: enclaveVk})
+	assert.NoError(t, err)
+	credentials := &protos.Credentials{SerializedAttestedData: attestedData}
+
+	var requestedEnclaveId string
+	provider := &EncryptionProviderImpl{
+		CSP: csp,
+		GetCcEncryptionKey: func() ([]byte, error) {
+			return []byte(base64.StdEncoding.EncodeToString(ccPubKey)), nil
+		},
+		GetEnclaveCredentials: func(enclaveId string) (*protos.Credentials, error) {
+			requestedEnclaveId = enclaveId
+			if enclaveId != "someEnclaveId" {
+				return nil, fmt.Errorf("enclave %s is not registered", enclaveId)
+			}
+			return credentials, nil
+		},
+	}
+
+	ctx, err := provider.NewEncryptionContext()
+	assert.NoError(t, err)
+	request, err := ctx.Conceal("some function", []string{"some", "args"})
+	assert.NoError(t, err)
+	requestBytes, err := base64.StdEncoding.DecodeString(request)
+	assert.NoError(t, err)
+	requestHash := sha256.Sum256(requestBytes)
+
+	// the enclave responds with the request hash and signs the response
+	respond := func(enclaveId string, requestHash []byte, signingKey []byte) []byte {
+		encryptedMsg, err := csp.EncryptMessage(ctx.(*EncryptionContextImpl).responseEncryptionKey, msg)
+		assert.NoError(t, err)
+		responseBytes := protoutil.MarshalOrPanic(&protos.ChaincodeResponseMessage{
+			EncryptedResponse:           encryptedMsg,
+			EnclaveId:                   enclaveId,
+			ChaincodeRequestMessageHash: requestHash,
+		})
+		var signature []byte
+		if signingKey != nil {
+			signature, err = csp.SignMessage(signingKey, responseBytes)
+			assert.NoError(t, err)
+		}
+		return []byte(utils.MarshallProtoBase64(&protos.SignedChaincodeResponseMessage{ChaincodeResponseMessage: responseBytes, Signature: signature}))
+	}
+
+	// should succeed
+	resp, err := ctx.Reveal(respond("someEnclaveId", requestHash[:], enclaveSk))
+	assert.NoError(t, err)
+	assert.Equal(t, msg, resp)
+	assert.Equal(t, "someEnclaveId", requestedEnclaveId)
+
+	// response to another request
+	otherHash := sha256.Sum256([]byte("other request"))
+	_, err = ctx.Reveal(respond("someEnclaveId", otherHash[:], enclaveSk))
+	assert.EqualError(t, err, "response verification failed: chaincode request message hash mismatch")
+
+	// unknown enclave
+	_, err = ctx.Reveal(respond("someOtherEnclaveId", requestHash[:], enclaveSk))
+	assert.EqualError(t, err, "response verification failed: cannot get credentials of enclave someOtherEnclaveId: enclave someOtherEnclaveId is not registered")
+
+	// not signed
+	_, err = ctx.Reveal(respond("someEnclaveId", requestHash[:], nil))
+	assert.EqualError(t, err, "response verification failed: no enclave signature")
+
+	// signed by another key
+	_, otherSk, err := csp.NewECDSAKeys()
+	assert.NoError(t, err)
+	_, err = ctx.Reveal(respond("someEnclaveId", requestHash[:], otherSk))
+	assert.EqualError(t, err, "response verification failed: enclave signature verification failed")
+
+	// events are verified as well
+	_, _, err = ctx.RevealEvent(respond("someEnclaveId", requestHash[:], otherSk))
+	assert.EqualError(t, err, "response verification failed: enclave signature verification failed")
+}
+
+func TestRevealEvent(t *testing.T) {
+	payload := []byte("some event payload")
+
+	responseEncryptionKey, err := GetDefaultCSP().NewSymmetricKey()
+	assert.NoError(t, err)
+
+	ctx := &EncryptionContextImpl{
+		csp:                   GetDefaultCSP(),
+		responseEncryptionKey: responseEncryptionKey,
+	}
+
+	reveal := func(response *protos.ChaincodeResponseMessage) (*protos.FPCEvent, []byte, error) {
+		responseBytes := protoutil.MarshalOrPanic(response)
+		return ctx.RevealEvent([]byte(utils.MarshallProtoBase64(&protos.SignedChaincodeResponseMessage{ChaincodeResponseMessage: responseBytes})))
+	}
+
+	// invalid input
+	event, clearPayload, err := ctx.RevealEvent([]byte("invalid input (not base64)"))
+	assert.Nil(t, event)
+	assert.Nil(t, clearPayload)
+	assert.Error(t, err)
+
+	// no event
+	event, clearPayload, err = reveal(&protos.ChaincodeResponseMessage{EncryptedResponse: []byte("some response")})
+	assert.Nil(t, event)
+	assert.Nil(t, clearPayload)
+	assert.NoError(t, err)
+
+	// payload not encrypted
+	event, clearPayload, err = reveal(&protos.ChaincodeResponseMessage{Event: &protos.FPCEvent{EventName: "someEvent", EncryptedPayload: payload}})
+	assert.Nil(t, event)
+	assert.Nil(t, clearPayload)
+	assert.Error(t, err)
+
+	// event encrypted with a chaincode-defined event key
+	event, clearPayload, err = reveal(&protos.ChaincodeResponseMessage{Event: &protos.FPCEvent{EventName: "someEvent", EncryptedPayload: payload, EventKeyId: "someKey"}})
+	assert.Equal(t, "someEvent", event.GetEventName())
+	assert.Nil(t, clearPayload)
+	assert.NoError(t, err)
+
+	// should succeed
+	encryptedPayload, err := GetDefaultCSP().EncryptMessage(responseEncryptionKey, payload)
+	assert.NoError(t, err)
+	event, clearPayload, err = reveal(&protos.ChaincodeResponseMessage{Event: &protos.FPCEvent{EventName: "someEvent", EncryptedPayload: encryptedPayload}})
+	assert.Equal(t, "someEvent", event.GetEventName())
+	assert.Equal(t, payload, clearPayload)
+	assert.NoError(t, err)
+}
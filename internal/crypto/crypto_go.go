@@ -240,6 +240,10 @@ func (g GoCrypto) EncryptMessage(key []byte, message []byte) (encryptedMessage [
 		return nil, err
 	}
 
+	return encryptMessageWithNonce(key, nonce, message)
+}
+
+func encryptMessageWithNonce(key []byte, nonce []byte, message []byte) (encryptedMessage []byte, err error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -169,3 +169,35 @@ func TestSymEncryption(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestDeterministicSymEncryption(t *testing.T) {
+	msg := []byte("some message")
+
+	for _, tc := range allTestCases {
+		key, err := tc.CSP.NewSymmetricKey()
+		assert.NoError(t, err)
+
+		csp := NewDeterministicCSP(tc.CSP, []byte("some context"))
+
+		cipher, err := csp.EncryptMessage([]byte("invalid key"), msg)
+		assert.Nil(t, cipher)
+		assert.Error(t, err)
+
+		// same key, context, and message result in the same ciphertext
+		cipher, err = csp.EncryptMessage(key, msg)
+		assert.NoError(t, err)
+		otherCipher, err := NewDeterministicCSP(tc.CSP, []byte("some context")).EncryptMessage(key, msg)
+		assert.NoError(t, err)
+		assert.Equal(t, cipher, otherCipher)
+
+		// a different context results in a different ciphertext
+		otherCipher, err = NewDeterministicCSP(tc.CSP, []byte("other context")).EncryptMessage(key, msg)
+		assert.NoError(t, err)
+		assert.NotEqual(t, cipher, otherCipher)
+
+		// decryption is not affected
+		plain, err := tc.CSP.DecryptMessage(key, cipher)
+		assert.Equal(t, msg, plain)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+const deterministicNonceLabel = "fpc-deterministic-nonce"
+
+// deterministicCSP is a CSP whose symmetric encryption derives the nonce from the key, a context, and the message,
+// instead of picking a random nonce. Thus, encrypting the same message with the same key and context always
+// produces the same ciphertext. The ciphertexts can be decrypted with DecryptMessage of any CSP.
+type deterministicCSP struct {
+	CSP
+	context []byte
+}
+
+// NewDeterministicCSP returns a CSP that behaves like the given CSP except that EncryptMessage is deterministic for
+// the given context. Note that deterministic encryption reveals whether two messages encrypted with the same key and
+// context are equal; hence, the context should be unique per use, e.g., the hash of a chaincode request.
+func NewDeterministicCSP(csp CSP, context []byte) CSP {
+	return &deterministicCSP{
+		CSP:     csp,
+		context: context,
+	}
+}
+
+func (d *deterministicCSP) EncryptMessage(key []byte, message []byte) (encryptedMessage []byte, err error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(deterministicNonceLabel))
+	mac.Write(d.context)
+	mac.Write(message)
+	nonce := mac.Sum(nil)[:NonceLength]
+
+	return encryptMessageWithNonce(key, nonce, message)
+}
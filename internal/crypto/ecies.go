@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// uncompressed P-256 point encoding (0x04 || X || Y)
+const eciesEphemeralKeyLength = 65
+
+// EciesEncryptMessage encrypts a message for the holder of the ECDSA (P-256) public key given as PEM encoded PKIX key.
+// An ephemeral ECDH key is used to derive a symmetric key, which encrypts the message using EncryptMessage.
+// The result is the uncompressed ephemeral public key followed by the encrypted message (nonce + tag + cipher).
+//
+// Note that this functionality is intentionally not part of CSP as it is only used by enclave_go (for key distribution)
+// and relies on the enclave signing key being an ECDSA key.
+func EciesEncryptMessage(publicKey []byte, message []byte) ([]byte, error) {
+	block, _ := pem.Decode(publicKey)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("failed to decode PEM block containing public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse public key")
+	}
+
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an ecdsa key")
+	}
+
+	receiverKey, err := ecdsaPub.ECDH()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert public key")
+	}
+
+	ephemeralKey, err := receiverKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate ephemeral key")
+	}
+
+	sharedSecret, err := ephemeralKey.ECDH(receiverKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralPublicKey := ephemeralKey.PublicKey().Bytes()
+	ciphertext, err := NewGoCrypto().EncryptMessage(eciesKdf(sharedSecret, ephemeralPublicKey), message)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ephemeralPublicKey, ciphertext...), nil
+}
+
+// EciesDecryptMessage decrypts a message created with EciesEncryptMessage using the PEM encoded ECDSA private key.
+func EciesDecryptMessage(privateKey []byte, encryptedMessage []byte) ([]byte, error) {
+	if len(encryptedMessage) <= eciesEphemeralKeyLength {
+		return nil, fmt.Errorf("encrypted message to small. expect len to be larger than %d, actual %d", eciesEphemeralKeyLength, len(encryptedMessage))
+	}
+
+	block, _ := pem.Decode(privateKey)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, fmt.Errorf("failed to decode PEM block containing private key")
+	}
+
+	priv, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	receiverKey, err := priv.ECDH()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert private key")
+	}
+
+	ephemeralPublicKey := encryptedMessage[:eciesEphemeralKeyLength]
+	ephemeralKey, err := ecdh.P256().NewPublicKey(ephemeralPublicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse ephemeral public key")
+	}
+
+	sharedSecret, err := receiverKey.ECDH(ephemeralKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGoCrypto().DecryptMessage(eciesKdf(sharedSecret, ephemeralPublicKey), encryptedMessage[eciesEphemeralKeyLength:])
+}
+
+func eciesKdf(sharedSecret, ephemeralPublicKey []byte) []byte {
+	h := sha256.New()
+	h.Write(sharedSecret)
+	h.Write(ephemeralPublicKey)
+	return h.Sum(nil)[:SymKeyLength]
+}
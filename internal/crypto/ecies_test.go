@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEciesEncryption(t *testing.T) {
+	msg := []byte("some message")
+
+	pubKey, privKey, err := NewGoCrypto().NewECDSAKeys()
+	assert.NotEmpty(t, pubKey)
+	assert.NotEmpty(t, privKey)
+	assert.NoError(t, err)
+
+	cipher, err := EciesEncryptMessage([]byte("invalid key"), msg)
+	assert.Nil(t, cipher)
+	assert.Error(t, err)
+
+	// should succeed
+	cipher, err = EciesEncryptMessage(pubKey, msg)
+	assert.NotNil(t, cipher)
+	assert.NoError(t, err)
+
+	plain, err := EciesDecryptMessage([]byte("invalid key"), cipher)
+	assert.Nil(t, plain)
+	assert.Error(t, err)
+
+	// fail with other key
+	_, otherPrivKey, err := NewGoCrypto().NewECDSAKeys()
+	assert.NoError(t, err)
+	plain, err = EciesDecryptMessage(otherPrivKey, cipher)
+	assert.Nil(t, plain)
+	assert.Error(t, err)
+
+	// fail with truncated ciphertext
+	plain, err = EciesDecryptMessage(privKey, cipher[:eciesEphemeralKeyLength])
+	assert.Nil(t, plain)
+	assert.Error(t, err)
+
+	// should succeed
+	plain, err = EciesDecryptMessage(privKey, cipher)
+	assert.Equal(t, msg, plain)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// VerifyCCKeyRegistrationMessage checks that the SignedCCKeyRegistrationMessage is issued by the enclave with the given
+// attested data, i.e., it is signed with the attested enclave_vk and refers to the attested enclave_id and cc_params,
+// and returns the CCKeyRegistrationMessage.
+// As the signing key never leaves the attested enclave, the chaincode_ek of a verified message is bound to the
+// attestation of the enclave, just like a chaincode_ek that is part of the attested data itself.
+func VerifyCCKeyRegistrationMessage(attestedData *protos.AttestedData, signedMsg *protos.SignedCCKeyRegistrationMessage) (*protos.CCKeyRegistrationMessage, error) {
+	msg, err := utils.UnmarshalCCKeyRegistrationMessage(signedMsg.GetSerializedCckeyRegMsg())
+	if err != nil {
+		return nil, err
+	}
+
+	enclaveId := utils.GetEnclaveId(attestedData)
+	if strings.ToUpper(hex.EncodeToString(msg.GetEnclaveId())) != enclaveId {
+		return nil, fmt.Errorf("cc key registration message is not issued by enclave %s", enclaveId)
+	}
+
+	if err := GetDefaultCSP().VerifyMessage(attestedData.GetEnclaveVk(), signedMsg.GetSerializedCckeyRegMsg().GetValue(), signedMsg.GetSignature()); err != nil {
+		return nil, errors.Wrap(err, "cc key registration message signature verification failed")
+	}
+
+	ccParamsHash, err := utils.GetCCParamsHash(attestedData.GetCcParams())
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(ccParamsHash, msg.GetCcParamsHash()) {
+		return nil, fmt.Errorf("cc_params_hash of cc key registration message does not match attested cc_params")
+	}
+
+	if len(msg.GetChaincodeEk()) == 0 {
+		return nil, fmt.Errorf("cc key registration message without chaincode_ek")
+	}
+
+	return msg, nil
+}
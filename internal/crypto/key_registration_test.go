@@ -0,0 +1,79 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func signedCCKeyRegistrationMessage(t *testing.T, signingKey []byte, msg *protos.CCKeyRegistrationMessage) *protos.SignedCCKeyRegistrationMessage {
+	serializedMsg, err := anypb.New(msg)
+	require.NoError(t, err)
+	sig, err := GetDefaultCSP().SignMessage(signingKey, serializedMsg.GetValue())
+	require.NoError(t, err)
+	return &protos.SignedCCKeyRegistrationMessage{SerializedCckeyRegMsg: serializedMsg, Signature: sig}
+}
+
+func TestVerifyCCKeyRegistrationMessage(t *testing.T) {
+	csp := GetDefaultCSP()
+	enclaveVk, enclaveSk, err := csp.NewECDSAKeys()
+	require.NoError(t, err)
+	_, otherSk, err := csp.NewECDSAKeys()
+	require.NoError(t, err)
+
+	attestedData := &protos.AttestedData{
+		EnclaveVk: enclaveVk,
+		CcParams:  &protos.CCParameters{ChaincodeId: "someChaincode", Version: "someMrEnclave", Sequence: 1, ChannelId: "someChannel"},
+	}
+	ccParamsHash, err := utils.GetCCParamsHash(attestedData.CcParams)
+	require.NoError(t, err)
+	enclaveId := sha256.Sum256(enclaveVk)
+
+	msg := &protos.CCKeyRegistrationMessage{
+		CcParamsHash: ccParamsHash,
+		ChaincodeEk:  []byte("someChaincodeEk"),
+		EnclaveId:    enclaveId[:],
+	}
+
+	verified, err := VerifyCCKeyRegistrationMessage(attestedData, signedCCKeyRegistrationMessage(t, enclaveSk, msg))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("someChaincodeEk"), verified.GetChaincodeEk())
+
+	// signed by another key
+	_, err = VerifyCCKeyRegistrationMessage(attestedData, signedCCKeyRegistrationMessage(t, otherSk, msg))
+	assert.Contains(t, err.Error(), "cc key registration message signature verification failed")
+
+	// other enclave id
+	_, err = VerifyCCKeyRegistrationMessage(attestedData, signedCCKeyRegistrationMessage(t, enclaveSk, &protos.CCKeyRegistrationMessage{
+		CcParamsHash: ccParamsHash,
+		ChaincodeEk:  []byte("someChaincodeEk"),
+		EnclaveId:    []byte("otherEnclave"),
+	}))
+	assert.EqualError(t, err, "cc key registration message is not issued by enclave "+utils.GetEnclaveId(attestedData))
+
+	// other cc params
+	_, err = VerifyCCKeyRegistrationMessage(attestedData, signedCCKeyRegistrationMessage(t, enclaveSk, &protos.CCKeyRegistrationMessage{
+		CcParamsHash: []byte("otherHash"),
+		ChaincodeEk:  []byte("someChaincodeEk"),
+		EnclaveId:    enclaveId[:],
+	}))
+	assert.EqualError(t, err, "cc_params_hash of cc key registration message does not match attested cc_params")
+
+	// no key
+	_, err = VerifyCCKeyRegistrationMessage(attestedData, signedCCKeyRegistrationMessage(t, enclaveSk, &protos.CCKeyRegistrationMessage{
+		CcParamsHash: ccParamsHash,
+		EnclaveId:    enclaveId[:],
+	}))
+	assert.EqualError(t, err, "cc key registration message without chaincode_ek")
+}
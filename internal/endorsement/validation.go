@@ -18,12 +18,22 @@ import (
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
 	"github.com/hyperledger/fabric-private-chaincode/internal/utils"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset/kvrwset"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/pkg/errors"
 )
 
 var logger = flogging.MustGetLogger("validate")
 
+// ReadConflictError is returned by ReplayReadWrites if the state read by the enclave changed after the invocation
+type ReadConflictError struct {
+	msg string
+}
+
+func (e *ReadConflictError) Error() string {
+	return e.msg
+}
+
 type Validation interface {
 	ReplayReadWrites(stub shim.ChaincodeStubInterface, fpcrwset *protos.FPCKVSet) error
 	Validate(signedResponseMessage *protos.SignedChaincodeResponseMessage, attestedData *protos.AttestedData) error
@@ -85,32 +95,19 @@ func (v *ValidatorImpl) ReplayReadWrites(stub shim.ChaincodeStubInterface, fpcrw
 				logger.Debugf("value(hex): %s", hex.EncodeToString(v))
 				logger.Debugf("computed hash(hex): %s", hex.EncodeToString(valueHash))
 				logger.Debugf("received hash(hex): %s", hex.EncodeToString(fpcrwset.ReadValueHashes[i]))
-				return fmt.Errorf("value hash mismatch for key %s", k)
+				return &ReadConflictError{msg: fmt.Sprintf("value hash mismatch for key %s", k)}
 			}
 		}
 	}
 
 	// range query reads
 	if rwset.GetRangeQueriesInfo() != nil {
-		return fmt.Errorf("RangeQuery support not implemented, missing hash check")
-		// TODO implement me when enabling support for range queries
-		//logger.Debugf("Replaying range queries")
-		//for _, rqi := range rwset.RangeQueriesInfo {
-		//	if rqi.GetRawReads() == nil {
-		//		// no raw reads available in this range query
-		//		continue
-		//	}
-		//	for _, qr := range rqi.GetRawReads().KvReads {
-		//		k := utils.TransformToFPCKey(qr.Key)
-		//		v, err := stub.GetState(k)
-		//		if err != nil {
-		//			return fmt.Errorf("error (%s) reading key %s", err, k)
-		//		}
-		//
-		//		_ = v
-		//		return fmt.Errorf("TODO: not implemented, missing hash check")
-		//	}
-		//}
+		logger.Debugf("Replaying range queries")
+		for _, rqi := range rwset.RangeQueriesInfo {
+			if err := replayRangeQuery(stub, rqi); err != nil {
+				return err
+			}
+		}
 	}
 
 	// writes
@@ -142,6 +139,46 @@ func (v *ValidatorImpl) ReplayReadWrites(stub shim.ChaincodeStubInterface, fpcrw
 	return nil
 }
 
+// replayRangeQuery re-runs a range query and checks that the results match the hash recorded by the enclave.
+// If the enclave has not exhausted the range query iterator, only the keys up to (and including) the end key are considered.
+func replayRangeQuery(stub shim.ChaincodeStubInterface, rqi *kvrwset.RangeQueryInfo) error {
+	summary := rqi.GetReadsMerkleHashes()
+	if summary == nil || len(summary.GetMaxLevelHashes()) != 1 {
+		return fmt.Errorf("no hash associated to range query start=%s end=%s", rqi.StartKey, rqi.EndKey)
+	}
+
+	endKey := rqi.EndKey
+	if !rqi.ItrExhausted {
+		// the end key is included, so we use the smallest key after the end key as (exclusive) upper bound
+		endKey = rqi.EndKey + "\x00"
+	}
+
+	iter, err := stub.GetStateByRange(rqi.StartKey, endKey)
+	if err != nil {
+		return fmt.Errorf("error (%s) reading range start=%s end=%s", err, rqi.StartKey, rqi.EndKey)
+	}
+	defer iter.Close()
+
+	hasher := utils.NewRangeQueryHasher()
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("error (%s) reading range start=%s end=%s", err, rqi.StartKey, rqi.EndKey)
+		}
+
+		// TODO: use CSP hash for consistency
+		valueHash := sha256.Sum256(kv.Value)
+		hasher.Add(utils.TransformToFPCKey(kv.Key), valueHash[:])
+		logger.Debugf("range read key='%s' value(hex)='%s'", kv.Key, hex.EncodeToString(kv.Value))
+	}
+
+	if !bytes.Equal(hasher.Sum(), summary.MaxLevelHashes[0]) {
+		return &ReadConflictError{msg: fmt.Sprintf("range query hash mismatch for range start=%s end=%s", rqi.StartKey, rqi.EndKey)}
+	}
+
+	return nil
+}
+
 func (v *ValidatorImpl) Validate(signedResponseMessage *protos.SignedChaincodeResponseMessage, attestedData *protos.AttestedData) error {
 	if signedResponseMessage.GetSignature() == nil {
 		return fmt.Errorf("no enclave signature")
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
 	"github.com/hyperledger/fabric-private-chaincode/internal/crypto"
 	"github.com/hyperledger/fabric-private-chaincode/internal/endorsement/fakes"
 	"github.com/hyperledger/fabric-private-chaincode/internal/protos"
@@ -97,7 +98,8 @@ func TestReplayReadWrites(t *testing.T) {
 		ReadValueHashes: someHashes,
 	}
 	err = v.ReplayReadWrites(stub, fpcrwset)
-	assert.Error(t, err)
+	var conflict *ReadConflictError
+	assert.ErrorAs(t, err, &conflict)
 
 	// no errors (reads)
 	value := []byte("some value")
@@ -162,7 +164,7 @@ func TestReplayReadWrites(t *testing.T) {
 	assert.EqualValues(t, expectedFabricCompKey, k)
 	assert.EqualValues(t, writeCompKey.Value, val)
 
-	// error when rangequery
+	// error when rangequery without hash
 	someRWSet = &kvrwset.KVRWSet{
 		RangeQueriesInfo: []*kvrwset.RangeQueryInfo{{
 			StartKey: "start",
@@ -176,6 +178,69 @@ func TestReplayReadWrites(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func rangeQueryInfo(startKey, endKey string, exhausted bool, kvs ...string) *kvrwset.RangeQueryInfo {
+	hasher := utils.NewRangeQueryHasher()
+	for i := 0; i < len(kvs); i += 2 {
+		hasher.Add(kvs[i], hash([]byte(kvs[i+1])))
+	}
+	return &kvrwset.RangeQueryInfo{
+		StartKey:     startKey,
+		EndKey:       endKey,
+		ItrExhausted: exhausted,
+		ReadsInfo: &kvrwset.RangeQueryInfo_ReadsMerkleHashes{
+			ReadsMerkleHashes: &kvrwset.QueryReadsMerkleSummary{MaxLevelHashes: [][]byte{hasher.Sum()}},
+		},
+	}
+}
+
+func TestReplayRangeQueries(t *testing.T) {
+	v := &ValidatorImpl{}
+	state := shimtest.NewMockStub("someChaincode", nil)
+	state.MockTransactionStart("someTxId")
+	stub := &fakes.ChaincodeStub{}
+	stub.GetStateByRangeStub = state.GetStateByRange
+
+	assert.NoError(t, state.PutState("keyA", []byte("valueA")))
+	assert.NoError(t, state.PutState("keyB", []byte("valueB")))
+	assert.NoError(t, state.PutState("keyD", []byte("valueD")))
+
+	replay := func(rqi *kvrwset.RangeQueryInfo) error {
+		return v.ReplayReadWrites(stub, &protos.FPCKVSet{
+			RwSet: &kvrwset.KVRWSet{RangeQueriesInfo: []*kvrwset.RangeQueryInfo{rqi}},
+		})
+	}
+
+	// error when GetStateByRange returns error
+	stub.GetStateByRangeReturns(nil, fmt.Errorf("some error"))
+	stub.GetStateByRangeStub = nil
+	assert.Error(t, replay(rangeQueryInfo("keyA", "keyC", true)))
+	stub.GetStateByRangeStub = state.GetStateByRange
+
+	// no error (exhausted range)
+	assert.NoError(t, replay(rangeQueryInfo("keyA", "keyC", true, "keyA", "valueA", "keyB", "valueB")))
+
+	// error when value changed
+	assert.EqualError(t, replay(rangeQueryInfo("keyA", "keyC", true, "keyA", "valueA", "keyB", "otherValue")),
+		"range query hash mismatch for range start=keyA end=keyC")
+	var conflict *ReadConflictError
+	assert.ErrorAs(t, replay(rangeQueryInfo("keyA", "keyC", true, "keyA", "valueA", "keyB", "otherValue")), &conflict)
+
+	// error when key missing (phantom)
+	assert.Error(t, replay(rangeQueryInfo("keyA", "keyE", true, "keyA", "valueA", "keyB", "valueB")))
+
+	// no error (not exhausted range covers keys up to the end key)
+	assert.NoError(t, replay(rangeQueryInfo("keyA", "keyA", false, "keyA", "valueA")))
+	assert.NoError(t, replay(rangeQueryInfo("keyA", "keyB", false, "keyA", "valueA", "keyB", "valueB")))
+
+	// error when key inserted (phantom)
+	assert.NoError(t, state.PutState("keyAA", []byte("valueAA")))
+	assert.Error(t, replay(rangeQueryInfo("keyA", "keyB", false, "keyA", "valueA", "keyB", "valueB")))
+
+	// no error when key inserted outside of not exhausted range
+	assert.NoError(t, state.PutState("keyC", []byte("valueC")))
+	assert.NoError(t, replay(rangeQueryInfo("keyD", "keyD", false, "keyD", "valueD")))
+}
+
 func TestValidate(t *testing.T) {
 	// TODO
 	c := &fakes.CryptoProvider{}
@@ -383,7 +383,10 @@ func (x *InitEnclaveMessage) GetAttestationParams() []byte {
 type CleartextChaincodeRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// the function and args to invoke
-	Input         *peer.ChaincodeInput `protobuf:"bytes,1,opt,name=input,proto3" json:"input,omitempty"`
+	Input *peer.ChaincodeInput `protobuf:"bytes,1,opt,name=input,proto3" json:"input,omitempty"`
+	// the transient data passed to the chaincode; exposed via GetTransient but, like in Fabric,
+	// never included in the response or the read/write set
+	TransientMap  map[string][]byte `protobuf:"bytes,2,rep,name=transient_map,json=transientMap,proto3" json:"transient_map,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -425,6 +428,13 @@ func (x *CleartextChaincodeRequest) GetInput() *peer.ChaincodeInput {
 	return nil
 }
 
+func (x *CleartextChaincodeRequest) GetTransientMap() map[string][]byte {
+	if x != nil {
+		return x.TransientMap
+	}
+	return nil
+}
+
 type ChaincodeRequestMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// an encryption (symmetric) of the serialization of CleartextChaincodeRequest with KeyTransportMessage.request_encryption_key
@@ -646,7 +656,9 @@ type ChaincodeResponseMessage struct {
 	// and not extracted from it; validation chaincode will check for consistency
 	ChaincodeRequestMessageHash []byte `protobuf:"bytes,4,opt,name=chaincode_request_message_hash,json=chaincodeRequestMessageHash,proto3" json:"chaincode_request_message_hash,omitempty"`
 	// identity for public key used to sign
-	EnclaveId     string `protobuf:"bytes,5,opt,name=enclave_id,json=enclaveId,proto3" json:"enclave_id,omitempty"`
+	EnclaveId string `protobuf:"bytes,5,opt,name=enclave_id,json=enclaveId,proto3" json:"enclave_id,omitempty"`
+	// chaincode event set by the chaincode (if any); committed as chaincode event by __endorse
+	Event         *FPCEvent `protobuf:"bytes,6,opt,name=event,proto3" json:"event,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -716,6 +728,77 @@ func (x *ChaincodeResponseMessage) GetEnclaveId() string {
 	return ""
 }
 
+func (x *ChaincodeResponseMessage) GetEvent() *FPCEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+// FPCEvent carries a confidential chaincode event
+type FPCEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name of the event; note that the name is not encrypted
+	EventName string `protobuf:"bytes,1,opt,name=event_name,json=eventName,proto3" json:"event_name,omitempty"`
+	// an encryption (symmetric) of the event payload with KeyTransportMessage.response_encryption_key or with a chaincode-defined event key
+	EncryptedPayload []byte `protobuf:"bytes,2,opt,name=encrypted_payload,json=encryptedPayload,proto3" json:"encrypted_payload,omitempty"`
+	// identifies the chaincode-defined event key used for the encryption; empty if KeyTransportMessage.response_encryption_key is used
+	EventKeyId    string `protobuf:"bytes,3,opt,name=event_key_id,json=eventKeyId,proto3" json:"event_key_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FPCEvent) Reset() {
+	*x = FPCEvent{}
+	mi := &file_fpc_fpc_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FPCEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FPCEvent) ProtoMessage() {}
+
+func (x *FPCEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_fpc_fpc_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FPCEvent.ProtoReflect.Descriptor instead.
+func (*FPCEvent) Descriptor() ([]byte, []int) {
+	return file_fpc_fpc_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FPCEvent) GetEventName() string {
+	if x != nil {
+		return x.EventName
+	}
+	return ""
+}
+
+func (x *FPCEvent) GetEncryptedPayload() []byte {
+	if x != nil {
+		return x.EncryptedPayload
+	}
+	return nil
+}
+
+func (x *FPCEvent) GetEventKeyId() string {
+	if x != nil {
+		return x.EventKeyId
+	}
+	return ""
+}
+
 type SignedChaincodeResponseMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// binary encoding of a ChaincodeResponseMessage protobuf
@@ -728,7 +811,7 @@ type SignedChaincodeResponseMessage struct {
 
 func (x *SignedChaincodeResponseMessage) Reset() {
 	*x = SignedChaincodeResponseMessage{}
-	mi := &file_fpc_fpc_proto_msgTypes[11]
+	mi := &file_fpc_fpc_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -740,7 +823,7 @@ func (x *SignedChaincodeResponseMessage) String() string {
 func (*SignedChaincodeResponseMessage) ProtoMessage() {}
 
 func (x *SignedChaincodeResponseMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_fpc_fpc_proto_msgTypes[11]
+	mi := &file_fpc_fpc_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -753,7 +836,7 @@ func (x *SignedChaincodeResponseMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SignedChaincodeResponseMessage.ProtoReflect.Descriptor instead.
 func (*SignedChaincodeResponseMessage) Descriptor() ([]byte, []int) {
-	return file_fpc_fpc_proto_rawDescGZIP(), []int{11}
+	return file_fpc_fpc_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *SignedChaincodeResponseMessage) GetChaincodeResponseMessage() []byte {
@@ -770,6 +853,207 @@ func (x *SignedChaincodeResponseMessage) GetSignature() []byte {
 	return nil
 }
 
+// FPCDeploymentPolicy restricts the enclaves that can be registered at ERCC for a FPC chaincode
+type FPCDeploymentPolicy struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// MSP IDs of the organizations that may host an enclave; if empty, any organization may host an enclave
+	AllowedMspIds []string `protobuf:"bytes,1,rep,name=allowed_msp_ids,json=allowedMspIds,proto3" json:"allowed_msp_ids,omitempty"`
+	// maximum number of enclaves registered for the current chaincode definition; 0 means no limit
+	MaxEnclaves uint32 `protobuf:"varint,2,opt,name=max_enclaves,json=maxEnclaves,proto3" json:"max_enclaves,omitempty"`
+	// maximum number of enclaves per organization registered for the current chaincode definition; 0 means no limit
+	MaxEnclavesPerMsp uint32 `protobuf:"varint,3,opt,name=max_enclaves_per_msp,json=maxEnclavesPerMsp,proto3" json:"max_enclaves_per_msp,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *FPCDeploymentPolicy) Reset() {
+	*x = FPCDeploymentPolicy{}
+	mi := &file_fpc_fpc_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FPCDeploymentPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FPCDeploymentPolicy) ProtoMessage() {}
+
+func (x *FPCDeploymentPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_fpc_fpc_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FPCDeploymentPolicy.ProtoReflect.Descriptor instead.
+func (*FPCDeploymentPolicy) Descriptor() ([]byte, []int) {
+	return file_fpc_fpc_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FPCDeploymentPolicy) GetAllowedMspIds() []string {
+	if x != nil {
+		return x.AllowedMspIds
+	}
+	return nil
+}
+
+func (x *FPCDeploymentPolicy) GetMaxEnclaves() uint32 {
+	if x != nil {
+		return x.MaxEnclaves
+	}
+	return 0
+}
+
+func (x *FPCDeploymentPolicy) GetMaxEnclavesPerMsp() uint32 {
+	if x != nil {
+		return x.MaxEnclavesPerMsp
+	}
+	return 0
+}
+
+// AttestationVerificationPolicy defines which attestation results ERCC accepts when registering enclaves for a FPC chaincode
+type AttestationVerificationPolicy struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// TCB status (e.g., UpToDate, SWHardeningNeeded) of the enclave platform that are accepted; if empty, only UpToDate is accepted
+	AcceptedTcbStatus []string `protobuf:"bytes,1,rep,name=accepted_tcb_status,json=acceptedTcbStatus,proto3" json:"accepted_tcb_status,omitempty"`
+	// advisory IDs (e.g., INTEL-SA-00334) that may apply to an accepted platform whose TCB status is not UpToDate
+	AllowedAdvisoryIds []string `protobuf:"bytes,2,rep,name=allowed_advisory_ids,json=allowedAdvisoryIds,proto3" json:"allowed_advisory_ids,omitempty"`
+	// mrenclaves (hex) of enclave builds that are accepted in addition to the mrenclave of the chaincode definition
+	AllowedMrenclaves []string `protobuf:"bytes,3,rep,name=allowed_mrenclaves,json=allowedMrenclaves,proto3" json:"allowed_mrenclaves,omitempty"`
+	// signers of enclave builds that are accepted in addition to the mrenclave of the chaincode definition
+	AllowedSigners []*EnclaveSignerPolicy `protobuf:"bytes,4,rep,name=allowed_signers,json=allowedSigners,proto3" json:"allowed_signers,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AttestationVerificationPolicy) Reset() {
+	*x = AttestationVerificationPolicy{}
+	mi := &file_fpc_fpc_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AttestationVerificationPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AttestationVerificationPolicy) ProtoMessage() {}
+
+func (x *AttestationVerificationPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_fpc_fpc_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AttestationVerificationPolicy.ProtoReflect.Descriptor instead.
+func (*AttestationVerificationPolicy) Descriptor() ([]byte, []int) {
+	return file_fpc_fpc_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AttestationVerificationPolicy) GetAcceptedTcbStatus() []string {
+	if x != nil {
+		return x.AcceptedTcbStatus
+	}
+	return nil
+}
+
+func (x *AttestationVerificationPolicy) GetAllowedAdvisoryIds() []string {
+	if x != nil {
+		return x.AllowedAdvisoryIds
+	}
+	return nil
+}
+
+func (x *AttestationVerificationPolicy) GetAllowedMrenclaves() []string {
+	if x != nil {
+		return x.AllowedMrenclaves
+	}
+	return nil
+}
+
+func (x *AttestationVerificationPolicy) GetAllowedSigners() []*EnclaveSignerPolicy {
+	if x != nil {
+		return x.AllowedSigners
+	}
+	return nil
+}
+
+// EnclaveSignerPolicy accepts all enclaves of a product signed by the same key with a minimum security version
+type EnclaveSignerPolicy struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// mrsigner (hex) of the enclave
+	Mrsigner string `protobuf:"bytes,1,opt,name=mrsigner,proto3" json:"mrsigner,omitempty"`
+	// product id of the enclave
+	IsvProdId uint32 `protobuf:"varint,2,opt,name=isv_prod_id,json=isvProdId,proto3" json:"isv_prod_id,omitempty"`
+	// minimum security version of the enclave
+	MinIsvSvn     uint32 `protobuf:"varint,3,opt,name=min_isv_svn,json=minIsvSvn,proto3" json:"min_isv_svn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnclaveSignerPolicy) Reset() {
+	*x = EnclaveSignerPolicy{}
+	mi := &file_fpc_fpc_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnclaveSignerPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnclaveSignerPolicy) ProtoMessage() {}
+
+func (x *EnclaveSignerPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_fpc_fpc_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnclaveSignerPolicy.ProtoReflect.Descriptor instead.
+func (*EnclaveSignerPolicy) Descriptor() ([]byte, []int) {
+	return file_fpc_fpc_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *EnclaveSignerPolicy) GetMrsigner() string {
+	if x != nil {
+		return x.Mrsigner
+	}
+	return ""
+}
+
+func (x *EnclaveSignerPolicy) GetIsvProdId() uint32 {
+	if x != nil {
+		return x.IsvProdId
+	}
+	return 0
+}
+
+func (x *EnclaveSignerPolicy) GetMinIsvSvn() uint32 {
+	if x != nil {
+		return x.MinIsvSvn
+	}
+	return 0
+}
+
 var File_fpc_fpc_proto protoreflect.FileDescriptor
 
 const file_fpc_fpc_proto_rawDesc = "" +
@@ -800,9 +1084,13 @@ const file_fpc_fpc_proto_rawDesc = "" +
 	"\bevidence\x18\x03 \x01(\fR\bevidence\"h\n" +
 	"\x12InitEnclaveMessage\x12#\n" +
 	"\rpeer_endpoint\x18\x01 \x01(\tR\fpeerEndpoint\x12-\n" +
-	"\x12attestation_params\x18\x02 \x01(\fR\x11attestationParams\"I\n" +
+	"\x12attestation_params\x18\x02 \x01(\fR\x11attestationParams\"\xe1\x01\n" +
 	"\x19CleartextChaincodeRequest\x12,\n" +
-	"\x05input\x18\x01 \x01(\v2\x16.protos.ChaincodeInputR\x05input\"\x8d\x01\n" +
+	"\x05input\x18\x01 \x01(\v2\x16.protos.ChaincodeInputR\x05input\x12U\n" +
+	"\rtransient_map\x18\x02 \x03(\v20.fpc.CleartextChaincodeRequest.TransientMapEntryR\ftransientMap\x1a?\n" +
+	"\x11TransientMapEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"\x8d\x01\n" +
 	"\x17ChaincodeRequestMessage\x12+\n" +
 	"\x11encrypted_request\x18\x01 \x01(\fR\x10encryptedRequest\x12E\n" +
 	"\x1fencrypted_key_transport_message\x18\x02 \x01(\fR\x1cencryptedKeyTransportMessage\"\x83\x01\n" +
@@ -813,7 +1101,7 @@ const file_fpc_fpc_proto_rawDesc = "" +
 	"\bresponse\x18\x01 \x01(\v2\x10.protos.ResponseR\bresponse\"_\n" +
 	"\bFPCKVSet\x12'\n" +
 	"\x06rw_set\x18\x01 \x01(\v2\x10.kvrwset.KVRWSetR\x05rwSet\x12*\n" +
-	"\x11read_value_hashes\x18\x02 \x03(\fR\x0freadValueHashes\"\x8e\x02\n" +
+	"\x11read_value_hashes\x18\x02 \x03(\fR\x0freadValueHashes\"\xb3\x02\n" +
 	"\x18ChaincodeResponseMessage\x12-\n" +
 	"\x12encrypted_response\x18\x01 \x01(\fR\x11encryptedResponse\x12+\n" +
 	"\n" +
@@ -821,10 +1109,30 @@ const file_fpc_fpc_proto_rawDesc = "" +
 	"\bproposal\x18\x03 \x01(\v2\x16.protos.SignedProposalR\bproposal\x12C\n" +
 	"\x1echaincode_request_message_hash\x18\x04 \x01(\fR\x1bchaincodeRequestMessageHash\x12\x1d\n" +
 	"\n" +
-	"enclave_id\x18\x05 \x01(\tR\tenclaveId\"|\n" +
+	"enclave_id\x18\x05 \x01(\tR\tenclaveId\x12#\n" +
+	"\x05event\x18\x06 \x01(\v2\r.fpc.FPCEventR\x05event\"x\n" +
+	"\bFPCEvent\x12\x1d\n" +
+	"\n" +
+	"event_name\x18\x01 \x01(\tR\teventName\x12+\n" +
+	"\x11encrypted_payload\x18\x02 \x01(\fR\x10encryptedPayload\x12 \n" +
+	"\fevent_key_id\x18\x03 \x01(\tR\n" +
+	"eventKeyId\"|\n" +
 	"\x1eSignedChaincodeResponseMessage\x12<\n" +
 	"\x1achaincode_response_message\x18\x01 \x01(\fR\x18chaincodeResponseMessage\x12\x1c\n" +
-	"\tsignature\x18\x02 \x01(\fR\tsignatureBAZ?github.com/hyperledger/fabric-private-chaincode/internal/protosb\x06proto3"
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"\x91\x01\n" +
+	"\x13FPCDeploymentPolicy\x12&\n" +
+	"\x0fallowed_msp_ids\x18\x01 \x03(\tR\rallowedMspIds\x12!\n" +
+	"\fmax_enclaves\x18\x02 \x01(\rR\vmaxEnclaves\x12/\n" +
+	"\x14max_enclaves_per_msp\x18\x03 \x01(\rR\x11maxEnclavesPerMsp\"\xf3\x01\n" +
+	"\x1dAttestationVerificationPolicy\x12.\n" +
+	"\x13accepted_tcb_status\x18\x01 \x03(\tR\x11acceptedTcbStatus\x120\n" +
+	"\x14allowed_advisory_ids\x18\x02 \x03(\tR\x12allowedAdvisoryIds\x12-\n" +
+	"\x12allowed_mrenclaves\x18\x03 \x03(\tR\x11allowedMrenclaves\x12A\n" +
+	"\x0fallowed_signers\x18\x04 \x03(\v2\x18.fpc.EnclaveSignerPolicyR\x0eallowedSigners\"q\n" +
+	"\x13EnclaveSignerPolicy\x12\x1a\n" +
+	"\bmrsigner\x18\x01 \x01(\tR\bmrsigner\x12\x1e\n" +
+	"\visv_prod_id\x18\x02 \x01(\rR\tisvProdId\x12\x1e\n" +
+	"\vmin_isv_svn\x18\x03 \x01(\rR\tminIsvSvnBAZ?github.com/hyperledger/fabric-private-chaincode/internal/protosb\x06proto3"
 
 var (
 	file_fpc_fpc_proto_rawDescOnce sync.Once
@@ -838,7 +1146,7 @@ func file_fpc_fpc_proto_rawDescGZIP() []byte {
 	return file_fpc_fpc_proto_rawDescData
 }
 
-var file_fpc_fpc_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_fpc_fpc_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_fpc_fpc_proto_goTypes = []any{
 	(*CCParameters)(nil),                   // 0: fpc.CCParameters
 	(*HostParameters)(nil),                 // 1: fpc.HostParameters
@@ -851,27 +1159,35 @@ var file_fpc_fpc_proto_goTypes = []any{
 	(*CleartextChaincodeResponse)(nil),     // 8: fpc.CleartextChaincodeResponse
 	(*FPCKVSet)(nil),                       // 9: fpc.FPCKVSet
 	(*ChaincodeResponseMessage)(nil),       // 10: fpc.ChaincodeResponseMessage
-	(*SignedChaincodeResponseMessage)(nil), // 11: fpc.SignedChaincodeResponseMessage
-	(*anypb.Any)(nil),                      // 12: google.protobuf.Any
-	(*peer.ChaincodeInput)(nil),            // 13: protos.ChaincodeInput
-	(*peer.Response)(nil),                  // 14: protos.Response
-	(*kvrwset.KVRWSet)(nil),                // 15: kvrwset.KVRWSet
-	(*peer.SignedProposal)(nil),            // 16: protos.SignedProposal
+	(*FPCEvent)(nil),                       // 11: fpc.FPCEvent
+	(*SignedChaincodeResponseMessage)(nil), // 12: fpc.SignedChaincodeResponseMessage
+	(*FPCDeploymentPolicy)(nil),            // 13: fpc.FPCDeploymentPolicy
+	(*AttestationVerificationPolicy)(nil),  // 14: fpc.AttestationVerificationPolicy
+	(*EnclaveSignerPolicy)(nil),            // 15: fpc.EnclaveSignerPolicy
+	nil,                                    // 16: fpc.CleartextChaincodeRequest.TransientMapEntry
+	(*anypb.Any)(nil),                      // 17: google.protobuf.Any
+	(*peer.ChaincodeInput)(nil),            // 18: protos.ChaincodeInput
+	(*peer.Response)(nil),                  // 19: protos.Response
+	(*kvrwset.KVRWSet)(nil),                // 20: kvrwset.KVRWSet
+	(*peer.SignedProposal)(nil),            // 21: protos.SignedProposal
 }
 var file_fpc_fpc_proto_depIdxs = []int32{
 	0,  // 0: fpc.AttestedData.cc_params:type_name -> fpc.CCParameters
 	1,  // 1: fpc.AttestedData.host_params:type_name -> fpc.HostParameters
-	12, // 2: fpc.Credentials.serialized_attested_data:type_name -> google.protobuf.Any
-	13, // 3: fpc.CleartextChaincodeRequest.input:type_name -> protos.ChaincodeInput
-	14, // 4: fpc.CleartextChaincodeResponse.response:type_name -> protos.Response
-	15, // 5: fpc.FPCKVSet.rw_set:type_name -> kvrwset.KVRWSet
-	9,  // 6: fpc.ChaincodeResponseMessage.fpc_rw_set:type_name -> fpc.FPCKVSet
-	16, // 7: fpc.ChaincodeResponseMessage.proposal:type_name -> protos.SignedProposal
-	8,  // [8:8] is the sub-list for method output_type
-	8,  // [8:8] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	17, // 2: fpc.Credentials.serialized_attested_data:type_name -> google.protobuf.Any
+	18, // 3: fpc.CleartextChaincodeRequest.input:type_name -> protos.ChaincodeInput
+	16, // 4: fpc.CleartextChaincodeRequest.transient_map:type_name -> fpc.CleartextChaincodeRequest.TransientMapEntry
+	19, // 5: fpc.CleartextChaincodeResponse.response:type_name -> protos.Response
+	20, // 6: fpc.FPCKVSet.rw_set:type_name -> kvrwset.KVRWSet
+	9,  // 7: fpc.ChaincodeResponseMessage.fpc_rw_set:type_name -> fpc.FPCKVSet
+	21, // 8: fpc.ChaincodeResponseMessage.proposal:type_name -> protos.SignedProposal
+	11, // 9: fpc.ChaincodeResponseMessage.event:type_name -> fpc.FPCEvent
+	15, // 10: fpc.AttestationVerificationPolicy.allowed_signers:type_name -> fpc.EnclaveSignerPolicy
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_fpc_fpc_proto_init() }
@@ -885,7 +1201,7 @@ func file_fpc_fpc_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_fpc_fpc_proto_rawDesc), len(file_fpc_fpc_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   12,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
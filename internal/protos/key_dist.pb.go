@@ -157,8 +157,10 @@ type ExportMessage struct {
 	ReceiverEnclaveVk []byte `protobuf:"bytes,4,opt,name=receiver_enclave_vk,json=receiverEnclaveVk,proto3" json:"receiver_enclave_vk,omitempty"`
 	// sender (creator) of this export message
 	SenderEnclaveVk []byte `protobuf:"bytes,5,opt,name=sender_enclave_vk,json=senderEnclaveVk,proto3" json:"sender_enclave_vk,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// serialization of type Credentials of the sender, which binds sender_enclave_vk to the attestation of the sender
+	SenderCredentials []byte `protobuf:"bytes,6,opt,name=sender_credentials,json=senderCredentials,proto3" json:"sender_credentials,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *ExportMessage) Reset() {
@@ -226,6 +228,13 @@ func (x *ExportMessage) GetSenderEnclaveVk() []byte {
 	return nil
 }
 
+func (x *ExportMessage) GetSenderCredentials() []byte {
+	if x != nil {
+		return x.SenderCredentials
+	}
+	return nil
+}
+
 type SignedExportMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// serialization of type ExportMessage
@@ -280,6 +289,142 @@ func (x *SignedExportMessage) GetSignature() []byte {
 	return nil
 }
 
+type CCKeys struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// symmetric state encryption key
+	StateKey []byte `protobuf:"bytes,1,opt,name=state_key,json=stateKey,proto3" json:"state_key,omitempty"`
+	// private chaincode decryption key
+	ChaincodeDk []byte `protobuf:"bytes,2,opt,name=chaincode_dk,json=chaincodeDk,proto3" json:"chaincode_dk,omitempty"`
+	// public chaincode encryption key
+	ChaincodeEk   []byte `protobuf:"bytes,3,opt,name=chaincode_ek,json=chaincodeEk,proto3" json:"chaincode_ek,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CCKeys) Reset() {
+	*x = CCKeys{}
+	mi := &file_fpc_key_dist_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CCKeys) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CCKeys) ProtoMessage() {}
+
+func (x *CCKeys) ProtoReflect() protoreflect.Message {
+	mi := &file_fpc_key_dist_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CCKeys.ProtoReflect.Descriptor instead.
+func (*CCKeys) Descriptor() ([]byte, []int) {
+	return file_fpc_key_dist_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CCKeys) GetStateKey() []byte {
+	if x != nil {
+		return x.StateKey
+	}
+	return nil
+}
+
+func (x *CCKeys) GetChaincodeDk() []byte {
+	if x != nil {
+		return x.ChaincodeDk
+	}
+	return nil
+}
+
+func (x *CCKeys) GetChaincodeEk() []byte {
+	if x != nil {
+		return x.ChaincodeEk
+	}
+	return nil
+}
+
+// SealedEnclaveState is the enclave state that is sealed to persist it across enclave restarts
+type SealedEnclaveState struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// private enclave signing key
+	EnclaveSk []byte `protobuf:"bytes,1,opt,name=enclave_sk,json=enclaveSk,proto3" json:"enclave_sk,omitempty"`
+	// public enclave signing key
+	EnclaveVk []byte `protobuf:"bytes,2,opt,name=enclave_vk,json=enclaveVk,proto3" json:"enclave_vk,omitempty"`
+	// serialization of the Credentials created at enclave initialization
+	Credentials []byte `protobuf:"bytes,3,opt,name=credentials,proto3" json:"credentials,omitempty"`
+	// chaincode keys; absent as long as the enclave is not provisioned
+	CcKeys        *CCKeys `protobuf:"bytes,4,opt,name=cc_keys,json=ccKeys,proto3" json:"cc_keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SealedEnclaveState) Reset() {
+	*x = SealedEnclaveState{}
+	mi := &file_fpc_key_dist_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SealedEnclaveState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SealedEnclaveState) ProtoMessage() {}
+
+func (x *SealedEnclaveState) ProtoReflect() protoreflect.Message {
+	mi := &file_fpc_key_dist_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SealedEnclaveState.ProtoReflect.Descriptor instead.
+func (*SealedEnclaveState) Descriptor() ([]byte, []int) {
+	return file_fpc_key_dist_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SealedEnclaveState) GetEnclaveSk() []byte {
+	if x != nil {
+		return x.EnclaveSk
+	}
+	return nil
+}
+
+func (x *SealedEnclaveState) GetEnclaveVk() []byte {
+	if x != nil {
+		return x.EnclaveVk
+	}
+	return nil
+}
+
+func (x *SealedEnclaveState) GetCredentials() []byte {
+	if x != nil {
+		return x.Credentials
+	}
+	return nil
+}
+
+func (x *SealedEnclaveState) GetCcKeys() *CCKeys {
+	if x != nil {
+		return x.CcKeys
+	}
+	return nil
+}
+
 var File_fpc_key_dist_proto protoreflect.FileDescriptor
 
 const file_fpc_key_dist_proto_rawDesc = "" +
@@ -292,17 +437,29 @@ const file_fpc_key_dist_proto_rawDesc = "" +
 	"enclave_id\x18\x03 \x01(\fR\tenclaveId\"\x8d\x01\n" +
 	"\x1eSignedCCKeyRegistrationMessage\x12M\n" +
 	"\x18serialized_cckey_reg_msg\x18\x01 \x01(\v2\x14.google.protobuf.AnyR\x15serializedCckeyRegMsg\x12\x1c\n" +
-	"\tsignature\x18\x02 \x01(\fR\tsignature\"\xd3\x01\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"\x82\x02\n" +
 	"\rExportMessage\x12$\n" +
 	"\x0ecc_params_hash\x18\x01 \x01(\fR\fccParamsHash\x12!\n" +
 	"\fchaincode_ek\x18\x02 \x01(\fR\vchaincodeEk\x12\x1d\n" +
 	"\n" +
 	"cckeys_enc\x18\x03 \x01(\fR\tcckeysEnc\x12.\n" +
 	"\x13receiver_enclave_vk\x18\x04 \x01(\fR\x11receiverEnclaveVk\x12*\n" +
-	"\x11sender_enclave_vk\x18\x05 \x01(\fR\x0fsenderEnclaveVk\"\x88\x01\n" +
+	"\x11sender_enclave_vk\x18\x05 \x01(\fR\x0fsenderEnclaveVk\x12-\n" +
+	"\x12sender_credentials\x18\x06 \x01(\fR\x11senderCredentials\"\x88\x01\n" +
 	"\x13SignedExportMessage\x12S\n" +
 	"\x1bserialized_export_msg_bytes\x18\x01 \x01(\v2\x14.google.protobuf.AnyR\x18serializedExportMsgBytes\x12\x1c\n" +
-	"\tsignature\x18\x02 \x01(\fR\tsignatureBAZ?github.com/hyperledger/fabric-private-chaincode/internal/protosb\x06proto3"
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"k\n" +
+	"\x06CCKeys\x12\x1b\n" +
+	"\tstate_key\x18\x01 \x01(\fR\bstateKey\x12!\n" +
+	"\fchaincode_dk\x18\x02 \x01(\fR\vchaincodeDk\x12!\n" +
+	"\fchaincode_ek\x18\x03 \x01(\fR\vchaincodeEk\"\xa7\x01\n" +
+	"\x12SealedEnclaveState\x12\x1d\n" +
+	"\n" +
+	"enclave_sk\x18\x01 \x01(\fR\tenclaveSk\x12\x1d\n" +
+	"\n" +
+	"enclave_vk\x18\x02 \x01(\fR\tenclaveVk\x12 \n" +
+	"\vcredentials\x18\x03 \x01(\fR\vcredentials\x121\n" +
+	"\acc_keys\x18\x04 \x01(\v2\x18.key_distribution.CCKeysR\x06ccKeysBAZ?github.com/hyperledger/fabric-private-chaincode/internal/protosb\x06proto3"
 
 var (
 	file_fpc_key_dist_proto_rawDescOnce sync.Once
@@ -316,22 +473,25 @@ func file_fpc_key_dist_proto_rawDescGZIP() []byte {
 	return file_fpc_key_dist_proto_rawDescData
 }
 
-var file_fpc_key_dist_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_fpc_key_dist_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_fpc_key_dist_proto_goTypes = []any{
 	(*CCKeyRegistrationMessage)(nil),       // 0: key_distribution.CCKeyRegistrationMessage
 	(*SignedCCKeyRegistrationMessage)(nil), // 1: key_distribution.SignedCCKeyRegistrationMessage
 	(*ExportMessage)(nil),                  // 2: key_distribution.ExportMessage
 	(*SignedExportMessage)(nil),            // 3: key_distribution.SignedExportMessage
-	(*anypb.Any)(nil),                      // 4: google.protobuf.Any
+	(*CCKeys)(nil),                         // 4: key_distribution.CCKeys
+	(*SealedEnclaveState)(nil),             // 5: key_distribution.SealedEnclaveState
+	(*anypb.Any)(nil),                      // 6: google.protobuf.Any
 }
 var file_fpc_key_dist_proto_depIdxs = []int32{
-	4, // 0: key_distribution.SignedCCKeyRegistrationMessage.serialized_cckey_reg_msg:type_name -> google.protobuf.Any
-	4, // 1: key_distribution.SignedExportMessage.serialized_export_msg_bytes:type_name -> google.protobuf.Any
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	6, // 0: key_distribution.SignedCCKeyRegistrationMessage.serialized_cckey_reg_msg:type_name -> google.protobuf.Any
+	6, // 1: key_distribution.SignedExportMessage.serialized_export_msg_bytes:type_name -> google.protobuf.Any
+	4, // 2: key_distribution.SealedEnclaveState.cc_keys:type_name -> key_distribution.CCKeys
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_fpc_key_dist_proto_init() }
@@ -345,7 +505,7 @@ func file_fpc_key_dist_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_fpc_key_dist_proto_rawDesc), len(file_fpc_key_dist_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -11,12 +11,17 @@ import (
 	"fmt"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/peer/lifecycle"
 	"github.com/hyperledger/fabric/protoutil"
 )
 
 const MrEnclaveLength = 32
 
+// ReadConflictStatus is the status of the __endorse response if the state read by the enclave changed after __invoke,
+// i.e., if the transaction may succeed when rerun
+const ReadConflictStatus = 409
+
 func GetChaincodeDefinition(chaincodeId string, stub shim.ChaincodeStubInterface) (*lifecycle.QueryChaincodeDefinitionResult, error) {
 	channelId := stub.GetChannelID()
 
@@ -43,6 +48,18 @@ func GetChaincodeDefinition(chaincodeId string, stub shim.ChaincodeStubInterface
 	return UnmarshalQueryChaincodeDefinitionResult(resp.Payload)
 }
 
+// GetChannelConfig returns the current configuration of the channel of the transaction as maintained by cscc
+func GetChannelConfig(stub shim.ChaincodeStubInterface) (*common.Config, error) {
+	channelId := stub.GetChannelID()
+
+	resp := stub.InvokeChaincode("cscc", [][]byte{[]byte("GetChannelConfig"), []byte(channelId)}, channelId)
+	if resp.Status != shim.OK {
+		return nil, fmt.Errorf("error while retrieving channel config: [%d] %s", resp.Status, resp.Message)
+	}
+
+	return UnmarshalConfig(resp.Payload)
+}
+
 func GetMrEnclave(chaincodeId string, stub shim.ChaincodeStubInterface) (string, error) {
 	ccDef, err := GetChaincodeDefinition(chaincodeId, stub)
 	if err != nil {
@@ -7,13 +7,26 @@ SPDX-License-Identifier: Apache-2.0
 package utils
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"sort"
+	"strings"
 
+	protoV1 "github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/protoutil"
+	"google.golang.org/protobuf/proto"
 )
 
+// adminOU is the organizational unit identifying admins when NodeOUs are enabled
+const adminOU = "admin"
+
 type IdentityEvaluatorInterface interface {
 	EvaluateCreatorIdentity(creatorIdentityBytes []byte, ownerMSP string) error
+	EvaluateAdminIdentity(creatorIdentityBytes []byte, stub shim.ChaincodeStubInterface) (mspId string, err error)
 }
 
 type IdentityEvaluator struct {
@@ -34,6 +47,119 @@ func (id *IdentityEvaluator) EvaluateCreatorIdentity(creatorIdentityBytes []byte
 	return nil
 }
 
+// EvaluateAdminIdentity checks that an identity is an admin of its organization and returns the msp id of the identity.
+// An identity is considered an admin if its certificate carries the admin OU (see Fabric NodeOUs) or if its certificate
+// is one of the admin certificates of its MSP in the channel configuration.
+// This function requires marshalled msp.SerializedIdentity as input.
+func (id *IdentityEvaluator) EvaluateAdminIdentity(creatorIdentityBytes []byte, stub shim.ChaincodeStubInterface) (string, error) {
+	sID, err := protoutil.UnmarshalSerializedIdentity(creatorIdentityBytes)
+	if err != nil {
+		return "", fmt.Errorf("error while deserialzing creator identity, err: %s", err)
+	}
+
+	block, _ := pem.Decode(sID.IdBytes)
+	if block == nil {
+		return "", fmt.Errorf("creator identity does not contain a certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse creator certificate, err: %s", err)
+	}
+
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if strings.EqualFold(ou, adminOU) {
+			return sID.Mspid, nil
+		}
+	}
+
+	admins, err := getMSPAdmins(sID.Mspid, stub)
+	if err != nil {
+		return "", fmt.Errorf("cannot get admins of msp %s, err: %s", sID.Mspid, err)
+	}
+	for _, admin := range admins {
+		if bytes.Equal(admin.Raw, cert.Raw) {
+			return sID.Mspid, nil
+		}
+	}
+
+	return "", fmt.Errorf("creator is not an admin")
+}
+
+// GetChannelMSPIDs returns the MSP IDs of the application organizations of the channel
+func GetChannelMSPIDs(stub shim.ChaincodeStubInterface) ([]string, error) {
+	configs, err := getApplicationMSPConfigs(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	var mspIds []string
+	for _, c := range configs {
+		mspIds = append(mspIds, c.GetName())
+	}
+	sort.Strings(mspIds)
+	return mspIds, nil
+}
+
+// getMSPAdmins returns the admin certificates of an MSP as defined in the channel configuration
+func getMSPAdmins(mspId string, stub shim.ChaincodeStubInterface) ([]*x509.Certificate, error) {
+	configs, err := getApplicationMSPConfigs(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range configs {
+		if c.GetName() != mspId {
+			continue
+		}
+
+		var admins []*x509.Certificate
+		for _, adminPEM := range c.GetAdmins() {
+			block, _ := pem.Decode(adminPEM)
+			if block == nil {
+				return nil, fmt.Errorf("invalid admin certificate")
+			}
+			admin, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			admins = append(admins, admin)
+		}
+		return admins, nil
+	}
+
+	return nil, fmt.Errorf("msp not found in channel config")
+}
+
+// getApplicationMSPConfigs returns the MSP configurations of the application organizations of the channel
+func getApplicationMSPConfigs(stub shim.ChaincodeStubInterface) ([]*msp.FabricMSPConfig, error) {
+	config, err := GetChannelConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*msp.FabricMSPConfig
+	application := config.GetChannelGroup().GetGroups()["Application"]
+	for _, org := range application.GetGroups() {
+		mspValue, ok := org.GetValues()["MSP"]
+		if !ok {
+			continue
+		}
+
+		mspConfig := &msp.MSPConfig{}
+		if err := proto.Unmarshal(mspValue.GetValue(), protoV1.MessageV2(mspConfig)); err != nil {
+			return nil, err
+		}
+		fabricConfig := &msp.FabricMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.GetConfig(), protoV1.MessageV2(fabricConfig)); err != nil {
+			return nil, err
+		}
+		configs = append(configs, fabricConfig)
+	}
+
+	return configs, nil
+}
+
 func ExtractMSPID(serializedIdentityRaw []byte) (string, error) {
 	sID, err := protoutil.UnmarshalSerializedIdentity(serializedIdentityRaw)
 	if err != nil {
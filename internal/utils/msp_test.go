@@ -1,6 +1,18 @@
 package utils
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-private-chaincode/internal/utils/fakes"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/protoutil"
 	. "github.com/onsi/ginkgo"
@@ -43,4 +55,120 @@ var _ = Describe("Chaincode utils", func() {
 			})
 		})
 	})
+
+	Context("EvaluateAdminIdentity", func() {
+
+		var (
+			eval *IdentityEvaluator
+			stub *fakes.ChaincodeStub
+		)
+
+		BeforeEach(func() {
+			eval = &IdentityEvaluator{}
+			stub = &fakes.ChaincodeStub{}
+			stub.InvokeChaincodeReturns(shim.Success(channelConfig("someMSP")))
+		})
+
+		When("creatorIdentity is invalid", func() {
+			It("should return an error", func() {
+				_, err := eval.EvaluateAdminIdentity([]byte("someGarbageBytes"), stub)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+
+		When("creatorIdentity has no certificate", func() {
+			It("should return an error", func() {
+				sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "someMSP", IdBytes: []byte("noCert")})
+				_, err := eval.EvaluateAdminIdentity(sid, stub)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+
+		When("creator is not an admin", func() {
+			It("should return an error", func() {
+				sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "someMSP", IdBytes: newCert("client")})
+				_, err := eval.EvaluateAdminIdentity(sid, stub)
+				Expect(err).Should(MatchError("creator is not an admin"))
+			})
+		})
+
+		When("creator has the admin OU", func() {
+			It("should return the mspid", func() {
+				sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "someMSP", IdBytes: newCert("admin")})
+				mspId, err := eval.EvaluateAdminIdentity(sid, stub)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(mspId).To(Equal("someMSP"))
+				Expect(stub.InvokeChaincodeCallCount()).To(Equal(0))
+			})
+		})
+
+		When("creator is an admin of the msp in the channel config", func() {
+			It("should return the mspid", func() {
+				admin := newCert("client")
+				stub.InvokeChaincodeReturns(shim.Success(channelConfig("someMSP", admin)))
+				sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "someMSP", IdBytes: admin})
+				mspId, err := eval.EvaluateAdminIdentity(sid, stub)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(mspId).To(Equal("someMSP"))
+
+				name, args, channel := stub.InvokeChaincodeArgsForCall(0)
+				Expect(name).To(Equal("cscc"))
+				Expect(args[0]).To(Equal([]byte("GetChannelConfig")))
+				Expect(args[1]).To(Equal([]byte(channel)))
+			})
+		})
+
+		When("creator is an admin of another msp in the channel config", func() {
+			It("should return an error", func() {
+				admin := newCert("client")
+				stub.InvokeChaincodeReturns(shim.Success(channelConfig("otherMSP", admin)))
+				sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "someMSP", IdBytes: admin})
+				_, err := eval.EvaluateAdminIdentity(sid, stub)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+
+		When("channel config is not available", func() {
+			It("should return an error", func() {
+				stub.InvokeChaincodeReturns(shim.Error("access denied"))
+				sid := protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "someMSP", IdBytes: newCert("client")})
+				_, err := eval.EvaluateAdminIdentity(sid, stub)
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
 })
+
+// channelConfig returns a serialized channel config with an application organization of the given msp and admins
+func channelConfig(mspId string, admins ...[]byte) []byte {
+	fabricConfig := protoutil.MarshalOrPanic(&msp.FabricMSPConfig{Name: mspId, Admins: admins})
+	mspConfig := protoutil.MarshalOrPanic(&msp.MSPConfig{Config: fabricConfig})
+
+	return protoutil.MarshalOrPanic(&common.Config{
+		ChannelGroup: &common.ConfigGroup{
+			Groups: map[string]*common.ConfigGroup{
+				"Application": {
+					Groups: map[string]*common.ConfigGroup{
+						"SomeOrg": {Values: map[string]*common.ConfigValue{"MSP": {Value: mspConfig}}},
+					},
+				},
+			},
+		},
+	})
+}
+
+func newCert(ou string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "someUser", OrganizationalUnit: []string{ou}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -101,6 +101,18 @@ func UnmarshalQueryChaincodeDefinitionResult(data []byte) (*lifecycle.QueryChain
 	return df, nil
 }
 
+func UnmarshalConfig(data []byte) (*common.Config, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("Config is empty")
+	}
+
+	config := &common.Config{}
+	if err := proto.Unmarshal(data, protoV1.MessageV2(config)); err != nil {
+		return nil, errors.Wrap(err, "invalid Config")
+	}
+	return config, nil
+}
+
 func UnmarshalSignedChaincodeResponseMessage(data []byte) (*protos.SignedChaincodeResponseMessage, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("SignedChaincodeResponseMessage is empty")
@@ -127,6 +139,74 @@ func UnmarshalChaincodeResponseMessage(data []byte) (*protos.ChaincodeResponseMe
 	return msg, nil
 }
 
+func UnmarshalSignedCCKeyRegistrationMessage(data []byte) (*protos.SignedCCKeyRegistrationMessage, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("SignedCCKeyRegistrationMessage is empty")
+	}
+
+	msg := &protos.SignedCCKeyRegistrationMessage{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, errors.Wrap(err, "invalid SignedCCKeyRegistrationMessage")
+	}
+
+	return msg, nil
+}
+
+func UnmarshalCCKeyRegistrationMessage(serializedCCKeyRegMsg *anypb.Any) (*protos.CCKeyRegistrationMessage, error) {
+	if serializedCCKeyRegMsg == nil {
+		return nil, errors.New("CCKeyRegistrationMessage is empty")
+	}
+
+	msg := &protos.CCKeyRegistrationMessage{}
+	if err := serializedCCKeyRegMsg.UnmarshalTo(msg); err != nil {
+		return nil, errors.Wrap(err, "invalid CCKeyRegistrationMessage")
+	}
+
+	return msg, nil
+}
+
+func UnmarshalSignedExportMessage(data []byte) (*protos.SignedExportMessage, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("SignedExportMessage is empty")
+	}
+
+	msg := &protos.SignedExportMessage{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, errors.Wrap(err, "invalid SignedExportMessage")
+	}
+
+	return msg, nil
+}
+
+func UnmarshalExportMessage(serializedExportMsg *anypb.Any) (*protos.ExportMessage, error) {
+	if serializedExportMsg == nil {
+		return nil, errors.New("ExportMessage is empty")
+	}
+
+	msg := &protos.ExportMessage{}
+	if err := serializedExportMsg.UnmarshalTo(msg); err != nil {
+		return nil, errors.Wrap(err, "invalid ExportMessage")
+	}
+
+	return msg, nil
+}
+
+// GetCCParamsHash returns the SHA256 hash over the deterministic serialization of cc_params.
+// This hash defines the context of key distribution messages.
+func GetCCParamsHash(ccParams *protos.CCParameters) ([]byte, error) {
+	if ccParams == nil {
+		return nil, errors.New("cc_params is empty")
+	}
+
+	ccParamsBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(ccParams)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot serialize cc_params")
+	}
+
+	h := sha256.Sum256(ccParamsBytes)
+	return h[:], nil
+}
+
 // GetEnclaveId returns enclave_id as hex-encoded string of SHA256 hash over enclave_vk.
 func GetEnclaveId(attestedData *protos.AttestedData) string {
 	// hash enclave vk
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/sha256"
+	"hash"
+)
+
+// RangeQueryHasher computes the hash over the results of a range query.
+// The enclave records this hash as part of the RangeQueryInfo and the validator re-computes it
+// when replaying the range query to detect modified, added (phantom), or removed keys.
+type RangeQueryHasher struct {
+	h hash.Hash
+}
+
+func NewRangeQueryHasher() *RangeQueryHasher {
+	return &RangeQueryHasher{h: sha256.New()}
+}
+
+// Add adds a query result given as (FPC) key and the hash of its (encrypted) value
+func (r *RangeQueryHasher) Add(key string, valueHash []byte) {
+	// note that we use the key hash to obtain an unambiguous encoding
+	keyHash := sha256.Sum256([]byte(key))
+	r.h.Write(keyHash[:])
+	r.h.Write(valueHash)
+}
+
+// Sum returns the hash over all query results added so far
+func (r *RangeQueryHasher) Sum() []byte {
+	return r.h.Sum(nil)
+}